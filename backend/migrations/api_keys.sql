@@ -0,0 +1,20 @@
+USE trill;
+
+-- API keys for bots and integrations. Only a SHA-256 hash of each key is stored.
+
+CREATE TABLE api_keys (
+    id char(36) NOT NULL,
+    username varchar(128),
+    name varchar(64),
+    prefix varchar(16),
+    key_hash char(64),
+    scopes varchar(512),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    last_used_at datetime(3) NULL,
+    revoked_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_api_keys_key_hash (key_hash),
+    INDEX idx_api_keys_username (username),
+    INDEX idx_api_keys_revoked_at (revoked_at),
+    CONSTRAINT fk_api_keys_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
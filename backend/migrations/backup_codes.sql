@@ -0,0 +1,16 @@
+USE trill;
+
+-- One-time codes for logging in without the authenticator app. Each code is stored as a SHA-256 hash
+-- of a per-code salt and the code, and used_at is set once it has logged someone in.
+
+CREATE TABLE backup_codes (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    salt char(32),
+    code_hash char(64),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    used_at datetime(3),
+    PRIMARY KEY (id),
+    INDEX idx_backup_codes_username (username),
+    CONSTRAINT fk_backup_codes_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
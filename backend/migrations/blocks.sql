@@ -0,0 +1,14 @@
+USE trill;
+
+-- Blocks, which hide each user from the other everywhere. The blocked index serves the checks that run
+-- in both directions.
+
+CREATE TABLE blocks (
+    blocker varchar(128) NOT NULL,
+    blocked varchar(128) NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker, blocked),
+    INDEX idx_blocks_blocked (blocked),
+    CONSTRAINT fk_blocks_blocker FOREIGN KEY (blocker) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_blocks_blocked FOREIGN KEY (blocked) REFERENCES users (username) ON UPDATE CASCADE
+);
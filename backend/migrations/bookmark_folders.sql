@@ -0,0 +1,18 @@
+USE trill;
+
+-- Users can sort their bookmarks into named folders. A bookmark is in at most one folder, and deleting
+-- a folder keeps its bookmarks, just outside any folder.
+
+CREATE TABLE bookmark_folders (
+    folder_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    name varchar(50),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (folder_id),
+    UNIQUE INDEX idx_bookmark_folders_username_name (username, name),
+    CONSTRAINT fk_bookmark_folders_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
+
+ALTER TABLE bookmarks
+    ADD COLUMN folder_id bigint,
+    ADD INDEX idx_bookmarks_folder_id (folder_id);
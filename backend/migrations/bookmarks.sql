@@ -0,0 +1,15 @@
+USE trill;
+
+-- Trills users have saved for later. Bookmarks are private, so unlike likes there's no count on the trill.
+
+CREATE TABLE bookmarks (
+    bookmark_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    trill_id bigint,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bookmark_id),
+    UNIQUE INDEX idx_bookmarks_username_trill_id (username, trill_id),
+    INDEX idx_bookmarks_trill_id (trill_id),
+    CONSTRAINT fk_bookmarks_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_bookmarks_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+USE trill;
+
+-- Data export jobs. The worker fills in object_key, the ZIP's key in the exports bucket, once the
+-- export is complete, or error if it failed.
+
+CREATE TABLE data_exports (
+    export_id varchar(32) NOT NULL,
+    username varchar(128),
+    status varchar(16),
+    object_key varchar(512),
+    error varchar(1024),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    completed_at datetime(3) NULL,
+    PRIMARY KEY (export_id),
+    INDEX idx_data_exports_username (username),
+    CONSTRAINT fk_data_exports_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
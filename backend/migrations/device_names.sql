@@ -0,0 +1,11 @@
+USE trill;
+
+-- Names users give their remembered Cognito devices, since Cognito can't rename them
+
+CREATE TABLE device_names (
+    username varchar(128) NOT NULL,
+    device_key varchar(64) NOT NULL,
+    name varchar(64),
+    updated_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, device_key)
+);
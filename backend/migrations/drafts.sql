@@ -0,0 +1,22 @@
+USE trill;
+
+-- Trills users have started and not posted yet. The media keys, alt text, and poll options are stored
+-- as JSON, the same way they'd be sent in a trill request.
+
+CREATE TABLE drafts (
+    draft_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    text varchar(280),
+    media text,
+    gif varchar(1024),
+    alt_text text,
+    poll_options text,
+    poll_duration_minutes int NOT NULL DEFAULT 0,
+    parent_id bigint,
+    quote_of_id bigint,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (draft_id),
+    INDEX idx_drafts_username (username),
+    CONSTRAINT fk_drafts_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
@@ -0,0 +1,8 @@
+USE trill;
+
+-- When each follow was made, so followers and following lists can be keyset paginated most recent first.
+-- Follows from before this all get the time it runs; the username breaks the tie.
+
+ALTER TABLE follows ADD COLUMN created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3);
+ALTER TABLE follows ADD INDEX idx_follows_following_created_at (following, created_at);
+ALTER TABLE follows ADD INDEX idx_follows_followee_created_at (followee, created_at);
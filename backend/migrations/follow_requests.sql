@@ -0,0 +1,16 @@
+USE trill;
+
+-- Private accounts, and the follow requests they have to approve. A request is deleted once it's approved
+-- or declined; approving one adds the follow.
+
+ALTER TABLE users ADD COLUMN is_private boolean NOT NULL DEFAULT false;
+
+CREATE TABLE follow_requests (
+    requester varchar(128) NOT NULL,
+    target varchar(128) NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (requester, target),
+    INDEX idx_follow_requests_target (target),
+    CONSTRAINT fk_follow_requests_requester FOREIGN KEY (requester) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_follow_requests_target FOREIGN KEY (target) REFERENCES users (username) ON UPDATE CASCADE
+);
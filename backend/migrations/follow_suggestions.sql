@@ -0,0 +1,18 @@
+USE trill;
+
+-- Who-to-follow suggestions, replaced every few hours by the suggestionAggregator worker. Each user keeps
+-- their top candidates by friends-of-friends overlap and hashtags used in common, with both counts kept
+-- so clients can say why an account was suggested.
+
+CREATE TABLE follow_suggestions (
+    username varchar(128) NOT NULL,
+    suggested varchar(128) NOT NULL,
+    score double NOT NULL DEFAULT 0,
+    mutual_follows bigint NOT NULL DEFAULT 0,
+    shared_hashtags bigint NOT NULL DEFAULT 0,
+    computed_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, suggested),
+    INDEX idx_follow_suggestions_suggested (suggested),
+    CONSTRAINT fk_follow_suggestions_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_follow_suggestions_suggested FOREIGN KEY (suggested) REFERENCES users (username) ON UPDATE CASCADE
+);
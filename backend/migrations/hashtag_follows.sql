@@ -0,0 +1,14 @@
+USE trill;
+
+-- Hashtags users follow. Public top-level trills using a followed tag are merged into the follower's home
+-- timeline when it's read, so nothing is fanned out for them.
+
+CREATE TABLE hashtag_follows (
+    username varchar(128) NOT NULL,
+    hashtag_id bigint NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, hashtag_id),
+    INDEX idx_hashtag_follows_hashtag_id (hashtag_id),
+    CONSTRAINT fk_hashtag_follows_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_hashtag_follows_hashtag_id FOREIGN KEY (hashtag_id) REFERENCES hashtags (hashtag_id)
+);
@@ -0,0 +1,22 @@
+USE trill;
+
+-- Hashtags parsed out of trill text when it's posted. Tags are stored once, lowercased, and linked to
+-- the trills that use them; the link's key leads with the hashtag so a tag page reads newest first by
+-- trill ID.
+
+CREATE TABLE hashtags (
+    hashtag_id bigint NOT NULL AUTO_INCREMENT,
+    tag varchar(100),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (hashtag_id),
+    UNIQUE INDEX idx_hashtags_tag (tag)
+);
+
+CREATE TABLE trill_hashtags (
+    hashtag_id bigint NOT NULL,
+    trill_id bigint NOT NULL,
+    PRIMARY KEY (hashtag_id, trill_id),
+    INDEX idx_trill_hashtags_trill_id (trill_id),
+    CONSTRAINT fk_trill_hashtags_hashtag_id FOREIGN KEY (hashtag_id) REFERENCES hashtags (hashtag_id),
+    CONSTRAINT fk_trill_hashtags_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
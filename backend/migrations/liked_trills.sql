@@ -0,0 +1,8 @@
+USE trill;
+
+-- Whether other users can list the trills a user has liked, and an index for paging through them newest
+-- first. Settings saved before this keep likes visible.
+
+ALTER TABLE user_settings ADD COLUMN show_liked_trills boolean NOT NULL DEFAULT true;
+
+CREATE INDEX idx_trill_likes_username_created_at ON trill_likes (username, created_at);
@@ -0,0 +1,22 @@
+USE trill;
+
+-- Open Graph previews of pages linked from trills, cached by a hash of the URL and shared by every trill
+-- that links the same page. trills.link_preview_id is set by the linkPreviews worker once the page is
+-- fetched, for the first link in the text.
+
+CREATE TABLE link_previews (
+    link_preview_id bigint NOT NULL AUTO_INCREMENT,
+    url_hash char(64) NOT NULL,
+    url varchar(2048),
+    title varchar(300),
+    description varchar(1000),
+    image_url varchar(2048),
+    site_name varchar(128),
+    status varchar(16),
+    fetched_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (link_preview_id),
+    UNIQUE INDEX idx_link_previews_url_hash (url_hash)
+);
+
+ALTER TABLE trills ADD COLUMN link_preview_id bigint;
+ALTER TABLE trills ADD CONSTRAINT fk_trills_link_preview_id FOREIGN KEY (link_preview_id) REFERENCES link_previews (link_preview_id) ON DELETE SET NULL;
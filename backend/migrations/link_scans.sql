@@ -0,0 +1,16 @@
+USE trill;
+
+-- The linkScanner worker checks each short link against the blocklist and Safe Browsing after the trill
+-- is posted or edited. A flagged link keeps its threat and stops redirecting, the trill is labeled with
+-- link_warning, and a malicious_link report pointing at the trill goes to the moderation queue.
+
+ALTER TABLE short_links
+    ADD COLUMN threat varchar(32),
+    ADD COLUMN scanned_at datetime(3),
+    ADD INDEX idx_short_links_url_hash (url_hash);
+
+ALTER TABLE trills ADD COLUMN link_warning varchar(32);
+
+ALTER TABLE reports
+    ADD COLUMN trill_id bigint,
+    ADD INDEX idx_reports_trill_id (trill_id);
@@ -0,0 +1,25 @@
+USE trill;
+
+-- Lists are collections of users someone curates, each with a timeline of its members' trills.
+-- member_count is kept up as members are added and removed.
+
+CREATE TABLE lists (
+    list_id bigint NOT NULL AUTO_INCREMENT,
+    owner varchar(128),
+    name varchar(50),
+    description varchar(200),
+    is_private boolean NOT NULL DEFAULT false,
+    member_count bigint NOT NULL DEFAULT 0,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (list_id),
+    INDEX idx_lists_owner (owner)
+);
+
+CREATE TABLE list_members (
+    list_id bigint NOT NULL,
+    username varchar(128) NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (list_id, username),
+    INDEX idx_list_members_username (username)
+);
@@ -0,0 +1,27 @@
+USE trill;
+
+-- Where each user has logged in from, for spotting logins from somewhere new, and the single-use
+-- links in new login emails that sign the user out everywhere. Only a SHA-256 hash of each link's token is stored.
+
+CREATE TABLE login_locations (
+    username varchar(128) NOT NULL,
+    network varchar(64) NOT NULL,
+    fingerprint char(64) NOT NULL,
+    source_ip varchar(45),
+    user_agent varchar(512),
+    first_seen_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, network, fingerprint),
+    CONSTRAINT fk_login_locations_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
+
+CREATE TABLE secure_account_tokens (
+    token_hash char(64) NOT NULL,
+    username varchar(128),
+    cognito_username varchar(128),
+    expires_at datetime(3),
+    PRIMARY KEY (token_hash),
+    INDEX idx_secure_account_tokens_username (username),
+    INDEX idx_secure_account_tokens_expires_at (expires_at),
+    CONSTRAINT fk_secure_account_tokens_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
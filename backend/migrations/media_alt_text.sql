@@ -0,0 +1,8 @@
+USE trill;
+
+-- Alt text for trill images, videos, and GIFs, and a setting that stops a user from posting media
+-- without it.
+
+ALTER TABLE media ADD COLUMN alt_text varchar(1000) NOT NULL DEFAULT '';
+
+ALTER TABLE user_settings ADD COLUMN require_alt_text boolean NOT NULL DEFAULT false;
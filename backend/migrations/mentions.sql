@@ -0,0 +1,34 @@
+USE trill;
+
+-- @mentions of existing users in trills, and the notifications they send. Offsets aren't stored; they're
+-- found again in the trill's text when it's shown. Notifications are generic so later kinds can share
+-- the table, and mention notifications can be turned off in settings.
+
+CREATE TABLE mentions (
+    trill_id bigint NOT NULL,
+    username varchar(128) NOT NULL,
+    PRIMARY KEY (trill_id, username),
+    INDEX idx_mentions_username (username),
+    CONSTRAINT fk_mentions_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_mentions_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
+
+CREATE TABLE notifications (
+    notification_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    type varchar(32),
+    actor varchar(128),
+    trill_id bigint,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    read_at datetime(3) NULL,
+    PRIMARY KEY (notification_id),
+    INDEX idx_notifications_username (username),
+    INDEX idx_notifications_actor (actor),
+    INDEX idx_notifications_trill_id (trill_id),
+    INDEX idx_notifications_read_at (read_at),
+    CONSTRAINT fk_notifications_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_notifications_actor FOREIGN KEY (actor) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_notifications_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
+
+ALTER TABLE user_settings ADD COLUMN notify_mentions boolean NOT NULL DEFAULT true;
@@ -0,0 +1,13 @@
+USE trill;
+
+-- Mutes, which only hide the muted account from the muter's timelines and notifications.
+
+CREATE TABLE mutes (
+    muter varchar(128) NOT NULL,
+    muted varchar(128) NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter, muted),
+    INDEX idx_mutes_muted (muted),
+    CONSTRAINT fk_mutes_muter FOREIGN KEY (muter) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_mutes_muted FOREIGN KEY (muted) REFERENCES users (username) ON UPDATE CASCADE
+);
@@ -0,0 +1,8 @@
+USE trill;
+
+-- The lowercased handle every username lookup compares on, so @Alice and @alice are the same account.
+-- Existing rows are filled before the unique index goes on; it fails if two users differ only in case.
+
+ALTER TABLE users ADD COLUMN normalized_username varchar(128);
+UPDATE users SET normalized_username = LOWER(username);
+CREATE UNIQUE INDEX idx_users_normalized_username ON users (normalized_username);
@@ -0,0 +1,50 @@
+USE trill;
+
+-- OAuth2 apps registered by users, and the codes and tokens issued to them. Client secrets,
+-- codes, and tokens are only stored as SHA-256 hashes.
+
+CREATE TABLE o_auth_apps (
+    client_id char(36) NOT NULL,
+    client_secret_hash char(64),
+    owner varchar(128),
+    name varchar(64),
+    redirect_uris varchar(1024),
+    scopes varchar(512),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    revoked_at datetime(3) NULL,
+    PRIMARY KEY (client_id),
+    INDEX idx_o_auth_apps_owner (owner),
+    INDEX idx_o_auth_apps_revoked_at (revoked_at),
+    CONSTRAINT fk_o_auth_apps_owner FOREIGN KEY (owner) REFERENCES users (username) ON UPDATE CASCADE
+);
+
+CREATE TABLE o_auth_codes (
+    code_hash char(64) NOT NULL,
+    client_id char(36),
+    username varchar(128),
+    redirect_uri varchar(512),
+    scopes varchar(512),
+    code_challenge varchar(128),
+    expires_at datetime(3),
+    PRIMARY KEY (code_hash),
+    INDEX idx_o_auth_codes_client_id (client_id),
+    INDEX idx_o_auth_codes_expires_at (expires_at),
+    CONSTRAINT fk_o_auth_codes_client_id FOREIGN KEY (client_id) REFERENCES o_auth_apps (client_id),
+    CONSTRAINT fk_o_auth_codes_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
+
+CREATE TABLE o_auth_tokens (
+    token_hash char(64) NOT NULL,
+    kind varchar(16),
+    client_id char(36),
+    username varchar(128),
+    scopes varchar(512),
+    expires_at datetime(3),
+    revoked_at datetime(3) NULL,
+    PRIMARY KEY (token_hash),
+    INDEX idx_o_auth_tokens_client_id (client_id),
+    INDEX idx_o_auth_tokens_username (username),
+    INDEX idx_o_auth_tokens_expires_at (expires_at),
+    CONSTRAINT fk_o_auth_tokens_client_id FOREIGN KEY (client_id) REFERENCES o_auth_apps (client_id),
+    CONSTRAINT fk_o_auth_tokens_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
@@ -0,0 +1,15 @@
+USE trill;
+
+-- Recent POST /auth/forgot calls, counted per username and per source IP to rate limit them.
+-- Rows older than the window are deleted as new attempts come in.
+
+CREATE TABLE password_reset_attempts (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    source_ip varchar(45),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    INDEX idx_password_reset_attempts_username (username),
+    INDEX idx_password_reset_attempts_source_ip (source_ip),
+    INDEX idx_password_reset_attempts_created_at (created_at)
+);
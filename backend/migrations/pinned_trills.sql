@@ -0,0 +1,6 @@
+USE trill;
+
+-- The trill a user has pinned to the top of their profile. Deleting the trill unpins it.
+
+ALTER TABLE users ADD COLUMN pinned_trill_id bigint;
+ALTER TABLE users ADD CONSTRAINT fk_users_pinned_trill_id FOREIGN KEY (pinned_trill_id) REFERENCES trills (trill_id) ON DELETE SET NULL;
@@ -0,0 +1,32 @@
+USE trill;
+
+-- Polls on trills. Each option and each poll keeps its own vote count, and poll_votes holds one vote per
+-- user per poll.
+
+CREATE TABLE polls (
+    trill_id bigint NOT NULL PRIMARY KEY,
+    closes_at datetime(3) NOT NULL,
+    vote_count bigint NOT NULL DEFAULT 0,
+    INDEX idx_polls_closes_at (closes_at),
+    CONSTRAINT fk_polls_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_options (
+    trill_id bigint NOT NULL,
+    position int NOT NULL,
+    text varchar(25) NOT NULL,
+    vote_count bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (trill_id, position),
+    CONSTRAINT fk_poll_options_trill_id FOREIGN KEY (trill_id) REFERENCES polls (trill_id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes (
+    trill_id bigint NOT NULL,
+    username varchar(128) NOT NULL,
+    position int NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (trill_id, username),
+    INDEX idx_poll_votes_username (username),
+    CONSTRAINT fk_poll_votes_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_poll_votes_trill_id FOREIGN KEY (trill_id) REFERENCES polls (trill_id) ON DELETE CASCADE
+);
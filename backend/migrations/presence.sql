@@ -0,0 +1,9 @@
+USE trill;
+
+-- When each user was last active, and the setting that shares it with mutual followers. Settings saved
+-- before this get it turned on.
+
+ALTER TABLE users ADD COLUMN last_active_at datetime(3) NULL;
+
+ALTER TABLE user_settings ADD COLUMN show_activity_status boolean NOT NULL DEFAULT true;
+UPDATE user_settings SET show_activity_status = true;
@@ -0,0 +1,16 @@
+USE trill;
+
+-- Profile views, at most one per viewer per day, and the running count the profile owner sees.
+
+ALTER TABLE users ADD COLUMN view_count bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE profile_views (
+    username varchar(128) NOT NULL,
+    viewer varchar(128) NOT NULL,
+    day date NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, viewer, day),
+    INDEX idx_profile_views_viewer (viewer),
+    CONSTRAINT fk_profile_views_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_profile_views_viewer FOREIGN KEY (viewer) REFERENCES users (username) ON UPDATE CASCADE
+);
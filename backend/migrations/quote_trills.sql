@@ -0,0 +1,8 @@
+USE trill;
+
+-- Quotes are trills with content of their own that embed another trill. quote_count on the quoted
+-- trill is kept apart from retrill_count.
+
+ALTER TABLE trills ADD COLUMN quote_of_id bigint;
+ALTER TABLE trills ADD COLUMN quote_count bigint NOT NULL DEFAULT 0;
+CREATE INDEX idx_trills_quote_of_id ON trills (quote_of_id);
@@ -0,0 +1,22 @@
+USE trill;
+
+-- Emoji reactions on trills, one row per user per emoji per trill. reaction_counts holds how many users
+-- used each emoji on a trill, kept up as reactions come and go, so trills can show their counts without
+-- counting rows. Emoji are compared byte for byte, since the default collation treats some that look
+-- different, like skin tone variants, as equal.
+
+CREATE TABLE reactions (
+    trill_id bigint NOT NULL,
+    username varchar(128) NOT NULL,
+    emoji varchar(32) COLLATE utf8mb4_bin NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (trill_id, username, emoji),
+    INDEX idx_reactions_username (username)
+);
+
+CREATE TABLE reaction_counts (
+    trill_id bigint NOT NULL,
+    emoji varchar(32) COLLATE utf8mb4_bin NOT NULL,
+    count bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (trill_id, emoji)
+);
@@ -0,0 +1,10 @@
+USE trill;
+
+-- Authors can limit who may reply to a trill to their followers or the users it mentions. Drafts and
+-- scheduled trills keep the choice until they're posted, empty meaning everyone.
+
+ALTER TABLE trills ADD COLUMN reply_audience varchar(16) NOT NULL DEFAULT 'everyone';
+
+ALTER TABLE drafts ADD COLUMN reply_audience varchar(16);
+
+ALTER TABLE scheduled_trills ADD COLUMN reply_audience varchar(16);
@@ -0,0 +1,22 @@
+USE trill;
+
+-- User reports and the moderation queue they feed. resolved_by is the admin who dismissed or actioned
+-- the report.
+
+CREATE TABLE reports (
+    report_id bigint NOT NULL AUTO_INCREMENT,
+    reporter varchar(128),
+    reported varchar(128),
+    reason varchar(32),
+    details varchar(1024),
+    status varchar(16),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    resolved_at datetime(3) NULL,
+    resolved_by varchar(128),
+    PRIMARY KEY (report_id),
+    INDEX idx_reports_reporter (reporter),
+    INDEX idx_reports_reported (reported),
+    INDEX idx_reports_status (status),
+    CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_reports_reported FOREIGN KEY (reported) REFERENCES users (username) ON UPDATE CASCADE
+);
@@ -0,0 +1,9 @@
+USE trill;
+
+-- Retrills are rows in trills with no content that point at the original. Each user can retrill a
+-- trill once, which the unique index enforces; trills that aren't retrills leave retrill_of_id NULL.
+
+ALTER TABLE trills ADD COLUMN retrill_of_id bigint;
+ALTER TABLE trills ADD COLUMN retrill_count bigint NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX idx_trills_retrill ON trills (username, retrill_of_id);
+CREATE INDEX idx_trills_retrill_of_id ON trills (retrill_of_id);
@@ -0,0 +1,26 @@
+USE trill;
+
+-- Trills waiting to be posted by their EventBridge schedule. Failed ones stay, with the reason, until the
+-- user deletes them.
+
+CREATE TABLE scheduled_trills (
+    scheduled_trill_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    text varchar(280),
+    media text,
+    gif varchar(1024),
+    alt_text text,
+    poll_options text,
+    poll_duration_minutes int NOT NULL DEFAULT 0,
+    parent_id bigint,
+    quote_of_id bigint,
+    publish_at datetime(3),
+    schedule_name varchar(64),
+    status varchar(16),
+    error varchar(255),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (scheduled_trill_id),
+    INDEX idx_scheduled_trills_username (username),
+    INDEX idx_scheduled_trills_publish_at (publish_at),
+    CONSTRAINT fk_scheduled_trills_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
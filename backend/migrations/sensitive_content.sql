@@ -0,0 +1,19 @@
+USE trill;
+
+-- Authors can mark a trill sensitive, optionally with a content warning, or mark just its media. Viewers
+-- see sensitive media blurred unless they turn on show_sensitive_media.
+
+ALTER TABLE trills ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+ALTER TABLE trills ADD COLUMN content_warning varchar(100) NOT NULL DEFAULT '';
+
+ALTER TABLE media ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+
+ALTER TABLE drafts ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+ALTER TABLE drafts ADD COLUMN content_warning varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE drafts ADD COLUMN sensitive_media boolean NOT NULL DEFAULT false;
+
+ALTER TABLE scheduled_trills ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+ALTER TABLE scheduled_trills ADD COLUMN content_warning varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE scheduled_trills ADD COLUMN sensitive_media boolean NOT NULL DEFAULT false;
+
+ALTER TABLE user_settings ADD COLUMN show_sensitive_media boolean NOT NULL DEFAULT false;
@@ -0,0 +1,25 @@
+USE trill;
+
+-- Each distinct link in a trill's text gets a short link when the trill is posted or edited, and every
+-- click through the shortLinks redirect is logged and counted. Trills posted before this have none, so
+-- their links are shown as they are.
+
+CREATE TABLE short_links (
+    code varchar(16) NOT NULL,
+    trill_id bigint,
+    url_hash char(64),
+    url varchar(2048),
+    click_count bigint NOT NULL DEFAULT 0,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (code),
+    UNIQUE INDEX idx_short_links_trill_url (trill_id, url_hash)
+);
+
+CREATE TABLE link_clicks (
+    click_id bigint NOT NULL AUTO_INCREMENT,
+    code varchar(16),
+    referrer varchar(255),
+    clicked_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (click_id),
+    INDEX idx_link_clicks_code (code)
+);
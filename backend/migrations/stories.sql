@@ -0,0 +1,29 @@
+USE trill;
+
+-- Stories are posts that expire a day after they go up, each with one image or video from the same
+-- uploads trills use. Expired stories are hidden right away and hard deleted, views and media included,
+-- by the storyCleanup lambda.
+
+CREATE TABLE stories (
+    story_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    caption varchar(200),
+    view_count bigint NOT NULL DEFAULT 0,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at datetime(3),
+    PRIMARY KEY (story_id),
+    INDEX idx_stories_username (username),
+    INDEX idx_stories_expires_at (expires_at)
+);
+
+CREATE TABLE story_views (
+    story_id bigint NOT NULL,
+    username varchar(128) NOT NULL,
+    viewed_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (story_id, username),
+    INDEX idx_story_views_username (username)
+);
+
+ALTER TABLE media
+    ADD COLUMN story_id bigint,
+    ADD INDEX idx_media_story_id (story_id);
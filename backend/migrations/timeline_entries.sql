@@ -0,0 +1,28 @@
+USE trill;
+
+-- Materialized home timelines, written by the timelineFanout worker as trills are posted: one row per
+-- trill for its author and each of their followers, unless the author has 10,000 or more followers, in
+-- which case their trills are pulled in when the timeline is read. Reads are a range scan on the primary
+-- key. The last week of trills is backfilled so home timelines aren't empty once this ships.
+
+CREATE TABLE timeline_entries (
+    username varchar(128) NOT NULL,
+    trill_id bigint NOT NULL,
+    author varchar(128),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (username, trill_id),
+    INDEX idx_timeline_entries_trill_id (trill_id),
+    INDEX idx_timeline_entries_author (author)
+);
+
+INSERT IGNORE INTO timeline_entries (username, trill_id, author, created_at)
+SELECT trills.username, trills.trill_id, trills.username, trills.created_at
+FROM trills
+WHERE trills.created_at >= NOW() - INTERVAL 7 DAY;
+
+INSERT IGNORE INTO timeline_entries (username, trill_id, author, created_at)
+SELECT follows.followee, trills.trill_id, trills.username, trills.created_at
+FROM trills
+JOIN follows ON follows.following = trills.username
+JOIN users ON users.username = trills.username
+WHERE trills.created_at >= NOW() - INTERVAL 7 DAY AND users.follower_count < 10000;
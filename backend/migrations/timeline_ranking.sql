@@ -0,0 +1,6 @@
+USE trill;
+
+-- Whether the home timeline defaults to newest first or to the top ranking. Rows saved before this read
+-- as latest.
+
+ALTER TABLE user_settings ADD COLUMN timeline_ranking varchar(16);
@@ -0,0 +1,15 @@
+USE trill;
+
+-- Lets the authorizer turn away access tokens that were logged out before they expired.
+-- users.tokens_revoked_at is set by a global logout, and revoked_tokens holds single tokens until they expire.
+
+ALTER TABLE users ADD COLUMN tokens_revoked_at datetime(3) NULL;
+
+CREATE TABLE revoked_tokens (
+    token_id varchar(64) NOT NULL,
+    username varchar(128),
+    expires_at datetime(3),
+    PRIMARY KEY (token_id),
+    INDEX idx_revoked_tokens_username (username),
+    INDEX idx_revoked_tokens_expires_at (expires_at)
+);
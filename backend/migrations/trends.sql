@@ -0,0 +1,17 @@
+USE trill;
+
+-- Trending hashtags, replaced wholesale by the trendAggregator worker every run. Worldwide trends have an
+-- empty region; the rest are keyed by the country in their authors' locale settings. The key leads with the
+-- region so a region's trends are read in order.
+
+CREATE TABLE trends (
+    region varchar(2) NOT NULL,
+    position bigint NOT NULL,
+    hashtag_id bigint,
+    tag varchar(100),
+    score double NOT NULL DEFAULT 0,
+    trill_count bigint NOT NULL DEFAULT 0,
+    computed_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (region, position),
+    INDEX idx_trends_hashtag_id (hashtag_id)
+);
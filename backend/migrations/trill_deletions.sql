@@ -0,0 +1,18 @@
+USE trill;
+
+-- Bulk deletions of a user's trills, by ID or by the date range they were posted in. Ranges are worked
+-- through by the trillDeleter worker, which keeps deleted_count up as it goes.
+
+CREATE TABLE trill_deletions (
+    deletion_id varchar(32) NOT NULL,
+    username varchar(128),
+    status varchar(16),
+    `from` datetime(3),
+    `until` datetime(3),
+    deleted_count bigint NOT NULL DEFAULT 0,
+    error varchar(1024),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    completed_at datetime(3),
+    PRIMARY KEY (deletion_id),
+    INDEX idx_trill_deletions_username (username)
+);
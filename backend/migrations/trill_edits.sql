@@ -0,0 +1,16 @@
+USE trill;
+
+-- Trills can be edited for a short while after they're posted. edited_at is when the text last changed,
+-- and each replaced version of the text is kept in trill_revisions.
+
+ALTER TABLE trills ADD COLUMN edited_at datetime(3);
+
+CREATE TABLE trill_revisions (
+    revision_id bigint NOT NULL AUTO_INCREMENT,
+    trill_id bigint,
+    text varchar(280),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (revision_id),
+    INDEX idx_trill_revisions_trill_id (trill_id),
+    CONSTRAINT fk_trill_revisions_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
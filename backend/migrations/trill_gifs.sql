@@ -0,0 +1,6 @@
+USE trill;
+
+-- GIFs from the picker attached to trills. They're served by the provider, so their media rows have an
+-- external URL in place of an object key.
+
+ALTER TABLE media ADD COLUMN external_url varchar(1024);
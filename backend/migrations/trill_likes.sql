@@ -0,0 +1,16 @@
+USE trill;
+
+-- Likes on trills, one per user per trill, with a denormalized like_count on each trill. Review likes
+-- stay in the likes table.
+
+CREATE TABLE trill_likes (
+    username varchar(128) NOT NULL,
+    trill_id bigint NOT NULL,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, trill_id),
+    INDEX idx_trill_likes_trill_id (trill_id),
+    CONSTRAINT fk_trill_likes_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_trill_likes_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
+
+ALTER TABLE trills ADD COLUMN like_count bigint NOT NULL DEFAULT 0;
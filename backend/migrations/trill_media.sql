@@ -0,0 +1,39 @@
+USE trill;
+
+-- Moves trill images out of the comma-separated trills.media column into their own table. A row is made
+-- when an upload URL is handed out and linked to a trill when it's posted, with the image's dimensions.
+-- Images attached before this keep 0x0, since their dimensions were never read.
+
+CREATE TABLE media (
+    media_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    object_key varchar(255),
+    content_type varchar(64),
+    trill_id bigint NULL,
+    position bigint NOT NULL DEFAULT 0,
+    width bigint NOT NULL DEFAULT 0,
+    height bigint NOT NULL DEFAULT 0,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (media_id),
+    UNIQUE INDEX idx_media_object_key (object_key),
+    INDEX idx_media_username (username),
+    INDEX idx_media_trill_id (trill_id),
+    CONSTRAINT fk_media_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE,
+    CONSTRAINT fk_media_trill_id FOREIGN KEY (trill_id) REFERENCES trills (trill_id) ON DELETE CASCADE
+);
+
+INSERT INTO media (username, object_key, content_type, trill_id, position, created_at)
+SELECT t.username, m.object_key,
+    CASE SUBSTRING_INDEX(m.object_key, '.', -1)
+        WHEN 'png' THEN 'image/png'
+        WHEN 'gif' THEN 'image/gif'
+        WHEN 'webp' THEN 'image/webp'
+        ELSE 'image/jpeg'
+    END,
+    t.trill_id, m.position - 1, t.created_at
+FROM trills t,
+    JSON_TABLE(CONCAT('["', REPLACE(t.media, ',', '","'), '"]'), '$[*]'
+        COLUMNS (position FOR ORDINALITY, object_key varchar(255) PATH '$')) m
+WHERE t.media <> '';
+
+ALTER TABLE trills DROP COLUMN media;
@@ -0,0 +1,12 @@
+USE trill;
+
+-- Threaded replies. parent_id is the trill being replied to, and conversation_id is the trill that
+-- started the thread, which is the trill's own ID when it isn't a reply. reply_count only counts
+-- direct replies.
+
+ALTER TABLE trills ADD COLUMN parent_id bigint;
+ALTER TABLE trills ADD COLUMN conversation_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE trills ADD COLUMN reply_count bigint NOT NULL DEFAULT 0;
+UPDATE trills SET conversation_id = trill_id;
+CREATE INDEX idx_trills_parent_id ON trills (parent_id);
+CREATE INDEX idx_trills_conversation_id ON trills (conversation_id);
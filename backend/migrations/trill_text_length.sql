@@ -0,0 +1,9 @@
+USE trill;
+
+-- The trill length limit is now counted in user-perceived characters with each link counting as 23, and
+-- can be raised per deployment, so the text can be longer than 280 code points. The API caps it at 8 KB.
+
+ALTER TABLE trills MODIFY text text;
+ALTER TABLE trill_revisions MODIFY text text;
+ALTER TABLE drafts MODIFY text text;
+ALTER TABLE scheduled_trills MODIFY text text;
@@ -0,0 +1,15 @@
+USE trill;
+
+-- Deleting a trill that has replies leaves a tombstone with its place in the conversation, so the
+-- replies stay attached to the thread. Nothing of its content or author is kept.
+
+CREATE TABLE trill_tombstones (
+    trill_id bigint NOT NULL,
+    parent_id bigint,
+    conversation_id bigint NOT NULL,
+    reply_count bigint NOT NULL DEFAULT 0,
+    deleted_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (trill_id),
+    INDEX idx_trill_tombstones_parent_id (parent_id),
+    INDEX idx_trill_tombstones_conversation_id (conversation_id)
+);
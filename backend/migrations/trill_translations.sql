@@ -0,0 +1,13 @@
+USE trill;
+
+-- Trill text translated by Amazon Translate, cached per trill and language. Editing a trill deletes
+-- its translations.
+
+CREATE TABLE trill_translations (
+    trill_id bigint NOT NULL,
+    language varchar(16) NOT NULL,
+    source_language varchar(16),
+    text text,
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (trill_id, language)
+);
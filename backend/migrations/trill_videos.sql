@@ -0,0 +1,14 @@
+USE trill;
+
+-- Videos on trills. They're transcoded by MediaConvert after upload, so media rows now carry a kind and
+-- a status, along with the job that's transcoding them and the HLS and MP4 renditions it made. Everything
+-- already in the table is an image, and images are ready as soon as they're uploaded.
+
+ALTER TABLE media
+    ADD COLUMN kind varchar(16) NOT NULL DEFAULT 'image',
+    ADD COLUMN status varchar(16) NOT NULL DEFAULT 'ready',
+    ADD COLUMN duration_ms bigint NOT NULL DEFAULT 0,
+    ADD COLUMN job_id varchar(64),
+    ADD COLUMN hls_key varchar(255),
+    ADD COLUMN mp4_key varchar(255),
+    ADD INDEX idx_media_job_id (job_id);
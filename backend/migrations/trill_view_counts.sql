@@ -0,0 +1,6 @@
+USE trill;
+
+-- How many times each trill has been shown to someone other than its author. Impressions are queued as
+-- trills are read and added up in batches by the trillViews worker.
+
+ALTER TABLE trills ADD COLUMN view_count bigint NOT NULL DEFAULT 0;
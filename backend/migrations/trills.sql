@@ -0,0 +1,18 @@
+USE trill;
+
+-- Trills, the posts the app is named for. media holds comma-separated keys of images in the content
+-- bucket under trill-media/, and users.trill_count is kept in step like review_count.
+
+CREATE TABLE trills (
+    trill_id bigint NOT NULL AUTO_INCREMENT,
+    username varchar(128),
+    text varchar(280),
+    media varchar(1024),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (trill_id),
+    INDEX idx_trills_username (username),
+    CONSTRAINT fk_trills_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
+
+ALTER TABLE users ADD COLUMN trill_count bigint NOT NULL DEFAULT 0;
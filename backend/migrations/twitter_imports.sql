@@ -0,0 +1,33 @@
+USE trill;
+
+-- Imports of Twitter data exports. Each import tracks how far through the archive's tweets the worker has
+-- got, and each imported tweet remembers the trill it became, so a retried run doesn't post it twice and
+-- replies in a thread can find their parent. Imported trills say where they came from.
+
+CREATE TABLE twitter_imports (
+    import_id varchar(32) NOT NULL,
+    username varchar(128),
+    status varchar(16),
+    object_key varchar(512),
+    total_tweets bigint NOT NULL DEFAULT 0,
+    processed_tweets bigint NOT NULL DEFAULT 0,
+    imported_tweets bigint NOT NULL DEFAULT 0,
+    skipped_tweets bigint NOT NULL DEFAULT 0,
+    error varchar(1024),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP(3),
+    started_at datetime(3),
+    completed_at datetime(3),
+    PRIMARY KEY (import_id),
+    INDEX idx_twitter_imports_username (username)
+);
+
+CREATE TABLE imported_tweets (
+    username varchar(128) NOT NULL,
+    tweet_id varchar(32) NOT NULL,
+    trill_id bigint,
+    PRIMARY KEY (username, tweet_id),
+    INDEX idx_imported_tweets_trill_id (trill_id)
+);
+
+ALTER TABLE trills
+    ADD COLUMN imported_from varchar(16);
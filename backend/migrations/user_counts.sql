@@ -0,0 +1,13 @@
+USE trill;
+
+-- Follower, following, and review counts kept on the user row instead of counted per request, then
+-- backfilled for existing users.
+
+ALTER TABLE users ADD COLUMN follower_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN following_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN review_count bigint NOT NULL DEFAULT 0;
+
+UPDATE users SET
+    follower_count = (SELECT COUNT(*) FROM follows WHERE following = users.username),
+    following_count = (SELECT COUNT(*) FROM follows WHERE followee = users.username),
+    review_count = (SELECT COUNT(*) FROM reviews WHERE username = users.username);
@@ -0,0 +1,7 @@
+USE trill;
+
+-- Keeps each user's email in RDS so profile reads don't call Cognito GetUser. The Cognito triggers
+-- fill it in on confirmation and on every login, so existing users get theirs the next time they log in.
+
+ALTER TABLE users ADD COLUMN email varchar(320) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email_verified boolean NOT NULL DEFAULT false;
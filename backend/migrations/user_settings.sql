@@ -0,0 +1,21 @@
+USE trill;
+
+-- Per-user settings, kept apart from the public profile. Users without a row get the defaults in
+-- DefaultUserSettings, so a row only appears once someone saves their settings. muted_words is a JSON
+-- array of words and phrases.
+
+CREATE TABLE user_settings (
+    username varchar(128) NOT NULL,
+    notify_new_followers boolean NOT NULL DEFAULT true,
+    notify_review_likes boolean NOT NULL DEFAULT true,
+    notify_following_reviews boolean NOT NULL DEFAULT true,
+    email_notifications boolean NOT NULL DEFAULT false,
+    discoverable boolean NOT NULL DEFAULT true,
+    show_liked_reviews boolean NOT NULL DEFAULT true,
+    language varchar(16),
+    locale varchar(16),
+    timezone varchar(64),
+    muted_words text,
+    PRIMARY KEY (username),
+    CONSTRAINT fk_user_settings_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
@@ -0,0 +1,13 @@
+USE trill;
+
+-- Every handle a user has given up, pointing at the one they use now, so old links can redirect and
+-- nobody else can claim an old handle.
+
+CREATE TABLE username_histories (
+    old_username varchar(128) NOT NULL,
+    username varchar(128),
+    created_at datetime(3) DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (old_username),
+    INDEX idx_username_histories_username (username),
+    CONSTRAINT fk_username_histories_username FOREIGN KEY (username) REFERENCES users (username) ON UPDATE CASCADE
+);
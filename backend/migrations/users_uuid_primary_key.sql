@@ -0,0 +1,40 @@
+USE trill;
+
+-- Moves users onto an immutable UUID primary key. username stays unique, and the tables here that
+-- point at a user by handle get a foreign key to it with ON UPDATE CASCADE, so a rename only has to
+-- update the users row. Tables created by their own migrations declare the same key there.
+--
+-- Dependent tables deliberately keep storing the handle rather than re-keying to users(id): every
+-- model, query, and view in the API joins on username, so moving them over is its own change. id is
+-- what clients and other services should hold on to in the meantime.
+--
+-- Adding the foreign keys fails if any table still has rows for a username that isn't in users,
+-- so clean those up first.
+
+ALTER TABLE users ADD COLUMN id char(36) NULL FIRST;
+UPDATE users SET id = UUID() WHERE id IS NULL;
+ALTER TABLE users MODIFY id char(36) NOT NULL;
+
+-- the old foreign keys reference the primary key that's about to move
+ALTER TABLE follows DROP FOREIGN KEY FK_followee, DROP FOREIGN KEY FK_following;
+
+ALTER TABLE users
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (id),
+    ADD UNIQUE INDEX idx_users_username (username);
+
+ALTER TABLE follows
+    ADD CONSTRAINT FK_followee FOREIGN KEY (followee) REFERENCES users(username) ON UPDATE CASCADE,
+    ADD CONSTRAINT FK_following FOREIGN KEY (following) REFERENCES users(username) ON UPDATE CASCADE;
+
+ALTER TABLE reviews
+    ADD CONSTRAINT FK_reviews_username FOREIGN KEY (username) REFERENCES users(username) ON UPDATE CASCADE;
+
+ALTER TABLE likes
+    ADD CONSTRAINT FK_likes_username FOREIGN KEY (username) REFERENCES users(username) ON UPDATE CASCADE;
+
+ALTER TABLE favorite_albums
+    ADD CONSTRAINT FK_favorite_albums_username FOREIGN KEY (username) REFERENCES users(username) ON UPDATE CASCADE;
+
+ALTER TABLE listen_later_albums
+    ADD CONSTRAINT FK_listen_later_albums_username FOREIGN KEY (username) REFERENCES users(username) ON UPDATE CASCADE;
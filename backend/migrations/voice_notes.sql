@@ -0,0 +1,8 @@
+USE trill;
+
+-- Voice notes on trills. They're transcoded by MediaConvert like videos, into an M4A that goes in the
+-- mp4_key column, and a WAV the waveform is read from. The waveform is stored as comma separated levels
+-- from 0 to 100.
+
+ALTER TABLE media
+    ADD COLUMN waveform varchar(512);
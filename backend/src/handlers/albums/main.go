@@ -146,5 +146,5 @@ func search(ctx context.Context, req Request) (Response, error) {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
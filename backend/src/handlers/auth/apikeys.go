@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"trill/src/handlers"
+	"trill/src/models"
+)
+
+var (
+	ErrorInvalidAPIKey   = errors.New("invalid or revoked API key")
+	ErrorAPIKeyRoute     = errors.New("API keys and OAuth tokens can't be used for account management")
+	ErrorMissingScope    = errors.New("missing the scope for this route")
+	ErrorUnknownAPIRoute = errors.New("unknown route")
+)
+
+// Routes that change the account itself, which need the user's own tokens; all of /auth and /oauth is included
+var accountRoutes = map[string]bool{
+	"PUT /users/username":    true,
+	"DELETE /users":          true,
+	"POST /users/deactivate": true,
+	"POST /users/export":     true,
+	"GET /users/export":      true,
+}
+
+// read:<resource> for GET routes and write:<resource> for the rest, e.g. "POST /reviews" -> write:reviews.
+// Empty for routes no API key can call.
+func requiredScope(routeKey string) (string, error) {
+	method, path, found := strings.Cut(routeKey, " ")
+	if !found {
+		return "", ErrorUnknownAPIRoute
+	}
+	resource, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if resource == "auth" || resource == "oauth" || accountRoutes[routeKey] {
+		return "", nil
+	}
+
+	if method == "GET" {
+		return "read:" + resource, nil
+	}
+	return "write:" + resource, nil
+}
+
+// Authorizes a request made with an API key in place of an access token
+func verifyAPIKey(ctx context.Context, req Request, rawKey string) (Response, error) {
+	scope, err := requiredScope(req.RouteKey)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if scope == "" {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorAPIKeyRoute), nil
+	}
+
+	var initCtx context.Context
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	key, err := models.AuthenticateAPIKey(initCtx, rawKey)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if key == nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorInvalidAPIKey), nil
+	} else if !key.HasScope(scope) {
+		return generatePolicy("", nil, "Deny", req.RouteArn, fmt.Errorf("%w: %s", ErrorMissingScope, scope)), nil
+	}
+
+	if deactivated, err := models.IsUserDeactivated(initCtx, key.Username); err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if deactivated {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorDeactivated), nil
+	}
+
+	emailVerified, err := models.IsEmailVerified(initCtx, key.Username)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	// keys never carry roles, so a leaked key can't reach the moderation endpoints
+	responseContext := map[string]interface{}{
+		"username":      key.Username,
+		"apiKeyID":      key.ID,
+		"roles":         "",
+		"emailVerified": emailVerified,
+	}
+	return generatePolicy(key.Username, responseContext, "Allow", req.RouteArn, nil), nil
+}
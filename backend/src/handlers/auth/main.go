@@ -5,97 +5,174 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"trill/src/handlers"
+	"trill/src/models"
 	"trill/src/utils"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jwt"
+	"gorm.io/gorm"
 )
 
 type Request = events.APIGatewayV2CustomAuthorizerV2Request
 type Response = events.APIGatewayV2CustomAuthorizerIAMPolicyResponse
 
-type CognitoClient struct {
-	Client      *cognitoidentityprovider.Client
-	AppClientId string
-	UserPoolId  string
-}
-
 var (
 	ErrorAuthorizationHeader = errors.New("missing or invalid authorization header")
 	ErrorUsernameNotFound    = errors.New("username not found in token")
 	ErrorCantCastUsername    = errors.New("cannot cast username")
+	ErrorDeactivated         = errors.New("account is deactivated")
+	ErrorTokenRevoked        = errors.New("token has been revoked")
 )
 
-func initClient(ctx context.Context) (*CognitoClient, error) {
-	cfg, err := config.LoadDefaultConfig(
-		ctx, config.WithRegion("us-east-1"),
-	)
-	if err != nil {
-		return nil, err
-	}
+// How often the pool's signing keys are re-fetched; Cognito rotates them rarely
+var jwksRefreshInterval = time.Hour
 
-	secrets := utils.GetSecrets()
-	return &CognitoClient{
-		cognitoidentityprovider.NewFromConfig(cfg),
-		secrets.CognitoAppClientId,
-		secrets.CognitoUserPoolId,
-	}, nil
+var db *gorm.DB
+
+// Outlives a single invocation, so a warm authorizer checks signatures without any network calls
+var jwksCache = jwk.NewAutoRefresh(context.Background())
+
+func userPoolIssuer(secrets utils.Secrets) string {
+	region := secrets.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, secrets.CognitoUserPoolId)
 }
 
+// Validates the access token against the user pool's cached JWKS, without asking Cognito about it.
+// Requests with an X-Api-Key header, or an API key or third-party app's OAuth token in place of the
+// access token, are looked up instead.
 func verifyToken(ctx context.Context, req Request) (Response, error) {
-	cognitoClient, err := initClient(ctx)
-	if err != nil {
-		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorAuthorizationHeader), nil
+	if apiKey := req.Headers["x-api-key"]; apiKey != "" {
+		return verifyAPIKey(ctx, req, apiKey)
 	}
 
+	secrets := utils.GetSecrets()
+
 	authHeader := req.Headers["authorization"]
-	// fmt.Printf("authHeader: %s\n", authHeader)
 	splitAuthHeader := strings.Split(authHeader, " ")
 	if len(splitAuthHeader) != 2 {
 		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorAuthorizationHeader), nil
 	}
+	if strings.HasPrefix(splitAuthHeader[1], models.OAuthAccessTokenPrefix) {
+		return verifyOAuthToken(ctx, req, splitAuthHeader[1])
+	} else if strings.HasPrefix(splitAuthHeader[1], models.APIKeyPrefix) {
+		return verifyAPIKey(ctx, req, splitAuthHeader[1])
+	}
 
-	pubKeyURL := "https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json"
-	formattedURL := fmt.Sprintf(pubKeyURL, "us-east-1", cognitoClient.UserPoolId) // TODO: change region to var
-	keySet, err := jwk.Fetch(ctx, formattedURL)
+	issuer := userPoolIssuer(secrets)
+	jwksURL := issuer + "/.well-known/jwks.json"
+	if !jwksCache.IsRegistered(jwksURL) {
+		jwksCache.Configure(jwksURL, jwk.WithRefreshInterval(jwksRefreshInterval))
+	}
+	keySet, err := jwksCache.Fetch(ctx, jwksURL)
 	if err != nil {
 		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
 	}
 
-	// fmt.Println(splitAuthHeader[1])
 	token, err := jwt.Parse(
 		[]byte(splitAuthHeader[1]),
 		jwt.WithKeySet(keySet),
 		jwt.WithValidate(true),
+		jwt.WithIssuer(issuer),
 		jwt.WithClaimValue("token_use", "access"),
+		jwt.WithClaimValue("client_id", secrets.CognitoAppClientId),
 		jwt.WithRequiredClaim("username"),
 	)
 	if err != nil {
 		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
 	}
 
-	// fmt.Printf("Token value: %+v\n", token)
-
 	rawUsername, found := token.Get("username")
 	if !found {
 		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorUsernameNotFound), nil
 	}
-	username, ok := rawUsername.(string)
+	cognitoUsername, ok := rawUsername.(string)
 	if !ok {
 		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorCantCastUsername), nil
 	}
 
+	// Cognito usernames never change, so map them to the user's current handle
+	var initCtx context.Context
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+	username, err := models.ResolveUsername(initCtx, cognitoUsername)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	// access tokens outlive the sign out that comes with deactivating
+	if deactivated, err := models.IsUserDeactivated(initCtx, username); err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if deactivated {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorDeactivated), nil
+	}
+
+	// unverified accounts can read but not post
+	emailVerified, err := models.IsEmailVerified(initCtx, username)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	// logging out can't recall a JWT, so revoked tokens are checked for here
+	if revoked, err := models.IsTokenRevoked(initCtx, username, token.JwtID(), token.IssuedAt()); err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if revoked {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorTokenRevoked), nil
+	}
+
+	// presence is best effort, so it never fails the request
+	if err := models.TouchLastActive(initCtx, username); err != nil {
+		fmt.Printf("failed to update last active for %s: %s\n", username, err.Error())
+	}
+
 	responseContext := map[string]interface{}{
-		"username": username,
-		"userID":   token.Subject(),
+		"username":        username,
+		"cognitoUsername": cognitoUsername,
+		"userID":          token.Subject(),
+		"roles":           groups(token).String(),
+		"tokenID":         token.JwtID(),
+		"tokenExpiresAt":  token.Expiration().Unix(),
+		"deviceKey":       stringClaim(token, "device_key"),
+		"emailVerified":   emailVerified,
 	}
 	return generatePolicy(username, responseContext, "Allow", req.RouteArn, nil), nil
 }
 
+// Empty if the claim is missing, e.g. device_key for a login from a device Cognito doesn't remember
+func stringClaim(token jwt.Token, name string) string {
+	value, _ := token.Get(name)
+	claim, _ := value.(string)
+	return claim
+}
+
+// Cognito lists the user's groups in the cognito:groups claim, which is missing if they have none
+func groups(token jwt.Token) models.Roles {
+	rawGroups, found := token.Get("cognito:groups")
+	if !found {
+		return nil
+	}
+	groups, ok := rawGroups.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var roles models.Roles
+	for _, g := range groups {
+		if group, ok := g.(string); ok {
+			roles = append(roles, models.Role(group))
+		}
+	}
+	return roles
+}
+
 func generatePolicy(principalID string, responseContext map[string]interface{}, effect string, resource string, err error) Response {
 	authResponse := Response{PrincipalID: principalID}
 
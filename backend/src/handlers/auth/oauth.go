@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+)
+
+var (
+	ErrorInvalidOAuthToken = errors.New("invalid, expired, or revoked OAuth token")
+)
+
+// Authorizes a request made with an access token issued to a third-party app by /oauth/token
+func verifyOAuthToken(ctx context.Context, req Request, rawToken string) (Response, error) {
+	scope, err := requiredScope(req.RouteKey)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if scope == "" {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorAPIKeyRoute), nil
+	}
+
+	var initCtx context.Context
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	token, err := models.AuthenticateOAuthToken(initCtx, rawToken)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if token == nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorInvalidOAuthToken), nil
+	} else if !token.HasScope(scope) {
+		return generatePolicy("", nil, "Deny", req.RouteArn, fmt.Errorf("%w: %s", ErrorMissingScope, scope)), nil
+	}
+
+	if deactivated, err := models.IsUserDeactivated(initCtx, token.Username); err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	} else if deactivated {
+		return generatePolicy("", nil, "Deny", req.RouteArn, ErrorDeactivated), nil
+	}
+
+	emailVerified, err := models.IsEmailVerified(initCtx, token.Username)
+	if err != nil {
+		return generatePolicy("", nil, "Deny", req.RouteArn, err), nil
+	}
+
+	// apps never get the user's roles
+	responseContext := map[string]interface{}{
+		"username":      token.Username,
+		"oauthClientID": token.ClientID,
+		"roles":         "",
+		"emailVerified": emailVerified,
+	}
+	return generatePolicy(token.Username, responseContext, "Allow", req.RouteArn, nil), nil
+}
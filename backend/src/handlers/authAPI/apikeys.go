@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Lists the requestor's API keys, without the keys themselves
+// Postman: GET - /auth/keys
+func listAPIKeys(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	keys, err := models.GetAPIKeys(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalAPIKeys(ctx, keys)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Creates an API key with the given scopes; the response is the only time the key is shown
+// Postman: POST - /auth/keys
+func createAPIKey(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var keyReq views.APIKeyRequest
+	if err := views.UnmarshalAPIKeyRequest(ctx, req.Body, &keyReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	key, rawKey, err := models.CreateAPIKey(ctx, requestor, keyReq.Name, keyReq.Scopes)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalNewAPIKey(ctx, key, rawKey)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Replaces an API key's secret, e.g. after it leaked; the old one stops working right away
+// Postman: POST - /auth/keys/{keyID}/rotate
+func rotateAPIKey(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	key, rawKey, err := models.RotateAPIKey(ctx, requestor, req.PathParameters["keyID"])
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalNewAPIKey(ctx, key, rawKey)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Revokes an API key for good
+// Postman: DELETE - /auth/keys/{keyID}
+func revokeAPIKey(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.RevokeAPIKey(ctx, requestor, req.PathParameters["keyID"]); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "API key revoked successfully", Headers: views.DefaultHeaders}, nil
+}
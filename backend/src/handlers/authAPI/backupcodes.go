@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Replaces the user's backup codes with a fresh set; the old codes stop working
+// Postman: POST - /auth/mfa/backup-codes
+func issueBackupCodes(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	codes, err := models.IssueBackupCodes(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalBackupCodes(ctx, codes)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// How many of the user's backup codes are left
+// Postman: GET - /auth/mfa/backup-codes
+func getBackupCodeStatus(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	remaining, err := models.CountBackupCodes(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalBackupCodeStatus(ctx, remaining)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Logs in with a backup code when the authenticator app isn't available. This turns authenticator app
+// codes off, so the user can set the app up again once they're in.
+// Postman: POST - /auth/recover
+func recoverLogin(ctx context.Context, req Request) (Response, error) {
+	var recoverReq views.RecoverRequest
+	if err := views.UnmarshalRecoverRequest(ctx, req.Body, &recoverReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	auth, err := models.RecoverCognitoLogin(ctx, recoverReq.Username, recoverReq.Password, recoverReq.BackupCode)
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordLogin(ctx, req, auth.AuthenticationResult)
+	return tokensOrChallenge(ctx, auth.AuthenticationResult, string(auth.ChallengeName), aws.ToString(auth.Session))
+}
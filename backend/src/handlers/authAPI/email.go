@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Emails the user a code for verifying their address, which they need to do before posting
+// Postman: POST - /auth/email/code
+func sendEmailCode(ctx context.Context, req Request) (Response, error) {
+	details, err := models.SendEmailVerificationCode(ctx, handlers.AccessToken(req))
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalCodeDelivery(ctx, details)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Verifies the user's email with the code from POST /auth/email/code
+// Postman: POST - /auth/email/verify
+func verifyEmail(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var verify views.VerifyEmailRequest
+	if err := views.UnmarshalVerifyEmailRequest(ctx, req.Body, &verify); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.VerifyCognitoEmail(ctx, handlers.AccessToken(req), requestor, verify.Code); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "email verified successfully", Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Remembers where a login came from and emails the user if it's somewhere new. Best effort, since
+// none of it should stop someone from logging in.
+func recordLogin(ctx context.Context, req Request, tokens *types.AuthenticationResultType) {
+	if tokens == nil {
+		return
+	}
+
+	// Cognito only just issued the token, so there's no need to check its signature here
+	token, err := jwt.ParseString(aws.ToString(tokens.AccessToken))
+	if err != nil {
+		fmt.Printf("failed to parse access token to record login: %s\n", err.Error())
+		return
+	}
+	cognitoUsername, _ := token.Get("username")
+	cognitoUsernameString, _ := cognitoUsername.(string)
+
+	username, err := models.ResolveUsername(ctx, cognitoUsernameString)
+	if err != nil {
+		fmt.Printf("failed to record login for %s: %s\n", cognitoUsernameString, err.Error())
+		return
+	}
+
+	sourceIP, userAgent := req.RequestContext.HTTP.SourceIP, req.RequestContext.HTTP.UserAgent
+	newLocation, err := models.RecordLogin(ctx, username, sourceIP, userAgent)
+	if err != nil {
+		fmt.Printf("failed to record login for %s: %s\n", username, err.Error())
+		return
+	} else if !newLocation {
+		return
+	}
+
+	if err := models.NotifyNewLoginLocation(ctx, username, cognitoUsernameString, sourceIP, userAgent); err != nil {
+		fmt.Printf("failed to send new login email to %s: %s\n", username, err.Error())
+	}
+}
+
+// Signs the user out on every device from the link in a new login email; no access token needed,
+// since whoever has the account's tokens may not be the user
+// Postman: POST - /auth/secure
+func secureAccount(ctx context.Context, req Request) (Response, error) {
+	var secure views.SecureAccountRequest
+	if err := views.UnmarshalSecureAccountRequest(ctx, req.Body, &secure); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.SecureAccount(ctx, secure.Token); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "signed out on every device, now reset your password", Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,574 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Account endpoints. Most are for people who don't have an access token yet; /auth/password, /auth/logout,
+// /auth/mfa, /auth/devices, and /auth/keys sit behind the authorizer.
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /auth/mfa":
+			return getMFA(initCtx, req)
+		case "GET /auth/mfa/backup-codes":
+			return getBackupCodeStatus(initCtx, req)
+		case "GET /auth/devices":
+			return listDevices(initCtx, req)
+		case "GET /auth/keys":
+			return listAPIKeys(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "PUT":
+		switch req.RouteKey {
+		case "PUT /auth/mfa":
+			return setMFA(initCtx, req)
+		case "PUT /auth/devices/{deviceKey}":
+			return nameDevice(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		switch req.RouteKey {
+		case "DELETE /auth/devices/{deviceKey}":
+			return forgetDevice(initCtx, req)
+		case "DELETE /auth/keys/{keyID}":
+			return revokeAPIKey(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /auth/signup":
+			return signUp(initCtx, req)
+		case "POST /auth/confirm":
+			return confirmSignUp(initCtx, req)
+		case "POST /auth/resend":
+			return resendCode(initCtx, req)
+		case "POST /auth/login":
+			return login(initCtx, req)
+		case "POST /auth/challenge":
+			return answerChallenge(initCtx, req)
+		case "POST /auth/refresh":
+			return refresh(initCtx, req)
+		case "POST /auth/forgot":
+			return forgotPassword(initCtx, req)
+		case "POST /auth/reset":
+			return resetPassword(initCtx, req)
+		case "POST /auth/recover":
+			return recoverLogin(initCtx, req)
+		case "POST /auth/secure":
+			return secureAccount(initCtx, req)
+		case "POST /auth/password":
+			return changePassword(initCtx, req)
+		case "POST /auth/logout":
+			return logout(initCtx, req)
+		case "POST /auth/keys":
+			return createAPIKey(initCtx, req)
+		case "POST /auth/keys/{keyID}/rotate":
+			return rotateAPIKey(initCtx, req)
+		case "POST /auth/mfa/setup":
+			return setUpMFA(initCtx, req)
+		case "POST /auth/mfa/verify":
+			return verifyMFA(initCtx, req)
+		case "POST /auth/mfa/backup-codes":
+			return issueBackupCodes(initCtx, req)
+		case "POST /auth/email/code":
+			return sendEmailCode(initCtx, req)
+		case "POST /auth/email/verify":
+			return verifyEmail(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// Creates the Cognito user and their profile, and has Cognito send the confirmation code
+// Postman: POST - /auth/signup
+func signUp(ctx context.Context, req Request) (Response, error) {
+	var signUpReq views.SignUpRequest
+	if err := views.UnmarshalSignUpRequest(ctx, req.Body, &signUpReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.ValidateUsername(signUpReq.Username); err != nil {
+		reason := "invalid_username"
+		if models.IsUsernameReserved(signUpReq.Username) {
+			reason = "username_not_allowed"
+		}
+		return authErrorResponse(ctx, &models.AuthError{Code: 400, Reason: reason, Err: err})
+	}
+
+	if resp, ok := handlers.RequireCaptcha(ctx, req); !ok {
+		return resp, nil
+	}
+
+	// checked here too so most clashes never reach Cognito; the PreSignUp trigger is the real guard
+	if available, err := models.IsUsernameAvailable(ctx, signUpReq.Username, ""); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !available {
+		return authErrorResponse(ctx, &models.AuthError{Code: 409, Reason: "username_exists", Err: models.ErrorUsernameUnavailable})
+	}
+
+	result, err := models.SignUpCognitoUser(ctx, signUpReq.Username, signUpReq.Email, signUpReq.Password, signUpReq.Nickname)
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// holds the handle from the start; the PostConfirmation trigger leaves an existing row alone
+	user := models.User{
+		Username: signUpReq.Username,
+		Nickname: signUpReq.Nickname,
+		Email:    signUpReq.Email,
+	}
+	if err := models.CreateUserIfNotExists(ctx, &user); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalSignUpResult(ctx, signUpReq.Username, result.UserConfirmed, result.CodeDeliveryDetails)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Confirms the account with the code Cognito sent, after which the user can log in
+// Postman: POST - /auth/confirm
+func confirmSignUp(ctx context.Context, req Request) (Response, error) {
+	var confirm views.ConfirmSignUpRequest
+	if err := views.UnmarshalConfirmSignUpRequest(ctx, req.Body, &confirm); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.ConfirmCognitoSignUp(ctx, confirm.Username, confirm.Code); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "account confirmed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Sends a new confirmation code, e.g. when the first one expired or never arrived
+// Postman: POST - /auth/resend
+func resendCode(ctx context.Context, req Request) (Response, error) {
+	var resend views.ResendCodeRequest
+	if err := views.UnmarshalResendCodeRequest(ctx, req.Body, &resend); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	details, err := models.ResendCognitoConfirmationCode(ctx, resend.Username)
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalCodeDelivery(ctx, details)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Logs in with a username and password, returning tokens or the challenge Cognito wants answered first
+// Postman: POST - /auth/login
+func login(ctx context.Context, req Request) (Response, error) {
+	var loginReq views.LoginRequest
+	if err := views.UnmarshalLoginRequest(ctx, req.Body, &loginReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	auth, err := models.LoginCognitoUser(ctx, loginReq.Username, loginReq.Password)
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordLogin(ctx, req, auth.AuthenticationResult)
+	return tokensOrChallenge(ctx, auth.AuthenticationResult, string(auth.ChallengeName), aws.ToString(auth.Session))
+}
+
+// Answers a challenge from login: NEW_PASSWORD_REQUIRED with the user's new password, or SOFTWARE_TOKEN_MFA
+// with a code from their authenticator app
+// Postman: POST - /auth/challenge
+func answerChallenge(ctx context.Context, req Request) (Response, error) {
+	var response views.ChallengeResponse
+	if err := views.UnmarshalChallengeResponse(ctx, req.Body, &response); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	var auth *cognitoidentityprovider.RespondToAuthChallengeOutput
+	var err error
+	if response.Challenge == string(types.ChallengeNameTypeSoftwareTokenMfa) {
+		auth, err = models.RespondToMFAChallenge(ctx, response.Username, response.Session, response.Code)
+	} else {
+		auth, err = models.RespondToNewPasswordChallenge(ctx, response.Username, response.Session, response.NewPassword)
+	}
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordLogin(ctx, req, auth.AuthenticationResult)
+	return tokensOrChallenge(ctx, auth.AuthenticationResult, string(auth.ChallengeName), aws.ToString(auth.Session))
+}
+
+// Trades a refresh token for new access and id tokens; the refresh token itself stays the same
+// Postman: POST - /auth/refresh
+func refresh(ctx context.Context, req Request) (Response, error) {
+	var refreshReq views.RefreshRequest
+	if err := views.UnmarshalRefreshRequest(ctx, req.Body, &refreshReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	tokens, err := models.RefreshCognitoTokens(ctx, refreshReq.RefreshToken, refreshReq.Username, refreshReq.DeviceKey)
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return tokensOrChallenge(ctx, tokens, "", "")
+}
+
+// Emails a password reset code. Answers the same whether or not the account exists, and is rate limited
+// per username and per IP.
+// Postman: POST - /auth/forgot
+func forgotPassword(ctx context.Context, req Request) (Response, error) {
+	var forgot views.ForgotPasswordRequest
+	if err := views.UnmarshalForgotPasswordRequest(ctx, req.Body, &forgot); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.RecordPasswordResetAttempt(ctx, forgot.Username, req.RequestContext.HTTP.SourceIP); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.ForgotCognitoPassword(ctx, forgot.Username); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 202, Body: "if that account exists, a reset code is on its way", Headers: views.DefaultHeaders}, nil
+}
+
+// Sets a new password with the code from /auth/forgot
+// Postman: POST - /auth/reset
+func resetPassword(ctx context.Context, req Request) (Response, error) {
+	var reset views.ResetPasswordRequest
+	if err := views.UnmarshalResetPasswordRequest(ctx, req.Body, &reset); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.ConfirmCognitoForgotPassword(ctx, reset.Username, reset.Code, reset.NewPassword); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "password reset successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Changes the logged-in user's password, given their current one
+// Postman: POST - /auth/password
+func changePassword(ctx context.Context, req Request) (Response, error) {
+	var change views.ChangePasswordRequest
+	if err := views.UnmarshalChangePasswordRequest(ctx, req.Body, &change); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.ChangeCognitoPassword(ctx, handlers.AccessToken(req), change.CurrentPassword, change.NewPassword); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "password changed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Signs out this device, or every device with global, and stops the access token working right away
+// Postman: POST - /auth/logout
+func logout(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	tokenID, ok := req.RequestContext.Authorizer.Lambda["tokenID"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse token id", Headers: views.DefaultHeaders}, nil
+	}
+	tokenExpiresAt, ok := req.RequestContext.Authorizer.Lambda["tokenExpiresAt"].(float64)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse token expiration", Headers: views.DefaultHeaders}, nil
+	}
+
+	logoutReq := views.LogoutRequest{}
+	if req.Body != "" {
+		if err := views.UnmarshalLogoutRequest(ctx, req.Body, &logoutReq); err != nil {
+			return handlers.InvalidRequest(ctx, err), nil
+		}
+	}
+
+	if logoutReq.Global {
+		if err := models.GlobalSignOutCognitoUser(ctx, handlers.AccessToken(req)); err != nil {
+			if authErr, ok := err.(*models.AuthError); ok {
+				return authErrorResponse(ctx, authErr)
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if err := models.RevokeAllTokens(ctx, requestor); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 200, Body: "logged out on all devices", Headers: views.DefaultHeaders}, nil
+	}
+
+	if logoutReq.RefreshToken != "" {
+		if err := models.RevokeCognitoRefreshToken(ctx, logoutReq.RefreshToken); err != nil {
+			if authErr, ok := err.(*models.AuthError); ok {
+				return authErrorResponse(ctx, authErr)
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+	if err := models.RevokeAccessToken(ctx, requestor, tokenID, time.Unix(int64(tokenExpiresAt), 0)); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "logged out successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Starts authenticator app setup, returning the secret and an otpauth:// URI for a QR code
+// Postman: POST - /auth/mfa/setup
+func setUpMFA(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	secretCode, err := models.AssociateSoftwareToken(ctx, handlers.AccessToken(req))
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalMFASetup(ctx, requestor, secretCode)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Checks the first code from the authenticator app and turns on two-factor auth
+// Postman: POST - /auth/mfa/verify
+func verifyMFA(ctx context.Context, req Request) (Response, error) {
+	var verify views.VerifyMFARequest
+	if err := views.UnmarshalVerifyMFARequest(ctx, req.Body, &verify); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.VerifySoftwareToken(ctx, handlers.AccessToken(req), verify.Code, verify.DeviceName); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalMFAStatus(ctx, true)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Whether the user needs an authenticator app code to log in
+// Postman: GET - /auth/mfa
+func getMFA(ctx context.Context, req Request) (Response, error) {
+	enabled, err := models.IsSoftwareTokenMFAEnabled(ctx, handlers.AccessToken(req))
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalMFAStatus(ctx, enabled)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Turns two-factor auth on or off; turning it on needs a verified authenticator app
+// Postman: PUT - /auth/mfa
+func setMFA(ctx context.Context, req Request) (Response, error) {
+	var preference views.MFAPreferenceRequest
+	if err := views.UnmarshalMFAPreferenceRequest(ctx, req.Body, &preference); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.SetSoftwareTokenMFA(ctx, handlers.AccessToken(req), *preference.Enabled); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalMFAStatus(ctx, *preference.Enabled)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Lists the devices Cognito remembers for the user, marking the one making the request
+// Postman: GET - /auth/devices
+func listDevices(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	currentDeviceKey, _ := req.RequestContext.Authorizer.Lambda["deviceKey"].(string)
+
+	devices, err := models.ListCognitoDevices(ctx, handlers.AccessToken(req))
+	if err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	names, err := models.GetDeviceNames(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalDevices(ctx, devices, names, currentDeviceKey)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gives one of the user's devices a name they'll recognize
+// Postman: PUT - /auth/devices/{deviceKey}
+func nameDevice(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var name views.DeviceNameRequest
+	if err := views.UnmarshalDeviceNameRequest(ctx, req.Body, &name); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.SetDeviceName(ctx, handlers.AccessToken(req), requestor, req.PathParameters["deviceKey"], name.Name); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "device renamed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Forgets a device, so its refresh token stops working and it has to log in again
+// Postman: DELETE - /auth/devices/{deviceKey}
+func forgetDevice(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.ForgetCognitoDevice(ctx, handlers.AccessToken(req), requestor, req.PathParameters["deviceKey"]); err != nil {
+		if authErr, ok := err.(*models.AuthError); ok {
+			return authErrorResponse(ctx, authErr)
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "device signed out successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// 200 with the tokens once Cognito hands them out, otherwise 202 with the challenge and the session to answer it with
+func tokensOrChallenge(ctx context.Context, tokens *types.AuthenticationResultType, challenge string, session string) (Response, error) {
+	if tokens == nil {
+		body, err := views.MarshalAuthChallenge(ctx, challenge, session)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 202, Body: body, Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTokens(ctx, aws.ToString(tokens.AccessToken), aws.ToString(tokens.IdToken),
+		aws.ToString(tokens.RefreshToken), tokens.ExpiresIn)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+func authErrorResponse(ctx context.Context, authErr *models.AuthError) (Response, error) {
+	body, err := views.MarshalAuthError(ctx, authErr)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: authErr.Code, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/utils"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+var db *gorm.DB
+
+// Processes the profile pictures users have confirmed with PUT /users/avatar. Uploads that are never
+// confirmed are never downloaded.
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var avatar models.AvatarEvent
+		if err := json.Unmarshal([]byte(record.Body), &avatar); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		if err := processAvatar(initCtx, &avatar); err != nil {
+			return fmt.Errorf("failed to process %s: %w", avatar.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// Crops and resizes a confirmed profile picture into the standard sizes, then points the user at them
+// unless they've confirmed another picture in the meantime. Re-encoding as WebP drops the EXIF/GPS
+// metadata.
+func processAvatar(ctx context.Context, avatar *models.AvatarEvent) error {
+	buf, err := models.GetContentObject(ctx, avatar.Key, models.MaxAvatarBytes)
+	if errors.Is(err, models.ErrorObjectTooLarge) {
+		fmt.Printf("skipping %s: %s\n", avatar.Key, err.Error())
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	img, err := utils.DecodeImage(buf)
+	if err != nil {
+		// nothing to retry for a corrupt or oversized upload
+		fmt.Printf("skipping %s: %s\n", avatar.Key, err.Error())
+		return nil
+	}
+
+	for _, size := range []int{models.AvatarSize, models.AvatarThumbnailSize} {
+		variant, err := utils.EncodeWebP(utils.CropSquare(img, size))
+		if err != nil {
+			return err
+		}
+		if err := models.PutContentObject(ctx, models.GetProcessedAvatarKey(avatar.Key, size), "image/webp", variant); err != nil {
+			return err
+		}
+	}
+
+	replaced, err := models.ReplaceProfilePicture(ctx, avatar.Username,
+		models.ContentBucketURL+avatar.Key,
+		models.ContentBucketURL+models.GetProcessedAvatarKey(avatar.Key, models.AvatarSize),
+		models.ContentBucketURL+models.GetProcessedAvatarKey(avatar.Key, models.AvatarThumbnailSize))
+	if err != nil {
+		return err
+	} else if !replaced {
+		fmt.Printf("skipping %s: no longer %s's profile picture\n", avatar.Key, avatar.Username)
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// The token the client got from solving a CAPTCHA challenge
+func CaptchaToken(req Request) string {
+	return req.Headers["x-captcha-token"]
+}
+
+// Checks the request's CAPTCHA token; handlers return the response as-is when ok is false
+func RequireCaptcha(ctx context.Context, req Request) (Response, bool) {
+	if err := models.CheckCaptcha(ctx, CaptchaToken(req), req.RequestContext.HTTP.SourceIP); err != nil {
+		return authErrorResponse(ctx, err), false
+	}
+	return Response{}, true
+}
+
+// Like RequireCaptcha, but only once the requestor is posting fast enough to look automated
+func RequirePostCaptcha(ctx context.Context, req Request, requestor string) (Response, bool) {
+	if required, err := models.RequiresPostCaptcha(ctx, requestor); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	} else if !required {
+		return Response{}, true
+	}
+	return RequireCaptcha(ctx, req)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Link preview metadata for trill and profile pages, for the web app's server rendering to put in the page
+// when it's shared elsewhere. There's no authorizer, since crawlers don't sign in, so only what anyone
+// could see is in a card.
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RouteKey {
+	case "GET /cards/trills/{trillID}":
+		return getTrillCard(initCtx, req)
+	case "GET /cards/users/{username}":
+		return getProfileCard(initCtx, req)
+	}
+	return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+}
+
+// The card for a trill's page; a retrill gets the original's
+// Postman: GET - /cards/trills/{trillID}
+func getTrillCard(ctx context.Context, req Request) (Response, error) {
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetSharedTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillCard(ctx, trill)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	return Response{StatusCode: 200, Body: body, Headers: cardHeaders()}, nil
+}
+
+// The card for a profile page, under the user's current username when they've changed it
+// Postman: GET - /cards/users/{username}
+func getProfileCard(ctx context.Context, req Request) (Response, error) {
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalProfileCard(ctx, user)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	return Response{StatusCode: 200, Body: body, Headers: cardHeaders()}, nil
+}
+
+// Cards are the same for everyone, so the edge can cache them for a little while
+func cardHeaders() map[string]string {
+	headers := map[string]string{"Cache-Control": fmt.Sprintf("public, max-age=%d", views.CardCacheAge)}
+	for header, value := range views.DefaultHeaders {
+		headers[header] = value
+	}
+	return headers
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
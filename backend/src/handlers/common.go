@@ -9,6 +9,7 @@ import (
 	"mime/multipart"
 	"strings"
 	"trill/src/models"
+	"trill/src/views"
 
 	"github.com/aws/aws-lambda-go/events"
 	"gorm.io/gorm"
@@ -30,6 +31,44 @@ func InitContext(ctx context.Context, db *gorm.DB) (context.Context, *gorm.DB, e
 	return context.WithValue(ctx, "db", db), db, nil
 }
 
+// A 400 listing what was wrong with each field of the request body
+func InvalidRequest(ctx context.Context, err error) Response {
+	var requestErr *views.RequestError
+	if !errors.As(err, &requestErr) {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	}
+
+	body, err := views.Marshal(ctx, requestErr)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	}
+
+	return Response{StatusCode: 400, Body: body, Headers: views.DefaultHeaders}
+}
+
+// The user's Cognito access token from the Authorization header, which the authorizer has already
+// verified on routes behind it. Third-party apps' OAuth tokens aren't Cognito tokens, so they're left out.
+func AccessToken(req Request) string {
+	if _, token, found := strings.Cut(req.Headers["authorization"], " "); found && !strings.HasPrefix(token, models.OAuthAccessTokenPrefix) {
+		return token
+	}
+	return ""
+}
+
+// Errors the client can act on, like AuthErrors from Cognito, in the same shape the auth API answers with
+func authErrorResponse(ctx context.Context, err error) Response {
+	authErr, ok := err.(*models.AuthError)
+	if !ok {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	}
+
+	body, err := views.MarshalAuthError(ctx, authErr)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	}
+	return Response{StatusCode: authErr.Code, Body: body, Headers: views.DefaultHeaders}
+}
+
 func ParseMultipartRequest(req *events.APIGatewayV2HTTPRequest) (*multipart.Form, error) {
 	mediaType, params, err := mime.ParseMediaType(req.Headers["content-type"])
 	if err != nil {
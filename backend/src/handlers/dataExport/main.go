@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+// should match maxReceiveCount on the queue's redrive policy
+const maxAttempts = 3
+
+var db *gorm.DB
+
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var job models.DataExportJob
+		if err := json.Unmarshal([]byte(record.Body), &job); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		export, err := models.GetLatestDataExport(initCtx, job.Username)
+		if err != nil {
+			return err
+		} else if export == nil || export.ExportID != job.ExportID {
+			fmt.Printf("skipping export %s: no longer the latest for %s\n", job.ExportID, job.Username)
+			continue
+		}
+
+		if err := runExport(initCtx, export); err != nil {
+			// the last attempt records the failure so the user can ask again
+			attempts, _ := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+			if attempts < maxAttempts {
+				return fmt.Errorf("failed to export %s: %w", job.ExportID, err)
+			}
+			export.Status = models.ExportFailed
+			export.Error = err.Error()
+			if err := models.UpdateDataExport(initCtx, export); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Zips up everything stored about the user and uploads it to the private exports bucket
+func runExport(ctx context.Context, export *models.DataExport) error {
+	export.Status = models.ExportRunning
+	if err := models.UpdateDataExport(ctx, export); err != nil {
+		return err
+	}
+
+	data, err := models.GetUserData(ctx, export.Username)
+	if err != nil {
+		return err
+	}
+
+	files, err := views.MarshalUserDataFiles(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	archive, err := zipFiles(files)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%s.zip", export.Username, export.ExportID)
+	if err := models.PutExportObject(ctx, key, archive); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	export.Status = models.ExportComplete
+	export.ObjectKey = key
+	export.CompletedAt = &now
+	return models.UpdateDataExport(ctx, export)
+}
+
+func zipFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
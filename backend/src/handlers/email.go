@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"trill/src/models"
+)
+
+// Whether the caller has verified their email, which the authorizer looked up
+func IsEmailVerified(req Request) bool {
+	verified, _ := req.RequestContext.Authorizer.Lambda["emailVerified"].(bool)
+	return verified
+}
+
+// A 403 with the email_not_verified reason unless the caller has verified their email; posting
+// handlers return the response as-is when ok is false. Reading never needs a verified email.
+func RequireVerifiedEmail(ctx context.Context, req Request) (Response, bool) {
+	if IsEmailVerified(req) {
+		return Response{}, true
+	}
+	return authErrorResponse(ctx, models.EmailNotVerifiedError()), false
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// What's happening across Trill, and the hashtags the requestor follows; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RouteKey {
+	case "GET /trends":
+		return getTrends(initCtx, req)
+	case "GET /hashtags/followed":
+		return getFollowedHashtags(initCtx, req)
+	case "POST /hashtags/{tag}/follow":
+		return followHashtag(initCtx, req)
+	case "DELETE /hashtags/{tag}/follow":
+		return unfollowHashtag(initCtx, req)
+	case "GET /users/suggestions":
+		return getSuggestions(initCtx, req)
+	}
+	return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+}
+
+// The trending hashtags worldwide, or in the country the region parameter names
+// Postman: GET - /trends?region=
+func getTrends(ctx context.Context, req Request) (Response, error) {
+	region, err := models.NormalizeRegion(req.QueryStringParameters["region"])
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	region, trends, err := models.GetTrends(ctx, region)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrends(ctx, region, trends)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The hashtags the requestor follows, most recently followed first
+// Postman: GET - /hashtags/followed
+func getFollowedHashtags(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	follows, err := models.GetFollowedHashtags(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalFollowedHashtags(ctx, follows)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Brings public trills using the hashtag into the requestor's home timeline; the tag matches with or
+// without the #, and doesn't have to have been used yet
+// Postman: POST - /hashtags/{tag}/follow
+func followHashtag(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	tag, ok := models.NormalizeHashtag(req.PathParameters["tag"])
+	if !ok {
+		return Response{StatusCode: 400, Body: models.ErrorInvalidHashtag.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.FollowHashtag(ctx, requestor, tag); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: "hashtag followed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Postman: DELETE - /hashtags/{tag}/follow
+func unfollowHashtag(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	tag, ok := models.NormalizeHashtag(req.PathParameters["tag"])
+	if !ok {
+		return Response{StatusCode: 400, Body: models.ErrorInvalidHashtag.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.UnfollowHashtag(ctx, requestor, tag); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "hashtag unfollowed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Accounts the requestor might want to follow, best first, from the accounts the people they follow follow
+// and the hashtags they've used lately
+// Postman: GET - /users/suggestions?limit=
+func getSuggestions(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, err := handlers.GetLimitFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	suggestions, err := models.GetSuggestions(ctx, requestor, limit)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalSuggestions(ctx, suggestions)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -86,6 +86,10 @@ func get(ctx context.Context, req Request) (Response, error) {
 	if !ok {
 		return Response{StatusCode: 500, Body: "Failed to parse username"}, nil
 	}
+	username, err := models.ResolveUsername(ctx, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
 	favoriteAlbums, err := models.GetFavoriteAlbums(ctx, username)
 	if err != nil {
@@ -138,5 +142,5 @@ func delete(ctx context.Context, req Request) (Response, error) {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
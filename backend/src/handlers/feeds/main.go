@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// RSS feeds of public profiles and hashtags, for following along in a feed reader. There's no authorizer,
+// since readers don't sign in, so only public trills are in a feed.
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RouteKey {
+	case "GET /users/{username}/feed.rss":
+		return getUserFeed(initCtx, req)
+	case "GET /hashtags/{tag}/feed.rss":
+		return getHashtagFeed(initCtx, req)
+	}
+	return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+}
+
+// The latest trills and retrills from a public account
+// Postman: GET - /users/{username}/feed.rss
+func getUserFeed(ctx context.Context, req Request) (Response, error) {
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user, trills, err := models.GetUserFeed(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	updated := views.FeedUpdatedAt(trills)
+	if notModified(req, updated) {
+		return Response{StatusCode: 304, Headers: feedHeaders(updated)}, nil
+	}
+
+	body, err := views.MarshalUserFeed(ctx, user, trills, feedURL(req))
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	return Response{StatusCode: 200, Body: body, Headers: feedHeaders(updated)}, nil
+}
+
+// The latest public trills using a hashtag; the tag matches with or without the #
+// Postman: GET - /hashtags/{tag}/feed.rss
+func getHashtagFeed(ctx context.Context, req Request) (Response, error) {
+	tag, ok := models.NormalizeHashtag(req.PathParameters["tag"])
+	if !ok {
+		return Response{StatusCode: 400, Body: models.ErrorInvalidHashtag.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, err := models.GetHashtagFeed(ctx, tag)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	updated := views.FeedUpdatedAt(trills)
+	if notModified(req, updated) {
+		return Response{StatusCode: 304, Headers: feedHeaders(updated)}, nil
+	}
+
+	body, err := views.MarshalHashtagFeed(ctx, tag, trills, feedURL(req))
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	return Response{StatusCode: 200, Body: body, Headers: feedHeaders(updated)}, nil
+}
+
+// Where the feed was fetched from, for its link to itself
+func feedURL(req Request) string {
+	return fmt.Sprintf("https://%s%s", req.RequestContext.DomainName, req.RawPath)
+}
+
+// Whether the reader's copy, from If-Modified-Since, is as new as the feed
+func notModified(req Request, updated time.Time) bool {
+	since, err := http.ParseTime(req.Headers["if-modified-since"])
+	if err != nil || updated.IsZero() {
+		return false
+	}
+	return !updated.Truncate(time.Second).After(since)
+}
+
+// Feeds are the same for everyone, so they're cached, and readers can ask whether they've changed
+func feedHeaders(updated time.Time) map[string]string {
+	headers := map[string]string{
+		"Content-Type":                "application/rss+xml; charset=utf-8",
+		"Access-Control-Allow-Origin": "*",
+		"Cache-Control":               fmt.Sprintf("public, max-age=%d", views.FeedCacheAge),
+	}
+	if !updated.IsZero() {
+		headers["Last-Modified"] = updated.UTC().Format(http.TimeFormat)
+	}
+	return headers
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
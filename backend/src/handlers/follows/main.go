@@ -27,9 +27,14 @@ func handler(ctx context.Context, req Request) (Response, error) {
 
 	switch req.RequestContext.HTTP.Method {
 	case "POST":
+		if req.RouteKey == "POST /follows/requests" {
+			return approveFollowRequest(initCtx, req)
+		}
 		return follow(initCtx, req)
 	case "GET":
-		if req.QueryStringParameters["type"] == "getFollowers" {
+		if req.QueryStringParameters["type"] == "getFollowRequests" {
+			return getFollowRequests(initCtx, req)
+		} else if req.QueryStringParameters["type"] == "getFollowers" {
 			return getFollowers(initCtx, req)
 		} else if req.QueryStringParameters["type"] == "getFollowing" {
 			return getFollowing(initCtx, req)
@@ -38,6 +43,9 @@ func handler(ctx context.Context, req Request) (Response, error) {
 			return Response{StatusCode: 400, Body: err.Error()}, err
 		}
 	case "DELETE":
+		if req.RouteKey == "DELETE /follows/requests" {
+			return declineFollowRequest(initCtx, req)
+		}
 		return unfollow(initCtx, req)
 	default:
 		err := fmt.Errorf("HTTP Method '%s' not allowed", req.RequestContext.HTTP.Method)
@@ -58,11 +66,42 @@ func follow(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response,
 	if !ok {
 		return Response{StatusCode: 500, Body: "Failed to parse username", Headers: views.DefaultHeaders}, nil
 	}
+	userToFollow, err := models.ResolveUsername(ctx, userToFollow)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
 	if username == userToFollow {
 		return Response{StatusCode: 500, Body: "User cannot follow themselves", Headers: views.DefaultHeaders}, nil
 	}
 
+	user, err := models.GetUser(ctx, userToFollow)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if blocked, err := models.IsBlocked(ctx, username, userToFollow); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// private accounts have to approve the follow first
+	if user.IsPrivate {
+		alreadyFollowing, err := models.IsFollowing(ctx, username, userToFollow)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if !alreadyFollowing {
+			if err := models.CreateFollowRequest(ctx, username, userToFollow); err != nil {
+				return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 202, Body: "Follow request sent", Headers: views.DefaultHeaders}, nil
+		}
+	}
+
 	follow := models.Follows{
 		Followee:  username,
 		Following: userToFollow,
@@ -79,21 +118,34 @@ func follow(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response,
 	}, nil
 }
 
-// Get Following
-// @PARAMS are QueryStringParameters : "username"
+// Get Following, most recently followed first
+// @PARAMS are QueryStringParameters : "username", "limit", "cursor"
 // Postman: follows?type=getFollowing&username=avwede
 func getFollowing(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response, error) {
 	followee, ok := req.QueryStringParameters["username"]
 	if !ok {
 		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
 	}
+	followee, err := models.ResolveUsername(ctx, followee)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
-	following, err := models.GetFollowing(ctx, followee)
+	if resp := checkCanView(ctx, req, followee); resp != nil {
+		return *resp, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	following, cursors, err := models.GetFollowingPage(ctx, followee, limit, cursor)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
-	body, err := views.MarshalUsers(ctx, following)
+	body, err := views.MarshalUserPage(ctx, following, cursors)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
@@ -105,21 +157,34 @@ func getFollowing(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Resp
 	}, nil
 }
 
-// Get Followers
-// @PARAMS are QueryStringParameters : "username"
+// Get Followers, most recent first
+// @PARAMS are QueryStringParameters : "username", "limit", "cursor"
 // Postman: follows?type=getFollowers&username=avwede
 func getFollowers(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response, error) {
 	followee, ok := req.QueryStringParameters["username"]
 	if !ok {
 		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
 	}
+	followee, err := models.ResolveUsername(ctx, followee)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if resp := checkCanView(ctx, req, followee); resp != nil {
+		return *resp, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
-	followers, err := models.GetFollowers(ctx, followee)
+	followers, cursors, err := models.GetFollowersPage(ctx, followee, limit, cursor)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
-	body, err := views.MarshalUsers(ctx, followers)
+	body, err := views.MarshalUserPage(ctx, followers, cursors)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
@@ -145,6 +210,10 @@ func unfollow(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response
 	if !ok {
 		return Response{StatusCode: 500, Body: "Failed to parse username", Headers: views.DefaultHeaders}, nil
 	}
+	userToUnfollow, err := models.ResolveUsername(ctx, userToUnfollow)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
 	if username == userToUnfollow {
 		return Response{StatusCode: 500, Body: "User cannot unfollow themselves", Headers: views.DefaultHeaders}, nil
@@ -158,6 +227,10 @@ func unfollow(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response
 	if err := models.DeleteFollow(ctx, &follow); err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
+	// also cancels a follow request that hasn't been approved yet
+	if err := models.DeleteFollowRequest(ctx, username, userToUnfollow); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 
 	return Response{
 		StatusCode: 200,
@@ -166,6 +239,108 @@ func unfollow(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response
 	}, nil
 }
 
+// Get the users waiting on the requestor to approve their follow
+// Postman: follows?type=getFollowRequests
+func getFollowRequests(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "Failed to get current user", Headers: views.DefaultHeaders}, nil
+	}
+
+	requesters, err := models.GetFollowRequests(ctx, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalUsers(ctx, requesters)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Requestor approves someone's request to follow them
+// POST - /follows/requests?username=avwede
+func approveFollowRequest(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "Failed to get current user", Headers: views.DefaultHeaders}, nil
+	}
+
+	requester, ok := req.QueryStringParameters["username"]
+	if !ok {
+		return Response{StatusCode: 400, Body: "Failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	requester, err := models.ResolveUsername(ctx, requester)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.ApproveFollowRequest(ctx, requester, username); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: "Follow request approved", Headers: views.DefaultHeaders}, nil
+}
+
+// Requestor declines someone's request to follow them
+// DELETE - /follows/requests?username=avwede
+func declineFollowRequest(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "Failed to get current user", Headers: views.DefaultHeaders}, nil
+	}
+
+	requester, ok := req.QueryStringParameters["username"]
+	if !ok {
+		return Response{StatusCode: 400, Body: "Failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	requester, err := models.ResolveUsername(ctx, requester)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteFollowRequest(ctx, requester, username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "Follow request declined", Headers: views.DefaultHeaders}, nil
+}
+
+// Returns a 403/404 response if the requestor isn't allowed to see the user's follows, otherwise nil
+func checkCanView(ctx context.Context, req Request, username string) *Response {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return &Response{StatusCode: 500, Body: "Failed to get current user", Headers: views.DefaultHeaders}
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return &Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}
+		}
+		return &Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	}
+
+	if blocked, err := models.IsBlocked(ctx, requestor, username); err != nil {
+		return &Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	} else if blocked {
+		return &Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}
+	}
+
+	if canView, err := models.CanViewUser(ctx, requestor, user); err != nil {
+		return &Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}
+	} else if !canView {
+		return &Response{StatusCode: 403, Body: models.ErrorPrivateAccount.Error(), Headers: views.DefaultHeaders}
+	}
+
+	return nil
+}
+
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
@@ -79,6 +79,19 @@ func like(ctx context.Context, req Request) (Response, error) {
 		return Response{StatusCode: 500, Body: "Failed to parse album ID", Headers: views.DefaultHeaders}, nil
 	}
 
+	review, err := models.GetReviewByID(ctx, reviewID)
+	if err == models.ErrorReviewNotFound {
+		return Response{StatusCode: 404, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if blocked, err := models.IsBlocked(ctx, username, review.Username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
 	like := models.Like{
 		Username: username,
 		ReviewID: reviewID,
@@ -133,5 +146,5 @@ func unlike(ctx context.Context, req events.APIGatewayV2HTTPRequest) (Response,
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
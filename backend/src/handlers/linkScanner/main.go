@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+var db *gorm.DB
+
+// Scans the links in newly posted or edited trills for malware and phishing. A failed batch is retried
+// whole, which is cheap since links already scanned are skipped.
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var scan models.LinkScanEvent
+		if err := json.Unmarshal([]byte(record.Body), &scan); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		if err := models.ScanTrillLinks(initCtx, &scan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -145,5 +145,5 @@ func delete(ctx context.Context, req Request) (Response, error) {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
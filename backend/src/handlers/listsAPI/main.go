@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Lists of users and their timelines; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /lists":
+			return getLists(initCtx, req)
+		case "GET /lists/{listID}":
+			return getList(initCtx, req)
+		case "GET /lists/{listID}/members":
+			return getListMembers(initCtx, req)
+		case "GET /lists/{listID}/trills":
+			return getListTrills(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /lists":
+			return createList(initCtx, req)
+		case "POST /lists/{listID}/members/{username}":
+			return addListMember(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "PATCH":
+		switch req.RouteKey {
+		case "PATCH /lists/{listID}":
+			return updateList(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		switch req.RouteKey {
+		case "DELETE /lists/{listID}":
+			return deleteList(initCtx, req)
+		case "DELETE /lists/{listID}/members/{username}":
+			return removeListMember(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// Makes a list owned by the requestor
+// Postman: POST - /lists
+func createList(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var listRequest views.ListRequest
+	if err := views.UnmarshalListRequest(ctx, req.Body, &listRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	list := listRequest.List(requestor)
+	if err := models.CreateList(ctx, list); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the owner and timestamps the database filled in
+	created, err := models.GetList(ctx, list.ListID, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalList(ctx, created)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A user's lists, defaulting to the requestor's own; other users' private lists are left out
+// Postman: GET - /lists?username=
+func getLists(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	owner := requestor
+	if value, ok := req.QueryStringParameters["username"]; ok {
+		resolved, err := models.ResolveUsername(ctx, value)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		owner = resolved
+	}
+
+	if owner != requestor {
+		if _, err := models.GetUser(ctx, owner); err != nil {
+			if httpErr, ok := err.(*models.HTTPError); ok {
+				return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if blocked, err := models.IsBlocked(ctx, requestor, owner); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if blocked {
+			return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
+	lists, err := models.GetUserLists(ctx, owner, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalLists(ctx, lists)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A list the requestor can see
+// Postman: GET - /lists/{listID}
+func getList(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	list, err := models.GetList(ctx, listID, requestor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalList(ctx, list)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Renames one of the requestor's lists, changes its description, or makes it public or private
+// Postman: PATCH - /lists/{listID}
+func updateList(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	var patch views.PatchList
+	if err := views.UnmarshalPatchList(ctx, req.Body, &patch); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	list, err := models.UpdateList(ctx, listID, requestor, patch.Changes())
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalList(ctx, list)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Deletes one of the requestor's lists
+// Postman: DELETE - /lists/{listID}
+func deleteList(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteList(ctx, listID, requestor); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "list deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Adds a user to one of the requestor's lists
+// Postman: POST - /lists/{listID}/members/{username}
+func addListMember(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.AddListMember(ctx, listID, requestor, username); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user added to list successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Takes a user off one of the requestor's lists
+// Postman: DELETE - /lists/{listID}/members/{username}
+func removeListMember(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.RemoveListMember(ctx, listID, requestor, username); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user removed from list successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// The members of a list the requestor can see, most recently added first
+// Postman: GET - /lists/{listID}/members
+func getListMembers(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if _, err := models.GetList(ctx, listID, requestor); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	members, cursors, err := models.GetListMembers(ctx, listID, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalListMemberPage(ctx, members, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The list's timeline: trills and retrills by its members, newest first, leaving out any the requestor
+// isn't allowed to see
+// Postman: GET - /lists/{listID}/trills
+func getListTrills(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	listID, err := strconv.ParseInt(req.PathParameters["listID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid list ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if _, err := models.GetList(ctx, listID, requestor); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, cursors, err := models.GetListTrills(ctx, listID, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Reading and clearing the requestor's notifications; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /notifications":
+			return getNotifications(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /notifications/read":
+			return markNotificationsRead(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// The requestor's notifications newest first, along with how many are unread
+// Postman: GET - /notifications
+func getNotifications(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	notifications, cursors, err := models.GetNotifications(ctx, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	unread, err := models.CountUnreadNotifications(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	var trills []models.Trill
+	for _, notification := range *notifications {
+		if notification.Trill != nil {
+			trills = append(trills, *notification.Trill)
+		}
+	}
+	viewer, err := models.GetTrillViewer(ctx, requestor, trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalNotificationPage(ctx, notifications, unread, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Marks all of the requestor's notifications as read
+// Postman: POST - /notifications/read
+func markNotificationsRead(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.MarkNotificationsRead(ctx, requestor); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "notifications marked as read", Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// OAuth2 for third-party apps. App registration and the consent screen's /oauth/authorize calls
+// sit behind the authorizer; the token and revocation endpoints authenticate the app instead.
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /oauth/apps":
+			return listApps(initCtx, req)
+		case "GET /oauth/authorize":
+			return getConsent(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /oauth/apps":
+			return registerApp(initCtx, req)
+		case "POST /oauth/authorize":
+			return authorize(initCtx, req)
+		case "POST /oauth/token":
+			return token(initCtx, req)
+		case "POST /oauth/revoke":
+			return revoke(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		if req.RouteKey == "DELETE /oauth/apps/{clientID}" {
+			return deleteApp(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// Lists the apps the requestor has registered
+// Postman: GET - /oauth/apps
+func listApps(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	apps, err := models.GetOAuthApps(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalOAuthApps(ctx, apps)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Registers an app; the response is the only time the client secret is shown
+// Postman: POST - /oauth/apps
+func registerApp(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var appReq views.OAuthAppRequest
+	if err := views.UnmarshalOAuthAppRequest(ctx, req.Body, &appReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	app, clientSecret, err := models.RegisterOAuthApp(ctx, requestor, appReq.Name, appReq.RedirectURIs, appReq.Scopes)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalNewOAuthApp(ctx, app, clientSecret)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.NoStoreHeaders}, nil
+}
+
+// Deletes one of the requestor's apps, revoking every token it was given
+// Postman: DELETE - /oauth/apps/{clientID}
+func deleteApp(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteOAuthApp(ctx, requestor, req.PathParameters["clientID"]); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "app deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Checks an authorization request and returns what the consent screen should show
+// Postman: GET - /oauth/authorize
+func getConsent(ctx context.Context, req Request) (Response, error) {
+	var authorizeReq views.AuthorizeRequest
+	if err := views.AuthorizeRequestFromQuery(ctx, req.QueryStringParameters, &authorizeReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	app, scopes, err := checkAuthorizeRequest(ctx, &authorizeReq)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	body, err := views.MarshalOAuthConsent(ctx, app, scopes)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Records the requestor's consent and returns the redirect URI with the authorization code attached
+// Postman: POST - /oauth/authorize
+func authorize(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var authorizeReq views.AuthorizeRequest
+	if err := views.UnmarshalAuthorizeRequest(ctx, req.Body, &authorizeReq); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	app, scopes, err := checkAuthorizeRequest(ctx, &authorizeReq)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	code, err := models.CreateOAuthCode(ctx, app, requestor, authorizeReq.RedirectURI, scopes, authorizeReq.CodeChallenge)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	redirect, err := url.Parse(authorizeReq.RedirectURI)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	if authorizeReq.State != "" {
+		query.Set("state", authorizeReq.State)
+	}
+	redirect.RawQuery = query.Encode()
+
+	body, err := views.MarshalAuthorizeResult(ctx, redirect.String())
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.NoStoreHeaders}, nil
+}
+
+// The app has to exist, the redirect URI has to be one it registered, and the scopes can't go beyond its own
+func checkAuthorizeRequest(ctx context.Context, authorizeReq *views.AuthorizeRequest) (*models.OAuthApp, []string, error) {
+	app, err := models.GetOAuthApp(ctx, authorizeReq.ClientID)
+	if err != nil {
+		return nil, nil, err
+	} else if !app.AllowsRedirect(authorizeReq.RedirectURI) {
+		return nil, nil, &models.AuthError{Code: http.StatusBadRequest, Reason: "invalid_request", Err: models.ErrorRedirectMismatch}
+	}
+
+	scopes, err := app.GrantableScopes(authorizeReq.ScopeList())
+	if err != nil {
+		return nil, nil, err
+	}
+	return app, scopes, nil
+}
+
+// Issues tokens for the authorization_code, refresh_token, and client_credentials grants
+// Postman: POST - /oauth/token
+func token(ctx context.Context, req Request) (Response, error) {
+	form, err := parseForm(req)
+	if err != nil {
+		return oauthErrorResponse(ctx, &models.AuthError{Code: http.StatusBadRequest, Reason: "invalid_request", Err: err})
+	}
+
+	app, err := authenticateClient(ctx, req, form)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	var grant *models.OAuthGrant
+	switch form.Get("grant_type") {
+	case "authorization_code":
+		grant, err = models.ExchangeOAuthCode(ctx, app, form.Get("code"), form.Get("redirect_uri"), form.Get("code_verifier"))
+	case "refresh_token":
+		grant, err = models.RefreshOAuthGrant(ctx, app, form.Get("refresh_token"))
+	case "client_credentials":
+		var scopes []string
+		if scopes, err = app.GrantableScopes(strings.Fields(form.Get("scope"))); err == nil {
+			grant, err = models.ClientCredentialsGrant(ctx, app, scopes)
+		}
+	default:
+		err = &models.AuthError{Code: http.StatusBadRequest, Reason: "unsupported_grant_type", Err: fmt.Errorf("grant_type '%s' isn't supported", form.Get("grant_type"))}
+	}
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	body, err := views.MarshalOAuthTokens(ctx, grant)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.NoStoreHeaders}, nil
+}
+
+// Revokes an access or refresh token the app was given (RFC 7009)
+// Postman: POST - /oauth/revoke
+func revoke(ctx context.Context, req Request) (Response, error) {
+	form, err := parseForm(req)
+	if err != nil {
+		return oauthErrorResponse(ctx, &models.AuthError{Code: http.StatusBadRequest, Reason: "invalid_request", Err: err})
+	}
+
+	app, err := authenticateClient(ctx, req, form)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	if err := models.RevokeOAuthToken(ctx, app, form.Get("token")); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Headers: views.NoStoreHeaders}, nil
+}
+
+// The token endpoints take application/x-www-form-urlencoded bodies, as RFC 6749 requires
+func parseForm(req Request) (url.Values, error) {
+	body := req.Body
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding base64-encoded body")
+		}
+		body = string(decoded)
+	}
+
+	return url.ParseQuery(body)
+}
+
+// Apps authenticate with HTTP Basic, or with client_id and client_secret in the form
+func authenticateClient(ctx context.Context, req Request, form url.Values) (*models.OAuthApp, error) {
+	clientID, clientSecret := form.Get("client_id"), form.Get("client_secret")
+	if scheme, credentials, found := strings.Cut(req.Headers["authorization"], " "); found && strings.EqualFold(scheme, "Basic") {
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return nil, &models.AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: models.ErrorInvalidClient}
+		}
+		id, secret, _ := strings.Cut(string(decoded), ":")
+		// both halves are form-encoded before they're joined (RFC 6749 section 2.3.1)
+		if clientID, err = url.QueryUnescape(id); err != nil {
+			return nil, &models.AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: models.ErrorInvalidClient}
+		}
+		if clientSecret, err = url.QueryUnescape(secret); err != nil {
+			return nil, &models.AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: models.ErrorInvalidClient}
+		}
+	}
+
+	return models.AuthenticateOAuthClient(ctx, clientID, clientSecret)
+}
+
+func oauthErrorResponse(ctx context.Context, err error) (Response, error) {
+	authErr, ok := err.(*models.AuthError)
+	if !ok {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalOAuthError(ctx, authErr)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: authErr.Code, Body: body, Headers: views.NoStoreHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// The oEmbed endpoint, for sites embedding a trill from its link. There's no authorizer, since consumers
+// call it server side, so only trills from public accounts can be embedded. Only the json format is served.
+// Postman: GET - /oembed
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if req.RouteKey != "GET /oembed" {
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	}
+
+	link := req.QueryStringParameters["url"]
+	if link == "" {
+		return Response{StatusCode: 400, Body: "url is required", Headers: views.DefaultHeaders}, nil
+	}
+	if format := req.QueryStringParameters["format"]; format != "" && format != "json" {
+		return Response{StatusCode: 501, Body: fmt.Sprintf("format '%s' not supported", format), Headers: views.DefaultHeaders}, nil
+	}
+	maxWidth := 0
+	if param := req.QueryStringParameters["maxwidth"]; param != "" {
+		maxWidth, err = strconv.Atoi(param)
+		if err != nil || maxWidth < 1 {
+			return Response{StatusCode: 400, Body: "invalid maxwidth", Headers: views.DefaultHeaders}, nil
+		}
+	}
+	omitScript := req.QueryStringParameters["omit_script"] == "true"
+
+	trillID, err := models.ParseTrillURL(link)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	trill, err := models.GetEmbeddableTrill(initCtx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillOEmbed(initCtx, trill, maxWidth, omitScript)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	headers := map[string]string{"Cache-Control": fmt.Sprintf("public, max-age=%d", views.EmbedCacheAge)}
+	for header, value := range views.DefaultHeaders {
+		headers[header] = value
+	}
+	return Response{StatusCode: 200, Body: body, Headers: headers}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
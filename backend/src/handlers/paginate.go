@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"trill/src/models"
+	"trill/src/pagination"
 )
 
 var (
@@ -23,6 +24,37 @@ func (e PaginateError) Error() string {
 	return fmt.Sprintf("pagination error: %s", e.Err.Error())
 }
 
+// Parses the limit and cursor params used by keyset-paginated endpoints; cursor is nil for the first page,
+// and a limit out of range is clamped rather than refused
+func GetCursorFromRequest(ctx context.Context, req Request) (int, *models.Cursor, error) {
+	limit, cursor, err := pagination.FromParams(req.QueryStringParameters)
+	if err != nil {
+		return 0, nil, PaginateError{Err: err}
+	}
+
+	return limit, cursor, nil
+}
+
+// Parses the limit param for lists that are short enough to come back in one page, clamping it the same way
+func GetLimitFromRequest(ctx context.Context, req Request) (int, error) {
+	limit, err := pagination.ParseLimit(req.QueryStringParameters["limit"])
+	if err != nil {
+		return 0, PaginateError{Err: err}
+	}
+
+	return limit, nil
+}
+
+// Parses the since_id and max_id params timelines take for incremental refreshes
+func GetIDRangeFromRequest(ctx context.Context, req Request) (models.IDRange, error) {
+	idRange, err := pagination.IDRangeFromParams(req.QueryStringParameters)
+	if err != nil {
+		return models.IDRange{}, PaginateError{Err: err}
+	}
+
+	return idRange, nil
+}
+
 func GetPaginateFromRequest(ctx context.Context, req Request) (*models.Paginate, error) {
 	limit := models.PAGINATE_DEFAULT_LIMIT
 	page := models.PAGINATE_DEFAULT_PAGE
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+var db *gorm.DB
+
+// Records queued profile views; a failed batch is retried whole, which the per-day dedup makes harmless
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var view models.ProfileViewEvent
+		if err := json.Unmarshal([]byte(record.Body), &view); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		if err := models.RecordProfileView(initCtx, &view); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
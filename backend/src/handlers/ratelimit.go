@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+type Handler = func(context.Context, Request) (Response, error)
+
+// Wraps an API Lambda's handler in a per-user token bucket, keyed by the caller's username or, on
+// routes without the authorizer, their IP. Requests over the limit get a 429 with Retry-After, and
+// every response carries X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset.
+func RateLimited(next Handler) Handler {
+	return func(ctx context.Context, req Request) (Response, error) {
+		key, class := rateLimitBucket(req)
+		limit, err := models.TakeRateLimitToken(ctx, key, class)
+		if err != nil {
+			// a broken limiter shouldn't take the API down with it
+			fmt.Printf("failed to check rate limit for %s: %s\n", key, err.Error())
+			return next(ctx, req)
+		}
+
+		if !limit.Allowed {
+			retryAfter := seconds(limit.RetryAfter)
+			resp := Response{StatusCode: 429, Body: fmt.Sprintf("rate limit exceeded, try again in %d seconds", retryAfter), Headers: views.DefaultHeaders}
+			resp.Headers = rateLimitHeaders(resp.Headers, limit)
+			resp.Headers["Retry-After"] = strconv.Itoa(retryAfter)
+			return resp, nil
+		}
+
+		resp, err := next(ctx, req)
+		resp.Headers = rateLimitHeaders(resp.Headers, limit)
+		return resp, err
+	}
+}
+
+// Which bucket a request draws from: the caller's own for their reads or writes, or their IP's for
+// the routes that hand out tokens, which includes reactivating since it takes a password
+func rateLimitBucket(req Request) (string, models.RateLimitClass) {
+	key := req.RequestContext.HTTP.SourceIP
+	if username, ok := req.RequestContext.Authorizer.Lambda["username"].(string); ok && username != "" {
+		key = username
+	} else if _, path, _ := strings.Cut(req.RouteKey, " "); strings.HasPrefix(path, "/auth/") || strings.HasPrefix(path, "/oauth/") || path == "/users/reactivate" {
+		return key, models.RateLimitAuth
+	}
+
+	if req.RequestContext.HTTP.Method == "GET" {
+		return key, models.RateLimitRead
+	}
+	return key, models.RateLimitWrite
+}
+
+// Copies the headers before adding to them, since most responses share views.DefaultHeaders
+func rateLimitHeaders(headers map[string]string, limit *models.RateLimit) map[string]string {
+	withLimits := make(map[string]string, len(headers)+3)
+	for name, value := range headers {
+		withLimits[name] = value
+	}
+	withLimits["X-RateLimit-Limit"] = strconv.Itoa(limit.Limit)
+	withLimits["X-RateLimit-Remaining"] = strconv.Itoa(limit.Remaining)
+	withLimits["X-RateLimit-Reset"] = strconv.Itoa(seconds(limit.Reset))
+	return withLimits
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
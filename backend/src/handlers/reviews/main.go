@@ -65,6 +65,10 @@ func getReview(ctx context.Context, req Request) (Response, error) {
 	reviewerUsername, queryOK := req.QueryStringParameters["username"]
 	if !queryOK {
 		reviewerUsername = requestor
+	} else if resolved, err := models.ResolveUsername(ctx, reviewerUsername); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else {
+		reviewerUsername = resolved
 	}
 
 	albumID, ok := req.QueryStringParameters["albumID"]
@@ -81,6 +85,17 @@ func getReview(ctx context.Context, req Request) (Response, error) {
 		return Response{StatusCode: 204, Headers: views.DefaultHeaders}, nil
 	}
 
+	if blocked, err := models.IsBlocked(ctx, requestor, review.Username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if canView, err := models.CanViewUser(ctx, requestor, &review.User); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !canView {
+		return Response{StatusCode: 403, Body: models.ErrorPrivateAccount.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
 	buf, err := utils.DoSpotifyRequest(ctx, utils.AlbumAPIURL, albumID)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
@@ -106,6 +121,13 @@ func getReviews(ctx context.Context, req Request) (Response, error) {
 	}
 
 	username, hasUserParam := req.QueryStringParameters["username"]
+	if hasUserParam {
+		resolved, err := models.ResolveUsername(ctx, username)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		username = resolved
+	}
 	albumID, hasAlbumParam := req.QueryStringParameters["albumID"]
 
 	followingRaw := req.QueryStringParameters["following"]
@@ -126,6 +148,27 @@ func getReviews(ctx context.Context, req Request) (Response, error) {
 		AlbumID: albumID,
 	}
 
+	// GetReviews already leaves out private and blocked accounts, but asking for one by name should say why it's empty
+	if hasUserParam && !hasAlbumParam && !following {
+		user, err := models.GetUser(ctx, username)
+		if err != nil {
+			if httpErr, ok := err.(*models.HTTPError); ok {
+				return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if blocked, err := models.IsBlocked(ctx, requestor, username); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if blocked {
+			return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if canView, err := models.CanViewUser(ctx, requestor, user); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if !canView {
+			return Response{StatusCode: 403, Body: models.ErrorPrivateAccount.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
 	var users *[]models.User = nil
 	if following {
 		var err error
@@ -141,7 +184,7 @@ func getReviews(ctx context.Context, req Request) (Response, error) {
 		"newest",
 		"oldest",
 		"popular":
-		reviews, err = models.GetReviews(ctx, &reviewQuery, users, paginate)
+		reviews, err = models.GetReviews(ctx, &reviewQuery, users, paginate, requestor)
 		if err != nil {
 			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 		}
@@ -182,7 +225,7 @@ func getReviews(ctx context.Context, req Request) (Response, error) {
 func createOrUpdateReview(ctx context.Context, req Request) (Response, error) {
 	var review models.Review
 	if err := views.UnmarshalReview(ctx, req.Body, &review); err != nil {
-		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		return handlers.InvalidRequest(ctx, err), nil
 	}
 
 	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
@@ -196,6 +239,13 @@ func createOrUpdateReview(ctx context.Context, req Request) (Response, error) {
 	review.Username = requestor
 	review.AlbumID = albumID
 
+	if resp, ok := handlers.RequireVerifiedEmail(ctx, req); !ok {
+		return resp, nil
+	}
+	if resp, ok := handlers.RequirePostCaptcha(ctx, req, requestor); !ok {
+		return resp, nil
+	}
+
 	if err := models.CreateReview(ctx, &review); err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
@@ -247,5 +297,5 @@ func deleteReview(ctx context.Context, req Request) (Response, error) {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
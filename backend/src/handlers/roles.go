@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// The caller's roles, which the authorizer read from the cognito:groups claim on their access token
+func GetRoles(req Request) models.Roles {
+	roles, _ := req.RequestContext.Authorizer.Lambda["roles"].(string)
+	return models.ParseRoles(roles)
+}
+
+// A 403 unless the caller has the role; handlers return the response as-is when ok is false
+func RequireRole(req Request, role models.Role) (Response, bool) {
+	if GetRoles(req).Has(role) {
+		return Response{}, true
+	}
+
+	err := models.MissingRoleError(role)
+	return Response{StatusCode: err.Code, Body: err.Error(), Headers: views.DefaultHeaders}, false
+}
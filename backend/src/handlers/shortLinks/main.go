@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Sends a short link on to where it points, logging the click. There's no authorizer, since the links
+// get followed from anywhere. The redirect is a 302 and isn't cached, so every click reaches here.
+// Postman: GET - /{code}
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if req.RouteKey != "GET /{code}" {
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	}
+
+	link, err := models.FollowShortLink(initCtx, req.PathParameters["code"], req.Headers["referer"])
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	headers := map[string]string{
+		"Location":      link.URL,
+		"Cache-Control": "no-store",
+	}
+	return Response{StatusCode: 302, Headers: headers}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
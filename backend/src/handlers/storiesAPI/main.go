@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Posting, watching, and deleting stories, which expire after a day; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /stories":
+			return getStories(initCtx, req)
+		case "GET /stories/{storyID}":
+			return getStory(initCtx, req)
+		case "GET /stories/{storyID}/viewers":
+			return getStoryViewers(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /stories":
+			return createStory(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		switch req.RouteKey {
+		case "DELETE /stories/{storyID}":
+			return deleteStory(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// Posts a story with an image or video from POST /trills/media; it expires a day later
+// Postman: POST - /stories
+func createStory(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if resp, ok := handlers.RequireVerifiedEmail(ctx, req); !ok {
+		return resp, nil
+	}
+	if resp, ok := handlers.RequirePostCaptcha(ctx, req, requestor); !ok {
+		return resp, nil
+	}
+
+	var storyRequest views.StoryRequest
+	if err := views.UnmarshalStoryRequest(ctx, req.Body, &storyRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	media, err := models.ValidateTrillMedia(ctx, requestor, []string{storyRequest.Media})
+	if err == nil {
+		err = models.ApplyAltText(ctx, requestor, media, storyRequest.AltTextByKey())
+	}
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	story := storyRequest.Story(requestor, media[0])
+	if err := models.CreateStory(ctx, story); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back with its author and media as stored
+	created, err := models.GetStory(ctx, story.StoryID, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalStory(ctx, created, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A user's stories that haven't expired, oldest first, defaulting to the requestor's own
+// Postman: GET - /stories?username=
+func getStories(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	username := requestor
+	if value, ok := req.QueryStringParameters["username"]; ok {
+		resolved, err := models.ResolveUsername(ctx, value)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		username = resolved
+	}
+
+	if username != requestor {
+		author, err := models.GetUser(ctx, username)
+		if err != nil {
+			if httpErr, ok := err.(*models.HTTPError); ok {
+				return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if blocked, err := models.IsBlocked(ctx, requestor, username); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if blocked {
+			return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if canView, err := models.CanViewUser(ctx, requestor, author); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		} else if !canView {
+			return Response{StatusCode: 403, Body: models.ErrorPrivateAccount.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
+	stories, err := models.GetUserStories(ctx, username, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalStories(ctx, stories, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A story the requestor can see, counting them as a viewer
+// Postman: GET - /stories/{storyID}
+func getStory(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	storyID, err := strconv.ParseInt(req.PathParameters["storyID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid story ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	story, err := models.GetStory(ctx, storyID, requestor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// a view that can't be recorded shouldn't keep the story from showing
+	if err := models.RecordStoryView(ctx, story, requestor); err != nil {
+		fmt.Printf("failed to record %s viewing story %d: %s\n", requestor, story.StoryID, err.Error())
+	}
+
+	body, err := views.MarshalStory(ctx, story, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Who viewed one of the requestor's stories, most recent first
+// Postman: GET - /stories/{storyID}/viewers
+func getStoryViewers(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	storyID, err := strconv.ParseInt(req.PathParameters["storyID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid story ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	storyViews, cursors, err := models.GetStoryViewers(ctx, storyID, requestor, limit, cursor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalStoryViewerPage(ctx, storyViews, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Deletes one of the requestor's stories before it expires, media and all
+// Postman: DELETE - /stories/{storyID}
+func deleteStory(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	storyID, err := strconv.ParseInt(req.PathParameters["storyID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid story ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteStory(ctx, storyID, requestor); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "story deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// Runs on a schedule to hard delete stories that have expired, along with their views and media
+func handler(ctx context.Context) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := models.DeleteExpiredStories(initCtx)
+	if deleted > 0 {
+		fmt.Printf("deleted %d expired stories\n", deleted)
+	}
+	return err
+}
+
+func main() {
+	lambda.Start(handler)
+}
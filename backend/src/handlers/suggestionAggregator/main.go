@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// Runs on a schedule to recompute every active user's follow suggestions
+func handler(ctx context.Context) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	stored, err := models.ComputeSuggestions(initCtx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("stored %d suggestions\n", stored)
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// The requestor's timelines; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RouteKey {
+	case "GET /timeline/home":
+		return getHomeTimeline(initCtx, req)
+	case "GET /timeline/explore":
+		return getExploreTimeline(initCtx, req)
+	case "GET /timeline/mentions":
+		return getMentionsTimeline(initCtx, req)
+	case "GET /users/{username}/trills":
+		return getUserTimeline(initCtx, req)
+	case "GET /users/{username}/likes":
+		return getLikedTrills(initCtx, req)
+	}
+	return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+}
+
+// Trills and retrills by the accounts the requestor follows, their own, and public trills using hashtags
+// they follow, newest first, or best first when ranking is top. Trills there for a hashtag say so in
+// reason. Without ranking, the requestor's timeline_ranking setting decides. since_id and max_id narrow
+// it for incremental refreshes.
+// Postman: GET - /timeline/home?ranking=&since_id=&max_id=
+func getHomeTimeline(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	idRange, err := handlers.GetIDRangeFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	ranking, ok := req.QueryStringParameters["ranking"]
+	if !ok {
+		settings, err := models.GetUserSettings(ctx, requestor)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		ranking = settings.TimelineRanking
+	} else if !models.ValidRanking(ranking) {
+		return Response{StatusCode: 400, Body: models.ErrorInvalidRanking.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	getTimeline := models.GetHomeTimeline
+	if ranking == models.RankingTop {
+		getTimeline = models.GetTopHomeTimeline
+	}
+	trills, cursors, err := getTimeline(ctx, requestor, idRange, limit, cursor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if viewer.HashtagReasons, err = models.GetHashtagReasons(ctx, requestor, *trills); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Popular and recent trills from accounts the requestor doesn't follow yet
+// Postman: GET - /timeline/explore
+func getExploreTimeline(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, cursors, err := models.GetExploreTimeline(ctx, requestor, limit, cursor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Trills that mention the requestor newest first, narrowed by since_id and max_id for incremental
+// refreshes. Mention notifications for the trills on the page are marked as read, so the notifications
+// badge drops once they've been seen here.
+// Postman: GET - /timeline/mentions?since_id=&max_id=
+func getMentionsTimeline(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	idRange, err := handlers.GetIDRangeFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, cursors, err := models.GetMentionsTimeline(ctx, requestor, idRange, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.MarkMentionsRead(ctx, requestor, *trills); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A user's trills newest first, with their replies and retrills unless include_replies or
+// include_retrills is false, narrowed by since_id and max_id for incremental refreshes
+// Postman: GET - /users/{username}/trills?since_id=&max_id=
+func getUserTimeline(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	idRange, err := handlers.GetIDRangeFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	options := models.UserTimelineOptions{
+		IncludeReplies:  req.QueryStringParameters["include_replies"] != "false",
+		IncludeRetrills: req.QueryStringParameters["include_retrills"] != "false",
+	}
+	trills, cursors, err := models.GetUserTimeline(ctx, requestor, username, options, idRange, limit, cursor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The trills a user has liked, most recently liked first. Unless it's the requestor's own, the user's
+// show_liked_trills setting has to allow it.
+// Postman: GET - /users/{username}/likes
+func getLikedTrills(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, cursors, err := models.GetLikedTrills(ctx, requestor, username, limit, cursor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTrillImpressions(ctx, requestor, *trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
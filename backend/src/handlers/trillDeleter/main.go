@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+// should match maxReceiveCount on the queue's redrive policy
+const maxAttempts = 3
+
+// time left before the timeout at which the worker stops and queues the rest of the job
+const handoffMargin = 30 * time.Second
+
+var db *gorm.DB
+
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var job models.TrillDeletionJob
+		if err := json.Unmarshal([]byte(record.Body), &job); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		deletion, err := models.GetTrillDeletion(initCtx, job.Username, job.DeletionID)
+		if err != nil {
+			if _, ok := err.(*models.HTTPError); ok {
+				fmt.Printf("skipping deletion %s: it no longer exists\n", job.DeletionID)
+				continue
+			}
+			return err
+		} else if deletion.Status == models.DeletionComplete || deletion.Status == models.DeletionFailed {
+			continue
+		}
+
+		if err := runDeletion(initCtx, deletion); err != nil {
+			// the last attempt records the failure so the user can ask again
+			attempts, _ := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+			if attempts < maxAttempts {
+				return fmt.Errorf("failed to run deletion %s: %w", job.DeletionID, err)
+			}
+			deletion.Status = models.DeletionFailed
+			deletion.Error = err.Error()
+			if err := models.UpdateTrillDeletion(initCtx, deletion); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Deletes batches until the range is empty. A job too big for one invocation queues itself again to
+// pick up where it left off, since each batch only looks at the trills that are still there.
+func runDeletion(ctx context.Context, deletion *models.TrillDeletion) error {
+	for {
+		done, err := models.RunTrillDeletionBatch(ctx, deletion)
+		if err != nil || done {
+			return err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < handoffMargin {
+			return models.EnqueueTrillDeletion(ctx, deletion)
+		}
+	}
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+var db *gorm.DB
+
+// Invoked by a scheduled trill's EventBridge schedule when it's time to post it
+func handler(ctx context.Context, event models.ScheduledTrillEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	return models.PublishScheduledTrill(initCtx, event.ScheduledTrillID)
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+var db *gorm.DB
+
+// Counts queued trill impressions, the whole batch at once
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	impressions := make([]models.TrillImpressionEvent, 0, len(event.Records))
+	for _, record := range event.Records {
+		var impression models.TrillImpressionEvent
+		if err := json.Unmarshal([]byte(record.Body), &impression); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+		impressions = append(impressions, impression)
+	}
+
+	return models.RecordTrillImpressions(initCtx, impressions)
+}
+
+func main() {
+	lambda.Start(handler)
+}
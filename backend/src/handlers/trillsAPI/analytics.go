@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Views, engagement, and clicks on each link for one of the requestor's trills
+// Postman: GET - /trills/{trillID}/analytics
+func getTrillAnalytics(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrillAnalytics(ctx, trillID, requestor)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillAnalytics(ctx, trill)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
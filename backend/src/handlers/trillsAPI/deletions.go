@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Deletes many of the requestor's trills at once. Up to 100 trill IDs are deleted before responding; a
+// date range is queued, and GET /trills/deletions/{deletionID} follows its progress.
+// Postman: POST - /trills/deletions
+func createTrillDeletion(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var deletionRequest views.TrillDeletionRequest
+	if err := views.UnmarshalTrillDeletionRequest(ctx, req.Body, &deletionRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	deletion, err := models.CreateTrillDeletion(ctx, requestor, deletionRequest.TrillIDs, deletionRequest.From, deletionRequest.Until)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	status := 200
+	if len(deletionRequest.TrillIDs) > 0 {
+		err = models.DeleteTrillsByID(ctx, deletion, deletionRequest.TrillIDs)
+	} else {
+		status = 202
+		err = models.EnqueueTrillDeletion(ctx, deletion)
+	}
+	if err != nil {
+		// don't leave a pending deletion around that nothing will ever finish
+		deletion.Status = models.DeletionFailed
+		deletion.Error = err.Error()
+		if updateErr := models.UpdateTrillDeletion(ctx, deletion); updateErr != nil {
+			return Response{StatusCode: 500, Body: updateErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillDeletion(ctx, deletion)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: status, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The status of one of the requestor's bulk deletions and how many trills it's deleted so far
+// Postman: GET - /trills/deletions/{deletionID}
+func getTrillDeletion(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	deletion, err := models.GetTrillDeletion(ctx, requestor, req.PathParameters["deletionID"])
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillDeletion(ctx, deletion)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,1512 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type Request = handlers.Request
+type Response = handlers.Response
+
+var db *gorm.DB
+
+// Posting, reading, and deleting trills, drafts, the hashtag pages that list them, and the GIF picker; every route sits behind the authorizer
+func handler(ctx context.Context, req Request) (Response, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	switch req.RequestContext.HTTP.Method {
+	case "GET":
+		switch req.RouteKey {
+		case "GET /trills":
+			return getTrills(initCtx, req)
+		case "GET /trills/bookmarks":
+			return getBookmarks(initCtx, req)
+		case "GET /trills/bookmarks/folders":
+			return getBookmarkFolders(initCtx, req)
+		case "GET /trills/deletions/{deletionID}":
+			return getTrillDeletion(initCtx, req)
+		case "GET /trills/drafts":
+			return getDrafts(initCtx, req)
+		case "GET /trills/scheduled":
+			return getScheduledTrills(initCtx, req)
+		case "GET /trills/{trillID}":
+			return getTrill(initCtx, req)
+		case "GET /trills/{trillID}/analytics":
+			return getTrillAnalytics(initCtx, req)
+		case "GET /trills/{trillID}/conversation":
+			return getConversation(initCtx, req)
+		case "GET /trills/{trillID}/history":
+			return getTrillHistory(initCtx, req)
+		case "GET /trills/{trillID}/likers":
+			return getEngagers(initCtx, req)
+		case "GET /trills/{trillID}/reactions":
+			return getReactors(initCtx, req)
+		case "GET /trills/{trillID}/retrillers":
+			return getEngagers(initCtx, req)
+		case "GET /trills/{trillID}/translation":
+			return getTrillTranslation(initCtx, req)
+		case "GET /hashtags/{tag}/trills":
+			return getHashtagTrills(initCtx, req)
+		case "GET /gifs/search":
+			return searchGIFs(initCtx, req)
+		case "GET /gifs/trending":
+			return searchGIFs(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "POST":
+		switch req.RouteKey {
+		case "POST /trills":
+			return createTrill(initCtx, req)
+		case "POST /trills/thread":
+			return createThread(initCtx, req)
+		case "POST /trills/{trillID}/replies":
+			return replyToTrill(initCtx, req)
+		case "POST /trills/{trillID}/retrill":
+			return retrill(initCtx, req)
+		case "POST /trills/{trillID}/like":
+			return likeTrill(initCtx, req)
+		case "POST /trills/{trillID}/reactions":
+			return reactToTrill(initCtx, req)
+		case "POST /trills/{trillID}/bookmark":
+			return bookmarkTrill(initCtx, req)
+		case "POST /trills/bookmarks/folders":
+			return createBookmarkFolder(initCtx, req)
+		case "POST /trills/deletions":
+			return createTrillDeletion(initCtx, req)
+		case "POST /trills/{trillID}/poll/votes":
+			return voteInPoll(initCtx, req)
+		case "POST /trills/{trillID}/pin":
+			return pinTrill(initCtx, req)
+		case "POST /trills/media":
+			return createMediaUpload(initCtx, req)
+		case "POST /trills/drafts":
+			return createDraft(initCtx, req)
+		case "POST /trills/drafts/{draftID}/publish":
+			return publishDraft(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "PUT":
+		switch req.RouteKey {
+		case "PUT /trills/drafts/{draftID}":
+			return updateDraft(initCtx, req)
+		case "PUT /trills/{trillID}/bookmark/folder":
+			return moveBookmark(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "PATCH":
+		switch req.RouteKey {
+		case "PATCH /trills/{trillID}":
+			return editTrill(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		switch req.RouteKey {
+		case "DELETE /trills/{trillID}":
+			return deleteTrill(initCtx, req)
+		case "DELETE /trills/{trillID}/retrill":
+			return undoRetrill(initCtx, req)
+		case "DELETE /trills/{trillID}/like":
+			return unlikeTrill(initCtx, req)
+		case "DELETE /trills/{trillID}/reactions/{emoji}":
+			return unreactToTrill(initCtx, req)
+		case "DELETE /trills/{trillID}/bookmark":
+			return removeBookmark(initCtx, req)
+		case "DELETE /trills/bookmarks/folders/{folderID}":
+			return deleteBookmarkFolder(initCtx, req)
+		case "DELETE /trills/{trillID}/pin":
+			return unpinTrill(initCtx, req)
+		case "DELETE /trills/drafts/{draftID}":
+			return deleteDraft(initCtx, req)
+		case "DELETE /trills/scheduled/{scheduledTrillID}":
+			return cancelScheduledTrill(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	default:
+		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
+		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+}
+
+// Posts a trill with text, media from POST /trills/media, or both, or text with a poll, optionally quoting another
+// trill; with publish_at it's scheduled instead
+// Postman: POST - /trills
+func createTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	return postTrill(ctx, req, requestor, nil)
+}
+
+// Replies to a trill the requestor can see, adding to its conversation
+// Postman: POST - /trills/{trillID}/replies
+func replyToTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	parent, resp, ok := getReplyParent(ctx, requestor, trillID)
+	if !ok {
+		return resp, nil
+	}
+
+	return postTrill(ctx, req, requestor, &parent.TrillID)
+}
+
+// The trill to reply to, which the requestor has to be able to see and its author has to let them reply
+// to; replying to a retrill replies to the original
+func getReplyParent(ctx context.Context, requestor string, trillID int64) (*models.Trill, Response, bool) {
+	parent, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return nil, Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, false
+		}
+		return nil, Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	}
+	if parent.RetrillOf != nil {
+		parent = parent.RetrillOf
+	}
+
+	if resp, ok := canSeeAuthor(ctx, requestor, &parent.User); !ok {
+		return nil, resp, false
+	}
+	if canReply, err := models.CanReply(ctx, requestor, parent); err != nil {
+		return nil, Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	} else if !canReply {
+		return nil, Response{StatusCode: 403, Body: models.ErrorRepliesRestricted.Error(), Headers: views.DefaultHeaders}, false
+	}
+	return parent, Response{}, true
+}
+
+// Creates the trill in the request body, as a reply when parentID is set
+func postTrill(ctx context.Context, req Request, requestor string, parentID *int64) (Response, error) {
+	var trillRequest views.TrillRequest
+	if err := views.UnmarshalTrillRequest(ctx, req.Body, &trillRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	return createTrillFromRequest(ctx, req, requestor, parentID, &trillRequest)
+}
+
+// Checks and creates a trill from a request that's already been validated, responding with the trill
+func createTrillFromRequest(ctx context.Context, req Request, requestor string, parentID *int64, trillRequest *views.TrillRequest) (Response, error) {
+	if resp, ok := handlers.RequireVerifiedEmail(ctx, req); !ok {
+		return resp, nil
+	}
+	if resp, ok := handlers.RequirePostCaptcha(ctx, req, requestor); !ok {
+		return resp, nil
+	}
+
+	pending := trillRequest.PendingTrill(parentID)
+	if trillRequest.QuoteOf != nil {
+		quoted, resp, ok := getQuotableTrill(ctx, requestor, *trillRequest.QuoteOf)
+		if !ok {
+			return resp, nil
+		}
+		pending.QuoteOfID = &quoted.TrillID
+	}
+	trill, err := models.PrepareTrill(ctx, requestor, &pending)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if trillRequest.PublishAt != nil {
+		return scheduleTrill(ctx, requestor, &pending, *trillRequest.PublishAt)
+	}
+
+	if err := models.CreateTrill(ctx, trill); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the author and timestamps the database filled in
+	created, err := models.GetTrill(ctx, trill.TrillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*created})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrill(ctx, created, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Posts several trills as a thread, each replying to the one before it, all at once or not at all
+// Postman: POST - /trills/thread
+func createThread(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var threadRequest views.ThreadRequest
+	if err := views.UnmarshalThreadRequest(ctx, req.Body, &threadRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if resp, ok := handlers.RequireVerifiedEmail(ctx, req); !ok {
+		return resp, nil
+	}
+	if resp, ok := handlers.RequirePostCaptcha(ctx, req, requestor); !ok {
+		return resp, nil
+	}
+
+	// every trill is checked before any is posted
+	trills := make([]*models.Trill, len(threadRequest.Trills))
+	for i := range threadRequest.Trills {
+		trillRequest := &threadRequest.Trills[i]
+		if trillRequest.PublishAt != nil {
+			return Response{StatusCode: 400, Body: models.ErrorThreadScheduled.Error(), Headers: views.DefaultHeaders}, nil
+		}
+
+		pending := trillRequest.PendingTrill(nil)
+		if trillRequest.QuoteOf != nil {
+			quoted, resp, ok := getQuotableTrill(ctx, requestor, *trillRequest.QuoteOf)
+			if !ok {
+				return resp, nil
+			}
+			pending.QuoteOfID = &quoted.TrillID
+		}
+		trill, err := models.PrepareTrill(ctx, requestor, &pending)
+		if err != nil {
+			if httpErr, ok := err.(*models.HTTPError); ok {
+				return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		trills[i] = trill
+	}
+
+	if err := models.CreateThread(ctx, trills); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read them back for the author and timestamps the database filled in
+	trillIDs := make([]int64, len(trills))
+	for i, trill := range trills {
+		trillIDs[i] = trill.TrillID
+	}
+	created, err := models.GetTrillsByID(ctx, trillIDs)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *created)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, created, viewer, models.Cursors{})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Saves a checked trill to be posted at publishAt, responding with the scheduled trill
+func scheduleTrill(ctx context.Context, requestor string, pending *models.PendingTrill, publishAt time.Time) (Response, error) {
+	scheduled, err := models.CreateScheduledTrill(ctx, requestor, pending, publishAt)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalScheduledTrill(ctx, scheduled)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 202, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The requestor's scheduled trills, soonest first, along with any that failed to post
+// Postman: GET - /trills/scheduled
+func getScheduledTrills(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	scheduled, err := models.GetScheduledTrills(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalScheduledTrills(ctx, scheduled)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Cancels one of the requestor's scheduled trills, or clears one that failed
+// Postman: DELETE - /trills/scheduled/{scheduledTrillID}
+func cancelScheduledTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	scheduledTrillID, err := strconv.ParseInt(req.PathParameters["scheduledTrillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid scheduled trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteScheduledTrill(ctx, requestor, scheduledTrillID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "scheduled trill canceled successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// The trill to embed in a quote, which has to be public and visible to the requestor; quoting a
+// retrill quotes the original
+func getQuotableTrill(ctx context.Context, requestor string, trillID int64) (*models.Trill, Response, bool) {
+	quoted, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return nil, Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, false
+		}
+		return nil, Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	}
+	if quoted.RetrillOf != nil {
+		quoted = quoted.RetrillOf
+	}
+
+	if resp, ok := canSeeAuthor(ctx, requestor, &quoted.User); !ok {
+		return nil, resp, false
+	} else if quoted.User.IsPrivate {
+		return nil, Response{StatusCode: 403, Body: models.ErrorQuotePrivate.Error(), Headers: views.DefaultHeaders}, false
+	}
+	return quoted, Response{}, true
+}
+
+// Saves a draft of a trill to finish later, possibly on another device
+// Postman: POST - /trills/drafts
+func createDraft(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var draftRequest views.DraftRequest
+	if err := views.UnmarshalDraftRequest(ctx, req.Body, &draftRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	draft := draftRequest.Draft(requestor)
+	if err := models.CreateDraft(ctx, draft); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the timestamps the database filled in
+	draft, err := models.GetDraft(ctx, requestor, draft.DraftID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalDraft(ctx, draft)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The requestor's drafts, most recently started first
+// Postman: GET - /trills/drafts
+func getDrafts(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	drafts, cursors, err := models.GetDrafts(ctx, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalDraftPage(ctx, drafts, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Replaces the contents of one of the requestor's drafts
+// Postman: PUT - /trills/drafts/{draftID}
+func updateDraft(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	draftID, err := strconv.ParseInt(req.PathParameters["draftID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid draft ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	var draftRequest views.DraftRequest
+	if err := views.UnmarshalDraftRequest(ctx, req.Body, &draftRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	draft := draftRequest.Draft(requestor)
+	draft.DraftID = draftID
+	if err := models.UpdateDraft(ctx, draft); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	draft, err = models.GetDraft(ctx, requestor, draftID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalDraft(ctx, draft)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Discards one of the requestor's drafts
+// Postman: DELETE - /trills/drafts/{draftID}
+func deleteDraft(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	draftID, err := strconv.ParseInt(req.PathParameters["draftID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid draft ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteDraft(ctx, requestor, draftID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "draft deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Posts one of the requestor's drafts as a trill, with the same checks as POST /trills, and deletes the
+// draft once it's posted
+// Postman: POST - /trills/drafts/{draftID}/publish
+func publishDraft(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	draftID, err := strconv.ParseInt(req.PathParameters["draftID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid draft ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	draft, err := models.GetDraft(ctx, requestor, draftID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// a draft can be saved half-finished, so it's only held to the trill rules now
+	trillRequest := views.NewDraftTrillRequest(draft)
+	if err := views.Validate(ctx, trillRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	var parentID *int64
+	if draft.ParentID != nil {
+		parent, resp, ok := getReplyParent(ctx, requestor, *draft.ParentID)
+		if !ok {
+			return resp, nil
+		}
+		parentID = &parent.TrillID
+	}
+
+	resp, err := createTrillFromRequest(ctx, req, requestor, parentID, trillRequest)
+	if err != nil || resp.StatusCode != 201 {
+		return resp, err
+	}
+
+	if err := models.DeleteDraft(ctx, requestor, draftID); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return resp, nil
+}
+
+// Gets a presigned URL the client uploads an image, video, or voice note for a trill to; the key it returns goes in the trill's media
+// Postman: POST - /trills/media
+func createMediaUpload(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var uploadRequest views.TrillMediaUploadRequest
+	if err := views.UnmarshalTrillMediaUploadRequest(ctx, req.Body, &uploadRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	ext, kind, err := models.GetMediaExtension(uploadRequest.ContentType)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// videos and voice notes aren't ready until they've been uploaded and transcoded
+	status := models.MediaStatusReady
+	if kind == models.MediaKindVideo || kind == models.MediaKindAudio {
+		status = models.MediaStatusPending
+	}
+	key := models.NewTrillMediaKey(requestor, ext)
+	media := models.Media{
+		Username:    requestor,
+		ObjectKey:   key,
+		ContentType: uploadRequest.ContentType,
+		Kind:        kind,
+		Status:      status,
+		AltText:     strings.TrimSpace(uploadRequest.AltText),
+	}
+	if err := models.CreateMedia(ctx, &media); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	uploadURL, err := models.PresignUpload(ctx, key, uploadRequest.ContentType)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalUpload(ctx, uploadURL, key)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gets a single trill, as long as the requestor is allowed to see its author. expand is a comma separated
+// list of author, quoted, and media to hydrate for a permalink page; leaving it off hydrates the quoted
+// trill and media, as this always has.
+// Postman: GET - /trills/{trillID}?expand=author,quoted,media
+func getTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	expand, err := parseTrillExpansions(req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*trill})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	var requestorFollows, followsRequestor, requestorMuted bool
+	if expand[views.ExpandAuthor] {
+		if requestorFollows, err = models.IsFollowing(ctx, requestor, trill.Username); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if followsRequestor, err = models.IsFollowing(ctx, trill.Username, requestor); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		if requestorMuted, err = models.IsMuted(ctx, requestor, trill.Username); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
+	body, err := views.MarshalTrillPermalink(ctx, trill, viewer, expand, requestorFollows, followsRequestor, requestorMuted)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordImpressions(ctx, requestor, []models.Trill{*trill})
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The expansions named in ?expand=, or quoted and media if it isn't given
+func parseTrillExpansions(req Request) (map[string]bool, error) {
+	value, ok := req.QueryStringParameters["expand"]
+	if !ok {
+		return map[string]bool{views.ExpandQuoted: true, views.ExpandMedia: true}, nil
+	}
+
+	expand := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case views.ExpandAuthor, views.ExpandQuoted, views.ExpandMedia:
+			expand[name] = true
+		default:
+			return nil, fmt.Errorf("can't expand '%s'", name)
+		}
+	}
+	return expand, nil
+}
+
+// Changes the text of one of the requestor's trills, within TRILL_EDIT_WINDOW_MINUTES of posting it
+// Postman: PATCH - /trills/{trillID}
+func editTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	var editRequest views.EditTrillRequest
+	if err := views.UnmarshalEditTrillRequest(ctx, req.Body, &editRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if err := models.EditTrill(ctx, trillID, requestor, editRequest.Text); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	edited, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*edited})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrill(ctx, edited, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Every version of a trill's text, newest first, as long as the requestor is allowed to see its author
+// Postman: GET - /trills/{trillID}/history
+func getTrillHistory(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	revisions, err := models.GetTrillRevisions(ctx, trill.TrillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillHistory(ctx, trill, revisions)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// A trill with the thread above it and a page of the replies below it. Each reply comes with its
+// first few replies; the rest of a branch is another call with that reply's ID. Deleted trills that
+// had replies show up as tombstones, and a tombstone's ID can be passed here too.
+// Postman: GET - /trills/{trillID}/conversation
+func getConversation(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	entry, err := models.GetThreadEntry(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	// a tombstone has no author left to check
+	if trill := entry.Trill; trill != nil {
+		if trill.RetrillOf != nil {
+			entry.Trill = trill.RetrillOf
+		}
+		if resp, ok := canSeeAuthor(ctx, requestor, &entry.Trill.User); !ok {
+			return resp, nil
+		}
+	}
+
+	ancestors, err := models.GetTrillAncestors(ctx, entry.ParentID(), requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	replies, cursors, err := models.GetReplies(ctx, entry.TrillID(), requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	replyIDs := make([]int64, len(replies))
+	for i := range replies {
+		replyIDs[i] = replies[i].TrillID()
+	}
+	previews, err := models.GetReplyPreviews(ctx, replyIDs, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// only trills that weren't deleted have anything to look up or count a view for
+	var shown []models.Trill
+	for _, shownEntry := range append(append([]models.ThreadEntry{*entry}, ancestors...), replies...) {
+		if shownEntry.Trill != nil {
+			shown = append(shown, *shownEntry.Trill)
+		}
+	}
+	for _, preview := range previews {
+		shown = append(shown, preview...)
+	}
+	viewer, err := models.GetTrillViewer(ctx, requestor, shown)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalConversation(ctx, entry, ancestors, replies, previews, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordImpressions(ctx, requestor, shown)
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Lists a user's trills newest first, defaulting to the requestor's own
+// Postman: GET - /trills?username=
+func getTrills(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	username := requestor
+	if value, ok := req.QueryStringParameters["username"]; ok {
+		resolved, err := models.ResolveUsername(ctx, value)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		username = resolved
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, user); !ok {
+		return resp, nil
+	}
+
+	trills, cursors, err := models.GetUserTrills(ctx, user.Username, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordImpressions(ctx, requestor, *trills)
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Who liked a trill on /likers, or retrilled it on /retrillers, most recent first. Users the requestor
+// can't see are left out, and the list is only there for trills they can see.
+// Postman: GET - /trills/{trillID}/likers
+func getEngagers(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	// a retrill's likes and retrills are the original's
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	var engagers *[]models.Engager
+	var cursors models.Cursors
+	if req.RouteKey == "GET /trills/{trillID}/retrillers" {
+		engagers, cursors, err = models.GetRetrillers(ctx, trill.TrillID, requestor, limit, cursor)
+	} else {
+		engagers, cursors, err = models.GetTrillLikers(ctx, trill.TrillID, requestor, limit, cursor)
+	}
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalEngagerPage(ctx, engagers, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// The requestor's bookmarked trills, most recently bookmarked first, optionally only the ones in a folder
+// Postman: GET - /trills/bookmarks?folder_id=
+func getBookmarks(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	var folderID *int64
+	if value, ok := req.QueryStringParameters["folder_id"]; ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return Response{StatusCode: 400, Body: "invalid folder ID", Headers: views.DefaultHeaders}, nil
+		}
+		if _, err := models.GetBookmarkFolder(ctx, requestor, parsed); err != nil {
+			if httpErr, ok := err.(*models.HTTPError); ok {
+				return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+			}
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		folderID = &parsed
+	}
+
+	trills, cursors, err := models.GetBookmarkedTrills(ctx, requestor, folderID, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordImpressions(ctx, requestor, *trills)
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Trills using a hashtag that the requestor can see, newest first; the tag matches with or without the #
+// Postman: GET - /hashtags/{tag}/trills
+func getHashtagTrills(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	tag, ok := models.NormalizeHashtag(req.PathParameters["tag"])
+	if !ok {
+		return Response{StatusCode: 400, Body: models.ErrorInvalidHashtag.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trills, cursors, err := models.GetHashtagTrills(ctx, tag, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, *trills)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillPage(ctx, trills, viewer, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordImpressions(ctx, requestor, *trills)
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// GIFs for the picker, matching q on /gifs/search or trending on /gifs/trending, paged with limit and page.
+// The provider's API key never leaves the server; attach a result by putting its url in a trill's gif.
+// Postman: GET - /gifs/search?q=
+func searchGIFs(ctx context.Context, req Request) (Response, error) {
+	query := ""
+	if req.RouteKey == "GET /gifs/search" {
+		query = strings.TrimSpace(req.QueryStringParameters["q"])
+		if query == "" {
+			return Response{StatusCode: 400, Body: "q is required", Headers: views.DefaultHeaders}, nil
+		}
+	}
+
+	paginate, err := handlers.GetPaginateFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	gifs, err := models.SearchGIFs(ctx, query, paginate)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalGIFPage(ctx, gifs, paginate)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Deletes one of the requestor's own trills
+// Postman: DELETE - /trills/{trillID}
+func deleteTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteTrill(ctx, trillID, requestor); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "trill deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Retrills a trill the requestor can see to their own timeline; retrilling a retrill retrills the original
+// Postman: POST - /trills/{trillID}/retrill
+func retrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	original, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if original.RetrillOf != nil {
+		original = original.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &original.User); !ok {
+		return resp, nil
+	}
+
+	created, err := models.CreateRetrill(ctx, requestor, original)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the retriller and the original's new count
+	created, err = models.GetTrill(ctx, created.TrillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*created})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrill(ctx, created, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Takes the requestor's retrill of a trill back off their timeline
+// Postman: DELETE - /trills/{trillID}/retrill
+func undoRetrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteRetrill(ctx, requestor, trillID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "retrill removed successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Likes a trill the requestor can see, returning it with the new count; liking a retrill likes the original
+// Postman: POST - /trills/{trillID}/like
+func likeTrill(ctx context.Context, req Request) (Response, error) {
+	return actOnTrill(ctx, req, true, models.LikeTrill)
+}
+
+// Takes back the requestor's like, returning the trill with the new count
+// Postman: DELETE - /trills/{trillID}/like
+func unlikeTrill(ctx context.Context, req Request) (Response, error) {
+	return actOnTrill(ctx, req, false, models.UnlikeTrill)
+}
+
+// Bookmarks a trill the requestor can see; bookmarking a retrill bookmarks the original
+// Postman: POST - /trills/{trillID}/bookmark
+func bookmarkTrill(ctx context.Context, req Request) (Response, error) {
+	return actOnTrill(ctx, req, true, models.BookmarkTrill)
+}
+
+// Removes one of the requestor's bookmarks
+// Postman: DELETE - /trills/{trillID}/bookmark
+func removeBookmark(ctx context.Context, req Request) (Response, error) {
+	return actOnTrill(ctx, req, false, models.RemoveBookmark)
+}
+
+// Moves one of the requestor's bookmarks into one of their folders, or out of its folder
+// Postman: PUT - /trills/{trillID}/bookmark/folder
+func moveBookmark(ctx context.Context, req Request) (Response, error) {
+	var move views.MoveBookmarkRequest
+	if err := views.UnmarshalMoveBookmarkRequest(ctx, req.Body, &move); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	return actOnTrill(ctx, req, false, func(ctx context.Context, username string, trillID int64) error {
+		return models.MoveBookmark(ctx, username, trillID, move.FolderID)
+	})
+}
+
+// The requestor's bookmark folders, alphabetically
+// Postman: GET - /trills/bookmarks/folders
+func getBookmarkFolders(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	folders, err := models.GetBookmarkFolders(ctx, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalBookmarkFolders(ctx, folders)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Makes a bookmark folder for the requestor
+// Postman: POST - /trills/bookmarks/folders
+func createBookmarkFolder(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var folderRequest views.BookmarkFolderRequest
+	if err := views.UnmarshalBookmarkFolderRequest(ctx, req.Body, &folderRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	folder := folderRequest.Folder(requestor)
+	if err := models.CreateBookmarkFolder(ctx, folder); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalBookmarkFolder(ctx, folder)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Deletes one of the requestor's bookmark folders, keeping the bookmarks that were in it
+// Postman: DELETE - /trills/bookmarks/folders/{folderID}
+func deleteBookmarkFolder(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	folderID, err := strconv.ParseInt(req.PathParameters["folderID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid folder ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteBookmarkFolder(ctx, requestor, folderID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "bookmark folder deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Pins one of the requestor's trills to their profile, replacing any trill pinned before
+// Postman: POST - /trills/{trillID}/pin
+func pinTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.PinTrill(ctx, requestor, trillID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "trill pinned successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Unpins the requestor's pinned trill
+// Postman: DELETE - /trills/{trillID}/pin
+func unpinTrill(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.UnpinTrill(ctx, requestor, trillID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "trill unpinned successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Votes in the poll on a trill the requestor can see, returning the trill with the results; voting on a
+// retrill votes in the original's poll
+// Postman: POST - /trills/{trillID}/poll/votes
+func voteInPoll(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	var voteRequest views.VoteRequest
+	if err := views.UnmarshalVoteRequest(ctx, req.Body, &voteRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	if err := models.VoteInPoll(ctx, requestor, trill.TrillID, *voteRequest.Option); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the new counts
+	updated, err := models.GetTrill(ctx, trill.TrillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*updated})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrill(ctx, updated, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Runs the action on the trill, or the original for a retrill, and returns the trill as it is afterwards.
+// Taking something back skips the visibility check, so it works even after a block or the author going private.
+func actOnTrill(ctx context.Context, req Request, checkVisible bool,
+	action func(ctx context.Context, username string, trillID int64) error) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+
+	if checkVisible {
+		if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+			return resp, nil
+		}
+	}
+	if err := action(ctx, requestor, trill.TrillID); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// read it back for the new counts
+	updated, err := models.GetTrill(ctx, trill.TrillID)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*updated})
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrill(ctx, updated, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Impressions that can't be queued are dropped rather than failing the response they were for
+func recordImpressions(ctx context.Context, requestor string, trills []models.Trill) {
+	if err := models.EnqueueTrillImpressions(ctx, requestor, trills); err != nil {
+		fmt.Printf("failed to record impressions for %s: %s\n", requestor, err.Error())
+	}
+}
+
+// 403 if either user has blocked the other, or the author's account is private and the requestor doesn't follow them
+func canSeeAuthor(ctx context.Context, requestor string, author *models.User) (Response, bool) {
+	if blocked, err := models.IsBlocked(ctx, requestor, author.Username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, false
+	}
+	if canView, err := models.CanViewUser(ctx, requestor, author); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, false
+	} else if !canView {
+		return Response{StatusCode: 403, Body: models.ErrorPrivateAccount.Error(), Headers: views.DefaultHeaders}, false
+	}
+	return Response{}, true
+}
+
+func main() {
+	lambda.Start(handlers.RateLimited(handler))
+}
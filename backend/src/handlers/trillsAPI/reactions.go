@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/utils"
+	"trill/src/views"
+)
+
+// Reacts to a trill the requestor can see with an emoji, returning the trill with the new counts;
+// reacting to a retrill reacts to the original
+// Postman: POST - /trills/{trillID}/reactions
+func reactToTrill(ctx context.Context, req Request) (Response, error) {
+	var reaction views.ReactionRequest
+	if err := views.UnmarshalReactionRequest(ctx, req.Body, &reaction); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	return actOnTrill(ctx, req, true, func(ctx context.Context, username string, trillID int64) error {
+		return models.ReactToTrill(ctx, username, trillID, reaction.Emoji)
+	})
+}
+
+// Takes back one of the requestor's reactions, returning the trill with the new counts
+// Postman: DELETE - /trills/{trillID}/reactions/{emoji}
+func unreactToTrill(ctx context.Context, req Request) (Response, error) {
+	emoji, err := url.PathUnescape(req.PathParameters["emoji"])
+	if err != nil || !utils.IsEmoji(emoji) {
+		return Response{StatusCode: 400, Body: "invalid emoji", Headers: views.DefaultHeaders}, nil
+	}
+
+	return actOnTrill(ctx, req, false, func(ctx context.Context, username string, trillID int64) error {
+		return models.UnreactToTrill(ctx, username, trillID, emoji)
+	})
+}
+
+// Who reacted to a trill, most recent first, optionally only with one emoji
+// Postman: GET - /trills/{trillID}/reactions?emoji=
+func getReactors(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	emoji := req.QueryStringParameters["emoji"]
+	if emoji != "" && !utils.IsEmoji(emoji) {
+		return Response{StatusCode: 400, Body: "invalid emoji", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	// a retrill's reactions are the original's
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	reactors, cursors, err := models.GetReactors(ctx, trill.TrillID, emoji, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalReactorPage(ctx, reactors, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// A trill's text translated into lang, defaulting to the language in the requestor's settings; a retrill
+// gives the original's
+// Postman: GET - /trills/{trillID}/translation?lang=
+func getTrillTranslation(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	trillID, err := strconv.ParseInt(req.PathParameters["trillID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid trill ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	trill, err := models.GetTrill(ctx, trillID)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if resp, ok := canSeeAuthor(ctx, requestor, &trill.User); !ok {
+		return resp, nil
+	}
+
+	language := req.QueryStringParameters["lang"]
+	if language == "" {
+		settings, err := models.GetUserSettings(ctx, requestor)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		language = models.TranslationLanguage(settings)
+	}
+
+	translation, err := models.GetTrillTranslation(ctx, trill, language)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTrillTranslation(ctx, translation)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/utils"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type SQSEvent = events.SQSEvent
+
+// should match maxReceiveCount on the queue's redrive policy
+const maxAttempts = 3
+
+// time left before the timeout at which the worker stops and queues the rest of the import
+const handoffMargin = time.Minute
+
+// tweets between saves of the import's progress
+const progressInterval = 25
+
+// the most images a trill can have
+const maxImportedImages = 4
+
+var archiveContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".mp4":  "video/mp4",
+}
+
+var db *gorm.DB
+
+func handler(ctx context.Context, event SQSEvent) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		var job models.TwitterImportJob
+		if err := json.Unmarshal([]byte(record.Body), &job); err != nil {
+			// retrying won't fix a malformed message
+			fmt.Printf("skipping message %s: %s\n", record.MessageId, err.Error())
+			continue
+		}
+
+		imp, err := models.GetLatestTwitterImport(initCtx, job.Username)
+		if err != nil {
+			return err
+		} else if imp == nil || imp.ImportID != job.ImportID {
+			fmt.Printf("skipping import %s: no longer the latest for %s\n", job.ImportID, job.Username)
+			continue
+		} else if imp.Status == models.ImportComplete || imp.Status == models.ImportFailed {
+			continue
+		}
+
+		if err := runImport(initCtx, imp); err != nil {
+			// the last attempt records the failure so the user can try again
+			attempts, _ := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+			if attempts < maxAttempts {
+				return fmt.Errorf("failed to run import %s: %w", job.ImportID, err)
+			}
+			imp.Status = models.ImportFailed
+			imp.Error = err.Error()
+			if err := models.UpdateTwitterImport(initCtx, imp); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Works through the archive's tweets from where the last run left off. An import too big for one
+// invocation saves its place and queues itself again; tweets imported past the last save are found by
+// their tweet ID rather than posted twice.
+func runImport(ctx context.Context, imp *models.TwitterImport) error {
+	if imp.Status != models.ImportRunning {
+		now := time.Now()
+		imp.Status = models.ImportRunning
+		imp.StartedAt = &now
+		if err := models.UpdateTwitterImport(ctx, imp); err != nil {
+			return err
+		}
+	}
+
+	archivePath := filepath.Join(os.TempDir(), imp.ImportID+".zip")
+	defer os.Remove(archivePath)
+	if err := models.DownloadImportArchive(ctx, imp, archivePath); err != nil {
+		return err
+	}
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return failImport(ctx, imp, err)
+	}
+	defer archive.Close()
+
+	tweets, err := utils.ParseTwitterArchive(&archive.Reader)
+	if err != nil {
+		return failImport(ctx, imp, err)
+	}
+
+	imp.TotalTweets = len(tweets)
+	for imp.ProcessedTweets < len(tweets) {
+		imported, err := importTweet(ctx, imp.Username, &archive.Reader, &tweets[imp.ProcessedTweets])
+		if err != nil {
+			return err
+		}
+		if imported {
+			imp.ImportedTweets++
+		} else {
+			imp.SkippedTweets++
+		}
+		imp.ProcessedTweets++
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < handoffMargin {
+			if err := models.UpdateTwitterImport(ctx, imp); err != nil {
+				return err
+			}
+			return models.EnqueueTwitterImport(ctx, imp)
+		} else if imp.ProcessedTweets%progressInterval == 0 {
+			if err := models.UpdateTwitterImport(ctx, imp); err != nil {
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	imp.Status = models.ImportComplete
+	imp.CompletedAt = &now
+	if err := models.UpdateTwitterImport(ctx, imp); err != nil {
+		return err
+	}
+	return models.DeleteImportArchive(ctx, imp)
+}
+
+// Retrying won't fix an archive that can't be read, so the import fails straight away
+func failImport(ctx context.Context, imp *models.TwitterImport, err error) error {
+	imp.Status = models.ImportFailed
+	imp.Error = err.Error()
+	return models.UpdateTwitterImport(ctx, imp)
+}
+
+// Posts the tweet as a trill, reporting whether it was imported. Retweets, replies to tweets that weren't
+// imported (which covers replies to other accounts), and tweets too long for a trill are skipped.
+func importTweet(ctx context.Context, username string, archive *zip.Reader, tweet *utils.ArchivedTweet) (bool, error) {
+	if tweet.IsRetweet {
+		return false, nil
+	}
+	if existing, err := models.GetImportedTrillID(ctx, username, tweet.ID); err != nil {
+		return false, err
+	} else if existing != nil {
+		return true, nil
+	}
+
+	var parentID *int64
+	if tweet.InReplyToID != "" {
+		var err error
+		if parentID, err = models.GetImportedTrillID(ctx, username, tweet.InReplyToID); err != nil {
+			return false, err
+		} else if parentID == nil {
+			return false, nil
+		}
+	}
+	if !models.TrillTextFits(tweet.Text) {
+		return false, nil
+	}
+
+	media, err := importMedia(ctx, username, archive, tweet)
+	if err != nil {
+		return false, err
+	} else if tweet.Text == "" && len(media) == 0 {
+		return false, nil
+	}
+
+	trill := models.Trill{
+		Username:      username,
+		Text:          tweet.Text,
+		ParentID:      parentID,
+		Media:         media,
+		Sensitive:     tweet.PossiblySensitive,
+		ReplyAudience: models.ReplyAudienceEveryone,
+		CreatedAt:     tweet.CreatedAt,
+		UpdatedAt:     tweet.CreatedAt,
+	}
+	if err := models.ImportTrill(ctx, &trill, tweet.ID); err != nil {
+		// the parent was deleted since it was imported
+		if _, ok := err.(*models.HTTPError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Uploads the tweet's photos, or its video or GIF, which Twitter stores as an MP4, to the content bucket.
+// Media the export left out, or that's too big for a trill, is skipped.
+func importMedia(ctx context.Context, username string, archive *zip.Reader, tweet *utils.ArchivedTweet) ([]models.Media, error) {
+	var media []models.Media
+	for _, archived := range tweet.Media {
+		if archived.File == "" || len(media) == maxImportedImages {
+			continue
+		}
+		ext := strings.ToLower(path.Ext(archived.File))
+		contentType, ok := archiveContentTypes[ext]
+		if !ok {
+			continue
+		}
+
+		kind, maxBytes, status := models.MediaKindImage, models.MaxTrillImageBytes, models.MediaStatusReady
+		if archived.Type != utils.ArchivedPhoto {
+			// videos are transcoded once they land in the bucket, like any other upload
+			kind, maxBytes, status = models.MediaKindVideo, models.MaxTrillVideoBytes, models.MediaStatusPending
+		}
+		// a trill has images or a video, not both
+		if len(media) > 0 && (kind == models.MediaKindVideo || media[0].Kind == models.MediaKindVideo) {
+			continue
+		}
+
+		buf, err := utils.ReadArchiveFile(archive, archived.File, maxBytes)
+		if errors.Is(err, utils.ErrorFileTooLarge) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		m := models.Media{
+			Username:    username,
+			ObjectKey:   models.NewTrillMediaKey(username, ext),
+			ContentType: contentType,
+			Kind:        kind,
+			Status:      status,
+			AltText:     archived.AltText,
+			Position:    len(media),
+		}
+		if kind == models.MediaKindImage {
+			if m.Width, m.Height, err = utils.ImageSize(buf); err != nil {
+				continue
+			}
+		}
+		// the row has to exist before the upload, for the video processor to find it
+		if err := models.CreateMedia(ctx, &m); err != nil {
+			return nil, err
+		}
+		if err := models.PutContentObject(ctx, m.ObjectKey, contentType, buf); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
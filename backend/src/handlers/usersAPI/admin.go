@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Grants or revokes a user's verified badge; only members of the admins Cognito group can call this
+// Postman: PUT - /users/{username}/verified
+func setVerified(ctx context.Context, req Request) (Response, error) {
+	if forbidden, ok := handlers.RequireRole(req, models.RoleAdmin); !ok {
+		return forbidden, nil
+	}
+
+	var verification views.Verification
+	if err := views.UnmarshalVerification(ctx, req.Body, &verification); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.SetUserVerified(ctx, username, *verification.Verified); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: fmt.Sprintf("%s verified: %t", username, *verification.Verified), Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Hides the requestor's profile and reviews and signs them out everywhere until they reactivate. Their
+// Cognito login stays enabled so reactivating can check their password; the authorizer turns away
+// tokens for deactivated accounts.
+// Postman: POST - /users/deactivate
+func deactivate(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	cognitoUsername, ok := req.RequestContext.Authorizer.Lambda["cognitoUsername"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse cognito username", Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeactivateUser(ctx, username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.AdminGlobalSignOutCognitoUser(ctx, cognitoUsername); err != nil {
+		// a hidden profile that's still signed in would be stuck, so undo the deactivation
+		if revertErr := models.ReactivateUser(ctx, username); revertErr != nil {
+			return Response{StatusCode: 500, Body: fmt.Sprintf("%s, and failed to revert: %s", err.Error(), revertErr.Error()), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "account deactivated successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Logs a deactivated user back in, which reactivates their account. The password is checked before
+// anything else, and a wrong password, an unknown user, and an account that isn't deactivated all get
+// the same 401, so this can't be used to find out which accounts are deactivated.
+// Postman: POST - /users/reactivate
+func reactivate(ctx context.Context, req Request) (Response, error) {
+	var reactivation views.Reactivation
+	if err := views.UnmarshalReactivation(ctx, req.Body, &reactivation); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	tokens, err := models.AuthenticateCognitoUser(ctx, reactivation.Username, reactivation.Password)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// the login may have been by email or an old handle, so go by whose tokens these are
+	cognitoUsername, err := models.GetCognitoUsername(ctx, aws.ToString(tokens.AccessToken))
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	username, err := models.ResolveUsername(ctx, cognitoUsername)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	if deactivated, err := models.IsUserDeactivated(ctx, username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !deactivated {
+		return Response{StatusCode: 401, Body: models.ErrorInvalidCredentials.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.ReactivateUser(ctx, username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTokens(ctx, aws.ToString(tokens.AccessToken), aws.ToString(tokens.IdToken),
+		aws.ToString(tokens.RefreshToken), tokens.ExpiresIn)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
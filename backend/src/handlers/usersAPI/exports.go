@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Queues a copy of everything stored about the requestor; poll GET /users/export for the download link
+// Postman: POST - /users/export
+func requestExport(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	export, err := models.CreateDataExport(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueDataExport(ctx, export); err != nil {
+		// don't leave a pending export around that nothing will ever pick up
+		export.Status = models.ExportFailed
+		export.Error = err.Error()
+		if updateErr := models.UpdateDataExport(ctx, export); updateErr != nil {
+			return Response{StatusCode: 500, Body: updateErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalDataExport(ctx, export, "")
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 202, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gets the status of the requestor's latest export, with a short-lived download link once it's ready
+// Postman: GET - /users/export
+func getExport(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	export, err := models.GetLatestDataExport(ctx, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if export == nil {
+		return Response{StatusCode: 404, Body: "no export has been requested", Headers: views.DefaultHeaders}, nil
+	}
+
+	downloadURL := ""
+	if export.Status == models.ExportComplete {
+		if downloadURL, err = models.PresignExportDownload(ctx, export.ObjectKey); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
+	body, err := views.MarshalDataExport(ctx, export, downloadURL)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Makes a new import of a Twitter data export, with a presigned URL to PUT the archive's ZIP to; then
+// POST /users/import/start to begin
+// Postman: POST - /users/import
+func createImport(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	imp, err := models.CreateTwitterImport(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	uploadURL, err := models.PresignImportUpload(ctx, imp)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTwitterImport(ctx, imp, uploadURL)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Queues the requestor's import once its archive is uploaded; poll GET /users/import for progress
+// Postman: POST - /users/import/start
+func startImport(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	imp, err := models.StartTwitterImport(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.EnqueueTwitterImport(ctx, imp); err != nil {
+		// don't leave a pending import around that nothing will ever pick up
+		imp.Status = models.ImportFailed
+		imp.Error = err.Error()
+		if updateErr := models.UpdateTwitterImport(ctx, imp); updateErr != nil {
+			return Response{StatusCode: 500, Body: updateErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTwitterImport(ctx, imp, "")
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 202, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gets the progress of the requestor's latest import
+// Postman: GET - /users/import
+func getImport(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	imp, err := models.GetLatestTwitterImport(ctx, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if imp == nil {
+		return Response{StatusCode: 404, Body: models.ErrorImportNotFound.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalTwitterImport(ctx, imp, "")
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
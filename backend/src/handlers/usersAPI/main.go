@@ -31,13 +31,83 @@ func handler(ctx context.Context, req Request) (Response, error) {
 
 	switch req.RequestContext.HTTP.Method {
 	case "GET":
-		if _, ok := req.QueryStringParameters["search"]; ok {
+		if req.RouteKey == "GET /users/settings" {
+			return getSettings(initCtx, req)
+		} else if req.RouteKey == "GET /users/search" {
+			return searchUsers(initCtx, req)
+		} else if req.RouteKey == "GET /users/export" {
+			return getExport(initCtx, req)
+		} else if req.RouteKey == "GET /users/import" {
+			return getImport(initCtx, req)
+		} else if req.RouteKey == "GET /users/reports" {
+			return getReports(initCtx, req)
+		} else if _, ok := req.QueryStringParameters["search"]; ok {
 			return search(initCtx, req)
+		} else if _, ok := req.PathParameters["username"]; ok {
+			return getProfile(initCtx, req)
 		} else {
 			return get(initCtx, req)
 		}
+	case "POST":
+		switch req.RouteKey {
+		case "POST /users/avatar":
+			return createUpload(initCtx, req, models.AvatarUploadPrefix)
+		case "POST /users/banner":
+			return createUpload(initCtx, req, models.BannerUploadPrefix)
+		case "POST /users/{username}/block":
+			return block(initCtx, req)
+		case "POST /users/{username}/mute":
+			return mute(initCtx, req)
+		case "POST /users/{username}/report":
+			return report(initCtx, req)
+		case "POST /users/deactivate":
+			return deactivate(initCtx, req)
+		case "POST /users/reactivate":
+			return reactivate(initCtx, req)
+		case "POST /users/export":
+			return requestExport(initCtx, req)
+		case "POST /users/import":
+			return createImport(initCtx, req)
+		case "POST /users/import/start":
+			return startImport(initCtx, req)
+		case "POST /users/batch":
+			return batch(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
 	case "PUT":
-		return update(initCtx, req)
+		switch req.RouteKey {
+		case "PUT /users/username":
+			return changeUsername(initCtx, req)
+		case "PUT /users/avatar":
+			return confirmAvatarUpload(initCtx, req)
+		case "PUT /users/banner":
+			return confirmBannerUpload(initCtx, req)
+		case "PUT /users/settings":
+			return updateSettings(initCtx, req)
+		case "PUT /users/{username}/verified":
+			return setVerified(initCtx, req)
+		case "PUT /users/reports/{reportID}":
+			return closeReport(initCtx, req)
+		case "PUT /users":
+			return update(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "PATCH":
+		switch req.RouteKey {
+		case "PATCH /users":
+			return patch(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
+	case "DELETE":
+		switch req.RouteKey {
+		case "DELETE /users/{username}/block":
+			return unblock(initCtx, req)
+		case "DELETE /users/{username}/mute":
+			return unmute(initCtx, req)
+		case "DELETE /users":
+			return deleteUser(initCtx, req)
+		}
+		return Response{StatusCode: 404, Body: fmt.Sprintf("route '%s' not found", req.RouteKey), Headers: views.DefaultHeaders}, nil
 	default:
 		err := fmt.Errorf("HTTP method '%s' not allowed", req.RequestContext.HTTP.Method)
 		return Response{StatusCode: 405, Body: err.Error(), Headers: views.DefaultHeaders}, nil
@@ -45,8 +115,13 @@ func handler(ctx context.Context, req Request) (Response, error) {
 }
 
 func search(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
 	search := req.QueryStringParameters["search"]
-	users, err := models.SearchUser(ctx, search)
+	users, err := models.SearchUser(ctx, search, requestor)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
@@ -59,6 +134,37 @@ func search(ctx context.Context, req Request) (Response, error) {
 	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
 }
 
+// Searches usernames and nicknames, a page at a time, with users the requestor follows first
+// Postman: GET - /users/search?q=paul&limit=20&cursor=
+func searchUsers(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	query := strings.TrimSpace(req.QueryStringParameters["q"])
+	if len(query) == 0 {
+		return Response{StatusCode: 400, Body: "missing search query 'q'", Headers: views.DefaultHeaders}, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	users, cursors, err := models.SearchUsers(ctx, query, requestor, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalUserSearchResults(ctx, users, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
 func get(ctx context.Context, req Request) (Response, error) {
 	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
 	if !ok {
@@ -67,17 +173,16 @@ func get(ctx context.Context, req Request) (Response, error) {
 
 	var body, userToGet string
 	username, ok := req.QueryStringParameters["username"]
-	privateCognitoUser := &models.PrivateCognitoUser{}
+	includeEmail := false
 	if !ok { // get public + private info
-		var err error
 		userToGet = requestor
-		authToken := strings.Split((req.Headers["authorization"]), " ")[1]
-		privateCognitoUser, err = models.GetPrivateCognitoUser(ctx, authToken)
-		if err != nil {
+		// only the user's own session sees their email, not their API keys or apps
+		includeEmail = handlers.AccessToken(req) != ""
+	} else { // get public info
+		var err error
+		if userToGet, err = models.ResolveUsername(ctx, username); err != nil {
 			return Response{StatusCode: 500, Body: err.Error()}, nil
 		}
-	} else { // get public info
-		userToGet = username
 	}
 
 	user, err := models.GetUser(ctx, userToGet)
@@ -88,6 +193,20 @@ func get(ctx context.Context, req Request) (Response, error) {
 		return Response{StatusCode: 500, Body: err.Error()}, nil
 	}
 
+	if blocked, err := models.IsBlocked(ctx, requestor, user.Username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordProfileView(ctx, user.Username, requestor)
+
+	if canView, err := models.CanViewUser(ctx, requestor, user); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !canView {
+		return restrictedProfile(ctx, requestor, user)
+	}
+
 	following, err := models.GetFollowing(ctx, userToGet)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
@@ -108,12 +227,18 @@ func get(ctx context.Context, req Request) (Response, error) {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
-	reviewCount, err := models.GetUserReviewCount(ctx, userToGet)
+	showPresence, err := sharesPresence(ctx, requestor, userToGet, requestorFollows && followsRequestor)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
-	body, err = views.MarshalFullUser(ctx, user, privateCognitoUser, following, followers, requestorFollows, followsRequestor, reviewCount)
+	pinned, viewer, err := getPinnedTrill(ctx, requestor, user)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err = views.MarshalFullUser(ctx, user, includeEmail, following, followers, requestorFollows, followsRequestor, userToGet == requestor, showPresence,
+		pinned, viewer)
 	if err != nil {
 		return Response{StatusCode: 500, Body: err.Error()}, nil
 	}
@@ -125,6 +250,151 @@ func get(ctx context.Context, req Request) (Response, error) {
 	}, nil
 }
 
+func sharesPresence(ctx context.Context, requestor string, username string, mutual bool) (bool, error) {
+	if !mutual || requestor == username {
+		return false, nil
+	}
+
+	return models.SharesPresence(ctx, requestor, username)
+}
+
+// The user's pinned trill and what the requestor has done to it, or nil if nothing is pinned
+func getPinnedTrill(ctx context.Context, requestor string, user *models.User) (*models.Trill, *models.TrillViewer, error) {
+	pinned, err := models.GetPinnedTrill(ctx, user)
+	if err != nil || pinned == nil {
+		return nil, nil, err
+	}
+
+	viewer, err := models.GetTrillViewer(ctx, requestor, []models.Trill{*pinned})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pinned, viewer, nil
+}
+
+// A view that can't be queued is dropped rather than failing the profile it was for
+func recordProfileView(ctx context.Context, username string, viewer string) {
+	if err := models.EnqueueProfileView(ctx, username, viewer); err != nil {
+		fmt.Printf("failed to record view of %s by %s: %s\n", username, viewer, err.Error())
+	}
+}
+
+// Profile of a private account for someone who isn't an approved follower
+func restrictedProfile(ctx context.Context, requestor string, user *models.User) (Response, error) {
+	followsRequestor, err := models.IsFollowing(ctx, user.Username, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	followRequested, err := models.HasRequestedFollow(ctx, requestor, user.Username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalRestrictedUser(ctx, user, followsRequestor, followRequested)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gets another user's public profile
+// Postman: GET - /users/{username}
+func getProfile(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	// old handles keep working after a username change
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if blocked, err := models.IsBlocked(ctx, requestor, user.Username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if blocked {
+		return Response{StatusCode: 403, Body: models.ErrorBlocked.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	recordProfileView(ctx, user.Username, requestor)
+
+	if canView, err := models.CanViewUser(ctx, requestor, user); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !canView {
+		return restrictedProfile(ctx, requestor, user)
+	}
+
+	requestorFollows, err := models.IsFollowing(ctx, requestor, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	followsRequestor, err := models.IsFollowing(ctx, username, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	requestorMuted, err := models.IsMuted(ctx, requestor, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	showPresence, err := sharesPresence(ctx, requestor, username, requestorFollows && followsRequestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	pinned, viewer, err := getPinnedTrill(ctx, requestor, user)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalPublicUser(ctx, user, requestorFollows, followsRequestor, requestorMuted, showPresence, pinned, viewer)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Gets the public profiles of up to 100 users in one query
+// Postman: POST - /users/batch
+func batch(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var batchUsers views.BatchUsers
+	if err := views.UnmarshalBatchUsers(ctx, req.Body, &batchUsers); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	users, err := models.GetUsers(ctx, batchUsers.Usernames, requestor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalUsers(ctx, users)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
 // func update(ctx context.Context, req Request) (Response, error) {
 // 	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
 // 	if !ok {
@@ -170,6 +440,26 @@ func update(ctx context.Context, req Request) (Response, error) {
 	if nickname, ok := form.Value["nickname"]; ok {
 		user.Nickname = nickname[0]
 	}
+	if displayName, ok := form.Value["displayName"]; ok {
+		user.DisplayName = displayName[0]
+	}
+	if pronouns, ok := form.Value["pronouns"]; ok {
+		user.Pronouns = pronouns[0]
+	}
+	if location, ok := form.Value["location"]; ok {
+		user.Location = location[0]
+	}
+	if website, ok := form.Value["website"]; ok {
+		user.Website = website[0]
+	}
+	if birthday, ok := form.Value["birthday"]; ok {
+		if user.Birthday, err = models.ParseBirthday(birthday[0]); err != nil {
+			return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+	if err := models.ValidateProfile(user); err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
 	if profilePicture, ok := form.File["profilePicture"]; ok {
 		file, err := profilePicture[0].Open()
 		if err != nil {
@@ -185,10 +475,10 @@ func update(ctx context.Context, req Request) (Response, error) {
 			}, nil
 		}
 
-		filePath := "profile-pictures/" + username + filepath.Ext(profilePicture[0].Filename)
+		filePath := models.AvatarUploadPrefix + username + filepath.Ext(profilePicture[0].Filename)
 
 		_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
-			Bucket: aws.String("trill-content"),
+			Bucket: aws.String(models.ContentBucket),
 			Key:    aws.String(filePath),
 			Body:   file,
 		})
@@ -196,17 +486,158 @@ func update(ctx context.Context, req Request) (Response, error) {
 			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 		}
 
-		url := "https://trill-content.s3.amazonaws.com/" + filePath
+		url := models.ContentBucketURL + filePath
 		user.ProfilePicture = url
+		user.ProfilePictureThumbnail = url
+	}
+
+	if err = models.UpdateUser(ctx, user); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user updated successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Updates only the whitelisted fields present in the JSON body
+// Postman: PATCH - /users
+func patch(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var userPatch views.PatchUser
+	if err := views.UnmarshalPatchUser(ctx, req.Body, &userPatch); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if userPatch.Bio != nil {
+		user.Bio = *userPatch.Bio
+	}
+	if userPatch.DisplayName != nil {
+		user.DisplayName = *userPatch.DisplayName
+	}
+	if userPatch.Pronouns != nil {
+		user.Pronouns = *userPatch.Pronouns
+	}
+	wasPrivate := user.IsPrivate
+	if userPatch.IsPrivate != nil {
+		user.IsPrivate = *userPatch.IsPrivate
+	}
+	if userPatch.Location != nil {
+		user.Location = *userPatch.Location
+	}
+	if userPatch.Website != nil {
+		user.Website = *userPatch.Website
+	}
+	if userPatch.Birthday != nil {
+		if user.Birthday, err = models.ParseBirthday(*userPatch.Birthday); err != nil {
+			return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+	if err := models.ValidateProfile(user); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
 	if err = models.UpdateUser(ctx, user); err != nil {
 		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
 	}
 
+	// nobody has to wait for approval on a public account
+	if wasPrivate && !user.IsPrivate {
+		if err := models.ApproveAllFollowRequests(ctx, username); err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+
 	return Response{StatusCode: 200, Body: "user updated successfully", Headers: views.DefaultHeaders}, nil
 }
 
+// Changes the requestor's handle in both Cognito and RDS
+// Postman: PUT - /users/username
+func changeUsername(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+	cognitoUsername, ok := req.RequestContext.Authorizer.Lambda["cognitoUsername"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse cognito username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var change views.ChangeUsername
+	if err := views.UnmarshalChangeUsername(ctx, req.Body, &change); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+	newUsername := change.Username
+
+	if err := models.ValidateUsername(newUsername); err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if newUsername == username {
+		return Response{StatusCode: 400, Body: "username is unchanged", Headers: views.DefaultHeaders}, nil
+	} else if models.NormalizeUsername(newUsername) == models.NormalizeUsername(username) {
+		return Response{StatusCode: 400, Body: "usernames aren't case-sensitive, so this is already your username", Headers: views.DefaultHeaders}, nil
+	}
+
+	available, err := models.IsUsernameAvailable(ctx, newUsername, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+	// users can always go back to the handle they signed up with
+	if available && !strings.EqualFold(newUsername, cognitoUsername) {
+		available, err = models.IsCognitoUsernameAvailable(ctx, newUsername)
+		if err != nil {
+			return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+		}
+	}
+	if !available {
+		return Response{StatusCode: 409, Body: models.ErrorUsernameUnavailable.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.ChangeUsername(ctx, cognitoUsername, username, newUsername); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "username updated successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Permanently deletes the requestor's account from Cognito and RDS
+// Postman: DELETE - /users
+func deleteUser(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	cognitoUsername, ok := req.RequestContext.Authorizer.Lambda["cognitoUsername"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse cognito username", Headers: views.DefaultHeaders}, nil
+	}
+
+	// Cognito goes first: once the row is gone the authorizer turns the user away, so a failure after
+	// that couldn't be retried and would leave them able to sign in to an account that doesn't exist
+	if err := models.DeleteCognitoUser(ctx, cognitoUsername); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.DeleteUser(ctx, username); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user deleted successfully", Headers: views.DefaultHeaders}, nil
+}
+
 func main() {
-	lambda.Start(handler)
+	lambda.Start(handlers.RateLimited(handler))
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/views"
+)
+
+// Flags a user for the moderators
+// Postman: POST - /users/{username}/report
+func report(ctx context.Context, req Request) (Response, error) {
+	requestor, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var reportRequest views.ReportRequest
+	if err := views.UnmarshalReportRequest(ctx, req.Body, &reportRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	username, err := models.ResolveUsername(ctx, req.PathParameters["username"])
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if username == requestor {
+		return Response{StatusCode: 400, Body: "users cannot report themselves", Headers: views.DefaultHeaders}, nil
+	}
+
+	if _, err := models.GetUser(ctx, username); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	newReport := models.Report{
+		Reporter: requestor,
+		Reported: username,
+		Reason:   reportRequest.Reason,
+		Details:  reportRequest.Details,
+	}
+	if err := models.CreateReport(ctx, &newReport); err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: "user reported successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// The moderation queue: open reports, oldest first; moderators and admins only
+// Postman: GET - /users/reports
+func getReports(ctx context.Context, req Request) (Response, error) {
+	if forbidden, ok := handlers.RequireRole(req, models.RoleModerator); !ok {
+		return forbidden, nil
+	}
+
+	limit, cursor, err := handlers.GetCursorFromRequest(ctx, req)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	reports, cursors, err := models.GetOpenReports(ctx, limit, cursor)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalReportQueue(ctx, reports, cursors)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Resolves or dismisses an open report; moderators and admins only
+// Postman: PUT - /users/reports/{reportID}
+func closeReport(ctx context.Context, req Request) (Response, error) {
+	if forbidden, ok := handlers.RequireRole(req, models.RoleModerator); !ok {
+		return forbidden, nil
+	}
+	moderator, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	reportID, err := strconv.ParseInt(req.PathParameters["reportID"], 10, 64)
+	if err != nil {
+		return Response{StatusCode: 400, Body: "invalid report ID", Headers: views.DefaultHeaders}, nil
+	}
+
+	var resolution views.ReportResolution
+	if err := views.UnmarshalReportResolution(ctx, req.Body, &resolution); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	closed, err := models.CloseReport(ctx, reportID, resolution.Status, moderator)
+	if err != nil {
+		if httpErr, ok := err.(*models.HTTPError); ok {
+			return Response{StatusCode: httpErr.Code, Body: httpErr.Error(), Headers: views.DefaultHeaders}, nil
+		}
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalReport(ctx, closed)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: body, Headers: views.DefaultHeaders}, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"trill/src/handlers"
+	"trill/src/models"
+	"trill/src/utils"
+	"trill/src/views"
+)
+
+// Gets a presigned URL the client uploads a new profile picture or banner to
+// Postman: POST - /users/avatar or /users/banner
+func createUpload(ctx context.Context, req Request, prefix string) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var uploadRequest views.UploadRequest
+	if err := views.UnmarshalUploadRequest(ctx, req.Body, &uploadRequest); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	ext, err := models.GetImageExtension(uploadRequest.ContentType)
+	if err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	// a new key per upload so clients don't keep showing a cached picture
+	key := fmt.Sprintf("%s%s-%d%s", prefix, username, time.Now().Unix(), ext)
+	uploadURL, err := models.PresignUpload(ctx, key, uploadRequest.ContentType)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	body, err := views.MarshalUpload(ctx, uploadURL, key)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 201, Body: body, Headers: views.DefaultHeaders}, nil
+}
+
+// Saves a profile picture once the client has finished uploading it to the presigned URL
+// Postman: PUT - /users/avatar
+func confirmAvatarUpload(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var confirm views.ConfirmUpload
+	if err := views.UnmarshalConfirmUpload(ctx, req.Body, &confirm); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if !isOwnUpload(confirm.Key, models.AvatarUploadPrefix, username) {
+		return Response{StatusCode: 403, Body: "upload does not belong to user", Headers: views.DefaultHeaders}, nil
+	}
+
+	exists, err := models.ContentObjectExists(ctx, confirm.Key)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !exists {
+		return Response{StatusCode: 404, Body: "upload not found", Headers: views.DefaultHeaders}, nil
+	}
+
+	// the upload stands in until avatarProcessor swaps in the resized variants, which it only does while
+	// this is still the user's picture
+	picture := models.ContentBucketURL + confirm.Key
+	if err := models.SetProfilePicture(ctx, username, picture, picture); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := models.QueueAvatarProcessing(ctx, &models.AvatarEvent{Username: username, Key: confirm.Key}); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user updated successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Saves a banner once the client has finished uploading it, as long as it's a landscape image within the size limit
+// Postman: PUT - /users/banner
+func confirmBannerUpload(ctx context.Context, req Request) (Response, error) {
+	username, ok := req.RequestContext.Authorizer.Lambda["username"].(string)
+	if !ok {
+		return Response{StatusCode: 500, Body: "failed to parse username", Headers: views.DefaultHeaders}, nil
+	}
+
+	var confirm views.ConfirmUpload
+	if err := views.UnmarshalConfirmUpload(ctx, req.Body, &confirm); err != nil {
+		return handlers.InvalidRequest(ctx, err), nil
+	}
+
+	if !isOwnUpload(confirm.Key, models.BannerUploadPrefix, username) {
+		return Response{StatusCode: 403, Body: "upload does not belong to user", Headers: views.DefaultHeaders}, nil
+	}
+
+	exists, err := models.ContentObjectExists(ctx, confirm.Key)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if !exists {
+		return Response{StatusCode: 404, Body: "upload not found", Headers: views.DefaultHeaders}, nil
+	}
+
+	buf, err := models.GetContentObject(ctx, confirm.Key, models.MaxBannerBytes)
+	if errors.Is(err, models.ErrorObjectTooLarge) {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	} else if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	if err := utils.ValidateBanner(buf); err != nil {
+		return Response{StatusCode: 400, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user, err := models.GetUser(ctx, username)
+	if err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	user.BannerImage = models.ContentBucketURL + confirm.Key
+	if err = models.UpdateUser(ctx, user); err != nil {
+		return Response{StatusCode: 500, Body: err.Error(), Headers: views.DefaultHeaders}, nil
+	}
+
+	return Response{StatusCode: 200, Body: "user updated successfully", Headers: views.DefaultHeaders}, nil
+}
+
+// Upload keys are <prefix><username>-<timestamp>.<ext>, and usernames can't contain a dash
+func isOwnUpload(key string, prefix string, username string) bool {
+	return strings.HasPrefix(key, prefix+username+"-")
+}
@@ -13,6 +13,9 @@ import (
 
 type CognitoEvent = events.CognitoEventUserPoolsPostConfirmation
 
+// Cognito fires PostConfirmation for password resets too, which must not create a user
+const triggerConfirmSignUp = "PostConfirmation_ConfirmSignUp"
+
 var db *gorm.DB
 
 func create(ctx context.Context, req CognitoEvent) (CognitoEvent, error) {
@@ -23,14 +26,26 @@ func create(ctx context.Context, req CognitoEvent) (CognitoEvent, error) {
 		return req, err
 	}
 
+	email := req.Request.UserAttributes["email"]
+	emailVerified := req.Request.UserAttributes["email_verified"] == "true"
+	if req.TriggerSource != triggerConfirmSignUp {
+		return req, models.SyncUserEmail(initCtx, req.UserName, email, emailVerified)
+	}
+
 	user := models.User{
 		Username:       req.UserName,
 		Nickname:       req.Request.UserAttributes["nickname"],
 		Bio:            "",
 		ProfilePicture: "",
+		Email:          email,
+		EmailVerified:  emailVerified,
+	}
+	if err := models.CreateUserIfNotExists(initCtx, &user); err != nil {
+		return req, err
 	}
 
-	return req, models.CreateUser(initCtx, &user)
+	// sign-up already created the row, before the email was confirmed
+	return req, models.SyncUserEmail(initCtx, req.UserName, email, emailVerified)
 }
 
 func main() {
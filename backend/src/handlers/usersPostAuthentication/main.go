@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type CognitoEvent = events.CognitoEventUserPoolsPostAuthentication
+
+var db *gorm.DB
+
+// Keeps the email on the user's row in step with Cognito each time they log in, which catches
+// changes made to the user pool directly
+func sync(ctx context.Context, req CognitoEvent) (CognitoEvent, error) {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		// an error here would fail the login
+		fmt.Printf("failed to sync email for %s: %s\n", req.UserName, err.Error())
+		return req, nil
+	}
+
+	email := req.Request.UserAttributes["email"]
+	emailVerified := req.Request.UserAttributes["email_verified"] == "true"
+	if err := models.SyncUserEmail(initCtx, req.UserName, email, emailVerified); err != nil {
+		fmt.Printf("failed to sync email for %s: %s\n", req.UserName, err.Error())
+	}
+
+	return req, nil
+}
+
+func main() {
+	lambda.Start(sync)
+}
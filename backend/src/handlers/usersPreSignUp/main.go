@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+type CognitoEvent = events.CognitoEventUserPoolsPreSignup
+
+// Sign-ups through Google or Apple on the hosted UI, whose usernames Cognito generates
+const triggerExternalProvider = "PreSignUp_ExternalProvider"
+
+var db *gorm.DB
+
+// Rejects sign-ups for reserved handles, and for handles that already belong to someone in RDS, e.g. after a username change
+func validate(ctx context.Context, req CognitoEvent) (CognitoEvent, error) {
+	if req.TriggerSource == triggerExternalProvider {
+		return req, linkExistingAccount(ctx, req)
+	}
+
+	if models.IsUsernameReserved(req.UserName) {
+		return req, models.ErrorUsernameReserved
+	}
+
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return req, err
+	}
+
+	available, err := models.IsUsernameAvailable(initCtx, req.UserName, "")
+	if err != nil {
+		return req, err
+	} else if !available {
+		return req, models.ErrorUsernameUnavailable
+	}
+
+	return req, nil
+}
+
+// Links a first-time Google or Apple sign-in to the existing account with the same verified email
+// instead of letting Cognito create a second one. Cognito can't switch the sign-in in progress over
+// to the linked account, so it's failed with a message asking the user to sign in again; every
+// sign-in after that goes straight to their account.
+func linkExistingAccount(ctx context.Context, req CognitoEvent) error {
+	provider, subject, ok := models.ParseFederatedUsername(req.UserName)
+	if !ok || req.Request.UserAttributes["email_verified"] != "true" {
+		return nil
+	}
+
+	cognitoUsername, err := models.FindLinkableCognitoUser(ctx, req.Request.UserAttributes["email"])
+	if err != nil {
+		return err
+	} else if cognitoUsername == "" {
+		return nil
+	}
+
+	if err := models.LinkFederatedIdentity(ctx, cognitoUsername, provider, subject); err != nil {
+		return err
+	}
+	return models.ErrorFederatedIdentityLinked
+}
+
+func main() {
+	lambda.Start(validate)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"trill/src/handlers"
+	"trill/src/models"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"gorm.io/gorm"
+)
+
+// Video uploads come in as S3 events and finished transcodes as EventBridge events, so this takes
+// the fields of both and looks at whichever is set
+type Event struct {
+	Records    []events.S3EventRecord `json:"Records"`
+	DetailType string                 `json:"detail-type"`
+	Detail     json.RawMessage        `json:"detail"`
+}
+
+var db *gorm.DB
+
+func handler(ctx context.Context, event Event) error {
+	var initCtx context.Context
+	var err error
+	initCtx, db, err = handlers.InitContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if event.DetailType == "MediaConvert Job State Change" {
+		var job models.TranscodeJobEvent
+		if err := json.Unmarshal(event.Detail, &job); err != nil {
+			return err
+		}
+		return models.FinishTranscode(initCtx, &job)
+	}
+
+	for _, record := range event.Records {
+		// object keys in S3 events are URL encoded
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return err
+		}
+
+		if err := models.StartTranscode(initCtx, key, record.S3.Object.Size); err != nil {
+			return fmt.Errorf("failed to start transcoding %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
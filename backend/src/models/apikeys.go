@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// A long-lived key a user hands to a bot or integration in place of their own tokens. Only a hash
+// of the key is stored; the key itself is shown once, when it is created or rotated.
+type APIKey struct {
+	ID         string    `gorm:"type:char(36);primarykey"`
+	Username   string    `gorm:"type:varchar(128);index"`
+	Name       string    `gorm:"type:varchar(64)"`
+	Prefix     string    `gorm:"type:varchar(16)"`
+	KeyHash    string    `gorm:"type:char(64);uniqueIndex"`
+	Scopes     string    `gorm:"type:varchar(512)"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time `gorm:"index"`
+}
+
+const (
+	APIKeyPrefix      = "trk_"
+	MaxAPIKeysPerUser = 10
+	// last_used_at is only written this often, so a busy bot isn't an UPDATE per request
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrorAPIKeyNotFound error = errors.New("no API key has that id")
+	ErrorTooManyAPIKeys error = fmt.Errorf("a user can have at most %d API keys", MaxAPIKeysPerUser)
+)
+
+func (key *APIKey) ScopeList() []string {
+	return splitScopes(key.Scopes)
+}
+
+func (key *APIKey) HasScope(scope string) bool {
+	return hasScope(key.Scopes, scope)
+}
+
+// A fresh random key, along with the part of it that's safe to show later and the hash that's stored
+func newAPIKeySecret() (string, string, string, error) {
+	rawKey, keyHash, err := randomToken(APIKeyPrefix)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return rawKey, rawKey[:len(APIKeyPrefix)+8], keyHash, nil
+}
+
+// Creates a key for the user, returning it along with the raw key, which can't be recovered later
+func CreateAPIKey(ctx context.Context, username string, name string, scopes []string) (*APIKey, string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes, err = ValidateScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var count int64
+	if err := db.Model(&APIKey{}).Where("username = ? AND revoked_at IS NULL", username).Count(&count).Error; err != nil {
+		return nil, "", err
+	} else if count >= MaxAPIKeysPerUser {
+		return nil, "", &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyAPIKeys}
+	}
+
+	rawKey, prefix, keyHash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := APIKey{
+		ID:       uuid.NewString(),
+		Username: username,
+		Name:     name,
+		Prefix:   prefix,
+		KeyHash:  keyHash,
+		Scopes:   strings.Join(scopes, ","),
+	}
+	if err := db.Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &key, rawKey, nil
+}
+
+// The user's keys that haven't been revoked, newest first
+func GetAPIKeys(ctx context.Context, username string) ([]APIKey, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	if err := db.Where("username = ? AND revoked_at IS NULL", username).Order("created_at desc").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func getAPIKey(db *gorm.DB, username string, id string) (*APIKey, error) {
+	var key APIKey
+	if result := db.Where("id = ? AND username = ? AND revoked_at IS NULL", id, username).Limit(1).Find(&key); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorAPIKeyNotFound}
+	}
+
+	return &key, nil
+}
+
+// Swaps the key's secret for a new one, keeping its name and scopes; the old secret stops working right away
+func RotateAPIKey(ctx context.Context, username string, id string) (*APIKey, string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := getAPIKey(db, username, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rawKey, prefix, keyHash, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := db.Model(key).Updates(map[string]interface{}{"prefix": prefix, "key_hash": keyHash, "last_used_at": nil}).Error; err != nil {
+		return nil, "", err
+	}
+	key.Prefix = prefix
+	key.KeyHash = keyHash
+	key.LastUsedAt = nil
+
+	return key, rawKey, nil
+}
+
+func RevokeAPIKey(ctx context.Context, username string, id string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := getAPIKey(db, username, id)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(key).Update("revoked_at", time.Now()).Error
+}
+
+// Looks up the key a request was made with, returning nil if it doesn't exist or was revoked
+func AuthenticateAPIKey(ctx context.Context, rawKey string) (*APIKey, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, nil
+	}
+
+	var key APIKey
+	if result := db.Where("key_hash = ? AND revoked_at IS NULL", hashSecret(rawKey)).Limit(1).Find(&key); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	// best effort, like presence
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchInterval {
+		now := time.Now()
+		if err := db.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			fmt.Printf("failed to update last used for API key %s: %s\n", key.ID, err.Error())
+		}
+		key.LastUsedAt = &now
+	}
+
+	return &key, nil
+}
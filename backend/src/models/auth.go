@@ -0,0 +1,339 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// A Cognito failure translated into something a client can show, along with the status to send it with
+type AuthError struct {
+	Code   int
+	Reason string
+	Err    error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+var (
+	ErrorTooManyRequests    error = errors.New("too many attempts, try again later")
+	ErrorCodeDeliveryFailed error = errors.New("the confirmation code couldn't be sent")
+	ErrorCodeMismatch       error = errors.New("the confirmation code is incorrect")
+	ErrorCodeExpired        error = errors.New("the confirmation code has expired, request a new one")
+	ErrorAlreadyConfirmed   error = errors.New("this account is already confirmed")
+	ErrorAccountNotFound    error = errors.New("no account has that username")
+	ErrorAccountDisabled    error = errors.New("this account is disabled; deactivated accounts can log back in with POST /users/reactivate")
+	ErrorNotConfirmed       error = errors.New("this account hasn't been confirmed yet")
+	ErrorPasswordReset      error = errors.New("this account needs a password reset before it can log in")
+	ErrorChallengeFailed    error = errors.New("the sign-in session is invalid or has expired, log in again")
+	ErrorRefreshFailed      error = errors.New("the refresh token is invalid, expired, or revoked, log in again")
+	ErrorIncorrectPassword  error = errors.New("the current password is incorrect")
+	ErrorSamePassword       error = errors.New("the new password must be different from the current one")
+	ErrorTokenRevoked       error = errors.New("the access token is invalid or has been revoked, log in again")
+)
+
+// Cognito wants a SECRET_HASH from app clients that have a secret; clients without one leave it out
+func cognitoSecretHash(username string) *string {
+	secrets := utils.GetSecrets()
+	if secrets.CognitoAppClientSecret == "" {
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, []byte(secrets.CognitoAppClientSecret))
+	mac.Write([]byte(username + secrets.CognitoAppClientId))
+	return aws.String(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// Adds SECRET_HASH to InitiateAuth parameters when the app client has a secret
+func withSecretHash(username string, params map[string]string) map[string]string {
+	if secretHash := cognitoSecretHash(username); secretHash != nil {
+		params["SECRET_HASH"] = *secretHash
+	}
+	return params
+}
+
+func withDeviceKey(deviceKey string, params map[string]string) map[string]string {
+	if deviceKey != "" {
+		params["DEVICE_KEY"] = deviceKey
+	}
+	return params
+}
+
+// Registers the user with Cognito, which emails them a confirmation code unless the pool auto-confirms
+func SignUpCognitoUser(ctx context.Context, username string, email string, password string, nickname string) (*cognitoidentityprovider.SignUpOutput, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []types.AttributeType{
+		{Name: aws.String("email"), Value: aws.String(email)},
+	}
+	if nickname != "" {
+		attributes = append(attributes, types.AttributeType{Name: aws.String("nickname"), Value: aws.String(nickname)})
+	}
+
+	signUp, err := cognitoClient.Client.SignUp(ctx, &cognitoidentityprovider.SignUpInput{
+		ClientId:       aws.String(cognitoClient.AppClientId),
+		SecretHash:     cognitoSecretHash(username),
+		Username:       aws.String(username),
+		Password:       aws.String(password),
+		UserAttributes: attributes,
+	})
+	if err != nil {
+		return nil, translateCognitoError(err)
+	}
+
+	return signUp, nil
+}
+
+func ConfirmCognitoSignUp(ctx context.Context, username string, code string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.ConfirmSignUp(ctx, &cognitoidentityprovider.ConfirmSignUpInput{
+		ClientId:         aws.String(cognitoClient.AppClientId),
+		SecretHash:       cognitoSecretHash(username),
+		Username:         aws.String(username),
+		ConfirmationCode: aws.String(code),
+	})
+	var notAuthorized *types.NotAuthorizedException
+	if errors.As(err, &notAuthorized) {
+		// the only thing an unauthenticated confirmation isn't allowed to do is confirm twice
+		return &AuthError{Code: http.StatusConflict, Reason: "already_confirmed", Err: ErrorAlreadyConfirmed}
+	} else if err != nil {
+		return translateCognitoError(err)
+	}
+
+	return nil
+}
+
+// Sends a fresh confirmation code to wherever the first one went
+func ResendCognitoConfirmationCode(ctx context.Context, username string) (*types.CodeDeliveryDetailsType, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resend, err := cognitoClient.Client.ResendConfirmationCode(ctx, &cognitoidentityprovider.ResendConfirmationCodeInput{
+		ClientId:   aws.String(cognitoClient.AppClientId),
+		SecretHash: cognitoSecretHash(username),
+		Username:   aws.String(username),
+	})
+	var invalidParameter *types.InvalidParameterException
+	if errors.As(err, &invalidParameter) && strings.Contains(aws.ToString(invalidParameter.Message), "already confirmed") {
+		return nil, &AuthError{Code: http.StatusConflict, Reason: "already_confirmed", Err: ErrorAlreadyConfirmed}
+	} else if err != nil {
+		return nil, translateCognitoError(err)
+	}
+
+	return resend.CodeDeliveryDetails, nil
+}
+
+// Password login through the app client (which needs ALLOW_USER_PASSWORD_AUTH). The result holds
+// either tokens or the challenge Cognito wants answered first.
+func LoginCognitoUser(ctx context.Context, username string, password string) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := cognitoClient.Client.InitiateAuth(ctx, &cognitoidentityprovider.InitiateAuthInput{
+		ClientId: aws.String(cognitoClient.AppClientId),
+		AuthFlow: types.AuthFlowTypeUserPasswordAuth,
+		AuthParameters: withSecretHash(username, map[string]string{
+			"USERNAME": username,
+			"PASSWORD": password,
+		}),
+	})
+	if err != nil {
+		return nil, translateLoginError(err)
+	}
+
+	return auth, nil
+}
+
+// Trades a refresh token for new access and id tokens. With an app client secret, Cognito checks the
+// SECRET_HASH against the user's Cognito username (the cognito:username claim), not an alias. Tokens
+// issued to a remembered device can only be refreshed with its device key.
+func RefreshCognitoTokens(ctx context.Context, refreshToken string, cognitoUsername string, deviceKey string) (*types.AuthenticationResultType, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := cognitoClient.Client.InitiateAuth(ctx, &cognitoidentityprovider.InitiateAuthInput{
+		ClientId: aws.String(cognitoClient.AppClientId),
+		AuthFlow: types.AuthFlowTypeRefreshTokenAuth,
+		AuthParameters: withSecretHash(cognitoUsername, withDeviceKey(deviceKey, map[string]string{
+			"REFRESH_TOKEN": refreshToken,
+		})),
+	})
+	var notAuthorized *types.NotAuthorizedException
+	if errors.As(err, &notAuthorized) {
+		return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_refresh_token", Err: ErrorRefreshFailed}
+	} else if err != nil {
+		return nil, translateLoginError(err)
+	} else if auth.AuthenticationResult == nil {
+		return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_refresh_token", Err: ErrorRefreshFailed}
+	}
+
+	return auth.AuthenticationResult, nil
+}
+
+// Sets the permanent password for a user created with a temporary one, finishing their login
+func RespondToNewPasswordChallenge(ctx context.Context, username string, session string, newPassword string) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := cognitoClient.Client.RespondToAuthChallenge(ctx, &cognitoidentityprovider.RespondToAuthChallengeInput{
+		ClientId:      aws.String(cognitoClient.AppClientId),
+		ChallengeName: types.ChallengeNameTypeNewPasswordRequired,
+		Session:       aws.String(session),
+		ChallengeResponses: withSecretHash(username, map[string]string{
+			"USERNAME":     username,
+			"NEW_PASSWORD": newPassword,
+		}),
+	})
+	if err != nil {
+		return nil, translateLoginError(err)
+	}
+
+	return auth, nil
+}
+
+// Changes the password of the user the access token belongs to; Cognito checks the new one against the pool's policy
+func ChangeCognitoPassword(ctx context.Context, accessToken string, currentPassword string, newPassword string) error {
+	if currentPassword == newPassword {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "same_password", Err: ErrorSamePassword}
+	}
+
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.ChangePassword(ctx, &cognitoidentityprovider.ChangePasswordInput{
+		AccessToken:      aws.String(accessToken),
+		PreviousPassword: aws.String(currentPassword),
+		ProposedPassword: aws.String(newPassword),
+	})
+	var notAuthorized *types.NotAuthorizedException
+	if errors.As(err, &notAuthorized) && !strings.Contains(strings.ToLower(aws.ToString(notAuthorized.Message)), "token") {
+		// a wrong current password comes back as NotAuthorized too
+		return &AuthError{Code: http.StatusBadRequest, Reason: "incorrect_password", Err: ErrorIncorrectPassword}
+	} else if err != nil {
+		return translateTokenError(err)
+	}
+
+	return nil
+}
+
+// For calls made with the user's access token, where NotAuthorized means the token is no good
+func translateTokenError(err error) error {
+	var notAuthorized *types.NotAuthorizedException
+	if errors.As(err, &notAuthorized) {
+		return &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_token", Err: ErrorTokenRevoked}
+	}
+
+	return translateCognitoError(err)
+}
+
+// Cognito answers most bad logins with NotAuthorizedException, telling them apart only by message
+func translateLoginError(err error) error {
+	var notAuthorized *types.NotAuthorizedException
+	var notConfirmed *types.UserNotConfirmedException
+	var resetRequired *types.PasswordResetRequiredException
+	var userNotFound *types.UserNotFoundException
+	var codeMismatch *types.CodeMismatchException
+	var expiredCode *types.ExpiredCodeException
+
+	switch {
+	case errors.As(err, &notAuthorized):
+		message := strings.ToLower(aws.ToString(notAuthorized.Message))
+		if strings.Contains(message, "disabled") {
+			return &AuthError{Code: http.StatusForbidden, Reason: "account_disabled", Err: ErrorAccountDisabled}
+		} else if strings.Contains(message, "session") {
+			return &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_session", Err: ErrorChallengeFailed}
+		}
+		return &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_credentials", Err: ErrorInvalidCredentials}
+	case errors.As(err, &userNotFound):
+		// same answer as a wrong password, so logins can't be used to find out who has an account
+		return &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_credentials", Err: ErrorInvalidCredentials}
+	case errors.As(err, &notConfirmed):
+		return &AuthError{Code: http.StatusForbidden, Reason: "user_not_confirmed", Err: ErrorNotConfirmed}
+	case errors.As(err, &resetRequired):
+		return &AuthError{Code: http.StatusForbidden, Reason: "password_reset_required", Err: ErrorPasswordReset}
+	case errors.As(err, &codeMismatch), errors.As(err, &expiredCode):
+		return &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_session", Err: ErrorChallengeFailed}
+	}
+
+	return translateCognitoError(err)
+}
+
+// Maps the Cognito exceptions clients can do something about to an AuthError, and passes anything else through
+func translateCognitoError(err error) error {
+	var usernameExists *types.UsernameExistsException
+	var invalidPassword *types.InvalidPasswordException
+	var invalidParameter *types.InvalidParameterException
+	var lambdaValidation *types.UserLambdaValidationException
+	var codeDelivery *types.CodeDeliveryFailureException
+	var tooManyRequests *types.TooManyRequestsException
+	var limitExceeded *types.LimitExceededException
+	var codeMismatch *types.CodeMismatchException
+	var expiredCode *types.ExpiredCodeException
+	var userNotFound *types.UserNotFoundException
+
+	switch {
+	case errors.As(err, &usernameExists):
+		return &AuthError{Code: http.StatusConflict, Reason: "username_exists", Err: ErrorUsernameUnavailable}
+	case errors.As(err, &invalidPassword):
+		return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_password", Err: errors.New(cognitoMessage(invalidPassword.Message))}
+	case errors.As(err, &invalidParameter):
+		return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_parameter", Err: errors.New(cognitoMessage(invalidParameter.Message))}
+	case errors.As(err, &lambdaValidation):
+		// our own PreSignUp trigger turned the user down, and its error is buried in the message
+		message := aws.ToString(lambdaValidation.Message)
+		if strings.Contains(message, ErrorUsernameReserved.Error()) {
+			return &AuthError{Code: http.StatusBadRequest, Reason: "username_not_allowed", Err: ErrorUsernameReserved}
+		} else if strings.Contains(message, ErrorUsernameUnavailable.Error()) {
+			return &AuthError{Code: http.StatusConflict, Reason: "username_exists", Err: ErrorUsernameUnavailable}
+		}
+		return err
+	case errors.As(err, &codeDelivery):
+		return &AuthError{Code: http.StatusBadGateway, Reason: "code_delivery_failed", Err: ErrorCodeDeliveryFailed}
+	case errors.As(err, &codeMismatch):
+		return &AuthError{Code: http.StatusBadRequest, Reason: "code_mismatch", Err: ErrorCodeMismatch}
+	case errors.As(err, &expiredCode):
+		return &AuthError{Code: http.StatusBadRequest, Reason: "code_expired", Err: ErrorCodeExpired}
+	case errors.As(err, &userNotFound):
+		return &AuthError{Code: http.StatusNotFound, Reason: "user_not_found", Err: ErrorAccountNotFound}
+	case errors.As(err, &tooManyRequests), errors.As(err, &limitExceeded):
+		return &AuthError{Code: http.StatusTooManyRequests, Reason: "too_many_requests", Err: ErrorTooManyRequests}
+	}
+
+	return err
+}
+
+// Cognito messages read like "Password did not conform with policy: Password must have uppercase characters"
+func cognitoMessage(message *string) string {
+	if _, detail, found := strings.Cut(aws.ToString(message), ": "); found {
+		return detail
+	}
+	return aws.ToString(message)
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"gorm.io/gorm"
+)
+
+// A one-time code for logging in without the authenticator app, e.g. after losing the phone it was
+// on. Codes are stored as salted hashes and shown once, when they're issued.
+type BackupCode struct {
+	ID        uint      `gorm:"primarykey"`
+	Username  string    `gorm:"type:varchar(128);index"`
+	Salt      string    `gorm:"type:char(32)"`
+	CodeHash  string    `gorm:"type:char(64)"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UsedAt    *time.Time
+}
+
+const (
+	BackupCodeCount = 10
+	// no 0/o or 1/l, so codes survive being read aloud or written down
+	backupCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+	backupCodeLength   = 12
+)
+
+var (
+	ErrorInvalidBackupCode error = errors.New("the backup code is incorrect or was already used")
+	ErrorMFANotRequired    error = errors.New("this account doesn't ask for an authenticator app code, log in normally")
+)
+
+// A random code, formatted xxxx-xxxx-xxxx
+func newBackupCode() (string, error) {
+	var code strings.Builder
+	for i := 0; i < backupCodeLength; i++ {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(backupCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(backupCodeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+// Codes are compared without dashes, spaces, or case, however the user typed them in
+func hashBackupCode(salt string, code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return hashSecret(salt + normalized)
+}
+
+// Replaces the user's backup codes with a fresh set, returning the codes, which can't be recovered later
+func IssueBackupCodes(ctx context.Context, username string) ([]string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, BackupCodeCount)
+	rows := make([]BackupCode, BackupCodeCount)
+	for i := range codes {
+		if codes[i], err = newBackupCode(); err != nil {
+			return nil, err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		rows[i] = BackupCode{Username: username, Salt: hex.EncodeToString(salt)}
+		rows[i].CodeHash = hashBackupCode(rows[i].Salt, codes[i])
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("username = ?", username).Delete(&BackupCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// How many of the user's backup codes haven't been used yet
+func CountBackupCodes(ctx context.Context, username string) (int64, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := db.Model(&BackupCode{}).Where("username = ? AND used_at IS NULL", username).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Uses up one of the user's backup codes, returning false if none of them match
+func RedeemBackupCode(ctx context.Context, username string, code string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var codes []BackupCode
+	if err := db.Where("username = ? AND used_at IS NULL", username).Find(&codes).Error; err != nil {
+		return false, err
+	}
+
+	for _, backupCode := range codes {
+		if subtle.ConstantTimeCompare([]byte(hashBackupCode(backupCode.Salt, code)), []byte(backupCode.CodeHash)) != 1 {
+			continue
+		}
+		// two requests racing with the same code can't both use it
+		result := db.Model(&BackupCode{}).Where("id = ? AND used_at IS NULL", backupCode.ID).Update("used_at", time.Now())
+		return result.RowsAffected == 1, result.Error
+	}
+	return false, nil
+}
+
+// Logs in with a backup code in place of an authenticator app code. The password is checked first,
+// then the code is used up and authenticator app codes are turned off, so the user gets tokens
+// straight away and can set up the app again on their new device.
+func RecoverCognitoLogin(ctx context.Context, loginUsername string, password string, code string) (*cognitoidentityprovider.InitiateAuthOutput, error) {
+	auth, err := LoginCognitoUser(ctx, loginUsername, password)
+	if err != nil {
+		return nil, err
+	} else if auth.ChallengeName != types.ChallengeNameTypeSoftwareTokenMfa {
+		return nil, &AuthError{Code: http.StatusConflict, Reason: "mfa_not_required", Err: ErrorMFANotRequired}
+	}
+
+	// the login name can be an alias, but the challenge always names the user
+	cognitoUsername := auth.ChallengeParameters["USER_ID_FOR_SRP"]
+	username, err := ResolveUsername(ctx, cognitoUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	if redeemed, err := RedeemBackupCode(ctx, username, code); err != nil {
+		return nil, err
+	} else if !redeemed {
+		return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_backup_code", Err: ErrorInvalidBackupCode}
+	}
+
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cognitoClient.Client.AdminSetUserMFAPreference(ctx, &cognitoidentityprovider.AdminSetUserMFAPreferenceInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Username:   aws.String(cognitoUsername),
+		SoftwareTokenMfaSettings: &types.SoftwareTokenMfaSettingsType{
+			Enabled:      false,
+			PreferredMfa: false,
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	return LoginCognitoUser(ctx, loginUsername, password)
+}
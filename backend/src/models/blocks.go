@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Block struct {
+	Blocker   string    `gorm:"type:varchar(128);primarykey"`
+	Blocked   string    `gorm:"type:varchar(128);primarykey"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	ErrorBlocked error = errors.New("this user is unavailable")
+)
+
+// Blocking also drops any follows and follow requests between the two users, in both directions, and
+// takes each off the other's lists
+func CreateBlock(ctx context.Context, blocker string, blocked string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		block := Block{Blocker: blocker, Blocked: blocked}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+			return err
+		}
+
+		for _, pair := range [][2]string{{blocker, blocked}, {blocked, blocker}} {
+			result := tx.Where("followee = ? AND following = ?", pair[0], pair[1]).Delete(&Follows{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if err := countFollows(tx, pair[0], pair[1], -result.RowsAffected); err != nil {
+				return err
+			}
+			if err := clearTimeline(tx, pair[0], pair[1]); err != nil {
+				return err
+			}
+		}
+
+		for _, pair := range [][2]string{{blocker, blocked}, {blocked, blocker}} {
+			if err := removeFromLists(tx, pair[1], tx.Model(&List{}).Select("list_id").Where("owner = ?", pair[0])); err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("(requester = ? AND target = ?) OR (requester = ? AND target = ?)", blocker, blocked, blocked, blocker).
+			Delete(&FollowRequest{}).Error
+	})
+}
+
+func DeleteBlock(ctx context.Context, blocker string, blocked string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("blocker = ? AND blocked = ?", blocker, blocked).Delete(&Block{}).Error
+}
+
+// true if either user has blocked the other
+func IsBlocked(ctx context.Context, username string, otherUsername string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Model(&Block{}).
+		Where("(blocker = ? AND blocked = ?) OR (blocker = ? AND blocked = ?)", username, otherUsername, otherUsername, username).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Filters out rows whose column is a user the requestor has blocked or been blocked by
+func excludeBlocked(query *gorm.DB, db *gorm.DB, column string, requestor string) *gorm.DB {
+	blocked := db.Model(&Block{}).Select("blocked").Where("blocker = ?", requestor)
+	blockers := db.Model(&Block{}).Select("blocker").Where("blocked = ?", requestor)
+	return query.Where(fmt.Sprintf("%s NOT IN (?) AND %s NOT IN (?)", column, column), blocked, blockers)
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A trill the user saved for later. Bookmarks are only ever shown to the user who made them, so
+// trills don't keep a count of them.
+type Bookmark struct {
+	BookmarkID int64     `gorm:"primarykey;autoIncrement"`
+	Username   string    `gorm:"type:varchar(128);uniqueIndex:idx_bookmarks_username_trill_id"`
+	TrillID    int64     `gorm:"uniqueIndex:idx_bookmarks_username_trill_id;index"`
+	FolderID   *int64    `gorm:"index"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// A named group of the user's bookmarks. A bookmark is in at most one folder; one that isn't in any
+// still shows up with all the others.
+type BookmarkFolder struct {
+	FolderID  int64     `gorm:"primarykey;autoIncrement"`
+	Username  string    `gorm:"type:varchar(128);uniqueIndex:idx_bookmark_folders_username_name"`
+	Name      string    `gorm:"type:varchar(50);uniqueIndex:idx_bookmark_folders_username_name"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	MaxBookmarkFolders = 50
+)
+
+var (
+	ErrorBookmarkFolderNotFound error = errors.New("bookmark folder does not exist")
+	ErrorBookmarkFolderExists   error = errors.New("there's already a bookmark folder with that name")
+	ErrorTooManyBookmarkFolders error = errors.New("at most 50 bookmark folders can be made")
+	ErrorBookmarkNotFound       error = errors.New("trill is not bookmarked")
+)
+
+// Bookmarking a trill that's already bookmarked does nothing
+func BookmarkTrill(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	bookmark := Bookmark{Username: username, TrillID: trillID}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error
+}
+
+// Removing a bookmark that doesn't exist does nothing
+func RemoveBookmark(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("username = ? AND trill_id = ?", username, trillID).Delete(&Bookmark{}).Error
+}
+
+// Moves one of the user's bookmarks into one of their folders, or out of any folder for a nil folderID.
+// Fails with a 404 HTTPError if the trill isn't bookmarked or the folder isn't theirs.
+func MoveBookmark(ctx context.Context, username string, trillID int64, folderID *int64) error {
+	if folderID != nil {
+		if _, err := GetBookmarkFolder(ctx, username, *folderID); err != nil {
+			return err
+		}
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var bookmark Bookmark
+	if result := db.Where("username = ? AND trill_id = ?", username, trillID).Limit(1).Find(&bookmark); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorBookmarkNotFound}
+	}
+
+	return db.Model(&Bookmark{}).Where("bookmark_id = ?", bookmark.BookmarkID).Update("folder_id", folderID).Error
+}
+
+// Fails with a 409 HTTPError if the user already has MaxBookmarkFolders or a folder with the same name
+func CreateBookmarkFolder(ctx context.Context, folder *BookmarkFolder) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var folders []BookmarkFolder
+	if err := db.Where("username = ?", folder.Username).Find(&folders).Error; err != nil {
+		return err
+	} else if len(folders) >= MaxBookmarkFolders {
+		return &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyBookmarkFolders}
+	}
+	for _, existing := range folders {
+		if existing.Name == folder.Name {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorBookmarkFolderExists}
+		}
+	}
+
+	return db.Create(folder).Error
+}
+
+// One of the user's folders, failing with a 404 HTTPError if it isn't theirs
+func GetBookmarkFolder(ctx context.Context, username string, folderID int64) (*BookmarkFolder, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var folder BookmarkFolder
+	if result := db.Where("folder_id = ? AND username = ?", folderID, username).Limit(1).Find(&folder); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorBookmarkFolderNotFound}
+	}
+
+	return &folder, nil
+}
+
+// The user's folders in alphabetical order. There are at most MaxBookmarkFolders, so they come back all at once.
+func GetBookmarkFolders(ctx context.Context, username string) (*[]BookmarkFolder, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var folders []BookmarkFolder
+	if err := db.Where("username = ?", username).Order("name ASC").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	return &folders, nil
+}
+
+// Deletes one of the user's folders. The bookmarks in it are kept, just no longer in a folder.
+func DeleteBookmarkFolder(ctx context.Context, username string, folderID int64) error {
+	folder, err := GetBookmarkFolder(ctx, username, folderID)
+	if err != nil {
+		return err
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Bookmark{}).Where("folder_id = ?", folder.FolderID).Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("folder_id = ?", folder.FolderID).Delete(&BookmarkFolder{}).Error
+	})
+}
+
+// The user's bookmarked trills, most recently bookmarked first, keyset paginated in either direction on the
+// bookmark ID; with a folderID, only the ones in that folder. Trills that were deleted or that the user can
+// no longer see are left out, so a page can come back short.
+func GetBookmarkedTrills(ctx context.Context, username string, folderID *int64, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Where("username = ?", username)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+
+	var bookmarks []Bookmark
+	if err := (pagination.Keyset{Value: "bookmark_id"}).Apply(query, cursor, limit).Find(&bookmarks).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	bookmarks, cursors := pagination.Trim(bookmarks, cursor, limit, func(bookmark *Bookmark) Cursor {
+		return Cursor{Value: bookmark.BookmarkID}
+	})
+
+	trillIDs := make([]int64, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		trillIDs[i] = bookmark.TrillID
+	}
+	var found []Trill
+	if len(trillIDs) > 0 {
+		if err := visibleTrills(preloadTrills(db), db, username).Where("trill_id IN ?", trillIDs).Find(&found).Error; err != nil {
+			return nil, Cursors{}, err
+		}
+	}
+
+	// back in the order they were bookmarked
+	byID := make(map[int64]Trill, len(found))
+	for _, trill := range found {
+		byID[trill.TrillID] = trill
+	}
+	trills := make([]Trill, 0, len(found))
+	for _, trillID := range trillIDs {
+		if trill, ok := byID[trillID]; ok {
+			trills = append(trills, trill)
+		}
+	}
+
+	return &trills, cursors, nil
+}
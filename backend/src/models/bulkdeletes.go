@@ -0,0 +1,218 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+)
+
+const (
+	DeletionPending  = "pending"
+	DeletionRunning  = "running"
+	DeletionComplete = "complete"
+	DeletionFailed   = "failed"
+)
+
+// One request to delete many of a user's trills at once, either a list of IDs, which is done right away,
+// or everything posted in a date range, which the trillDeleter worker works through in batches. From is
+// inclusive and Until exclusive; either can be left open.
+type TrillDeletion struct {
+	DeletionID   string `gorm:"type:varchar(32);primarykey"`
+	Username     string `gorm:"type:varchar(128);index"`
+	Status       string `gorm:"type:varchar(16)"`
+	From         *time.Time
+	Until        *time.Time
+	DeletedCount int64     `gorm:"not null;default:0"`
+	Error        string    `gorm:"type:varchar(1024)"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	CompletedAt  *time.Time
+}
+
+// What gets queued for the trillDeleter worker
+type TrillDeletionJob struct {
+	DeletionID string `json:"deletion_id"`
+	Username   string `json:"username"`
+}
+
+var (
+	MaxBulkDeleteIDs = 100
+	// trills deleted per transaction by the worker
+	TrillDeletionBatch = 100
+)
+
+var (
+	ErrorDeletionNotFound   error = errors.New("trill deletion does not exist")
+	ErrorDeletionInProgress error = errors.New("a deletion by date is already in progress")
+	ErrorDeletionScope      error = errors.New("give either trill_ids or a from and until range, not both")
+	ErrorDeletionRange      error = errors.New("from must be before until")
+)
+
+// Records a bulk deletion to run. Fails with a 400 HTTPError if it gives both IDs and a range, or
+// neither, or a range that ends before it starts, and a 409 if a deletion by date is already running.
+func CreateTrillDeletion(ctx context.Context, username string, trillIDs []int64, from *time.Time, until *time.Time) (*TrillDeletion, error) {
+	byRange := from != nil || until != nil
+	if byRange == (len(trillIDs) > 0) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorDeletionScope}
+	} else if from != nil && until != nil && !from.Before(*until) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorDeletionRange}
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if byRange {
+		var count int64
+		if err := db.Model(&TrillDeletion{}).Where("username = ? AND status IN ?", username, []string{DeletionPending, DeletionRunning}).
+			Count(&count).Error; err != nil {
+			return nil, err
+		} else if count > 0 {
+			return nil, &HTTPError{Code: http.StatusConflict, Err: ErrorDeletionInProgress}
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	deletion := TrillDeletion{DeletionID: hex.EncodeToString(id), Username: username, Status: DeletionPending, From: from, Until: until}
+	if err := db.Create(&deletion).Error; err != nil {
+		return nil, err
+	}
+
+	return &deletion, nil
+}
+
+// One of the user's deletions, failing with a 404 HTTPError if it isn't theirs
+func GetTrillDeletion(ctx context.Context, username string, deletionID string) (*TrillDeletion, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletion TrillDeletion
+	if result := db.Where("deletion_id = ? AND username = ?", deletionID, username).Limit(1).Find(&deletion); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorDeletionNotFound}
+	}
+
+	return &deletion, nil
+}
+
+func UpdateTrillDeletion(ctx context.Context, deletion *TrillDeletion) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Save(deletion).Error
+}
+
+func EnqueueTrillDeletion(ctx context.Context, deletion *TrillDeletion) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(TrillDeletionJob{DeletionID: deletion.DeletionID, Username: deletion.Username})
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().TrillDeletionQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Deletes whichever of the trills are the user's, in one transaction, and marks the deletion complete.
+// IDs that don't exist or belong to someone else are skipped.
+func DeleteTrillsByID(ctx context.Context, deletion *TrillDeletion, trillIDs []int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trills []Trill
+	if err := db.Where("trill_id IN ? AND username = ?", trillIDs, deletion.Username).Order("trill_id").Find(&trills).Error; err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return deleteTrills(tx, trills)
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deletion.Status = DeletionComplete
+	deletion.DeletedCount = int64(len(trills))
+	deletion.CompletedAt = &now
+	return UpdateTrillDeletion(ctx, deletion)
+}
+
+// Deletes the next TrillDeletionBatch of the user's trills in the deletion's range, oldest first, and
+// saves the progress. Returns true once there are none left, marking the deletion complete.
+func RunTrillDeletionBatch(ctx context.Context, deletion *TrillDeletion) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := db.Where("username = ?", deletion.Username)
+	if deletion.From != nil {
+		query = query.Where("created_at >= ?", *deletion.From)
+	}
+	if deletion.Until != nil {
+		query = query.Where("created_at < ?", *deletion.Until)
+	}
+
+	var trills []Trill
+	if err := query.Order("trill_id").Limit(TrillDeletionBatch).Find(&trills).Error; err != nil {
+		return false, err
+	}
+
+	if len(trills) > 0 {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return deleteTrills(tx, trills)
+		})
+		if err != nil {
+			return false, err
+		}
+		deletion.DeletedCount += int64(len(trills))
+	}
+
+	done := len(trills) < TrillDeletionBatch
+	deletion.Status = DeletionRunning
+	if done {
+		now := time.Now()
+		deletion.Status = DeletionComplete
+		deletion.CompletedAt = &now
+	}
+	return done, UpdateTrillDeletion(ctx, deletion)
+}
+
+// Deletes each trill the way DeleteTrill does. A retrill of one deleted earlier in the same batch is
+// already gone, which deleteTrill handles.
+func deleteTrills(tx *gorm.DB, trills []Trill) error {
+	for i := range trills {
+		if err := deleteTrill(tx, &trills[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
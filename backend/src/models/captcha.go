@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"trill/src/utils"
+)
+
+var (
+	// posting more than the threshold within the window asks for a CAPTCHA before the next post
+	CaptchaPostWindow           = 10 * time.Minute
+	DefaultCaptchaPostThreshold = 10
+)
+
+var (
+	ErrorCaptchaRequired error = errors.New("solve the CAPTCHA and send its token in the X-Captcha-Token header")
+	ErrorCaptchaFailed   error = errors.New("the CAPTCHA token is invalid or expired")
+)
+
+// CAPTCHAs are only checked once a secret key is configured, so local and test stages can skip them
+func CaptchaEnabled() bool {
+	return utils.GetSecrets().CaptchaSecret != ""
+}
+
+// How many posts within CaptchaPostWindow it takes to be asked for a CAPTCHA; 0 never asks
+func CaptchaPostThreshold() int {
+	threshold, err := strconv.Atoi(utils.GetSecrets().CaptchaPostThreshold)
+	if err != nil || threshold < 0 {
+		return DefaultCaptchaPostThreshold
+	}
+	return threshold
+}
+
+// true if the user has been posting fast enough that their next post needs a CAPTCHA
+func RequiresPostCaptcha(ctx context.Context, username string) (bool, error) {
+	threshold := CaptchaPostThreshold()
+	if !CaptchaEnabled() || threshold == 0 {
+		return false, nil
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// reviews and trills both count as posts
+	since := time.Now().Add(-CaptchaPostWindow)
+	var total int64
+	for _, model := range []interface{}{&Review{}, &Trill{}} {
+		var count int64
+		if err := db.Model(model).Where("username = ? AND created_at >= ?", username, since).Count(&count).Error; err != nil {
+			return false, err
+		}
+		total += count
+	}
+	return total >= int64(threshold), nil
+}
+
+// Verifies a CAPTCHA token, returning an AuthError the client can act on if it's missing or wrong
+func CheckCaptcha(ctx context.Context, token string, sourceIP string) error {
+	if !CaptchaEnabled() {
+		return nil
+	} else if token == "" {
+		return &AuthError{Code: http.StatusForbidden, Reason: "captcha_required", Err: ErrorCaptchaRequired}
+	}
+
+	ok, err := utils.VerifyCaptcha(ctx, token, sourceIP)
+	if err != nil {
+		return err
+	} else if !ok {
+		return &AuthError{Code: http.StatusForbidden, Reason: "captcha_failed", Err: ErrorCaptchaFailed}
+	}
+	return nil
+}
@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"trill/src/utils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/mediaconvert"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -69,6 +75,66 @@ func InitS3Client(ctx context.Context) (*s3.Client, error) {
 	return s3.NewFromConfig(cfg), nil
 }
 
+func InitSQSClient(ctx context.Context) (*sqs.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqs.NewFromConfig(cfg), nil
+}
+
+func InitDynamoDBClient(ctx context.Context) (*dynamodb.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamodb.NewFromConfig(cfg), nil
+}
+
+func InitSchedulerClient(ctx context.Context) (*scheduler.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduler.NewFromConfig(cfg), nil
+}
+
+func InitTranslateClient(ctx context.Context) (*translate.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return translate.NewFromConfig(cfg), nil
+}
+
+// Uses the account's MediaConvert endpoint when MEDIACONVERT_ENDPOINT is set, otherwise the regional one
+func InitMediaConvertClient(ctx context.Context) (*mediaconvert.Client, error) {
+	cfg, err := config.LoadDefaultConfig(
+		ctx, config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mediaconvert.NewFromConfig(cfg, func(o *mediaconvert.Options) {
+		if endpoint := utils.GetSecrets().MediaConvertEndpoint; endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	}), nil
+}
+
 func (e *HTTPError) Error() string {
 	return e.Err.Error()
 }
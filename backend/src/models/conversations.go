@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// how far up a thread GetTrillAncestors walks before giving up
+	MaxConversationAncestors = 50
+	// how many replies to each reply come back with a conversation page
+	ReplyPreviewCount = 2
+)
+
+// Leaves out trills by deactivated accounts, private accounts the requestor doesn't follow, or anyone they have a block with
+func visibleTrills(query *gorm.DB, db *gorm.DB, requestor string) *gorm.DB {
+	query = query.Where("username NOT IN (?) AND username NOT IN (?)", hiddenPrivateUsers(db, requestor), deactivatedUsers(db))
+	return excludeBlocked(query, db, "username", requestor)
+}
+
+// The trills above parentID in the thread, starting from the top, with tombstones in place of deleted
+// ones. The walk stops at a trill the requestor can't see.
+func GetTrillAncestors(ctx context.Context, parentID *int64, requestor string) ([]ThreadEntry, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestors []ThreadEntry
+	for parentID != nil && len(ancestors) < MaxConversationAncestors {
+		var parent Trill
+		result := visibleTrills(preloadTrills(db), db, requestor).Where("trill_id = ?", *parentID).Limit(1).Find(&parent)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		entry := ThreadEntry{Trill: &parent}
+		if result.RowsAffected == 0 {
+			tombstone, found, err := getTombstone(ctx, *parentID)
+			if err != nil {
+				return nil, err
+			} else if !found {
+				break
+			}
+			entry = ThreadEntry{Tombstone: tombstone}
+		}
+		ancestors = append([]ThreadEntry{entry}, ancestors...)
+		parentID = entry.ParentID()
+	}
+
+	return ancestors, nil
+}
+
+// Direct replies to a trill oldest first, keyset paginated in either direction on the trill ID. Deleted
+// replies that still have replies under them come back as tombstones in their place.
+func GetReplies(ctx context.Context, trillID int64, requestor string, limit int, cursor *Cursor) ([]ThreadEntry, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	keyset := pagination.Keyset{Value: "trill_id", Ascending: true}
+	query := visibleTrills(preloadTrills(db), db, requestor).Where("parent_id = ?", trillID)
+	var replies []Trill
+	if err := keyset.Apply(query, cursor, limit).Find(&replies).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+	var tombstones []TrillTombstone
+	if err := keyset.Apply(db.Where("parent_id = ? AND reply_count > 0", trillID), cursor, limit).Find(&tombstones).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	// both are in the order they were read in, which is reversed going backward, so merging them keeps it
+	backward := cursor != nil && cursor.Backward
+	entries := make([]ThreadEntry, 0, len(replies)+len(tombstones))
+	for i, j := 0, 0; i < len(replies) || j < len(tombstones); {
+		if j == len(tombstones) || (i < len(replies) && (replies[i].TrillID < tombstones[j].TrillID) != backward) {
+			entries = append(entries, ThreadEntry{Trill: &replies[i]})
+			i++
+		} else {
+			entries = append(entries, ThreadEntry{Tombstone: &tombstones[j]})
+			j++
+		}
+	}
+
+	entries, cursors := pagination.Trim(entries, cursor, limit, func(entry *ThreadEntry) Cursor {
+		return Cursor{Value: entry.TrillID()}
+	})
+	return entries, cursors, nil
+}
+
+// The first few replies to each of the trills, by parent ID, so a page of a conversation can show
+// where each branch goes without another request per reply
+func GetReplyPreviews(ctx context.Context, trillIDs []int64, requestor string) (map[int64][]Trill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make(map[int64][]Trill, len(trillIDs))
+	if len(trillIDs) == 0 {
+		return previews, nil
+	}
+
+	var replies []Trill
+	if err := visibleTrills(preloadTrills(db), db, requestor).
+		Where("parent_id IN ?", trillIDs).Order("trill_id ASC").Find(&replies).Error; err != nil {
+		return nil, err
+	}
+
+	for _, reply := range replies {
+		if len(previews[*reply.ParentID]) < ReplyPreviewCount {
+			previews[*reply.ParentID] = append(previews[*reply.ParentID], reply)
+		}
+	}
+	return previews, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrorInvalidCredentials error = errors.New("incorrect username or password")
+	ErrorChallengeRequired  error = errors.New("this account needs to finish signing in before it can be reactivated")
+)
+
+// Subquery of deactivated accounts, whose profiles and reviews stay hidden until they log back in
+func deactivatedUsers(db *gorm.DB) *gorm.DB {
+	return db.Model(&User{}).Select("username").Where("deactivated_at IS NOT NULL")
+}
+
+func DeactivateUser(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).Update("deactivated_at", time.Now()).Error
+}
+
+func ReactivateUser(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).Update("deactivated_at", nil).Error
+}
+
+// GetUser hides deactivated accounts, so this is the only way to tell a deactivated account from a missing one
+func IsUserDeactivated(ctx context.Context, username string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Model(&User{}).Where("username = ? AND deactivated_at IS NOT NULL", username).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Password login on the server's behalf; the app client needs ALLOW_ADMIN_USER_PASSWORD_AUTH
+func AuthenticateCognitoUser(ctx context.Context, username string, password string) (*types.AuthenticationResultType, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := cognitoClient.Client.AdminInitiateAuth(ctx, &cognitoidentityprovider.AdminInitiateAuthInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		ClientId:   aws.String(cognitoClient.AppClientId),
+		AuthFlow:   types.AuthFlowTypeAdminUserPasswordAuth,
+		AuthParameters: withSecretHash(username, map[string]string{
+			"USERNAME": username,
+			"PASSWORD": password,
+		}),
+	})
+	var notAuthorized *types.NotAuthorizedException
+	var notFound *types.UserNotFoundException
+	if errors.As(err, &notAuthorized) || errors.As(err, &notFound) {
+		return nil, &HTTPError{Code: http.StatusUnauthorized, Err: ErrorInvalidCredentials}
+	} else if err != nil {
+		return nil, err
+	} else if auth.AuthenticationResult == nil {
+		return nil, &HTTPError{Code: http.StatusUnauthorized, Err: ErrorChallengeRequired}
+	}
+
+	return auth.AuthenticationResult, nil
+}
+
+func GetCognitoUsername(ctx context.Context, accessToken string) (string, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	cognitoUser, err := cognitoClient.Client.GetUser(ctx, &cognitoidentityprovider.GetUserInput{
+		AccessToken: aws.String(accessToken),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(cognitoUser.Username), nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"gorm.io/gorm/clause"
+)
+
+// A name the user gave one of their devices. Cognito only keeps the name the device picked for
+// itself when it was first remembered, and has no way to change it.
+type DeviceName struct {
+	Username  string    `gorm:"type:varchar(128);primarykey"`
+	DeviceKey string    `gorm:"type:varchar(64);primarykey"`
+	Name      string    `gorm:"type:varchar(64)"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	ErrorDeviceNotFound error = errors.New("no remembered device has that key")
+)
+
+// Every device Cognito remembers for the user, across all pages of results
+func ListCognitoDevices(ctx context.Context, accessToken string) ([]types.DeviceType, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []types.DeviceType
+	var paginationToken *string
+	for {
+		page, err := cognitoClient.Client.ListDevices(ctx, &cognitoidentityprovider.ListDevicesInput{
+			AccessToken:     aws.String(accessToken),
+			Limit:           aws.Int32(60),
+			PaginationToken: paginationToken,
+		})
+		if err != nil {
+			return nil, translateTokenError(err)
+		}
+
+		devices = append(devices, page.Devices...)
+		if page.PaginationToken == nil {
+			return devices, nil
+		}
+		paginationToken = page.PaginationToken
+	}
+}
+
+// Names the user has given their devices, by device key
+func GetDeviceNames(ctx context.Context, username string) (map[string]string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deviceNames []DeviceName
+	if err := db.Where("username = ?", username).Find(&deviceNames).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(deviceNames))
+	for _, deviceName := range deviceNames {
+		names[deviceName.DeviceKey] = deviceName.Name
+	}
+	return names, nil
+}
+
+// Names one of the user's remembered devices, after checking with Cognito that it is theirs
+func SetDeviceName(ctx context.Context, accessToken string, username string, deviceKey string, name string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.GetDevice(ctx, &cognitoidentityprovider.GetDeviceInput{
+		AccessToken: aws.String(accessToken),
+		DeviceKey:   aws.String(deviceKey),
+	})
+	if err != nil {
+		return translateDeviceError(err)
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	deviceName := DeviceName{Username: username, DeviceKey: deviceKey, Name: name}
+	return db.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"})}).Create(&deviceName).Error
+}
+
+// Forgets a remembered device. Refresh tokens issued to a remembered device need its key, so this
+// also stops the device from getting new access tokens.
+func ForgetCognitoDevice(ctx context.Context, accessToken string, username string, deviceKey string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.ForgetDevice(ctx, &cognitoidentityprovider.ForgetDeviceInput{
+		AccessToken: aws.String(accessToken),
+		DeviceKey:   aws.String(deviceKey),
+	})
+	if err != nil {
+		return translateDeviceError(err)
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("username = ? AND device_key = ?", username, deviceKey).Delete(&DeviceName{}).Error
+}
+
+func translateDeviceError(err error) error {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return &AuthError{Code: http.StatusNotFound, Reason: "device_not_found", Err: ErrorDeviceNotFound}
+	}
+
+	return translateTokenError(err)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+)
+
+// What a trill request holds, kept for a trill that isn't posted yet. Media are upload keys and GIF is
+// the picker URL, so they're only looked up when the trill is prepared.
+type PendingTrill struct {
+	Text                string            `gorm:"type:text"`
+	Media               []string          `gorm:"type:text;serializer:json"`
+	GIF                 string            `gorm:"type:varchar(1024)"`
+	AltText             map[string]string `gorm:"type:text;serializer:json"`
+	PollOptions         []string          `gorm:"type:text;serializer:json"`
+	PollDurationMinutes int               `gorm:"not null;default:0"`
+	ParentID            *int64
+	QuoteOfID           *int64
+	Sensitive           bool   `gorm:"not null;default:false"`
+	ContentWarning      string `gorm:"type:varchar(100)"`
+	SensitiveMedia      bool   `gorm:"not null;default:false"`
+	ReplyAudience       string `gorm:"type:varchar(16)"`
+}
+
+// A trill the user started and hasn't posted yet. Drafts are unchecked beyond their shape, so uploads
+// and the trills they reply to or quote are only checked on publishing.
+type Draft struct {
+	DraftID      int64  `gorm:"primarykey;autoIncrement"`
+	Username     string `gorm:"type:varchar(128);index"`
+	PendingTrill `gorm:"embedded"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	MaxDrafts = 100
+)
+
+var (
+	ErrorDraftNotFound error = errors.New("draft does not exist")
+	ErrorTooManyDrafts error = errors.New("at most 100 drafts can be saved")
+)
+
+// Fails with a 409 HTTPError if the user already has MaxDrafts
+func CreateDraft(ctx context.Context, draft *Draft) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&Draft{}).Where("username = ?", draft.Username).Count(&count).Error; err != nil {
+		return err
+	} else if count >= int64(MaxDrafts) {
+		return &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyDrafts}
+	}
+
+	return db.Create(draft).Error
+}
+
+// One of the user's drafts; other users' drafts are a 404 HTTPError, the same as ones that don't exist
+func GetDraft(ctx context.Context, username string, draftID int64) (*Draft, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var draft Draft
+	if result := db.Where("draft_id = ? AND username = ?", draftID, username).Limit(1).Find(&draft); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorDraftNotFound}
+	}
+
+	return &draft, nil
+}
+
+// The user's drafts newest first, keyset paginated in either direction on the draft ID
+func GetDrafts(ctx context.Context, username string, limit int, cursor *Cursor) (*[]Draft, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var drafts []Draft
+	query := db.Where("username = ?", username)
+	if err := (pagination.Keyset{Value: "draft_id"}).Apply(query, cursor, limit).Find(&drafts).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	drafts, cursors := pagination.Trim(drafts, cursor, limit, func(draft *Draft) Cursor {
+		return Cursor{Value: draft.DraftID}
+	})
+	return &drafts, cursors, nil
+}
+
+// Replaces everything in one of the user's drafts, failing with a 404 HTTPError if they don't have it
+func UpdateDraft(ctx context.Context, draft *Draft) error {
+	if _, err := GetDraft(ctx, draft.Username, draft.DraftID); err != nil {
+		return err
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Select makes empty fields overwrite what was there
+	return db.Model(&Draft{}).Where("draft_id = ? AND username = ?", draft.DraftID, draft.Username).
+		Select("text", "media", "gif", "alt_text", "poll_options", "poll_duration_minutes", "parent_id", "quote_of_id",
+			"sensitive", "content_warning", "sensitive_media", "reply_audience", "updated_at").
+		Updates(draft).Error
+}
+
+// Fails with a 404 HTTPError if the user doesn't have the draft
+func DeleteDraft(ctx context.Context, username string, draftID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := db.Where("draft_id = ? AND username = ?", draftID, username).Delete(&Draft{})
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorDraftNotFound}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"gorm.io/gorm"
+)
+
+// A version of a trill's text from before an edit, stamped with when that version was posted
+type TrillRevision struct {
+	RevisionID int64     `gorm:"primarykey;autoIncrement"`
+	TrillID    int64     `gorm:"index"`
+	Text       string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	DefaultTrillEditWindow = 30 * time.Minute
+	MaxTrillEdits          = 5
+)
+
+var (
+	ErrorNotTrillEditor     error = errors.New("only the author can edit a trill")
+	ErrorEditWindowClosed   error = errors.New("trills can only be edited shortly after they're posted")
+	ErrorTooManyEdits       error = errors.New("a trill can be edited at most 5 times")
+	ErrorRetrillNotEditable error = errors.New("retrills can't be edited")
+	ErrorTrillTextRequired  error = errors.New("a trill without media needs text")
+)
+
+// How long after posting a trill can be edited, from the TRILL_EDIT_WINDOW_MINUTES setting; 0 turns editing off
+func TrillEditWindow() time.Duration {
+	minutes, err := strconv.Atoi(utils.GetSecrets().TrillEditWindow)
+	if err != nil || minutes < 0 {
+		return DefaultTrillEditWindow
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Replaces the trill's text, keeping the old text as a revision. Hashtags and mentions follow the new
+// text, and only users it newly mentions are notified. A new first link gets a new preview. Fails with a 404 HTTPError if the trill doesn't
+// exist, a 403 if the requestor didn't write it, it's a retrill, it's past the edit window, or it's
+// been edited MaxTrillEdits times, or a 400 if it would be left with no text and no media.
+func EditTrill(ctx context.Context, trillID int64, requestor string, text string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// set when the first link changes, so the new one gets a preview
+	relink := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var trill Trill
+		if result := tx.Preload("Mentions").Where("trill_id = ?", trillID).Limit(1).Find(&trill); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+		} else if trill.Username != requestor {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorNotTrillEditor}
+		} else if trill.RetrillOfID != nil {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorRetrillNotEditable}
+		} else if time.Since(trill.CreatedAt) > TrillEditWindow() {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorEditWindowClosed}
+		}
+
+		var edits int64
+		if err := tx.Model(&TrillRevision{}).Where("trill_id = ?", trillID).Count(&edits).Error; err != nil {
+			return err
+		} else if edits >= int64(MaxTrillEdits) {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorTooManyEdits}
+		}
+
+		if strings.TrimSpace(text) == "" {
+			var media int64
+			if err := tx.Model(&Media{}).Where("trill_id = ?", trillID).Count(&media).Error; err != nil {
+				return err
+			} else if media == 0 {
+				return &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillTextRequired}
+			}
+		}
+
+		// the version being replaced dates from the last edit, or the post if there wasn't one
+		postedAt := trill.CreatedAt
+		if trill.EditedAt != nil {
+			postedAt = *trill.EditedAt
+		}
+		revision := TrillRevision{TrillID: trillID, Text: trill.Text, CreatedAt: postedAt}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"text": text, "edited_at": time.Now()}
+		if relink = FirstLink(text) != FirstLink(trill.Text); relink {
+			updates["link_preview_id"] = nil
+		}
+		if err := tx.Model(&trill).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		trill.Text = text
+
+		if err := tx.Where("trill_id = ?", trillID).Delete(&TrillHashtag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trill_id = ?", trillID).Delete(&TrillTranslation{}).Error; err != nil {
+			return err
+		}
+		if err := tagTrill(tx, trillID, ParseHashtags(text)); err != nil {
+			return err
+		}
+		if err := shortenLinks(tx, &trill); err != nil {
+			return err
+		}
+
+		mentionedBefore := make(map[string]bool, len(trill.Mentions))
+		for _, mention := range trill.Mentions {
+			mentionedBefore[mention.Username] = true
+		}
+		if err := tx.Where("trill_id = ?", trillID).Delete(&Mention{}).Error; err != nil {
+			return err
+		}
+		trill.Mentions = nil
+		if err := mentionUsers(tx, &trill); err != nil {
+			return err
+		}
+		var newMentions []Mention
+		for _, mention := range trill.Mentions {
+			if !mentionedBefore[mention.Username] {
+				newMentions = append(newMentions, mention)
+			}
+		}
+		trill.Mentions = newMentions
+
+		return notifyMentions(tx, &trill)
+	})
+	if err != nil {
+		return err
+	}
+
+	if relink {
+		queueLinkPreview(ctx, &Trill{TrillID: trillID, Text: text})
+	}
+	queueLinkScan(ctx, &Trill{TrillID: trillID, Text: text})
+	return nil
+}
+
+// The trill's earlier versions, newest first
+func GetTrillRevisions(ctx context.Context, trillID int64) (*[]TrillRevision, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []TrillRevision
+	if err := db.Where("trill_id = ?", trillID).Order("revision_id DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	return &revisions, nil
+}
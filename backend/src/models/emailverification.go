@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+var (
+	ErrorEmailNotVerified error = errors.New("verify your email address with POST /auth/email/code and POST /auth/email/verify before posting")
+)
+
+// The AuthError posting handlers answer with, so clients know to ask the user to verify their email
+func EmailNotVerifiedError() *AuthError {
+	return &AuthError{Code: http.StatusForbidden, Reason: "email_not_verified", Err: ErrorEmailNotVerified}
+}
+
+// true once the user has verified their email. Accounts from before emails were kept in RDS have
+// none recorded until they next log in, and count as verified, since Cognito confirmed them by email.
+func IsEmailVerified(ctx context.Context, username string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var user User
+	if result := db.Select("email", "email_verified").Where("username = ?", username).Limit(1).Find(&user); result.Error != nil {
+		return false, result.Error
+	} else if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	return user.EmailVerified || user.Email == "", nil
+}
+
+// Has Cognito email the user a code for verifying their address
+func SendEmailVerificationCode(ctx context.Context, accessToken string) (*types.CodeDeliveryDetailsType, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	send, err := cognitoClient.Client.GetUserAttributeVerificationCode(ctx, &cognitoidentityprovider.GetUserAttributeVerificationCodeInput{
+		AccessToken:   aws.String(accessToken),
+		AttributeName: aws.String("email"),
+	})
+	if err != nil {
+		return nil, translateTokenError(err)
+	}
+
+	return send.CodeDeliveryDetails, nil
+}
+
+// Verifies the user's email with the code Cognito sent, and lets them post from then on
+func VerifyCognitoEmail(ctx context.Context, accessToken string, username string, code string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cognitoClient.Client.VerifyUserAttribute(ctx, &cognitoidentityprovider.VerifyUserAttributeInput{
+		AccessToken:   aws.String(accessToken),
+		AttributeName: aws.String("email"),
+		Code:          aws.String(code),
+	}); err != nil {
+		return translateTokenError(err)
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).Update("email_verified", true).Error
+}
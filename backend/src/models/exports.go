@@ -0,0 +1,245 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const (
+	ExportPending  = "pending"
+	ExportRunning  = "running"
+	ExportComplete = "complete"
+	ExportFailed   = "failed"
+)
+
+// One request for a copy of everything we store about a user
+type DataExport struct {
+	ExportID    string    `gorm:"type:varchar(32);primarykey"`
+	Username    string    `gorm:"type:varchar(128);index"`
+	Status      string    `gorm:"type:varchar(16)"`
+	ObjectKey   string    `gorm:"type:varchar(512)"`
+	Error       string    `gorm:"type:varchar(1024)"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	CompletedAt *time.Time
+}
+
+// What gets queued for the dataExport worker
+type DataExportJob struct {
+	ExportID string `json:"export_id"`
+	Username string `json:"username"`
+}
+
+// Everything stored about a user, collected for an export
+type UserData struct {
+	User               *User
+	Settings           *UserSettings
+	Reviews            []Review
+	Trills             []Trill
+	Likes              []Like
+	TrillLikes         []TrillLike
+	Reactions          []Reaction
+	Bookmarks          []Bookmark
+	Following          []string
+	Followers          []string
+	FollowRequestsSent []string
+	FollowRequestsGot  []string
+	FavoriteAlbums     []FavoriteAlbum
+	ListenLaterAlbums  []ListenLaterAlbum
+	Blocks             []Block
+	Mutes              []Mute
+	PreviousUsernames  []UsernameHistory
+}
+
+var (
+	DownloadExpiration = time.Hour
+	ExportCooldown     = 24 * time.Hour
+)
+
+var (
+	ErrorExportInProgress error = errors.New("an export is already in progress")
+	ErrorExportTooSoon    error = errors.New("only one export can be requested per day")
+)
+
+// Starts a new export unless one is already running or finished recently
+func CreateDataExport(ctx context.Context, username string) (*DataExport, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := GetLatestDataExport(ctx, username)
+	if err != nil {
+		return nil, err
+	} else if latest != nil {
+		if latest.Status == ExportPending || latest.Status == ExportRunning {
+			return nil, &HTTPError{Code: http.StatusConflict, Err: ErrorExportInProgress}
+		} else if latest.Status == ExportComplete && time.Since(latest.CreatedAt) < ExportCooldown {
+			return nil, &HTTPError{Code: http.StatusTooManyRequests, Err: ErrorExportTooSoon}
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	export := DataExport{ExportID: hex.EncodeToString(id), Username: username, Status: ExportPending}
+	if err := db.Create(&export).Error; err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// Returns nil if the user has never requested an export
+func GetLatestDataExport(ctx context.Context, username string) (*DataExport, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var export DataExport
+	if result := db.Where("username = ?", username).Order("created_at desc").Limit(1).Find(&export); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &export, nil
+}
+
+func UpdateDataExport(ctx context.Context, export *DataExport) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Save(export).Error
+}
+
+func EnqueueDataExport(ctx context.Context, export *DataExport) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(DataExportJob{ExportID: export.ExportID, Username: export.Username})
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().ExportQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Collects the user's data from every table that stores any of it
+func GetUserData(ctx context.Context, username string) (*UserData, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := UserData{}
+	var user User
+	if err := db.Unscoped().Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	data.User = &user
+
+	if data.Settings, err = GetUserSettings(ctx, username); err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		model interface{}
+		where string
+	}{
+		{&data.Reviews, "username = ?"},
+		{&data.Likes, "username = ?"},
+		{&data.TrillLikes, "username = ?"},
+		{&data.Reactions, "username = ?"},
+		{&data.Bookmarks, "username = ?"},
+		{&data.FavoriteAlbums, "username = ?"},
+		{&data.ListenLaterAlbums, "username = ?"},
+		{&data.Blocks, "blocker = ?"},
+		{&data.Mutes, "muter = ?"},
+		{&data.PreviousUsernames, "username = ?"},
+	}
+	for _, q := range queries {
+		if err := db.Where(q.where, username).Find(q.model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Preload("Media", orderMedia).Where("username = ?", username).Find(&data.Trills).Error; err != nil {
+		return nil, err
+	}
+
+	usernames := []struct {
+		dest   *[]string
+		model  interface{}
+		column string
+		where  string
+	}{
+		{&data.Following, &Follows{}, "following", "followee = ?"},
+		{&data.Followers, &Follows{}, "followee", "following = ?"},
+		{&data.FollowRequestsSent, &FollowRequest{}, "target", "requester = ?"},
+		{&data.FollowRequestsGot, &FollowRequest{}, "requester", "target = ?"},
+	}
+	for _, u := range usernames {
+		if err := db.Model(u.model).Where(u.where, username).Pluck(u.column, u.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &data, nil
+}
+
+func PutExportObject(ctx context.Context, key string, body []byte) error {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(utils.GetSecrets().ExportBucket),
+		Key:         aws.String(key),
+		ContentType: aws.String("application/zip"),
+		Body:        bytes.NewReader(body),
+	})
+	return err
+}
+
+// The exports bucket is private, so downloads only work through a short-lived link. Lambda credentials
+// are temporary and the link dies with them, so make a new one each time instead of storing it.
+func PresignExportDownload(ctx context.Context, key string) (string, error) {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	presignClient := s3.NewPresignClient(s3Client)
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(utils.GetSecrets().ExportBucket),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(`attachment; filename="trill-data.zip"`),
+	}, s3.WithPresignExpires(DownloadExpiration))
+	if err != nil {
+		return "", err
+	}
+
+	return presigned.URL, nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// Identity providers set up on the user pool's hosted UI, by the lowercased prefix Cognito gives
+// the usernames of users who sign in through them
+var FederatedProviders = map[string]string{
+	"google":          "Google",
+	"signinwithapple": "SignInWithApple",
+}
+
+var (
+	ErrorFederatedIdentityLinked error = errors.New("this sign-in was linked to your existing Trill account with the same email, sign in again to continue")
+)
+
+// A federated user's Cognito username is the provider's name and the user's id there, e.g. Google_1098...
+func ParseFederatedUsername(cognitoUsername string) (provider string, subject string, ok bool) {
+	prefix, subject, found := strings.Cut(cognitoUsername, "_")
+	if !found || subject == "" {
+		return "", "", false
+	}
+	provider, ok = FederatedProviders[strings.ToLower(prefix)]
+	return provider, subject, ok
+}
+
+// The username of the one confirmed, enabled, password-based account with this verified email, or
+// "" if there isn't exactly one. Accounts that never verified the email are left alone, since
+// otherwise anyone could claim an address and wait for its owner to sign in with Google.
+func FindLinkableCognitoUser(ctx context.Context, email string) (string, error) {
+	// the filter is a quoted string; addresses that would need escaping aren't worth linking
+	if email == "" || strings.ContainsAny(email, `"\`) {
+		return "", nil
+	}
+
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	users, err := cognitoClient.Client.ListUsers(ctx, &cognitoidentityprovider.ListUsersInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Filter:     aws.String(fmt.Sprintf("email = \"%s\"", email)),
+		Limit:      aws.Int32(10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var match string
+	for _, user := range users.Users {
+		if !user.Enabled || user.UserStatus != types.UserStatusTypeConfirmed || !hasVerifiedEmail(user.Attributes, email) {
+			continue
+		} else if match != "" {
+			return "", nil
+		}
+		match = aws.ToString(user.Username)
+	}
+	return match, nil
+}
+
+func hasVerifiedEmail(attributes []types.AttributeType, email string) bool {
+	var matches, verified bool
+	for _, attribute := range attributes {
+		switch aws.ToString(attribute.Name) {
+		case "email":
+			matches = strings.EqualFold(aws.ToString(attribute.Value), email)
+		case "email_verified":
+			verified = aws.ToString(attribute.Value) == "true"
+		}
+	}
+	return matches && verified
+}
+
+// Links a provider's user to an existing account, so signing in through the provider signs in as
+// that account from then on
+func LinkFederatedIdentity(ctx context.Context, cognitoUsername string, provider string, subject string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.AdminLinkProviderForUser(ctx, &cognitoidentityprovider.AdminLinkProviderForUserInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		DestinationUser: &types.ProviderUserIdentifierType{
+			ProviderName:           aws.String("Cognito"),
+			ProviderAttributeValue: aws.String(cognitoUsername),
+		},
+		SourceUser: &types.ProviderUserIdentifierType{
+			ProviderName:           aws.String(provider),
+			ProviderAttributeName:  aws.String("Cognito_Subject"),
+			ProviderAttributeValue: aws.String(subject),
+		},
+	})
+	return err
+}
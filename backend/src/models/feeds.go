@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// trills in a feed; readers poll, so they only need the latest
+	FeedSize = 20
+)
+
+// A profile's page in the web app
+func ProfileURL(username string) string {
+	return fmt.Sprintf("%s/%s", WebAppURL(), url.PathEscape(username))
+}
+
+// A hashtag's page in the web app
+func HashtagURL(tag string) string {
+	return fmt.Sprintf("%s/hashtags/%s", WebAppURL(), url.PathEscape(tag))
+}
+
+// The user and their latest trills and retrills, for a feed reader; no one signs in to those, so only public
+// accounts have a feed, and a 403 HTTPError comes back for private ones. Retrills of trills that have since
+// gone private are left out.
+func GetUserFeed(ctx context.Context, username string) (*User, []Trill, error) {
+	user, err := GetUser(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	} else if user.IsPrivate {
+		return nil, nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorPrivateAccount}
+	}
+
+	trills, _, err := GetUserTrills(ctx, user.Username, FeedSize, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feed := make([]Trill, 0, len(*trills))
+	for _, trill := range *trills {
+		if original := trill.RetrillOf; original != nil && (original.User.IsPrivate || original.User.DeactivatedAt != nil) {
+			continue
+		}
+		feed = append(feed, trill)
+	}
+	return user, feed, nil
+}
+
+// The latest public trills using the tag, for a feed reader. The tag should already be normalized.
+func GetHashtagFeed(ctx context.Context, tag string) ([]Trill, error) {
+	// no requestor hides every private account
+	trills, _, err := GetHashtagTrills(ctx, tag, "", FeedSize, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *trills, nil
+}
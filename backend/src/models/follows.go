@@ -2,15 +2,35 @@ package models
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Follows struct {
 	Followee      string
 	Following     string
-	FolloweeUser  User `gorm:"foreignKey:Username;references:Followee"`
-	FollowingUser User `gorm:"foreignKey:Username;references:Following"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	FolloweeUser  User      `gorm:"foreignKey:Username;references:Followee"`
+	FollowingUser User      `gorm:"foreignKey:Username;references:Following"`
+}
+
+// A follow of a private account that is waiting on the account owner's approval
+type FollowRequest struct {
+	Requester     string    `gorm:"type:varchar(128);primarykey"`
+	Target        string    `gorm:"type:varchar(128);primarykey"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	RequesterUser User      `gorm:"foreignKey:Username;references:Requester"`
 }
 
+var (
+	ErrorFollowRequestNotFound error = errors.New("follow request does not exist")
+)
+
 // TODO consolidate GetFollowing and GetFollowers
 func GetFollowing(ctx context.Context, followee string) (*[]User, error) {
 	db, err := GetDBFromContext(ctx)
@@ -19,7 +39,7 @@ func GetFollowing(ctx context.Context, followee string) (*[]User, error) {
 	}
 
 	var following []Follows
-	if err := db.Preload("FollowingUser").Where("followee = ?", followee).Find(&following).Error; err != nil {
+	if err := db.Preload("FollowingUser").Where("followee = ? AND following NOT IN (?)", followee, deactivatedUsers(db)).Find(&following).Error; err != nil {
 		return nil, err
 	}
 
@@ -38,7 +58,7 @@ func GetFollowers(ctx context.Context, followee string) (*[]User, error) {
 	}
 
 	var followers []Follows
-	if err := db.Preload("FolloweeUser").Where("following = ?", followee).Find(&followers).Error; err != nil {
+	if err := db.Preload("FolloweeUser").Where("following = ? AND followee NOT IN (?)", followee, deactivatedUsers(db)).Find(&followers).Error; err != nil {
 		return nil, err
 	}
 
@@ -50,24 +70,111 @@ func GetFollowers(ctx context.Context, followee string) (*[]User, error) {
 	return &users, nil
 }
 
+// Follows sort newest first on when they were made, with the other account's username to break ties
+func followKeyset(other string) pagination.Keyset {
+	return pagination.Keyset{Value: "created_at", Key: other, Time: true}
+}
+
+// The accounts the user follows, most recently followed first, keyset paginated in either direction
+func GetFollowingPage(ctx context.Context, followee string, limit int, cursor *Cursor) (*[]User, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var follows []Follows
+	query := db.Preload("FollowingUser").Where("followee = ? AND following NOT IN (?)", followee, deactivatedUsers(db))
+	if err := followKeyset("following").Apply(query, cursor, limit).Find(&follows).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	follows, cursors := pagination.Trim(follows, cursor, limit, func(follow *Follows) Cursor {
+		return pagination.TimeCursor(follow.CreatedAt, follow.Following)
+	})
+	users := make([]User, len(follows))
+	for i, f := range follows {
+		users[i] = f.FollowingUser
+	}
+
+	return &users, cursors, nil
+}
+
+// The user's followers, most recent first, keyset paginated in either direction
+func GetFollowersPage(ctx context.Context, following string, limit int, cursor *Cursor) (*[]User, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var follows []Follows
+	query := db.Preload("FolloweeUser").Where("following = ? AND followee NOT IN (?)", following, deactivatedUsers(db))
+	if err := followKeyset("followee").Apply(query, cursor, limit).Find(&follows).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	follows, cursors := pagination.Trim(follows, cursor, limit, func(follow *Follows) Cursor {
+		return pagination.TimeCursor(follow.CreatedAt, follow.Followee)
+	})
+	users := make([]User, len(follows))
+	for i, f := range follows {
+		users[i] = f.FolloweeUser
+	}
+
+	return &users, cursors, nil
+}
+
 func CreateFollow(ctx context.Context, follows *Follows) error {
-	if db, err := GetDBFromContext(ctx); err != nil {
-		return err
-	} else if err := db.Create(&follows).Error; err != nil {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		// following twice would count twice
+		var count int64
+		if err := tx.Model(&Follows{}).Where("followee = ? AND following = ?", follows.Followee, follows.Following).Count(&count).Error; err != nil {
+			return err
+		} else if count > 0 {
+			return nil
+		}
+
+		if err := tx.Create(&follows).Error; err != nil {
+			return err
+		}
+		if err := backfillTimeline(tx, follows.Following, follows.Followee); err != nil {
+			return err
+		}
+
+		return countFollows(tx, follows.Followee, follows.Following, 1)
+	})
 }
 
 func DeleteFollow(ctx context.Context, follows *Follows) error {
-	if db, err := GetDBFromContext(ctx); err != nil {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
 		return err
-	} else if err := db.Where("followee = ? AND following = ?", follows.Followee, follows.Following).Delete(follows).Error; err != nil {
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("followee = ? AND following = ?", follows.Followee, follows.Following).Delete(follows)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := clearTimeline(tx, follows.Followee, follows.Following); err != nil {
+			return err
+		}
+
+		return countFollows(tx, follows.Followee, follows.Following, -result.RowsAffected)
+	})
+}
+
+// Keeps both users' counters in step with follows being added (positive delta) or removed (negative)
+func countFollows(tx *gorm.DB, followee string, following string, delta int64) error {
+	if err := incrementUserCounter(tx, "following_count", delta, followee); err != nil {
 		return err
 	}
 
-	return nil
+	return incrementUserCounter(tx, "follower_count", delta, following)
 }
 
 // true if followee follows following
@@ -85,3 +192,123 @@ func IsFollowing(ctx context.Context, followee string, following string) (bool,
 
 	return count > 0, nil
 }
+
+// Does nothing if the request is already pending
+func CreateFollowRequest(ctx context.Context, requester string, target string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	request := FollowRequest{Requester: requester, Target: target}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&request).Error
+}
+
+// Users waiting on target to approve their follow, oldest first
+func GetFollowRequests(ctx context.Context, target string) (*[]User, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []FollowRequest
+	if err := db.Preload("RequesterUser").Where("target = ? AND requester NOT IN (?)", target, deactivatedUsers(db)).
+		Order("created_at asc").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+
+	users := make([]User, len(requests))
+	for i, r := range requests {
+		users[i] = r.RequesterUser
+	}
+
+	return &users, nil
+}
+
+func HasRequestedFollow(ctx context.Context, requester string, target string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Model(&FollowRequest{}).Where("requester = ? AND target = ?", requester, target).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Turns the pending request into a follow, failing with a 404 HTTPError if there is no such request
+func ApproveFollowRequest(ctx context.Context, requester string, target string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("requester = ? AND target = ?", requester, target).Delete(&FollowRequest{})
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorFollowRequestNotFound}
+		}
+
+		if err := tx.Create(&Follows{Followee: requester, Following: target}).Error; err != nil {
+			return err
+		}
+		if err := backfillTimeline(tx, target, requester); err != nil {
+			return err
+		}
+
+		return countFollows(tx, requester, target, 1)
+	})
+}
+
+// Used when a private account goes public, since nothing is gating the follows anymore
+func ApproveAllFollowRequests(ctx context.Context, target string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var requests []FollowRequest
+		if err := tx.Where("target = ?", target).Find(&requests).Error; err != nil {
+			return err
+		} else if len(requests) == 0 {
+			return nil
+		}
+
+		follows := make([]Follows, len(requests))
+		requesters := make([]string, len(requests))
+		for i, r := range requests {
+			follows[i] = Follows{Followee: r.Requester, Following: target}
+			requesters[i] = r.Requester
+		}
+		if err := tx.Create(&follows).Error; err != nil {
+			return err
+		}
+		if err := backfillTimeline(tx, target, requesters...); err != nil {
+			return err
+		}
+		if err := incrementUserCounter(tx, "following_count", 1, requesters...); err != nil {
+			return err
+		}
+		if err := incrementUserCounter(tx, "follower_count", int64(len(requests)), target); err != nil {
+			return err
+		}
+
+		return tx.Where("target = ?", target).Delete(&FollowRequest{}).Error
+	})
+}
+
+// Declining a request and cancelling one are the same delete
+func DeleteFollowRequest(ctx context.Context, requester string, target string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("requester = ? AND target = ?", requester, target).Delete(&FollowRequest{}).Error
+}
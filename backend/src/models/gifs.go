@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"trill/src/utils"
+)
+
+// A GIF from the picker, the same whichever provider it came from. url is what gets attached to a trill.
+type GIF struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	MP4URL     string `json:"mp4_url"`
+	PreviewURL string `json:"preview_url"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+}
+
+const (
+	GIFContentType = "image/gif"
+)
+
+var (
+	ErrorGIFsUnavailable error = errors.New("GIF search isn't available")
+	ErrorGIFInvalid      error = errors.New("gif must be a URL from the GIF picker")
+)
+
+// GIPHY serves GIFs from media.giphy.com and numbered hosts like media2.giphy.com
+func isGiphyMediaHost(host string) bool {
+	return host == "i.giphy.com" || (strings.HasPrefix(host, "media") && strings.HasSuffix(host, ".giphy.com"))
+}
+
+func newGIF(gif *utils.GiphyGIF) GIF {
+	width, _ := strconv.Atoi(gif.Images.Original.Width)
+	height, _ := strconv.Atoi(gif.Images.Original.Height)
+	return GIF{
+		ID:         gif.ID,
+		Title:      gif.Title,
+		URL:        gif.Images.Original.URL,
+		MP4URL:     gif.Images.Original.MP4,
+		PreviewURL: gif.Images.FixedWidth.URL,
+		Width:      width,
+		Height:     height,
+	}
+}
+
+// One page of GIFs matching the query, or trending GIFs when the query is empty. The provider's key
+// stays on the server; a 503 HTTPError means it isn't configured.
+func SearchGIFs(ctx context.Context, query string, paginate *Paginate) ([]GIF, error) {
+	if utils.GetSecrets().GiphyAPIKey == "" {
+		return nil, &HTTPError{Code: http.StatusServiceUnavailable, Err: ErrorGIFsUnavailable}
+	}
+
+	page := paginate.Page
+	if page < 1 {
+		page = 1
+	}
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(paginate.Limit))
+	params.Set("offset", strconv.Itoa((page-1)*paginate.Limit))
+	endpoint := "/trending"
+	if query != "" {
+		endpoint = "/search"
+		params.Set("q", query)
+	}
+
+	var results []utils.GiphyGIF
+	if _, err := utils.DoGiphyRequest(ctx, endpoint, params, &results); err != nil {
+		return nil, err
+	}
+
+	gifs := make([]GIF, len(results))
+	for i := range results {
+		gifs[i] = newGIF(&results[i])
+	}
+	return gifs, nil
+}
+
+// Looks up a GIF the user picked by its URL, failing with a 400 HTTPError if it isn't one the
+// provider serves, and returns it as media to attach to a trill
+func ResolveGIF(ctx context.Context, username string, gifURL string) (*Media, error) {
+	if utils.GetSecrets().GiphyAPIKey == "" {
+		return nil, &HTTPError{Code: http.StatusServiceUnavailable, Err: ErrorGIFsUnavailable}
+	}
+
+	// e.g. https://media2.giphy.com/media/<id>/giphy.gif, sometimes with a version segment before the ID
+	parsed, err := url.Parse(gifURL)
+	if err != nil || parsed.Scheme != "https" || !isGiphyMediaHost(parsed.Host) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorGIFInvalid}
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 2 {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorGIFInvalid}
+	}
+
+	var result utils.GiphyGIF
+	if _, err := utils.DoGiphyRequest(ctx, "/"+url.PathEscape(segments[len(segments)-2]), url.Values{}, &result); errors.Is(err, utils.ErrorGiphyNotFound) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorGIFInvalid}
+	} else if err != nil {
+		return nil, err
+	}
+
+	gif := newGIF(&result)
+	return &Media{
+		Username:    username,
+		ContentType: GIFContentType,
+		Kind:        MediaKindGIF,
+		Status:      MediaStatusReady,
+		ExternalURL: gif.URL,
+		// the provider's title is the best description there is until the user writes one
+		AltText: gif.Title,
+		Width:   gif.Width,
+		Height:  gif.Height,
+	}, nil
+}
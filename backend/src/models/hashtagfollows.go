@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A user following a hashtag. Public top-level trills that use it are merged into their home timeline,
+// marked with the tag that brought them in.
+type HashtagFollow struct {
+	Username  string    `gorm:"type:varchar(128);primarykey"`
+	HashtagID int64     `gorm:"primarykey;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Hashtag   Hashtag   `gorm:"foreignKey:HashtagID;references:HashtagID"`
+}
+
+// A home timeline trill from an account the requestor doesn't follow, there because it uses a tag they do
+type hashtagReason struct {
+	TrillID int64
+	Tag     string
+}
+
+const (
+	MaxFollowedHashtags = 100
+)
+
+var (
+	ErrorTooManyHashtagFollows error = errors.New("at most 100 hashtags can be followed")
+)
+
+// Follows the tag, which should already be normalized, adding it if no trill has used it yet. Following a
+// tag that's already followed does nothing.
+func FollowHashtag(ctx context.Context, username string, tag string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		hashtag := Hashtag{Tag: tag}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&hashtag).Error; err != nil {
+			return err
+		}
+		// the ID doesn't come back when the tag already existed
+		if err := tx.Where("tag = ?", tag).First(&hashtag).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&HashtagFollow{}).Where("username = ? AND hashtag_id <> ?", username, hashtag.HashtagID).Count(&count).Error; err != nil {
+			return err
+		} else if count >= MaxFollowedHashtags {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorTooManyHashtagFollows}
+		}
+
+		follow := HashtagFollow{Username: username, HashtagID: hashtag.HashtagID}
+		return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error
+	})
+}
+
+// Unfollowing a tag that isn't followed does nothing
+func UnfollowHashtag(ctx context.Context, username string, tag string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	hashtags := db.Model(&Hashtag{}).Select("hashtag_id").Where("tag = ?", tag)
+	return db.Where("username = ? AND hashtag_id IN (?)", username, hashtags).Delete(&HashtagFollow{}).Error
+}
+
+// The tags the user follows, most recently followed first
+func GetFollowedHashtags(ctx context.Context, username string) (*[]HashtagFollow, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []HashtagFollow
+	if err := db.Preload("Hashtag").Where("username = ?", username).Order("created_at DESC").Find(&follows).Error; err != nil {
+		return nil, err
+	}
+
+	return &follows, nil
+}
+
+// Public top-level trills by other accounts that use a tag the requestor follows; these join the home
+// timeline alongside the accounts they follow
+func followedHashtagTrills(db *gorm.DB, requestor string) *gorm.DB {
+	followed := db.Model(&HashtagFollow{}).Select("hashtag_id").Where("username = ?", requestor)
+	tagged := db.Model(&TrillHashtag{}).Select("trill_id").Where("hashtag_id IN (?)", followed)
+	private := db.Model(&User{}).Select("username").Where("is_private = ?", true)
+	return db.Model(&Trill{}).Where("trill_id IN (?) AND parent_id IS NULL AND retrill_of_id IS NULL", tagged).
+		Where("username <> ? AND username NOT IN (?)", requestor, private)
+}
+
+// For each of the home timeline trills that's only there because of a followed tag, the tag that brought
+// it in. Trills by the requestor or accounts they follow aren't attributed, even when they use a followed tag.
+func GetHashtagReasons(ctx context.Context, requestor string, trills []Trill) (map[int64]string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[int64]string)
+	var trillIDs []int64
+	for _, trill := range trills {
+		if trill.Username != requestor {
+			trillIDs = append(trillIDs, trill.TrillID)
+		}
+	}
+	if len(trillIDs) == 0 {
+		return reasons, nil
+	}
+
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	var tagged []hashtagReason
+	if err := db.Table("trill_hashtags").Select("trill_hashtags.trill_id, hashtags.tag").
+		Joins("JOIN hashtags ON hashtags.hashtag_id = trill_hashtags.hashtag_id").
+		Joins("JOIN hashtag_follows ON hashtag_follows.hashtag_id = trill_hashtags.hashtag_id AND hashtag_follows.username = ?", requestor).
+		Joins("JOIN trills ON trills.trill_id = trill_hashtags.trill_id").
+		Where("trill_hashtags.trill_id IN ? AND trills.username NOT IN (?)", trillIDs, following).
+		Order("hashtag_follows.created_at, hashtags.tag").Scan(&tagged).Error; err != nil {
+		return nil, err
+	}
+
+	// a trill with several followed tags is put down to the one followed first
+	for _, reason := range tagged {
+		if _, ok := reasons[reason.TrillID]; !ok {
+			reasons[reason.TrillID] = reason.Tag
+		}
+	}
+	return reasons, nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"trill/src/pagination"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A tag that's been used in at least one trill, stored lowercased so #Music and #music are the same page
+type Hashtag struct {
+	HashtagID int64     `gorm:"primarykey;autoIncrement"`
+	Tag       string    `gorm:"type:varchar(100);uniqueIndex"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// A hashtag used in a trill. The key leads with the hashtag so a tag page reads its trills in ID order.
+type TrillHashtag struct {
+	HashtagID int64 `gorm:"primarykey"`
+	TrillID   int64 `gorm:"primarykey;index"`
+}
+
+const (
+	MaxHashtagLength = 100
+)
+
+var (
+	ErrorInvalidHashtag error = errors.New("invalid hashtag")
+)
+
+var (
+	// a # that doesn't follow a word character, so emails and URL fragments don't count; RE2 has no
+	// lookbehind, so the character before it is part of the match
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+	tagPattern     = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+// The distinct hashtags in a trill's text, lowercased, in the order they first appear. Tags that are
+// all digits or longer than MaxHashtagLength are left out.
+func ParseHashtags(text string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		if tag, ok := NormalizeHashtag(match[1]); ok && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Lowercases the tag, dropping a leading #, and reports whether what's left is a tag ParseHashtags would find
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if len([]rune(tag)) > MaxHashtagLength || !tagPattern.MatchString(tag) {
+		return "", false
+	}
+	if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return "", false
+	}
+	return tag, true
+}
+
+// Links the trill to its hashtags, adding any the database hasn't seen yet
+func tagTrill(tx *gorm.DB, trillID int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	hashtags := make([]Hashtag, len(tags))
+	for i, tag := range tags {
+		hashtags[i] = Hashtag{Tag: tag}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&hashtags).Error; err != nil {
+		return err
+	}
+
+	// the IDs of tags that already existed don't come back from the insert
+	hashtags = nil
+	if err := tx.Where("tag IN ?", tags).Find(&hashtags).Error; err != nil {
+		return err
+	}
+
+	links := make([]TrillHashtag, len(hashtags))
+	for i, hashtag := range hashtags {
+		links[i] = TrillHashtag{HashtagID: hashtag.HashtagID, TrillID: trillID}
+	}
+	return tx.Create(&links).Error
+}
+
+// Trills the requestor can see that use the tag, newest first, keyset paginated on the trill ID in either
+// direction. The tag should already be normalized.
+func GetHashtagTrills(ctx context.Context, tag string, requestor string, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	tagged := db.Model(&TrillHashtag{}).Select("trill_hashtags.trill_id").
+		Joins("JOIN hashtags ON hashtags.hashtag_id = trill_hashtags.hashtag_id").Where("hashtags.tag = ?", tag)
+	query := visibleTrills(preloadTrills(db), db, requestor).Where("trill_id IN (?)", tagged)
+
+	var trills []Trill
+	if err := trillKeyset.Apply(query, cursor, limit).Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trills, cursors := pagination.Trim(trills, cursor, limit, trillPosition)
+	return &trills, cursors, nil
+}
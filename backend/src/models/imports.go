@@ -0,0 +1,292 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportAwaitingUpload = "awaiting_upload"
+	ImportPending        = "pending"
+	ImportRunning        = "running"
+	ImportComplete       = "complete"
+	ImportFailed         = "failed"
+
+	ImportedFromTwitter = "twitter"
+	// archives are uploaded to the private exports bucket under imports/<username>/
+	TwitterImportPrefix = "imports/"
+)
+
+// One import of a Twitter data export. The user uploads the archive's ZIP, then starts the import, and the
+// twitterImport worker recreates the tweets as trills oldest first, a run at a time, keeping count as it
+// goes. Retweets and replies to other accounts don't come across.
+type TwitterImport struct {
+	ImportID        string    `gorm:"type:varchar(32);primarykey"`
+	Username        string    `gorm:"type:varchar(128);index"`
+	Status          string    `gorm:"type:varchar(16)"`
+	ObjectKey       string    `gorm:"type:varchar(512)"`
+	TotalTweets     int       `gorm:"not null;default:0"`
+	ProcessedTweets int       `gorm:"not null;default:0"`
+	ImportedTweets  int       `gorm:"not null;default:0"`
+	SkippedTweets   int       `gorm:"not null;default:0"`
+	Error           string    `gorm:"type:varchar(1024)"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	StartedAt       *time.Time
+	CompletedAt     *time.Time
+}
+
+// The trill a tweet became, so a retried run doesn't post it twice and replies can find their parent
+type ImportedTweet struct {
+	Username string `gorm:"type:varchar(128);primarykey"`
+	TweetID  string `gorm:"type:varchar(32);primarykey"`
+	TrillID  int64  `gorm:"index"`
+}
+
+// What gets queued for the twitterImport worker
+type TwitterImportJob struct {
+	ImportID string `json:"import_id"`
+	Username string `json:"username"`
+}
+
+var (
+	MaxTwitterArchiveBytes int64 = 4 << 30
+	ImportUploadExpiration       = time.Hour
+)
+
+var (
+	ErrorImportNotFound    error = errors.New("no import has been started")
+	ErrorImportInProgress  error = errors.New("an import is already in progress")
+	ErrorImportNotReady    error = errors.New("the import isn't waiting for an archive")
+	ErrorImportNotUploaded error = errors.New("upload the archive before starting the import")
+	ErrorImportTooLarge    error = errors.New("archive is too large, the limit is 4 GB")
+)
+
+// Makes a new import waiting for its archive, unless one is already running
+func CreateTwitterImport(ctx context.Context, username string) (*TwitterImport, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := GetLatestTwitterImport(ctx, username)
+	if err != nil {
+		return nil, err
+	} else if latest != nil && (latest.Status == ImportPending || latest.Status == ImportRunning) {
+		return nil, &HTTPError{Code: http.StatusConflict, Err: ErrorImportInProgress}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	importID := hex.EncodeToString(id)
+	imp := TwitterImport{
+		ImportID:  importID,
+		Username:  username,
+		Status:    ImportAwaitingUpload,
+		ObjectKey: fmt.Sprintf("%s%s/%s.zip", TwitterImportPrefix, username, importID),
+	}
+	if err := db.Create(&imp).Error; err != nil {
+		return nil, err
+	}
+
+	return &imp, nil
+}
+
+// Returns nil if the user has never started an import
+func GetLatestTwitterImport(ctx context.Context, username string) (*TwitterImport, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var imp TwitterImport
+	if result := db.Where("username = ?", username).Order("created_at desc").Limit(1).Find(&imp); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &imp, nil
+}
+
+func UpdateTwitterImport(ctx context.Context, imp *TwitterImport) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Save(imp).Error
+}
+
+// Marks the user's latest import ready for the worker once its archive is uploaded. Fails with a 404
+// HTTPError if there's no import, a 409 if it isn't waiting for an archive, and a 400 if the archive
+// hasn't been uploaded or is over MaxTwitterArchiveBytes.
+func StartTwitterImport(ctx context.Context, username string) (*TwitterImport, error) {
+	imp, err := GetLatestTwitterImport(ctx, username)
+	if err != nil {
+		return nil, err
+	} else if imp == nil {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorImportNotFound}
+	} else if imp.Status != ImportAwaitingUpload {
+		return nil, &HTTPError{Code: http.StatusConflict, Err: ErrorImportNotReady}
+	}
+
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(utils.GetSecrets().ExportBucket),
+		Key:    aws.String(imp.ObjectKey),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorImportNotUploaded}
+	} else if err != nil {
+		return nil, err
+	} else if head.ContentLength > MaxTwitterArchiveBytes {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorImportTooLarge}
+	}
+
+	imp.Status = ImportPending
+	if err := UpdateTwitterImport(ctx, imp); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+func EnqueueTwitterImport(ctx context.Context, imp *TwitterImport) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(TwitterImportJob{ImportID: imp.ImportID, Username: imp.Username})
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().TwitterImportQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// A URL the client can PUT the archive to; the bucket is private, so like export downloads it only
+// works through a short-lived link
+func PresignImportUpload(ctx context.Context, imp *TwitterImport) (string, error) {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	presignClient := s3.NewPresignClient(s3Client)
+	presigned, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(utils.GetSecrets().ExportBucket),
+		Key:         aws.String(imp.ObjectKey),
+		ContentType: aws.String("application/zip"),
+	}, s3.WithPresignExpires(ImportUploadExpiration))
+	if err != nil {
+		return "", err
+	}
+
+	return presigned.URL, nil
+}
+
+// Copies the import's archive to a local file, since a ZIP has to be read from the end
+func DownloadImportArchive(ctx context.Context, imp *TwitterImport, dest string) error {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(utils.GetSecrets().ExportBucket),
+		Key:    aws.String(imp.ObjectKey),
+	})
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, io.LimitReader(object.Body, MaxTwitterArchiveBytes))
+	return err
+}
+
+// Deletes the archive once the import is done with it
+func DeleteImportArchive(ctx context.Context, imp *TwitterImport) error {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(utils.GetSecrets().ExportBucket),
+		Key:    aws.String(imp.ObjectKey),
+	})
+	return err
+}
+
+// The trill the user's tweet was imported as, or nil if it hasn't been
+func GetImportedTrillID(ctx context.Context, username string, tweetID string) (*int64, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var imported ImportedTweet
+	if result := db.Where("username = ? AND tweet_id = ?", username, tweetID).Limit(1).Find(&imported); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &imported.TrillID, nil
+}
+
+// Posts a tweet's trill, backdated to when it was tweeted, and records which tweet it came from. Its
+// text isn't checked for mentions, since Twitter handles aren't Trill usernames, so no one's notified.
+func ImportTrill(ctx context.Context, trill *Trill, tweetID string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	trill.ImportedFrom = ImportedFromTwitter
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := createTrill(tx, trill); err != nil {
+			return err
+		}
+		return tx.Create(&ImportedTweet{Username: trill.Username, TweetID: tweetID, TrillID: trill.TrillID}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	queueLinkPreview(ctx, trill)
+	queueLinkScan(ctx, trill)
+	return nil
+}
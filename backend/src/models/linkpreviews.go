@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm/clause"
+)
+
+// The card shown under a trill for the first link in its text, from the page's Open Graph tags. Pages
+// are fetched by the linkPreviews worker after the trill is posted and cached by URL, so a link that's
+// trilled often is only fetched once per LinkPreviewTTL. A page that can't be fetched is cached as failed
+// for a shorter while so the worker doesn't keep retrying it.
+type LinkPreview struct {
+	LinkPreviewID int64     `gorm:"primarykey;autoIncrement"`
+	URLHash       string    `gorm:"type:char(64);uniqueIndex"`
+	URL           string    `gorm:"type:varchar(2048)"`
+	Title         string    `gorm:"type:varchar(300)"`
+	Description   string    `gorm:"type:varchar(1000)"`
+	ImageURL      string    `gorm:"type:varchar(2048)"`
+	SiteName      string    `gorm:"type:varchar(128)"`
+	Status        string    `gorm:"type:varchar(16)"`
+	FetchedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// What gets queued for the linkPreviews worker
+type LinkPreviewEvent struct {
+	TrillID int64  `json:"trill_id"`
+	URL     string `json:"url"`
+}
+
+const (
+	LinkPreviewStatusReady  = "ready"
+	LinkPreviewStatusFailed = "failed"
+)
+
+var (
+	LinkPreviewTTL       = 7 * 24 * time.Hour
+	FailedLinkPreviewTTL = time.Hour
+)
+
+// The first link in the text, without punctuation that most likely ends the sentence rather than the link
+func FirstLink(text string) string {
+	link := trimLink(linkPattern.FindString(text))
+	if len(link) > 2048 {
+		return ""
+	}
+	return link
+}
+
+func linkHash(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return hex.EncodeToString(sum[:])
+}
+
+// Queues a preview for the trill's first link, if it has one. Failing to queue it only costs the
+// preview, so it's logged rather than failing whatever posted or edited the trill.
+func queueLinkPreview(ctx context.Context, trill *Trill) {
+	link := FirstLink(trill.Text)
+	if link == "" {
+		return
+	}
+	if err := enqueueLinkPreview(ctx, &LinkPreviewEvent{TrillID: trill.TrillID, URL: link}); err != nil {
+		fmt.Printf("failed to queue link preview for trill %d: %s\n", trill.TrillID, err.Error())
+	}
+}
+
+func enqueueLinkPreview(ctx context.Context, event *LinkPreviewEvent) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().LinkPreviewQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Attaches a preview of the link to the trill, fetching the page unless it's cached. Does nothing if the
+// trill was deleted or edited to a different link since the event was queued, and a page that can't be
+// fetched just leaves the trill without a preview.
+func RecordLinkPreview(ctx context.Context, event *LinkPreviewEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trill Trill
+	if result := db.Where("trill_id = ?", event.TrillID).Limit(1).Find(&trill); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || FirstLink(trill.Text) != event.URL {
+		return nil
+	}
+
+	preview, err := getLinkPreview(ctx, event.URL)
+	if err != nil {
+		return err
+	} else if preview.Status != LinkPreviewStatusReady {
+		return nil
+	}
+
+	// the text is checked again in case it was edited while the page was fetched
+	return db.Model(&Trill{}).Where("trill_id = ? AND text = ?", trill.TrillID, trill.Text).
+		UpdateColumn("link_preview_id", preview.LinkPreviewID).Error
+}
+
+// The cached preview for the link, or a fresh one if it's missing or stale
+func getLinkPreview(ctx context.Context, link string) (*LinkPreview, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := linkHash(link)
+	var cached LinkPreview
+	if result := db.Where("url_hash = ?", hash).Limit(1).Find(&cached); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected > 0 {
+		ttl := LinkPreviewTTL
+		if cached.Status == LinkPreviewStatusFailed {
+			ttl = FailedLinkPreviewTTL
+		}
+		if time.Since(cached.FetchedAt) < ttl {
+			return &cached, nil
+		}
+	}
+
+	preview := LinkPreview{URLHash: hash, URL: link, Status: LinkPreviewStatusReady, FetchedAt: time.Now()}
+	graph, err := utils.FetchOpenGraph(ctx, link)
+	if err != nil || graph.Title == "" {
+		// a page with nothing to show is no better than one that couldn't be fetched
+		preview.Status = LinkPreviewStatusFailed
+		if err != nil {
+			fmt.Printf("failed to preview %s: %s\n", link, err.Error())
+		}
+	} else {
+		preview.Title = truncateRunes(graph.Title, 300)
+		preview.Description = truncateRunes(graph.Description, 1000)
+		preview.SiteName = truncateRunes(graph.SiteName, 128)
+		if len(graph.ImageURL) <= 2048 {
+			preview.ImageURL = graph.ImageURL
+		}
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "url_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "description", "image_url", "site_name", "status", "fetched_at"}),
+	}).Create(&preview).Error; err != nil {
+		return nil, err
+	}
+
+	// MySQL doesn't reliably hand back the ID of a row the upsert updated
+	var saved LinkPreview
+	if err := db.Where("url_hash = ?", hash).Take(&saved).Error; err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// Cuts the text down to at most max characters, for columns sized in characters
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+)
+
+// What gets queued for the linkScanner worker when a trill with links is posted or edited
+type LinkScanEvent struct {
+	TrillID int64 `json:"trill_id"`
+}
+
+// The threat recorded for a link whose host is on the LINK_BLOCKLIST setting; links Safe Browsing flags
+// get its threat type, e.g. MALWARE or SOCIAL_ENGINEERING
+const LinkThreatBlocklisted = "BLOCKLISTED"
+
+var (
+	// how long a verdict on a URL is reused for the same URL in other trills
+	LinkScanTTL = 24 * time.Hour
+)
+
+var (
+	ErrorLinkFlagged error = errors.New("this link was flagged as unsafe")
+)
+
+// Queues a scan of the links in the trill, if it has any. Failing to queue it only leaves the links
+// unscanned, so it's logged rather than failing whatever posted or edited the trill.
+func queueLinkScan(ctx context.Context, trill *Trill) {
+	if len(FindLinks(trill.Text)) == 0 {
+		return
+	}
+	if err := enqueueLinkScan(ctx, &LinkScanEvent{TrillID: trill.TrillID}); err != nil {
+		fmt.Printf("failed to queue link scan for trill %d: %s\n", trill.TrillID, err.Error())
+	}
+}
+
+func enqueueLinkScan(ctx context.Context, event *LinkScanEvent) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().LinkScanQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Whether the link's host, or a domain it's under, is on the LINK_BLOCKLIST setting
+func linkBlocklisted(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range strings.Split(utils.GetSecrets().LinkBlocklist, ",") {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the trill's short links that haven't been scanned against the blocklist, then Safe Browsing,
+// reusing a recent verdict on the same URL where there is one. Safe Browsing is skipped while
+// SAFE_BROWSING_API_KEY is unset. Flagged links stop redirecting, the trill is labeled with the first
+// one's threat, and a report goes to the moderation queue; dismissing it clears the flags.
+func ScanTrillLinks(ctx context.Context, event *LinkScanEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var links []ShortLink
+	if err := db.Where("trill_id = ? AND scanned_at IS NULL", event.TrillID).Order("created_at").Find(&links).Error; err != nil {
+		return err
+	} else if len(links) == 0 {
+		return nil
+	}
+
+	threats := map[string]string{}
+	var lookup []string
+	for _, link := range links {
+		if linkBlocklisted(link.URL) {
+			threats[link.URL] = LinkThreatBlocklisted
+			continue
+		}
+
+		var recent ShortLink
+		if result := db.Where("url_hash = ? AND scanned_at > ?", link.URLHash, time.Now().Add(-LinkScanTTL)).
+			Order("scanned_at DESC").Limit(1).Find(&recent); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected > 0 {
+			threats[link.URL] = recent.Threat
+			continue
+		}
+		lookup = append(lookup, link.URL)
+	}
+
+	if len(lookup) > 0 && utils.GetSecrets().SafeBrowsingAPIKey != "" {
+		matches, err := utils.CheckSafeBrowsing(ctx, lookup)
+		if err != nil {
+			return err
+		}
+		for link, threat := range matches {
+			threats[link] = threat
+		}
+	}
+
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var flagged []string
+		warning := ""
+		for i := range links {
+			threat := threats[links[i].URL]
+			if err := tx.Model(&links[i]).Updates(map[string]interface{}{"threat": threat, "scanned_at": now}).Error; err != nil {
+				return err
+			}
+			if threat != "" {
+				if warning == "" {
+					warning = threat
+				}
+				flagged = append(flagged, fmt.Sprintf("%s (%s)", links[i].URL, threat))
+			}
+		}
+		if len(flagged) == 0 {
+			return nil
+		}
+
+		var trill Trill
+		if result := tx.Where("trill_id = ?", event.TrillID).Limit(1).Find(&trill); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Model(&trill).UpdateColumn("link_warning", warning).Error; err != nil {
+			return err
+		}
+
+		report := Report{
+			Reported: trill.Username,
+			TrillID:  &trill.TrillID,
+			Reason:   ReportMaliciousLink,
+			Details:  truncateRunes(fmt.Sprintf("trill %d links to %s", trill.TrillID, strings.Join(flagged, ", ")), 1024),
+			Status:   ReportOpen,
+		}
+		return tx.Create(&report).Error
+	})
+}
+
+// Takes the flags off the trill's links and its label, for a report a moderator dismissed
+func clearLinkThreats(tx *gorm.DB, trillID int64) error {
+	if err := tx.Model(&ShortLink{}).Where("trill_id = ?", trillID).UpdateColumn("threat", "").Error; err != nil {
+		return err
+	}
+	return tx.Model(&Trill{}).Where("trill_id = ?", trillID).UpdateColumn("link_warning", "").Error
+}
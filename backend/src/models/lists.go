@@ -0,0 +1,309 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A collection of users someone curates, with a timeline of its members' trills. A private list is
+// only visible to its owner, and only the owner can change it or who's on it.
+type List struct {
+	ListID      int64     `gorm:"primarykey;autoIncrement"`
+	Owner       string    `gorm:"type:varchar(128);index"`
+	Name        string    `gorm:"type:varchar(50)"`
+	Description string    `gorm:"type:varchar(200)"`
+	IsPrivate   bool      `gorm:"not null;default:false"`
+	MemberCount int64     `gorm:"not null;default:0"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	OwnerUser   User      `gorm:"foreignKey:Username;references:Owner"`
+}
+
+// A user on a list. The key leads with the list so its timeline reads its members in one range.
+type ListMember struct {
+	ListID    int64     `gorm:"primarykey"`
+	Username  string    `gorm:"type:varchar(128);primarykey;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	User      User      `gorm:"foreignKey:Username;references:Username"`
+}
+
+// The fields of a list its owner can change; nil leaves one as it is
+type ListChanges struct {
+	Name        *string
+	Description *string
+	IsPrivate   *bool
+}
+
+var (
+	MaxLists       = 100
+	MaxListMembers = 5000
+)
+
+var (
+	ErrorListNotFound      error = errors.New("list does not exist")
+	ErrorNotListOwner      error = errors.New("only the owner can change a list")
+	ErrorTooManyLists      error = errors.New("at most 100 lists can be made")
+	ErrorListFull          error = errors.New("a list can have at most 5000 members")
+	ErrorListMemberBlocked error = errors.New("this user can't be added to the list")
+	ErrorNotListMember     error = errors.New("user is not on the list")
+)
+
+// Fails with a 409 HTTPError if the owner already has MaxLists
+func CreateList(ctx context.Context, list *List) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&List{}).Where("owner = ?", list.Owner).Count(&count).Error; err != nil {
+		return err
+	} else if count >= int64(MaxLists) {
+		return &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyLists}
+	}
+
+	return db.Omit(clause.Associations).Create(list).Error
+}
+
+// A list the requestor can see. Someone else's private list is a 404 HTTPError, the same as one that
+// doesn't exist, and so is a list whose owner has blocked the requestor or been blocked by them.
+func GetList(ctx context.Context, listID int64, requestor string) (*List, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var list List
+	query := db.Preload("OwnerUser").Where("list_id = ? AND (is_private = ? OR owner = ?)", listID, false, requestor).
+		Where("owner NOT IN (?)", deactivatedUsers(db))
+	if result := excludeBlocked(query, db, "owner", requestor).Limit(1).Find(&list); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorListNotFound}
+	}
+
+	return &list, nil
+}
+
+// One of the requestor's own lists, failing with a 404 HTTPError if they can't see it or a 403 if it's someone else's
+func getOwnList(ctx context.Context, listID int64, requestor string) (*List, error) {
+	list, err := GetList(ctx, listID, requestor)
+	if err != nil {
+		return nil, err
+	} else if list.Owner != requestor {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorNotListOwner}
+	}
+
+	return list, nil
+}
+
+// The owner's lists the requestor can see, most recently made first. There are at most MaxLists, so
+// they come back all at once.
+func GetUserLists(ctx context.Context, owner string, requestor string) (*[]List, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Preload("OwnerUser").Where("owner = ?", owner)
+	if owner != requestor {
+		query = query.Where("is_private = ?", false)
+	}
+
+	var lists []List
+	if err := query.Order("list_id DESC").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+
+	return &lists, nil
+}
+
+// Changes one of the requestor's lists, returning it as it is now
+func UpdateList(ctx context.Context, listID int64, requestor string, changes *ListChanges) (*List, error) {
+	list, err := getOwnList(ctx, listID, requestor)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if changes.Name != nil {
+		updates["name"] = *changes.Name
+	}
+	if changes.Description != nil {
+		updates["description"] = *changes.Description
+	}
+	if changes.IsPrivate != nil {
+		updates["is_private"] = *changes.IsPrivate
+	}
+	if err := db.Model(&List{}).Where("list_id = ?", list.ListID).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return GetList(ctx, listID, requestor)
+}
+
+// Deletes one of the requestor's lists along with its memberships
+func DeleteList(ctx context.Context, listID int64, requestor string) error {
+	list, err := getOwnList(ctx, listID, requestor)
+	if err != nil {
+		return err
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ListID).Delete(&ListMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("list_id = ?", list.ListID).Delete(&List{}).Error
+	})
+}
+
+// Adds the user to one of the requestor's lists; adding someone already on it does nothing. Fails with
+// a 404 HTTPError if the user doesn't exist, a 403 if there's a block between them, or a 409 if the list
+// already has MaxListMembers.
+func AddListMember(ctx context.Context, listID int64, requestor string, username string) error {
+	list, err := getOwnList(ctx, listID, requestor)
+	if err != nil {
+		return err
+	}
+
+	if _, err := GetUser(ctx, username); err != nil {
+		return err
+	}
+	if blocked, err := IsBlocked(ctx, requestor, username); err != nil {
+		return err
+	} else if blocked {
+		return &HTTPError{Code: http.StatusForbidden, Err: ErrorListMemberBlocked}
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&ListMember{}).Where("list_id = ?", list.ListID).Count(&count).Error; err != nil {
+			return err
+		} else if count >= int64(MaxListMembers) {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorListFull}
+		}
+
+		member := ListMember{ListID: list.ListID, Username: username}
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&member)
+		if result.Error != nil {
+			return result.Error
+		}
+		return countListMembers(tx, list.ListID, result.RowsAffected)
+	})
+}
+
+// Takes the user off one of the requestor's lists, failing with a 404 HTTPError if they aren't on it
+func RemoveListMember(ctx context.Context, listID int64, requestor string, username string) error {
+	list, err := getOwnList(ctx, listID, requestor)
+	if err != nil {
+		return err
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("list_id = ? AND username = ?", list.ListID, username).Delete(&ListMember{})
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorNotListMember}
+		}
+		return countListMembers(tx, list.ListID, -result.RowsAffected)
+	})
+}
+
+func countListMembers(tx *gorm.DB, listID int64, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	return tx.Model(&List{}).Where("list_id = ?", listID).
+		UpdateColumn("member_count", gorm.Expr("member_count + ?", delta)).Error
+}
+
+// Takes the user off every list in the query, keeping the lists' member counts right
+func removeFromLists(tx *gorm.DB, username string, lists *gorm.DB) error {
+	var listIDs []int64
+	if err := tx.Model(&ListMember{}).Where("username = ? AND list_id IN (?)", username, lists).
+		Pluck("list_id", &listIDs).Error; err != nil {
+		return err
+	}
+	if len(listIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("username = ? AND list_id IN ?", username, listIDs).Delete(&ListMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&List{}).Where("list_id IN ?", listIDs).
+		UpdateColumn("member_count", gorm.Expr("member_count - 1")).Error
+}
+
+// The list's members most recently added first, keyset paginated in either direction on when they were
+// added. Deactivated members and anyone with a block with the requestor are left out.
+func GetListMembers(ctx context.Context, listID int64, requestor string, limit int, cursor *Cursor) (*[]ListMember, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Preload("User").Where("list_id = ? AND username NOT IN (?)", listID, deactivatedUsers(db))
+	query = excludeBlocked(query, db, "username", requestor)
+	keyset := pagination.Keyset{Value: "created_at", Key: "username", KeyAscending: true, Time: true}
+
+	var members []ListMember
+	if err := keyset.Apply(query, cursor, limit).Find(&members).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	members, cursors := pagination.Trim(members, cursor, limit, func(member *ListMember) Cursor {
+		return pagination.TimeCursor(member.CreatedAt, member.Username)
+	})
+	return &members, cursors, nil
+}
+
+// Trills and retrills by the list's members that the requestor can see, newest first, keyset paginated
+// in either direction on the trill ID
+func GetListTrills(ctx context.Context, listID int64, requestor string, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	members := db.Model(&ListMember{}).Select("username").Where("list_id = ?", listID)
+	originals := visibleTrills(db.Model(&Trill{}).Select("trill_id"), db, requestor)
+	query := visibleTrills(preloadTrills(db), db, requestor).Where("username IN (?)", members).
+		Where("retrill_of_id IS NULL OR retrill_of_id IN (?)", originals)
+
+	var trills []Trill
+	if err := trillKeyset.Apply(query, cursor, limit).Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trills, cursors := pagination.Trim(trills, cursor, limit, trillPosition)
+	return &trills, cursors, nil
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"gorm.io/gorm/clause"
+)
+
+// A network and device the user has logged in from. Networks are IPv4 /24s and IPv6 /48s, which
+// stay the same as an ISP hands out new addresses but change when the user is somewhere else.
+type LoginLocation struct {
+	Username    string    `gorm:"type:varchar(128);primarykey"`
+	Network     string    `gorm:"type:varchar(64);primarykey"`
+	Fingerprint string    `gorm:"type:char(64);primarykey"`
+	SourceIP    string    `gorm:"type:varchar(45)"`
+	UserAgent   string    `gorm:"type:varchar(512)"`
+	FirstSeenAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	LastSeenAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// A single-use link from a new login email that signs the user out everywhere
+type SecureAccountToken struct {
+	TokenHash       string    `gorm:"type:char(64);primarykey"`
+	Username        string    `gorm:"type:varchar(128);index"`
+	CognitoUsername string    `gorm:"type:varchar(128)"`
+	ExpiresAt       time.Time `gorm:"index"`
+}
+
+var (
+	SecureAccountTokenLifetime = 7 * 24 * time.Hour
+	DefaultWebAppURL           = "https://www.trytrill.com"
+)
+
+var (
+	ErrorSecureAccountLink error = errors.New("this link is invalid, expired, or was already used")
+)
+
+// Where the web app lives, from the WEB_APP_URL setting, without a trailing slash
+func WebAppURL() string {
+	webAppURL := utils.GetSecrets().WebAppURL
+	if webAppURL == "" {
+		webAppURL = DefaultWebAppURL
+	}
+	return strings.TrimRight(webAppURL, "/")
+}
+
+// The network an address belongs to, for telling a new location from a new address on the same connection
+func loginNetwork(sourceIP string) string {
+	ip := net.ParseIP(sourceIP)
+	if ip == nil {
+		return sourceIP
+	} else if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// Remembers where the user just logged in from, returning true if it's a network they've never
+// logged in from before. The first login anyone makes isn't new, since there's nothing to compare it to.
+func RecordLogin(ctx context.Context, username string, sourceIP string, userAgent string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	network := loginNetwork(sourceIP)
+
+	var known, seen int64
+	if err := db.Model(&LoginLocation{}).Where("username = ?", username).Count(&known).Error; err != nil {
+		return false, err
+	}
+	if err := db.Model(&LoginLocation{}).Where("username = ? AND network = ?", username, network).Count(&seen).Error; err != nil {
+		return false, err
+	}
+
+	location := LoginLocation{
+		Username:    username,
+		Network:     network,
+		Fingerprint: hashSecret(userAgent),
+		SourceIP:    sourceIP,
+		UserAgent:   userAgent,
+		LastSeenAt:  time.Now(),
+	}
+	if err := db.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"source_ip", "last_seen_at"})}).Create(&location).Error; err != nil {
+		return false, err
+	}
+
+	return known > 0 && seen == 0, nil
+}
+
+// Emails the user about a login from a new network, with a link that signs them out everywhere
+func NotifyNewLoginLocation(ctx context.Context, username string, cognitoUsername string, sourceIP string, userAgent string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var user User
+	if result := db.Select("email").Where("username = ?", username).Limit(1).Find(&user); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || user.Email == "" || utils.GetSecrets().EmailSender == "" {
+		return nil
+	}
+
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&SecureAccountToken{}).Error; err != nil {
+		return err
+	}
+
+	rawToken, tokenHash, err := randomToken("")
+	if err != nil {
+		return err
+	}
+	token := SecureAccountToken{
+		TokenHash:       tokenHash,
+		Username:        username,
+		CognitoUsername: cognitoUsername,
+		ExpiresAt:       time.Now().Add(SecureAccountTokenLifetime),
+	}
+	if err := db.Create(&token).Error; err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/secure-account?token=%s", WebAppURL(), url.QueryEscape(rawToken))
+	text := fmt.Sprintf("Hi %s,\n\n"+
+		"Your Trill account was just logged into from somewhere new:\n\n"+
+		"  IP address: %s\n  Device: %s\n  Time: %s\n\n"+
+		"If this was you, there's nothing to do.\n\n"+
+		"If it wasn't, secure your account now. This link signs you out on every device, after which you "+
+		"should reset your password:\n\n%s\n\n"+
+		"The link works once and expires in 7 days.\n",
+		username, sourceIP, userAgent, time.Now().UTC().Format(time.RFC1123), link)
+
+	return utils.SendEmail(ctx, user.Email, "New login to your Trill account", text)
+}
+
+// Signs the user out everywhere from the link in a new login email. The token is deleted first, so
+// the link only ever works once.
+func SecureAccount(ctx context.Context, rawToken string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var token SecureAccountToken
+	if result := db.Where("token_hash = ?", hashSecret(rawToken)).Limit(1).Find(&token); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_link", Err: ErrorSecureAccountLink}
+	}
+	if result := db.Where("token_hash = ?", token.TokenHash).Delete(&SecureAccountToken{}); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || time.Now().After(token.ExpiresAt) {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_link", Err: ErrorSecureAccountLink}
+	}
+
+	if err := AdminGlobalSignOutCognitoUser(ctx, token.CognitoUsername); err != nil {
+		return err
+	}
+	return RevokeAllTokens(ctx, token.Username)
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"trill/src/utils"
+
+	"gorm.io/gorm"
+)
+
+// An upload for a trill. The row is made when the upload URL is handed out, and gets its trill and
+// position when a trill is posted with its key; a key can only be used once. Images get their dimensions
+// when they're attached. Videos are transcoded after they're uploaded, and get their dimensions, duration,
+// and HLS and MP4 renditions once the job finishes, which can be after the trill is posted. GIFs from the
+// picker stay on the provider's servers, so they have an external URL and no object key, and their row is
+// only made when the trill is posted. Voice notes are transcoded like videos, and get their duration,
+// an M4A rendition, and a waveform to draw. An upload can go on a story instead of a trill, but not both.
+type Media struct {
+	MediaID     int64     `gorm:"primarykey;autoIncrement"`
+	Username    string    `gorm:"type:varchar(128);index"`
+	ObjectKey   string    `gorm:"type:varchar(255);uniqueIndex"`
+	ContentType string    `gorm:"type:varchar(64)"`
+	Kind        string    `gorm:"type:varchar(16)"`
+	Status      string    `gorm:"type:varchar(16)"`
+	TrillID     *int64    `gorm:"index"`
+	StoryID     *int64    `gorm:"index"`
+	Position    int       `gorm:"not null;default:0"`
+	Width       int       `gorm:"not null;default:0"`
+	Height      int       `gorm:"not null;default:0"`
+	DurationMs  int64     `gorm:"not null;default:0"`
+	JobID       string    `gorm:"type:varchar(64);index"`
+	HLSKey      string    `gorm:"type:varchar(255)"`
+	MP4Key      string    `gorm:"type:varchar(255)"`
+	ExternalURL string    `gorm:"type:varchar(1024)"`
+	Waveform    string    `gorm:"type:varchar(512)"`
+	AltText     string    `gorm:"type:varchar(1000)"`
+	Sensitive   bool      `gorm:"not null;default:false"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+const (
+	MediaKindImage = "image"
+	MediaKindVideo = "video"
+	MediaKindGIF   = "gif"
+	MediaKindAudio = "audio"
+
+	// images are ready as soon as they're uploaded; videos and voice notes wait on the upload, then the transcode
+	MediaStatusPending    = "pending"
+	MediaStatusProcessing = "processing"
+	MediaStatusReady      = "ready"
+	MediaStatusFailed     = "failed"
+)
+
+var (
+	MaxTrillVideos           = 1
+	MaxTrillVideoBytes int64 = 512 << 20
+)
+
+var (
+	ErrorTrillMediaUsed    error = errors.New("media is already attached to a trill or story")
+	ErrorTrillMediaInvalid error = errors.New("media is not a valid image")
+	ErrorTrillMediaMixed   error = errors.New("a trill can have up to 4 images, 1 video, or 1 voice note")
+	ErrorTrillMediaFailed  error = errors.New("video could not be processed")
+	ErrorUnsupportedMedia  error = errors.New("unsupported media type, expected jpeg, png, gif, webp, mp4, mov, m4a, mp3, aac, wav, or webm audio")
+	ErrorAltTextRequired   error = errors.New("every image, video, GIF, and voice note needs alt text; this can be turned off in settings")
+)
+
+var videoExtensions = map[string]string{
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+}
+
+// The file extension and kind of media for an upload's content type
+func GetMediaExtension(contentType string) (string, string, error) {
+	if ext, ok := imageExtensions[contentType]; ok {
+		return ext, MediaKindImage, nil
+	} else if ext, ok := videoExtensions[contentType]; ok {
+		return ext, MediaKindVideo, nil
+	} else if ext, ok := audioExtensions[contentType]; ok {
+		return ext, MediaKindAudio, nil
+	}
+
+	return "", "", ErrorUnsupportedMedia
+}
+
+// Registers an upload the user is about to make, so it can be attached to a trill later
+func CreateMedia(ctx context.Context, media *Media) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Create(media).Error
+}
+
+// Looks up each key as one of the user's unattached uploads, in the order given, and fills in image
+// dimensions from the uploaded object. Fails with a 403 HTTPError if a key isn't the user's, a 404 if
+// it was never uploaded to, a 409 if it's already on a trill, or a 400 if it isn't a usable image, is a
+// video or voice note that failed to transcode, or mixes videos or voice notes with other media.
+func ValidateTrillMedia(ctx context.Context, username string, keys []string) ([]Media, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	media := make([]Media, len(keys))
+	for i, key := range keys {
+		if result := db.Where("object_key = ?", key).Limit(1).Find(&media[i]); result.Error != nil {
+			return nil, result.Error
+		} else if result.RowsAffected == 0 {
+			return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillMediaAbsent}
+		} else if media[i].Username != username {
+			return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorTrillMediaOwner}
+		} else if media[i].TrillID != nil || media[i].StoryID != nil {
+			return nil, &HTTPError{Code: http.StatusConflict, Err: ErrorTrillMediaUsed}
+		}
+
+		exists, err := ContentObjectExists(ctx, key)
+		if err != nil {
+			return nil, err
+		} else if !exists {
+			return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillMediaAbsent}
+		}
+		media[i].Position = i
+
+		// a video or voice note can go on a trill while it's still transcoding
+		if media[i].Kind == MediaKindVideo {
+			if media[i].Status == MediaStatusFailed {
+				return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillMediaFailed}
+			} else if len(keys) > MaxTrillVideos {
+				return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillMediaMixed}
+			}
+			continue
+		} else if media[i].Kind == MediaKindAudio {
+			if media[i].Status == MediaStatusFailed {
+				return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorVoiceNoteFailed}
+			} else if len(keys) > MaxTrillVoiceNotes {
+				return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillMediaMixed}
+			}
+			continue
+		}
+
+		buf, err := GetContentObject(ctx, key, MaxTrillImageBytes)
+		if errors.Is(err, ErrorObjectTooLarge) {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Err: err}
+		} else if err != nil {
+			return nil, err
+		}
+		if media[i].Width, media[i].Height, err = utils.ImageSize(buf); err != nil {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillMediaInvalid}
+		}
+	}
+
+	return media, nil
+}
+
+// Applies alt text given when the trill is posted, keyed by object key or GIF URL, then fails with a 400
+// HTTPError if the user requires alt text and any media is still missing it
+func ApplyAltText(ctx context.Context, username string, media []Media, altText map[string]string) error {
+	for i := range media {
+		key := media[i].ObjectKey
+		if media[i].ExternalURL != "" {
+			key = media[i].ExternalURL
+		}
+		if text, ok := altText[key]; ok {
+			media[i].AltText = strings.TrimSpace(text)
+		}
+	}
+
+	settings, err := GetUserSettings(ctx, username)
+	if err != nil {
+		return err
+	} else if !settings.RequireAltText {
+		return nil
+	}
+	for _, m := range media {
+		if m.AltText == "" {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorAltTextRequired}
+		}
+	}
+	return nil
+}
+
+// Links the trill's uploads to it and adds rows for its GIFs. Another trill claiming the same upload
+// first is a 409 HTTPError.
+func attachMedia(tx *gorm.DB, trill *Trill) error {
+	for i := range trill.Media {
+		media := &trill.Media[i]
+		media.TrillID = &trill.TrillID
+		if media.MediaID == 0 {
+			// object_key is left NULL, since the unique index would only allow one empty key
+			if err := tx.Omit("ObjectKey").Create(media).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		updates := map[string]interface{}{"trill_id": trill.TrillID, "position": media.Position, "alt_text": media.AltText,
+			"sensitive": media.Sensitive}
+		// a video's dimensions come from its transcode, which may already have finished
+		if media.Kind != MediaKindVideo {
+			updates["width"], updates["height"] = media.Width, media.Height
+		}
+		result := tx.Model(&Media{}).Where("media_id = ? AND trill_id IS NULL AND story_id IS NULL", media.MediaID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorTrillMediaUsed}
+		}
+	}
+	return nil
+}
+
+// Media come back in the order they were attached
+func orderMedia(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
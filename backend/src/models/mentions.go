@@ -0,0 +1,80 @@
+package models
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// A user mentioned in a trill. Only users that existed when the trill was posted get a row; where the
+// mentions fall in the text isn't stored, since FindMentions finds them again when the trill is shown.
+type Mention struct {
+	TrillID  int64  `gorm:"primarykey"`
+	Username string `gorm:"type:varchar(128);primarykey;index"`
+}
+
+// An @username in a trill's text. Start and End count characters rather than bytes, with Start on the @
+// and End just past the last character of the name.
+type MentionMatch struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// an @ that doesn't follow a word character or another @, so email addresses don't count; RE2 has no
+// lookbehind, so the character before it is part of the match
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]+)`)
+
+// Every @username in the text, in order, including repeats. Names that can't be usernames are left out.
+func FindMentions(text string) []MentionMatch {
+	var matches []MentionMatch
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		username := text[loc[2]:loc[3]]
+		if !usernamePattern.MatchString(username) {
+			continue
+		}
+		// the @ sits right before the name
+		start := utf8.RuneCountInString(text[:loc[2]-1])
+		matches = append(matches, MentionMatch{
+			Username: username,
+			Start:    start,
+			End:      start + 1 + utf8.RuneCountInString(username),
+		})
+	}
+	return matches
+}
+
+// Records a mention for each distinct @username in the trill that belongs to an active user, setting
+// the trill's Mentions to the rows it made
+func mentionUsers(tx *gorm.DB, trill *Trill) error {
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range FindMentions(trill.Text) {
+		if name := NormalizeUsername(match.Username); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var users []User
+	if err := tx.Select("username").Where("normalized_username IN ? AND deactivated_at IS NULL", names).Find(&users).Error; err != nil {
+		return err
+	} else if len(users) == 0 {
+		return nil
+	}
+
+	mentions := make([]Mention, len(users))
+	for i, user := range users {
+		mentions[i] = Mention{TrillID: trill.TrillID, Username: user.Username}
+	}
+	if err := tx.Create(&mentions).Error; err != nil {
+		return err
+	}
+
+	trill.Mentions = mentions
+	return nil
+}
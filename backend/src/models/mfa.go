@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// The issuer authenticator apps show next to the code
+const MFAIssuer = "Trill"
+
+var (
+	ErrorMFANotSetUp error = errors.New("set up an authenticator app with POST /auth/mfa/setup before turning on two-factor auth")
+)
+
+// Starts authenticator app setup, returning the secret the app needs to generate codes
+func AssociateSoftwareToken(ctx context.Context, accessToken string) (string, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	associate, err := cognitoClient.Client.AssociateSoftwareToken(ctx, &cognitoidentityprovider.AssociateSoftwareTokenInput{
+		AccessToken: aws.String(accessToken),
+	})
+	if err != nil {
+		return "", translateTokenError(err)
+	}
+
+	return aws.ToString(associate.SecretCode), nil
+}
+
+// Checks a code from the authenticator app to finish setup, then makes it the user's second factor
+func VerifySoftwareToken(ctx context.Context, accessToken string, code string, deviceName string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	input := &cognitoidentityprovider.VerifySoftwareTokenInput{
+		AccessToken: aws.String(accessToken),
+		UserCode:    aws.String(code),
+	}
+	if deviceName != "" {
+		input.FriendlyDeviceName = aws.String(deviceName)
+	}
+	verify, err := cognitoClient.Client.VerifySoftwareToken(ctx, input)
+	var enableFailed *types.EnableSoftwareTokenMFAException
+	if errors.As(err, &enableFailed) {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "code_mismatch", Err: ErrorCodeMismatch}
+	} else if err != nil {
+		return translateTokenError(err)
+	} else if verify.Status != types.VerifySoftwareTokenResponseTypeSuccess {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "code_mismatch", Err: ErrorCodeMismatch}
+	}
+
+	return SetSoftwareTokenMFA(ctx, accessToken, true)
+}
+
+// Turns authenticator app codes on or off as part of logging in
+func SetSoftwareTokenMFA(ctx context.Context, accessToken string, enabled bool) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.SetUserMFAPreference(ctx, &cognitoidentityprovider.SetUserMFAPreferenceInput{
+		AccessToken: aws.String(accessToken),
+		SoftwareTokenMfaSettings: &types.SoftwareTokenMfaSettingsType{
+			Enabled:      enabled,
+			PreferredMfa: enabled,
+		},
+	})
+	var invalidParameter *types.InvalidParameterException
+	if errors.As(err, &invalidParameter) && strings.Contains(strings.ToLower(aws.ToString(invalidParameter.Message)), "software token") {
+		return &AuthError{Code: http.StatusConflict, Reason: "mfa_not_set_up", Err: ErrorMFANotSetUp}
+	} else if err != nil {
+		return translateTokenError(err)
+	}
+
+	return nil
+}
+
+// true if the user has to enter an authenticator app code to log in
+func IsSoftwareTokenMFAEnabled(ctx context.Context, accessToken string) (bool, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	cognitoUser, err := cognitoClient.Client.GetUser(ctx, &cognitoidentityprovider.GetUserInput{
+		AccessToken: aws.String(accessToken),
+	})
+	if err != nil {
+		return false, translateTokenError(err)
+	}
+
+	for _, setting := range cognitoUser.UserMFASettingList {
+		if setting == string(types.ChallengeNameTypeSoftwareTokenMfa) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Finishes a login that came back with a SOFTWARE_TOKEN_MFA challenge
+func RespondToMFAChallenge(ctx context.Context, username string, session string, code string) (*cognitoidentityprovider.RespondToAuthChallengeOutput, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := cognitoClient.Client.RespondToAuthChallenge(ctx, &cognitoidentityprovider.RespondToAuthChallengeInput{
+		ClientId:      aws.String(cognitoClient.AppClientId),
+		ChallengeName: types.ChallengeNameTypeSoftwareTokenMfa,
+		Session:       aws.String(session),
+		ChallengeResponses: withSecretHash(username, map[string]string{
+			"USERNAME":                username,
+			"SOFTWARE_TOKEN_MFA_CODE": code,
+		}),
+	})
+	var codeMismatch *types.CodeMismatchException
+	if errors.As(err, &codeMismatch) {
+		// a wrong code can be tried again with the same session
+		return nil, &AuthError{Code: http.StatusBadRequest, Reason: "code_mismatch", Err: ErrorCodeMismatch}
+	} else if err != nil {
+		return nil, translateLoginError(err)
+	}
+
+	return auth, nil
+}
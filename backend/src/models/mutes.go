@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Unlike a block, a mute is one-sided and invisible to the muted user
+type Mute struct {
+	Muter     string    `gorm:"type:varchar(128);primarykey"`
+	Muted     string    `gorm:"type:varchar(128);primarykey"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func CreateMute(ctx context.Context, muter string, muted string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	mute := Mute{Muter: muter, Muted: muted}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mute).Error
+}
+
+func DeleteMute(ctx context.Context, muter string, muted string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Where("muter = ? AND muted = ?", muter, muted).Delete(&Mute{}).Error
+}
+
+func IsMuted(ctx context.Context, muter string, muted string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Model(&Mute{}).Where("muter = ? AND muted = ?", muter, muted).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Filters out rows whose column is a user the requestor has muted
+func excludeMuted(query *gorm.DB, db *gorm.DB, column string, requestor string) *gorm.DB {
+	muted := db.Model(&Mute{}).Select("muted").Where("muter = ?", requestor)
+	return query.Where(fmt.Sprintf("%s NOT IN (?)", column), muted)
+}
+
+// A MySQL regular expression matching any of the muted words or phrases as whole words, ignoring case
+// once it's passed to REGEXP_LIKE with 'i'. A word also matches as a hashtag or mention, and the words
+// of a phrase can be split by any whitespace. Empty when nothing is muted.
+func mutedWordsPattern(words []string) string {
+	var alternatives []string
+	for _, word := range words {
+		fields := strings.Fields(word)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, field := range fields {
+			fields[i] = regexp.QuoteMeta(field)
+		}
+		alternatives = append(alternatives, strings.Join(fields, `\s+`))
+	}
+	if len(alternatives) == 0 {
+		return ""
+	}
+	return `(^|[^\p{L}\p{N}_#@])[#@]?(` + strings.Join(alternatives, "|") + `)($|[^\p{L}\p{N}_])`
+}
+
+// Filters out trills, from a query on the trills table, whose text uses one of the requestor's muted
+// words, along with retrills and quotes of them. The requestor's own trills are never filtered.
+func excludeMutedWords(query *gorm.DB, db *gorm.DB, requestor string, words []string) *gorm.DB {
+	pattern := mutedWordsPattern(words)
+	if pattern == "" {
+		return query
+	}
+	return query.Where("trills.username = ? OR (NOT REGEXP_LIKE(trills.text, ?, 'i') AND NOT EXISTS (?))", requestor, pattern,
+		db.Table("trills AS originals").Select("1").
+			Where("originals.trill_id IN (trills.retrill_of_id, trills.quote_of_id) AND REGEXP_LIKE(originals.text, ?, 'i')", pattern))
+}
+
+// Filters out notifications about a trill that uses one of the user's muted words
+func excludeMutedWordNotifications(query *gorm.DB, db *gorm.DB, words []string) *gorm.DB {
+	pattern := mutedWordsPattern(words)
+	if pattern == "" {
+		return query
+	}
+	return query.Where("NOT EXISTS (?)", db.Table("trills").Select("1").
+		Where("trills.trill_id = notifications.trill_id AND REGEXP_LIKE(trills.text, ?, 'i')", pattern))
+}
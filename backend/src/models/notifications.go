@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Something another user did that involves the user, shown in their notifications until it's deleted
+// along with whatever it points at
+type Notification struct {
+	NotificationID int64      `gorm:"primarykey;autoIncrement"`
+	Username       string     `gorm:"type:varchar(128);index"`
+	Type           string     `gorm:"type:varchar(32)"`
+	Actor          string     `gorm:"type:varchar(128);index"`
+	TrillID        *int64     `gorm:"index"`
+	CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	ReadAt         *time.Time `gorm:"index"`
+	ActorUser      User       `gorm:"foreignKey:Actor;references:Username"`
+	Trill          *Trill     `gorm:"foreignKey:TrillID;references:TrillID"`
+}
+
+const (
+	NotificationTypeMention = "mention"
+)
+
+// Notifies the users in the trill's Mentions, skipping the author, anyone with a block or mute
+// between them, anyone who turned mention notifications off, and anyone who can't see a private author
+func notifyMentions(tx *gorm.DB, trill *Trill) error {
+	if len(trill.Mentions) == 0 {
+		return nil
+	}
+
+	var author User
+	if err := tx.Select("username", "is_private").Where("username = ?", trill.Username).Limit(1).Find(&author).Error; err != nil {
+		return err
+	}
+
+	mentioned := make([]string, len(trill.Mentions))
+	for i, mention := range trill.Mentions {
+		mentioned[i] = mention.Username
+	}
+
+	query := tx.Model(&Mention{}).Where("trill_id = ? AND username IN ? AND username <> ?", trill.TrillID, mentioned, trill.Username)
+	query = excludeBlocked(query, tx, "username", trill.Username)
+	query = query.Where("username NOT IN (?)", tx.Model(&Mute{}).Select("muter").Where("muted = ?", trill.Username))
+	query = query.Where("username NOT IN (?)", tx.Model(&UserSettings{}).Select("username").Where("notify_mentions = ?", false))
+	if author.IsPrivate {
+		query = query.Where("username IN (?)", tx.Model(&Follows{}).Select("followee").Where("following = ?", trill.Username))
+	}
+
+	var recipients []string
+	if err := query.Pluck("username", &recipients).Error; err != nil {
+		return err
+	} else if len(recipients) == 0 {
+		return nil
+	}
+
+	notifications := make([]Notification, len(recipients))
+	for i, recipient := range recipients {
+		notifications[i] = Notification{
+			Username: recipient,
+			Type:     NotificationTypeMention,
+			Actor:    trill.Username,
+			TrillID:  &trill.TrillID,
+		}
+	}
+	return tx.Omit(clause.Associations).Create(&notifications).Error
+}
+
+// The user's notifications newest first, keyset paginated on the notification ID in either direction
+func GetNotifications(ctx context.Context, username string, limit int, cursor *Cursor) (*[]Notification, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	settings, err := GetUserSettings(ctx, username)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var notifications []Notification
+	query := visibleNotifications(db.Preload("ActorUser"), db, username, settings.MutedWords)
+	if err := (pagination.Keyset{Value: "notification_id"}).Apply(query, cursor, limit).Find(&notifications).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	notifications, cursors := pagination.Trim(notifications, cursor, limit, func(notification *Notification) Cursor {
+		return Cursor{Value: notification.NotificationID}
+	})
+
+	// the trills go through preloadTrills for their authors and the trills they point at
+	var trillIDs []int64
+	for _, notification := range notifications {
+		if notification.TrillID != nil {
+			trillIDs = append(trillIDs, *notification.TrillID)
+		}
+	}
+	if len(trillIDs) > 0 {
+		var trills []Trill
+		if err := preloadTrills(db).Where("trill_id IN ?", trillIDs).Find(&trills).Error; err != nil {
+			return nil, Cursors{}, err
+		}
+		byID := make(map[int64]*Trill, len(trills))
+		for i := range trills {
+			byID[trills[i].TrillID] = &trills[i]
+		}
+		for i := range notifications {
+			if notifications[i].TrillID != nil {
+				notifications[i].Trill = byID[*notifications[i].TrillID]
+			}
+		}
+	}
+
+	return &notifications, cursors, nil
+}
+
+// Leaves out notifications from users the user has since blocked, muted, or been blocked by, or who
+// deactivated, and notifications about trills using one of the user's muted words
+func visibleNotifications(query *gorm.DB, db *gorm.DB, username string, mutedWords []string) *gorm.DB {
+	query = query.Where("username = ? AND actor NOT IN (?)", username, deactivatedUsers(db))
+	query = excludeMuted(excludeBlocked(query, db, "actor", username), db, "actor", username)
+	return excludeMutedWordNotifications(query, db, mutedWords)
+}
+
+// Counts the same notifications GetNotifications returns
+func CountUnreadNotifications(ctx context.Context, username string) (int64, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	settings, err := GetUserSettings(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := visibleNotifications(db.Model(&Notification{}), db, username, settings.MutedWords).Where("read_at IS NULL").Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Marks every notification the user has as read
+func MarkNotificationsRead(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&Notification{}).Where("username = ? AND read_at IS NULL", username).Update("read_at", time.Now()).Error
+}
+
+// Marks the user's mention notifications for the trills as read, once they've seen those trills in their
+// mentions timeline
+func MarkMentionsRead(ctx context.Context, username string, trills []Trill) error {
+	if len(trills) == 0 {
+		return nil
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	trillIDs := make([]int64, len(trills))
+	for i, trill := range trills {
+		trillIDs[i] = trill.TrillID
+	}
+	return db.Model(&Notification{}).
+		Where("username = ? AND type = ? AND trill_id IN ? AND read_at IS NULL", username, NotificationTypeMention, trillIDs).
+		Update("read_at", time.Now()).Error
+}
@@ -0,0 +1,434 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// A third-party application registered by a Trill user. Its client secret is stored hashed and
+// shown once, when the app is registered.
+type OAuthApp struct {
+	ClientID         string     `gorm:"type:char(36);primarykey"`
+	ClientSecretHash string     `gorm:"type:char(64)"`
+	Owner            string     `gorm:"type:varchar(128);index"`
+	Name             string     `gorm:"type:varchar(64)"`
+	RedirectURIs     string     `gorm:"type:varchar(1024)"`
+	Scopes           string     `gorm:"type:varchar(512)"`
+	CreatedAt        time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	RevokedAt        *time.Time `gorm:"index"`
+}
+
+// An authorization code waiting to be exchanged for tokens; each can only be used once
+type OAuthCode struct {
+	CodeHash      string    `gorm:"type:char(64);primarykey"`
+	ClientID      string    `gorm:"type:char(36);index"`
+	Username      string    `gorm:"type:varchar(128)"`
+	RedirectURI   string    `gorm:"type:varchar(512)"`
+	Scopes        string    `gorm:"type:varchar(512)"`
+	CodeChallenge string    `gorm:"type:varchar(128)"`
+	ExpiresAt     time.Time `gorm:"index"`
+}
+
+// An access or refresh token issued to an app, acting for Username within Scopes
+type OAuthToken struct {
+	TokenHash string    `gorm:"type:char(64);primarykey"`
+	Kind      string    `gorm:"type:varchar(16)"`
+	ClientID  string    `gorm:"type:char(36);index"`
+	Username  string    `gorm:"type:varchar(128);index"`
+	Scopes    string    `gorm:"type:varchar(512)"`
+	ExpiresAt time.Time `gorm:"index"`
+	RevokedAt *time.Time
+}
+
+// Tokens handed to an app by the token endpoint
+type OAuthGrant struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+	Scopes       []string
+}
+
+const (
+	OAuthAccessTokenPrefix  = "tro_"
+	OAuthRefreshTokenPrefix = "trr_"
+	OAuthAccessToken        = "access"
+	OAuthRefreshToken       = "refresh"
+	MaxOAuthAppsPerUser     = 10
+	MaxRedirectURIs         = 5
+)
+
+var (
+	OAuthCodeLifetime         = 10 * time.Minute
+	OAuthAccessTokenLifetime  = time.Hour
+	OAuthRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+var (
+	ErrorOAuthAppNotFound     error = errors.New("no app has that client id")
+	ErrorTooManyOAuthApps     error = fmt.Errorf("a user can register at most %d apps", MaxOAuthAppsPerUser)
+	ErrorRedirectURIs         error = fmt.Errorf("apps need 1-%d redirect URIs", MaxRedirectURIs)
+	ErrorInvalidClient        error = errors.New("client authentication failed")
+	ErrorRedirectMismatch     error = errors.New("redirect_uri isn't registered for this app")
+	ErrorScopeNotAllowed      error = errors.New("the app isn't registered for one or more of the requested scopes")
+	ErrorInvalidGrant         error = errors.New("the code or refresh token is invalid, expired, or was issued to another client")
+	ErrorCodeVerifierMismatch error = errors.New("code_verifier doesn't match the code_challenge")
+)
+
+func (app *OAuthApp) RedirectURIList() []string {
+	return strings.Fields(app.RedirectURIs)
+}
+
+func (app *OAuthApp) ScopeList() []string {
+	return splitScopes(app.Scopes)
+}
+
+// Redirect URIs have to match one the app registered exactly
+func (app *OAuthApp) AllowsRedirect(redirectURI string) bool {
+	for _, registered := range app.RedirectURIList() {
+		if registered == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// The scopes to grant for a request, which can't go beyond what the app registered for; asking
+// for none grants all of them
+func (app *OAuthApp) GrantableScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return app.ScopeList(), nil
+	}
+
+	scopes, err := ValidateScopes(requested)
+	if err != nil {
+		return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_scope", Err: err}
+	}
+	for _, scope := range scopes {
+		if !hasScope(app.Scopes, scope) {
+			return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_scope", Err: ErrorScopeNotAllowed}
+		}
+	}
+	return scopes, nil
+}
+
+func (token *OAuthToken) HasScope(scope string) bool {
+	return hasScope(token.Scopes, scope)
+}
+
+// https for anything but local development; custom schemes like trillapp:// are fine for mobile apps
+func validateRedirectURIs(redirectURIs []string) error {
+	if len(redirectURIs) == 0 || len(redirectURIs) > MaxRedirectURIs {
+		return &HTTPError{Code: http.StatusBadRequest, Err: ErrorRedirectURIs}
+	}
+
+	for _, redirectURI := range redirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.ContainsAny(redirectURI, " ,") {
+			return &HTTPError{Code: http.StatusBadRequest, Err: fmt.Errorf("'%s' isn't a valid redirect URI", redirectURI)}
+		} else if parsed.Scheme == "http" && parsed.Hostname() != "localhost" && parsed.Hostname() != "127.0.0.1" {
+			return &HTTPError{Code: http.StatusBadRequest, Err: fmt.Errorf("'%s' has to use https", redirectURI)}
+		}
+	}
+	return nil
+}
+
+// Registers an app, returning it along with its client secret, which can't be recovered later
+func RegisterOAuthApp(ctx context.Context, owner string, name string, redirectURIs []string, scopes []string) (*OAuthApp, string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := validateRedirectURIs(redirectURIs); err != nil {
+		return nil, "", err
+	}
+	scopes, err = ValidateScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var count int64
+	if err := db.Model(&OAuthApp{}).Where("owner = ? AND revoked_at IS NULL", owner).Count(&count).Error; err != nil {
+		return nil, "", err
+	} else if count >= MaxOAuthAppsPerUser {
+		return nil, "", &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyOAuthApps}
+	}
+
+	clientSecret, secretHash, err := randomToken("")
+	if err != nil {
+		return nil, "", err
+	}
+
+	app := OAuthApp{
+		ClientID:         uuid.NewString(),
+		ClientSecretHash: secretHash,
+		Owner:            owner,
+		Name:             name,
+		RedirectURIs:     strings.Join(redirectURIs, " "),
+		Scopes:           strings.Join(scopes, ","),
+	}
+	if err := db.Create(&app).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &app, clientSecret, nil
+}
+
+// The apps the user has registered, newest first
+func GetOAuthApps(ctx context.Context, owner string) ([]OAuthApp, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []OAuthApp
+	if err := db.Where("owner = ? AND revoked_at IS NULL", owner).Order("created_at desc").Find(&apps).Error; err != nil {
+		return nil, err
+	}
+
+	return apps, nil
+}
+
+// Returns an AuthError with invalid_client if the app doesn't exist or was deleted
+func GetOAuthApp(ctx context.Context, clientID string) (*OAuthApp, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var app OAuthApp
+	if result := db.Where("client_id = ? AND revoked_at IS NULL", clientID).Limit(1).Find(&app); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: ErrorOAuthAppNotFound}
+	}
+
+	return &app, nil
+}
+
+// Checks the credentials an app sent to the token endpoint
+func AuthenticateOAuthClient(ctx context.Context, clientID string, clientSecret string) (*OAuthApp, error) {
+	app, err := GetOAuthApp(ctx, clientID)
+	if err != nil {
+		if _, ok := err.(*AuthError); ok {
+			return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: ErrorInvalidClient}
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(clientSecret)), []byte(app.ClientSecretHash)) != 1 {
+		return nil, &AuthError{Code: http.StatusUnauthorized, Reason: "invalid_client", Err: ErrorInvalidClient}
+	}
+	return app, nil
+}
+
+// Deletes one of the user's apps and revokes every token it was given
+func DeleteOAuthApp(ctx context.Context, owner string, clientID string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&OAuthApp{}).Where("client_id = ? AND owner = ? AND revoked_at IS NULL", clientID, owner).Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorOAuthAppNotFound}
+		}
+
+		if err := tx.Where("client_id = ?", clientID).Delete(&OAuthCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&OAuthToken{}).Where("client_id = ? AND revoked_at IS NULL", clientID).Update("revoked_at", now).Error
+	})
+}
+
+// Issues a code for the user's consent to the app, to be exchanged at the token endpoint. A
+// code_challenge (S256 only) makes the exchange require the matching code_verifier.
+func CreateOAuthCode(ctx context.Context, app *OAuthApp, username string, redirectURI string, scopes []string, codeChallenge string) (string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	rawCode, codeHash, err := randomToken("")
+	if err != nil {
+		return "", err
+	}
+
+	code := OAuthCode{
+		CodeHash:      codeHash,
+		ClientID:      app.ClientID,
+		Username:      username,
+		RedirectURI:   redirectURI,
+		Scopes:        strings.Join(scopes, ","),
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(OAuthCodeLifetime),
+	}
+	if err := db.Create(&code).Error; err != nil {
+		return "", err
+	}
+
+	return rawCode, nil
+}
+
+// Trades an authorization code for tokens. The code is deleted whether or not the exchange works,
+// so it can never be tried twice.
+func ExchangeOAuthCode(ctx context.Context, app *OAuthApp, rawCode string, redirectURI string, codeVerifier string) (*OAuthGrant, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var code OAuthCode
+	if result := db.Where("code_hash = ?", hashSecret(rawCode)).Limit(1).Find(&code); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorInvalidGrant}
+	}
+	// only the request that deletes the row gets to use the code
+	if result := db.Where("code_hash = ?", code.CodeHash).Delete(&OAuthCode{}); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorInvalidGrant}
+	}
+
+	if code.ClientID != app.ClientID || code.RedirectURI != redirectURI || time.Now().After(code.ExpiresAt) {
+		return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorInvalidGrant}
+	}
+	if code.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != code.CodeChallenge {
+			return nil, &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorCodeVerifierMismatch}
+		}
+	}
+
+	return issueOAuthTokens(db, app.ClientID, code.Username, splitScopes(code.Scopes), true)
+}
+
+// Client credentials tokens act as the app's owner, e.g. a bot posting from its own account
+func ClientCredentialsGrant(ctx context.Context, app *OAuthApp, scopes []string) (*OAuthGrant, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueOAuthTokens(db, app.ClientID, app.Owner, scopes, false)
+}
+
+// Trades a refresh token for a new pair; the old refresh token is revoked, so each can only be used once
+func RefreshOAuthGrant(ctx context.Context, app *OAuthApp, rawRefreshToken string) (*OAuthGrant, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var grant *OAuthGrant
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var refresh OAuthToken
+		if result := tx.Where("token_hash = ? AND kind = ?", hashSecret(rawRefreshToken), OAuthRefreshToken).Limit(1).Find(&refresh); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 || refresh.ClientID != app.ClientID {
+			return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorInvalidGrant}
+		}
+
+		result := tx.Model(&OAuthToken{}).Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", refresh.TokenHash, time.Now()).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_grant", Err: ErrorInvalidGrant}
+		}
+
+		grant, err = issueOAuthTokens(tx, app.ClientID, refresh.Username, splitScopes(refresh.Scopes), true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+func issueOAuthTokens(tx *gorm.DB, clientID string, username string, scopes []string, withRefresh bool) (*OAuthGrant, error) {
+	accessToken, accessHash, err := randomToken(OAuthAccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	joinedScopes := strings.Join(scopes, ",")
+	tokens := []OAuthToken{{
+		TokenHash: accessHash,
+		Kind:      OAuthAccessToken,
+		ClientID:  clientID,
+		Username:  username,
+		Scopes:    joinedScopes,
+		ExpiresAt: time.Now().Add(OAuthAccessTokenLifetime),
+	}}
+	grant := OAuthGrant{AccessToken: accessToken, ExpiresIn: int(OAuthAccessTokenLifetime.Seconds()), Scopes: scopes}
+
+	if withRefresh {
+		refreshToken, refreshHash, err := randomToken(OAuthRefreshTokenPrefix)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, OAuthToken{
+			TokenHash: refreshHash,
+			Kind:      OAuthRefreshToken,
+			ClientID:  clientID,
+			Username:  username,
+			Scopes:    joinedScopes,
+			ExpiresAt: time.Now().Add(OAuthRefreshTokenLifetime),
+		})
+		grant.RefreshToken = refreshToken
+	}
+
+	if err := tx.Create(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Revokes one of the app's tokens. Unknown tokens aren't an error, as RFC 7009 asks.
+func RevokeOAuthToken(ctx context.Context, app *OAuthApp, rawToken string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&OAuthToken{}).Where("token_hash = ? AND client_id = ? AND revoked_at IS NULL", hashSecret(rawToken), app.ClientID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Looks up the access token a request was made with, returning nil if it doesn't exist, expired,
+// was revoked, or belongs to a deleted app
+func AuthenticateOAuthToken(ctx context.Context, rawToken string) (*OAuthToken, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	activeApps := db.Model(&OAuthApp{}).Select("client_id").Where("revoked_at IS NULL")
+	var token OAuthToken
+	if result := db.Where("token_hash = ? AND kind = ? AND revoked_at IS NULL AND expires_at > ? AND client_id IN (?)",
+		hashSecret(rawToken), OAuthAccessToken, time.Now(), activeApps).Limit(1).Find(&token); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &token, nil
+}
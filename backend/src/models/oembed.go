@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// a trill's page in the web app, /<username>/trills/<id>, or /trills/<id> without the author
+	trillPagePattern = regexp.MustCompile(`^(?:/[A-Za-z0-9_]+)?/trills/([0-9]+)/?$`)
+)
+
+var (
+	ErrorTrillURL           error = errors.New("url is not a link to a trill")
+	ErrorTrillNotEmbeddable error = errors.New("only trills from public accounts can be embedded")
+)
+
+// The trill's page in the web app
+func TrillURL(trill *Trill) string {
+	return fmt.Sprintf("%s/%s/trills/%d", WebAppURL(), url.PathEscape(trill.Username), trill.TrillID)
+}
+
+// The ID of the trill a web app link points at. Fails with a 404 HTTPError if it isn't a link to a trill
+// on the web app, which is what oEmbed expects for URLs a provider doesn't serve.
+func ParseTrillURL(link string) (int64, error) {
+	notFound := &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillURL}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return 0, notFound
+	}
+	webApp, err := url.Parse(WebAppURL())
+	if err != nil {
+		return 0, err
+	}
+	// the bare domain and www are the same site
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if host != strings.TrimPrefix(strings.ToLower(webApp.Hostname()), "www.") {
+		return 0, notFound
+	}
+
+	match := trillPagePattern.FindStringSubmatch(parsed.Path)
+	if match == nil {
+		return 0, notFound
+	}
+	trillID, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, notFound
+	}
+	return trillID, nil
+}
+
+// The trill a shared link points at, for showing off the site; a retrill gives the original. Callers
+// decide what to show of private authors' trills.
+func GetSharedTrill(ctx context.Context, trillID int64) (*Trill, error) {
+	trill, err := GetTrill(ctx, trillID)
+	if err != nil {
+		return nil, err
+	}
+	if trill.RetrillOf != nil {
+		trill = trill.RetrillOf
+	}
+	if trill.User.DeactivatedAt != nil {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+	}
+	return trill, nil
+}
+
+// A trill anyone can see, for embedding on other sites. Fails with a 404 HTTPError if it doesn't exist
+// and a 401 if its author is private, as oEmbed has it.
+func GetEmbeddableTrill(ctx context.Context, trillID int64) (*Trill, error) {
+	trill, err := GetSharedTrill(ctx, trillID)
+	if err != nil {
+		return nil, err
+	}
+	if trill.User.IsPrivate {
+		return nil, &HTTPError{Code: http.StatusUnauthorized, Err: ErrorTrillNotEmbeddable}
+	}
+	return trill, nil
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+)
 
 type Paginate struct {
 	Limit int
@@ -14,6 +18,15 @@ var (
 	PAGINATE_DEFAULT_SORT  = "newest"
 )
 
+// Keyset pagination lives in the pagination package; these keep the models' signatures short
+type Cursor = pagination.Cursor
+type Cursors = pagination.Cursors
+type IDRange = pagination.IDRange
+
+var (
+	ErrorCursorInvalid = pagination.ErrorCursorInvalid
+)
+
 func BuildQueryFromPaginate(db *gorm.DB, pagination *Paginate) (*gorm.DB, error) {
 	offset := (pagination.Page - 1) * pagination.Limit
 	query := db.Limit(pagination.Limit).Offset(offset)
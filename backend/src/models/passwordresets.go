@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// One call to POST /auth/forgot, kept just long enough to rate limit the next ones
+type PasswordResetAttempt struct {
+	ID        uint      `gorm:"primarykey"`
+	Username  string    `gorm:"type:varchar(128);index"`
+	SourceIP  string    `gorm:"type:varchar(45);index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+var (
+	PasswordResetWindow  = time.Hour
+	MaxResetsPerUsername = 3
+	MaxResetsPerSourceIP = 10
+)
+
+var (
+	ErrorTooManyResets error = errors.New("too many password reset requests, try again in an hour")
+)
+
+// Counts a reset request against both the account and the caller's IP, turning it down once either
+// has used up its requests for the window. Limiting by IP stops one caller from walking a list of
+// usernames, and limiting by username stops anyone from flooding one person's inbox.
+func RecordPasswordResetAttempt(ctx context.Context, username string, sourceIP string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-PasswordResetWindow)
+	if err := db.Where("created_at < ?", cutoff).Delete(&PasswordResetAttempt{}).Error; err != nil {
+		return err
+	}
+
+	normalized := NormalizeUsername(username)
+	var usernameCount int64
+	if err := db.Model(&PasswordResetAttempt{}).Where("username = ? AND created_at >= ?", normalized, cutoff).Count(&usernameCount).Error; err != nil {
+		return err
+	}
+	var sourceIPCount int64
+	if err := db.Model(&PasswordResetAttempt{}).Where("source_ip = ? AND created_at >= ?", sourceIP, cutoff).Count(&sourceIPCount).Error; err != nil {
+		return err
+	}
+	if usernameCount >= int64(MaxResetsPerUsername) || sourceIPCount >= int64(MaxResetsPerSourceIP) {
+		return &AuthError{Code: http.StatusTooManyRequests, Reason: "too_many_requests", Err: ErrorTooManyResets}
+	}
+
+	return db.Create(&PasswordResetAttempt{Username: normalized, SourceIP: sourceIP}).Error
+}
+
+// Has Cognito send a reset code. Accounts that don't exist or have nowhere to send the code look the
+// same as ones that do, so the endpoint can't be used to find out who has an account.
+func ForgotCognitoPassword(ctx context.Context, username string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.ForgotPassword(ctx, &cognitoidentityprovider.ForgotPasswordInput{
+		ClientId:   aws.String(cognitoClient.AppClientId),
+		SecretHash: cognitoSecretHash(username),
+		Username:   aws.String(username),
+	})
+	var userNotFound *types.UserNotFoundException
+	var invalidParameter *types.InvalidParameterException
+	var notAuthorized *types.NotAuthorizedException
+	if errors.As(err, &userNotFound) || errors.As(err, &invalidParameter) || errors.As(err, &notAuthorized) {
+		return nil
+	} else if err != nil {
+		return translateCognitoError(err)
+	}
+
+	return nil
+}
+
+// Sets a new password with the code from ForgotCognitoPassword
+func ConfirmCognitoForgotPassword(ctx context.Context, username string, code string, newPassword string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.ConfirmForgotPassword(ctx, &cognitoidentityprovider.ConfirmForgotPasswordInput{
+		ClientId:         aws.String(cognitoClient.AppClientId),
+		SecretHash:       cognitoSecretHash(username),
+		Username:         aws.String(username),
+		ConfirmationCode: aws.String(code),
+		Password:         aws.String(newPassword),
+	})
+	var userNotFound *types.UserNotFoundException
+	if errors.As(err, &userNotFound) {
+		// an account that doesn't exist never got a code, so any code is wrong for it
+		return &AuthError{Code: http.StatusBadRequest, Reason: "code_mismatch", Err: ErrorCodeMismatch}
+	} else if err != nil {
+		return translateCognitoError(err)
+	}
+
+	return nil
+}
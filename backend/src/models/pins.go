@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrorNotPinAuthor       error = errors.New("only the author can pin a trill")
+	ErrorRetrillNotPinnable error = errors.New("retrills can't be pinned")
+	ErrorTrillNotPinned     error = errors.New("trill is not pinned")
+)
+
+// Pins one of the user's trills to their profile in place of whatever was pinned before. Fails with a
+// 404 HTTPError if the trill doesn't exist, or a 403 if it's someone else's or a retrill.
+func PinTrill(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trill Trill
+	if result := db.Where("trill_id = ?", trillID).Limit(1).Find(&trill); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+	} else if trill.Username != username {
+		return &HTTPError{Code: http.StatusForbidden, Err: ErrorNotPinAuthor}
+	} else if trill.RetrillOfID != nil {
+		return &HTTPError{Code: http.StatusForbidden, Err: ErrorRetrillNotPinnable}
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).UpdateColumn("pinned_trill_id", trillID).Error
+}
+
+// Fails with a 404 HTTPError if the trill isn't the one the user has pinned
+func UnpinTrill(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := db.Model(&User{}).Where("username = ? AND pinned_trill_id = ?", username, trillID).
+		UpdateColumn("pinned_trill_id", nil)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotPinned}
+	}
+
+	return nil
+}
+
+// The trill the user pinned, with everything a trill response needs, or nil if they haven't pinned one
+func GetPinnedTrill(ctx context.Context, user *User) (*Trill, error) {
+	if user.PinnedTrillID == nil {
+		return nil, nil
+	}
+
+	trill, err := GetTrill(ctx, *user.PinnedTrillID)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code == http.StatusNotFound {
+		return nil, nil
+	}
+	return trill, err
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A poll attached to a trill when it's posted. It can't be changed afterwards, and closes on its own at
+// ClosesAt. The vote counts are kept on the poll and its options so results don't need counting.
+type Poll struct {
+	TrillID   int64        `gorm:"primarykey"`
+	ClosesAt  time.Time    `gorm:"index"`
+	VoteCount int64        `gorm:"not null;default:0"`
+	Options   []PollOption `gorm:"foreignKey:TrillID;references:TrillID"`
+}
+
+// One of a poll's choices, numbered from 0 in the order they were given
+type PollOption struct {
+	TrillID   int64  `gorm:"primarykey"`
+	Position  int    `gorm:"primarykey"`
+	Text      string `gorm:"type:varchar(25)"`
+	VoteCount int64  `gorm:"not null;default:0"`
+}
+
+// A user's vote. The composite key keeps it to one vote per user per poll, and votes can't be changed.
+type PollVote struct {
+	TrillID   int64     `gorm:"primarykey"`
+	Username  string    `gorm:"type:varchar(128);primarykey;index"`
+	Position  int       `gorm:"not null"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	MinPollOptions      = 2
+	MaxPollOptions      = 4
+	MaxPollOptionLength = 25
+	MinPollDuration     = 5 * time.Minute
+	MaxPollDuration     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrorPollNotFound      error = errors.New("trill does not have a poll")
+	ErrorPollClosed        error = errors.New("poll has closed")
+	ErrorPollOptionInvalid error = errors.New("poll does not have that option")
+	ErrorAlreadyVoted      error = errors.New("you already voted in this poll")
+	ErrorPollWithMedia     error = errors.New("a trill can't have both a poll and media")
+)
+
+// A poll with the given options that closes after the duration
+func NewPoll(options []string, duration time.Duration) *Poll {
+	poll := Poll{
+		ClosesAt: time.Now().Add(duration).UTC().Truncate(time.Second),
+		Options:  make([]PollOption, len(options)),
+	}
+	for i, text := range options {
+		poll.Options[i] = PollOption{Position: i, Text: text}
+	}
+	return &poll
+}
+
+func (poll *Poll) Closed() bool {
+	return !time.Now().Before(poll.ClosesAt)
+}
+
+// Saves the new trill's poll, if it has one
+func createPoll(tx *gorm.DB, trill *Trill) error {
+	if trill.Poll == nil {
+		return nil
+	}
+
+	trill.Poll.TrillID = trill.TrillID
+	for i := range trill.Poll.Options {
+		trill.Poll.Options[i].TrillID = trill.TrillID
+	}
+	if err := tx.Omit(clause.Associations).Create(trill.Poll).Error; err != nil {
+		return err
+	}
+	return tx.Create(&trill.Poll.Options).Error
+}
+
+// Records the user's vote for the option at position. Fails with a 404 HTTPError if the trill has no
+// poll, a 400 if the option doesn't exist or the poll has closed, or a 409 if the user already voted.
+func VoteInPoll(ctx context.Context, username string, trillID int64, position int) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var poll Poll
+		if result := tx.Where("trill_id = ?", trillID).Limit(1).Find(&poll); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorPollNotFound}
+		} else if poll.Closed() {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorPollClosed}
+		}
+
+		option := tx.Model(&PollOption{}).Where("trill_id = ? AND position = ?", trillID, position)
+		if result := option.UpdateColumn("vote_count", gorm.Expr("vote_count + 1")); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorPollOptionInvalid}
+		}
+
+		vote := PollVote{TrillID: trillID, Username: username, Position: position}
+		if result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&vote); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			// rolls back the option's count along with the rest of the transaction
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorAlreadyVoted}
+		}
+
+		return tx.Model(&poll).UpdateColumn("vote_count", gorm.Expr("vote_count + 1")).Error
+	})
+}
+
+// Options come back in the order they were given
+func orderPollOptions(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// Removes the votes in the trills' polls along with the polls
+func deletePolls(tx *gorm.DB, trillIDs interface{}) error {
+	if err := tx.Where("trill_id IN (?)", trillIDs).Delete(&PollVote{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id IN (?)", trillIDs).Delete(&PollOption{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("trill_id IN (?)", trillIDs).Delete(&Poll{}).Error
+}
+
+// Takes back the user's votes, keeping the counts right on polls that stay
+func deletePollVotes(tx *gorm.DB, username string) error {
+	voted := tx.Model(&PollVote{}).Select("trill_id", "position").Where("username = ?", username)
+	if err := tx.Model(&PollOption{}).Where("(trill_id, position) IN (?)", voted).
+		UpdateColumn("vote_count", gorm.Expr("vote_count - 1")).Error; err != nil {
+		return err
+	}
+	votedTrills := tx.Model(&PollVote{}).Select("trill_id").Where("username = ?", username)
+	if err := tx.Model(&Poll{}).Where("trill_id IN (?)", votedTrills).
+		UpdateColumn("vote_count", gorm.Expr("vote_count - 1")).Error; err != nil {
+		return err
+	}
+	return tx.Where("username = ?", username).Delete(&PollVote{}).Error
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	// someone active this recently shows as online
+	OnlineWindow = 5 * time.Minute
+	// how stale last_active_at can get before a request bothers writing it again
+	LastActiveThrottle = time.Minute
+)
+
+// Marks the user as active now, unless that was already recorded within LastActiveThrottle
+func TouchLastActive(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return db.Model(&User{}).
+		Where("username = ? AND (last_active_at IS NULL OR last_active_at < ?)", username, now.Add(-LastActiveThrottle)).
+		UpdateColumn("last_active_at", now).Error
+}
+
+func IsOnline(user *User) bool {
+	return user.LastActiveAt != nil && time.Since(*user.LastActiveAt) < OnlineWindow
+}
+
+// Presence is only shared between mutual followers, and only if both of them share theirs;
+// callers check the follows first since they usually have them already
+func SharesPresence(ctx context.Context, requestor string, username string) (bool, error) {
+	requestorSettings, err := GetUserSettings(ctx, requestor)
+	if err != nil {
+		return false, err
+	}
+	userSettings, err := GetUserSettings(ctx, username)
+	if err != nil {
+		return false, err
+	}
+
+	return requestorSettings.ShowActivityStatus && userSettings.ShowActivityStatus, nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Birthdays are sent and shown as plain dates
+const BirthdayLayout = "2006-01-02"
+
+var (
+	MaxNicknameLength    = 50
+	MaxDisplayNameLength = 50
+	MaxPronounsLength    = 30
+	MaxBioLength         = 300
+	MaxLocationLength    = 100
+	MaxWebsiteLength     = 255
+	MinUserAge           = 13
+)
+
+var (
+	ErrorWebsiteInvalid  error = errors.New("website must be an http or https URL")
+	ErrorBirthdayInvalid error = errors.New("birthday must be a date like 2000-01-31")
+	ErrorBirthdayRange   error = fmt.Errorf("birthday must be after 1900 and at least %d years ago", MinUserAge)
+)
+
+// "" clears the birthday
+func ParseBirthday(raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	birthday, err := time.Parse(BirthdayLayout, raw)
+	if err != nil {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorBirthdayInvalid}
+	}
+
+	return &birthday, nil
+}
+
+func FormatBirthday(birthday *time.Time) string {
+	if birthday == nil {
+		return ""
+	}
+
+	return birthday.Format(BirthdayLayout)
+}
+
+// Normalizes the editable profile fields in place, returning a 400 HTTPError for the first one that's invalid
+func ValidateProfile(user *User) error {
+	user.Nickname = strings.TrimSpace(user.Nickname)
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
+	user.Pronouns = strings.TrimSpace(user.Pronouns)
+	user.Bio = strings.TrimSpace(user.Bio)
+	user.Location = strings.TrimSpace(user.Location)
+	user.Website = strings.TrimSpace(user.Website)
+
+	lengths := []struct {
+		field string
+		value string
+		max   int
+	}{
+		{"nickname", user.Nickname, MaxNicknameLength},
+		{"display name", user.DisplayName, MaxDisplayNameLength},
+		{"pronouns", user.Pronouns, MaxPronounsLength},
+		{"bio", user.Bio, MaxBioLength},
+		{"location", user.Location, MaxLocationLength},
+		{"website", user.Website, MaxWebsiteLength},
+	}
+	for _, l := range lengths {
+		if utf8.RuneCountInString(l.value) > l.max {
+			return &HTTPError{Code: http.StatusBadRequest, Err: fmt.Errorf("%s must be at most %d characters", l.field, l.max)}
+		}
+	}
+
+	if len(user.Website) > 0 {
+		website, err := url.Parse(user.Website)
+		if err != nil || (website.Scheme != "http" && website.Scheme != "https") || len(website.Host) == 0 {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorWebsiteInvalid}
+		}
+	}
+
+	if user.Birthday != nil {
+		earliest := time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+		latest := time.Now().UTC().AddDate(-MinUserAge, 0, 0)
+		if user.Birthday.Before(earliest) || user.Birthday.After(latest) {
+			return &HTTPError{Code: http.StatusBadRequest, Err: ErrorBirthdayRange}
+		}
+	}
+
+	return nil
+}
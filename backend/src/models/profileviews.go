@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// At most one row per viewer per profile per (UTC) day, so refreshing a profile doesn't inflate its count
+type ProfileView struct {
+	Username  string    `gorm:"type:varchar(128);primarykey"`
+	Viewer    string    `gorm:"type:varchar(128);primarykey"`
+	Day       time.Time `gorm:"type:date;primarykey"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// What gets queued for the profileViews worker
+type ProfileViewEvent struct {
+	Username string    `json:"username"`
+	Viewer   string    `json:"viewer"`
+	ViewedAt time.Time `json:"viewed_at"`
+}
+
+// Queues the view instead of writing it, so viewing a profile doesn't wait on the insert
+func EnqueueProfileView(ctx context.Context, username string, viewer string) error {
+	if username == viewer {
+		return nil
+	}
+
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(ProfileViewEvent{Username: username, Viewer: viewer, ViewedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().ProfileViewQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Counts the view unless the viewer already viewed the profile that day; safe to call again for the same event
+func RecordProfileView(ctx context.Context, event *ProfileViewEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	day := event.ViewedAt.UTC().Truncate(24 * time.Hour)
+	return db.Transaction(func(tx *gorm.DB) error {
+		view := ProfileView{Username: event.Username, Viewer: event.Viewer, Day: day}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&view)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return incrementUserCounter(tx, "view_count", result.RowsAffected, event.Username)
+	})
+}
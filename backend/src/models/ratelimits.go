@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// A token bucket for one caller and class of route, kept in the RATE_LIMIT_TABLE DynamoDB table so
+// checking it doesn't cost a MySQL transaction on every request. Tokens are topped up lazily from
+// RefilledAt whenever the bucket is next used, and DynamoDB's TTL deletes each item once its
+// expires_at has passed, since by then the bucket would be full again anyway.
+type RateLimitBucket struct {
+	BucketKey  string
+	Tokens     float64
+	RefilledAt time.Time
+}
+
+// How many requests a caller can burst to, and how quickly they earn them back
+type RateLimitClass struct {
+	Name            string
+	Capacity        int
+	RefillPerSecond float64
+}
+
+var (
+	// reads are cheap and pages fire a few at once
+	RateLimitRead  = RateLimitClass{Name: "read", Capacity: 120, RefillPerSecond: 2}
+	RateLimitWrite = RateLimitClass{Name: "write", Capacity: 30, RefillPerSecond: 0.5}
+	// logging in, signing up, and getting tokens, which are limited by IP since there's no user yet
+	RateLimitAuth = RateLimitClass{Name: "auth", Capacity: 10, RefillPerSecond: 0.1}
+
+	// how many times a request retries its write after losing a race for the bucket
+	maxRateLimitAttempts = 3
+)
+
+// Where a caller stands after a request, for the X-RateLimit-* headers
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request would be allowed, when this one wasn't
+}
+
+// Takes a token from the caller's bucket for the class, turning the request down if it's empty.
+// The write is conditional on the bucket being as it was read, so concurrent requests can't spend
+// the same token; the one that loses reads the bucket again and retries.
+func TakeRateLimitToken(ctx context.Context, key string, class RateLimitClass) (*RateLimit, error) {
+	dynamoClient, err := InitDynamoDBClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketKey := class.Name + ":" + key
+	for attempt := 1; ; attempt++ {
+		limit, err := takeRateLimitToken(ctx, dynamoClient, bucketKey, class)
+		var conflict *types.ConditionalCheckFailedException
+		if errors.As(err, &conflict) && attempt < maxRateLimitAttempts {
+			continue
+		}
+		return limit, err
+	}
+}
+
+func takeRateLimitToken(ctx context.Context, dynamoClient *dynamodb.Client, bucketKey string, class RateLimitClass) (*RateLimit, error) {
+	table := aws.String(utils.GetSecrets().RateLimitTable)
+	item, err := dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      table,
+		Key:            map[string]types.AttributeValue{"bucket_key": &types.AttributeValueMemberS{Value: bucketKey}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	capacity := float64(class.Capacity)
+	bucket := RateLimitBucket{BucketKey: bucketKey, Tokens: capacity}
+	// a caller's first two requests can both find no item
+	condition := aws.String("attribute_not_exists(bucket_key)")
+	var values map[string]types.AttributeValue
+	if item.Item != nil {
+		if bucket, err = unmarshalRateLimitBucket(bucketKey, item.Item); err != nil {
+			return nil, err
+		}
+		bucket.Tokens = math.Min(capacity, bucket.Tokens+now.Sub(bucket.RefilledAt).Seconds()*class.RefillPerSecond)
+		condition = aws.String("refilled_at = :read")
+		values = map[string]types.AttributeValue{":read": item.Item["refilled_at"]}
+	}
+
+	var limit RateLimit
+	limit.Allowed = bucket.Tokens >= 1
+	limit.Limit = class.Capacity
+	if !limit.Allowed {
+		// the bucket is only written when a token is spent, so turning a request down costs a read
+		limit.Reset = refillTime(capacity-bucket.Tokens, class)
+		limit.RetryAfter = refillTime(1-bucket.Tokens, class)
+		return &limit, nil
+	}
+
+	bucket.Tokens--
+	bucket.RefilledAt = now
+	limit.Remaining = int(bucket.Tokens)
+	limit.Reset = refillTime(capacity-bucket.Tokens, class)
+
+	_, err = dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: table,
+		Item: map[string]types.AttributeValue{
+			"bucket_key":  &types.AttributeValueMemberS{Value: bucketKey},
+			"tokens":      &types.AttributeValueMemberN{Value: strconv.FormatFloat(bucket.Tokens, 'f', -1, 64)},
+			"refilled_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMicro(), 10)},
+			"expires_at":  &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(limit.Reset).Unix()+1, 10)},
+		},
+		ConditionExpression:       condition,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &limit, nil
+}
+
+func unmarshalRateLimitBucket(bucketKey string, item map[string]types.AttributeValue) (RateLimitBucket, error) {
+	bucket := RateLimitBucket{BucketKey: bucketKey}
+	tokens, ok := item["tokens"].(*types.AttributeValueMemberN)
+	if !ok {
+		return bucket, errors.New("rate limit bucket is missing tokens")
+	}
+	refilledAt, ok := item["refilled_at"].(*types.AttributeValueMemberN)
+	if !ok {
+		return bucket, errors.New("rate limit bucket is missing refilled_at")
+	}
+
+	var err error
+	if bucket.Tokens, err = strconv.ParseFloat(tokens.Value, 64); err != nil {
+		return bucket, err
+	}
+	micros, err := strconv.ParseInt(refilledAt.Value, 10, 64)
+	if err != nil {
+		return bucket, err
+	}
+	bucket.RefilledAt = time.UnixMicro(micros)
+
+	return bucket, nil
+}
+
+func refillTime(tokens float64, class RateLimitClass) time.Duration {
+	return time.Duration(tokens / class.RefillPerSecond * float64(time.Second))
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A user reacting to a trill with an emoji. A user can react with several different emoji, but each
+// one only once; likes stay separate.
+type Reaction struct {
+	TrillID   int64     `gorm:"primarykey"`
+	Username  string    `gorm:"type:varchar(128);primarykey;index"`
+	Emoji     string    `gorm:"type:varchar(32);primarykey"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// How many users reacted to a trill with an emoji, kept up as reactions come and go so trills can show
+// their counts without counting rows. Rows are deleted when they reach zero.
+type ReactionCount struct {
+	TrillID int64  `gorm:"primarykey"`
+	Emoji   string `gorm:"type:varchar(32);primarykey"`
+	Count   int64  `gorm:"not null;default:0"`
+}
+
+// Most used first, so the counts a client shows first are the ones that matter
+func orderReactionCounts(db *gorm.DB) *gorm.DB {
+	return db.Order("count DESC, emoji")
+}
+
+// Someone who reacted to a trill, with the emoji and when, which is also the keyset sort value
+type Reactor struct {
+	User      `gorm:"embedded"`
+	Emoji     string
+	ReactedAt time.Time
+}
+
+var (
+	// different emoji one user can leave on one trill
+	MaxReactionsPerUser = 10
+)
+
+var (
+	ErrorTooManyReactions error = errors.New("at most 10 different reactions can be left on a trill")
+)
+
+// Reacting with an emoji already used on the trill does nothing. Fails with a 409 HTTPError if the user
+// has already left MaxReactionsPerUser different emoji on it.
+func ReactToTrill(ctx context.Context, username string, trillID int64, emoji string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Reaction{}).Where("trill_id = ? AND username = ? AND emoji <> ?", trillID, username, emoji).
+			Count(&count).Error; err != nil {
+			return err
+		} else if count >= int64(MaxReactionsPerUser) {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyReactions}
+		}
+
+		reaction := Reaction{TrillID: trillID, Username: username, Emoji: emoji}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + 1")}),
+		}).Create(&ReactionCount{TrillID: trillID, Emoji: emoji, Count: 1}).Error
+	})
+}
+
+// Taking back a reaction that isn't there does nothing
+func UnreactToTrill(ctx context.Context, username string, trillID int64, emoji string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("trill_id = ? AND username = ? AND emoji = ?", trillID, username, emoji).Delete(&Reaction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return decrementReactionCounts(tx, trillID, []string{emoji})
+	})
+}
+
+// Takes one off each emoji's count on the trill, dropping counts that reach zero
+func decrementReactionCounts(tx *gorm.DB, trillID int64, emoji []string) error {
+	if err := tx.Model(&ReactionCount{}).Where("trill_id = ? AND emoji IN ?", trillID, emoji).
+		UpdateColumn("count", gorm.Expr("count - 1")).Error; err != nil {
+		return err
+	}
+	return tx.Where("trill_id = ? AND count <= 0", trillID).Delete(&ReactionCount{}).Error
+}
+
+// The emoji the requestor reacted to each of the trills with, by trill ID
+func getReacted(db *gorm.DB, requestor string, trillIDs []int64) (map[int64][]string, error) {
+	var reactions []Reaction
+	if err := db.Where("username = ? AND trill_id IN ?", requestor, trillIDs).Order("created_at").Find(&reactions).Error; err != nil {
+		return nil, err
+	}
+
+	reacted := make(map[int64][]string)
+	for _, reaction := range reactions {
+		reacted[reaction.TrillID] = append(reacted[reaction.TrillID], reaction.Emoji)
+	}
+	return reacted, nil
+}
+
+// Who reacted to the trill, optionally only with one emoji, most recent first. A user who reacted with
+// several emoji is listed once for each. Keyset paginated in either direction on when they reacted, then
+// the username and emoji; only users the requestor can see are listed.
+func GetReactors(ctx context.Context, trillID int64, emoji string, requestor string, limit int, cursor *Cursor) (*[]Reactor, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Model(&User{}).Select("users.*, reactions.emoji AS emoji, reactions.created_at AS reacted_at").
+		Joins("JOIN reactions ON reactions.username = users.username").Where("reactions.trill_id = ?", trillID)
+	if emoji != "" {
+		query = query.Where("reactions.emoji = ?", emoji)
+	}
+	// the key is the username and emoji joined by a slash, which is unambiguous since usernames can't have one
+	keyset := pagination.Keyset{Value: "reactions.created_at", Key: "CONCAT(users.username, '/', reactions.emoji)", KeyAscending: true, Time: true}
+
+	var reactors []Reactor
+	if err := keyset.Apply(visibleUsers(query, db, requestor), cursor, limit).Find(&reactors).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	reactors, cursors := pagination.Trim(reactors, cursor, limit, func(reactor *Reactor) Cursor {
+		return pagination.TimeCursor(reactor.ReactedAt, fmt.Sprintf("%s/%s", reactor.Username, reactor.Emoji))
+	})
+	return &reactors, cursors, nil
+}
+
+// Deletes the reactions on the given trills and their counts
+func deleteReactions(tx *gorm.DB, trillIDs interface{}) error {
+	if err := tx.Where("trill_id IN (?)", trillIDs).Delete(&Reaction{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("trill_id IN (?)", trillIDs).Delete(&ReactionCount{}).Error
+}
+
+// Takes back every reaction the user left, keeping the counts right on trills that stay
+func deleteUserReactions(tx *gorm.DB, username string) error {
+	var reactions []Reaction
+	if err := tx.Where("username = ?", username).Find(&reactions).Error; err != nil {
+		return err
+	}
+
+	byTrill := make(map[int64][]string)
+	for _, reaction := range reactions {
+		byTrill[reaction.TrillID] = append(byTrill[reaction.TrillID], reaction.Emoji)
+	}
+	for trillID, emoji := range byTrill {
+		if err := decrementReactionCounts(tx, trillID, emoji); err != nil {
+			return err
+		}
+	}
+	return tx.Where("username = ?", username).Delete(&Reaction{}).Error
+}
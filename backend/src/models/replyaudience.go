@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Who the author lets reply to a trill. The author can always reply to their own.
+const (
+	ReplyAudienceEveryone  = "everyone"
+	ReplyAudienceFollowers = "followers"
+	ReplyAudienceMentioned = "mentioned"
+)
+
+var (
+	ErrorRepliesRestricted error = errors.New("the author has limited who can reply to this trill")
+)
+
+// Whether the user may reply to the trill, given whether they follow its author. A mentioned-only
+// trill needs its mentions loaded.
+func (trill *Trill) allowsReplyFrom(username string, followsAuthor bool) bool {
+	if username == trill.Username {
+		return true
+	}
+
+	switch trill.ReplyAudience {
+	case ReplyAudienceFollowers:
+		return followsAuthor
+	case ReplyAudienceMentioned:
+		for _, mention := range trill.Mentions {
+			if mention.Username == username {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Whether the user may reply to the trill under the audience its author chose
+func CanReply(ctx context.Context, username string, trill *Trill) (bool, error) {
+	followsAuthor := false
+	if trill.ReplyAudience == ReplyAudienceFollowers && username != trill.Username {
+		following, err := IsFollowing(ctx, username, trill.Username)
+		if err != nil {
+			return false, err
+		}
+		followsAuthor = following
+	}
+
+	return trill.allowsReplyFrom(username, followsAuthor), nil
+}
+
+// Which of the trills, by ID, the requestor may reply to, looking up who they follow among the
+// authors of followers-only trills in one query
+func repliableTrills(db *gorm.DB, requestor string, trills []*Trill) (map[int64]bool, error) {
+	var authors []string
+	for _, trill := range trills {
+		if trill.ReplyAudience == ReplyAudienceFollowers {
+			authors = append(authors, trill.Username)
+		}
+	}
+
+	followed := map[string]bool{}
+	if len(authors) > 0 {
+		var following []string
+		if err := db.Model(&Follows{}).Where("followee = ? AND following IN ?", requestor, authors).
+			Pluck("following", &following).Error; err != nil {
+			return nil, err
+		}
+		for _, username := range following {
+			followed[username] = true
+		}
+	}
+
+	repliable := make(map[int64]bool, len(trills))
+	for _, trill := range trills {
+		repliable[trill.TrillID] = trill.allowsReplyFrom(requestor, followed[trill.Username])
+	}
+	return repliable, nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReportSpam                 = "spam"
+	ReportHarassment           = "harassment"
+	ReportHateSpeech           = "hate_speech"
+	ReportImpersonation        = "impersonation"
+	ReportInappropriateContent = "inappropriate_content"
+	ReportSelfHarm             = "self_harm"
+	ReportOther                = "other"
+	// opened by the linkScanner worker rather than a user, so it has no reporter
+	ReportMaliciousLink = "malicious_link"
+)
+
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// A user flagging another user for moderators; open reports are the moderation queue. A report about a
+// trill, like one for a malicious link, points at it.
+type Report struct {
+	ReportID   int64     `gorm:"primarykey;autoIncrement"`
+	Reporter   string    `gorm:"type:varchar(128);index"`
+	Reported   string    `gorm:"type:varchar(128);index"`
+	TrillID    *int64    `gorm:"index"`
+	Reason     string    `gorm:"type:varchar(32)"`
+	Details    string    `gorm:"type:varchar(1024)"`
+	Status     string    `gorm:"type:varchar(16);index"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ResolvedAt *time.Time
+	ResolvedBy string `gorm:"type:varchar(128)"`
+}
+
+var (
+	ErrorAlreadyReported  error = errors.New("you already have an open report about this user for that reason")
+	ErrorReportNotFound   error = errors.New("report does not exist")
+	ErrorReportNotPending error = errors.New("report has already been closed")
+)
+
+// Fails with a 409 HTTPError if the reporter already has the same report waiting on a moderator
+func CreateReport(ctx context.Context, report *Report) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&Report{}).
+		Where("reporter = ? AND reported = ? AND reason = ? AND status = ?", report.Reporter, report.Reported, report.Reason, ReportOpen).
+		Count(&count).Error; err != nil {
+		return err
+	} else if count > 0 {
+		return &HTTPError{Code: http.StatusConflict, Err: ErrorAlreadyReported}
+	}
+
+	report.Status = ReportOpen
+	return db.Create(report).Error
+}
+
+// Open reports oldest first, keyset paginated in either direction on the report ID
+func GetOpenReports(ctx context.Context, limit int, cursor *Cursor) (*[]Report, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var reports []Report
+	query := db.Where("status = ?", ReportOpen)
+	if err := (pagination.Keyset{Value: "report_id", Ascending: true}).Apply(query, cursor, limit).Find(&reports).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	reports, cursors := pagination.Trim(reports, cursor, limit, func(report *Report) Cursor {
+		return Cursor{Value: report.ReportID}
+	})
+	return &reports, cursors, nil
+}
+
+// Takes an open report off the queue, failing with a 404 or 409 HTTPError if it isn't open. Dismissing a
+// malicious link report clears the flags on the trill's links.
+func CloseReport(ctx context.Context, reportID int64, status string, moderator string) (*Report, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var report Report
+	if result := db.Where("report_id = ?", reportID).Limit(1).Find(&report); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorReportNotFound}
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Report{}).Where("report_id = ? AND status = ?", reportID, ReportOpen).
+			Updates(map[string]interface{}{"status": status, "resolved_at": now, "resolved_by": moderator})
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorReportNotPending}
+		}
+
+		if status == ReportDismissed && report.Reason == ReportMaliciousLink && report.TrillID != nil {
+			return clearLinkThreats(tx, *report.TrillID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Status = status
+	report.ResolvedAt = &now
+	report.ResolvedBy = moderator
+	return &report, nil
+}
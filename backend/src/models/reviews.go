@@ -43,7 +43,9 @@ func GetReview(ctx context.Context, username string, albumID string) (*Review, e
 	}
 
 	var review *Review
-	if result := db.Preload("User").Preload("Likes").Where("username = ? AND album_id = ?", username, albumID).Limit(1).Find(&review); result.Error != nil {
+	if result := db.Preload("User").Preload("Likes").
+		Where("username = ? AND album_id = ? AND username NOT IN (?)", username, albumID, deactivatedUsers(db)).
+		Limit(1).Find(&review); result.Error != nil {
 		return nil, err
 	} else if result.RowsAffected == 0 {
 		return nil, ErrorReviewNotFound
@@ -52,7 +54,24 @@ func GetReview(ctx context.Context, username string, albumID string) (*Review, e
 	}
 }
 
-func GetReviews(ctx context.Context, review *Review, following *[]User, paginate *Paginate) (*[]Review, error) {
+func GetReviewByID(ctx context.Context, reviewID int) (*Review, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var review Review
+	if result := db.Where("review_id = ? AND username NOT IN (?)", reviewID, deactivatedUsers(db)).Limit(1).Find(&review); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, ErrorReviewNotFound
+	}
+
+	return &review, nil
+}
+
+// Reviews by deactivated accounts, private accounts the requestor doesn't follow, or anyone they have a block with are always left out
+func GetReviews(ctx context.Context, review *Review, following *[]User, paginate *Paginate, requestor string) (*[]Review, error) {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -66,9 +85,14 @@ func GetReviews(ctx context.Context, review *Review, following *[]User, paginate
 	if paginate.Sort == "popular" {
 		prepend = "reviews."
 	}
+	queryBuilder = queryBuilder.Where(fmt.Sprintf("%susername NOT IN (?) AND %susername NOT IN (?)", prepend, prepend),
+		hiddenPrivateUsers(db, requestor), deactivatedUsers(db))
+	queryBuilder = excludeBlocked(queryBuilder, db, fmt.Sprintf("%susername", prepend), requestor)
 
 	query := make(map[string]interface{})
 	if following != nil {
+		// muted users still count as followed, they just stay out of the home timeline
+		queryBuilder = excludeMuted(queryBuilder, db, fmt.Sprintf("%susername", prepend), requestor)
 		usernames := make([]string, len(*following))
 		for i, f := range *following {
 			usernames[i] = f.Username
@@ -128,7 +152,7 @@ func GetPopularAlbumsFromReviews(ctx context.Context, timespan string) (*[]strin
 
 	err = db.Model(&Review{}).
 		Select("album_id, COUNT(*) as count").
-		Where("created_at >= ?", threshold).
+		Where("created_at >= ? AND username NOT IN (?)", threshold, deactivatedUsers(db)).
 		Group("album_id").
 		Order("count DESC").
 		Limit(maxPopularAlbums).
@@ -146,28 +170,40 @@ func GetPopularAlbumsFromReviews(ctx context.Context, timespan string) (*[]strin
 }
 
 func CreateReview(ctx context.Context, review *Review) error {
-	if db, err := GetDBFromContext(ctx); err != nil {
-		return err
-	} else if updateRes := db.Model(&review).Where("username = ? AND album_id = ?", &review.Username, &review.AlbumID).Updates(&review); updateRes.Error != nil {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
 		return err
-	} else if updateRes.RowsAffected == 0 {
-		err = db.Create(&review).Error
-		if err != nil {
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if updateRes := tx.Model(&review).Where("username = ? AND album_id = ?", &review.Username, &review.AlbumID).Updates(&review); updateRes.Error != nil {
+			return updateRes.Error
+		} else if updateRes.RowsAffected > 0 {
+			return nil
+		}
+
+		if err := tx.Create(&review).Error; err != nil {
 			return err
 		}
-	}
 
-	return nil
+		return incrementUserCounter(tx, "review_count", 1, review.Username)
+	})
 }
 
 func DeleteReview(ctx context.Context, review *Review) error {
-	if db, err := GetDBFromContext(ctx); err != nil {
-		return err
-	} else if err := db.Model(&review).Where("username = ? AND album_id = ?", &review.Username, &review.AlbumID).Delete(&review).Error; err != nil {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&review).Where("username = ? AND album_id = ?", &review.Username, &review.AlbumID).Delete(&review)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return incrementUserCounter(tx, "review_count", -result.RowsAffected, review.Username)
+	})
 }
 
 func GetAlbumReviewStats(ctx context.Context, albumID string, requestor string) (*ReviewStats, error) {
@@ -179,7 +215,7 @@ func GetAlbumReviewStats(ctx context.Context, albumID string, requestor string)
 	var reviewStats *ReviewStats
 	if err := db.Model(&Review{}).
 		Select("AVG(rating) as average_rating, COUNT(*) as num_ratings").
-		Where("album_id = ?", albumID).Scan(&reviewStats).Error; err != nil {
+		Where("album_id = ? AND username NOT IN (?)", albumID, deactivatedUsers(db)).Scan(&reviewStats).Error; err != nil {
 		return nil, err
 	}
 
@@ -191,21 +227,6 @@ func GetAlbumReviewStats(ctx context.Context, albumID string, requestor string)
 	return reviewStats, nil
 }
 
-func GetUserReviewCount(ctx context.Context, username string) (int64, error) {
-	db, err := GetDBFromContext(ctx)
-	if err != nil {
-		return 0, err
-	}
-
-	var reviewCount int64
-	if err := db.Model(&Review{}).
-		Where("username = ?", username).Count(&reviewCount).Error; err != nil {
-		return 0, err
-	}
-
-	return reviewCount, nil
-}
-
 func RequestorReviewed(ctx context.Context, albumID string, requestor string) (bool, error) {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
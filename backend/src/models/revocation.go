@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// An access token signed out before it expired. Cognito can't revoke a JWT that's already out there,
+// so the authorizer checks here; rows are only needed until the token would have expired anyway.
+type RevokedToken struct {
+	TokenID   string    `gorm:"type:varchar(64);primarykey"`
+	Username  string    `gorm:"type:varchar(128);index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// Signs out a single access token, e.g. the one used to log out on this device
+func RevokeAccessToken(ctx context.Context, username string, tokenID string, expiresAt time.Time) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return db.Create(&RevokedToken{TokenID: tokenID, Username: username, ExpiresAt: expiresAt}).Error
+}
+
+// Signs out every access token the user was given up to now
+func RevokeAllTokens(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).Update("tokens_revoked_at", time.Now()).Error
+}
+
+// true if the token was signed out on its own, or was issued before the user signed out everywhere
+func IsTokenRevoked(ctx context.Context, username string, tokenID string, issuedAt time.Time) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var revokedCount int64
+	if err := db.Model(&RevokedToken{}).Where("token_id = ?", tokenID).Count(&revokedCount).Error; err != nil {
+		return false, err
+	} else if revokedCount > 0 {
+		return true, nil
+	}
+
+	var signedOutCount int64
+	if err := db.Model(&User{}).Where("username = ? AND tokens_revoked_at > ?", username, issuedAt).Count(&signedOutCount).Error; err != nil {
+		return false, err
+	}
+
+	return signedOutCount > 0, nil
+}
+
+// Revokes a refresh token, and with it the tokens Cognito issued from it
+func RevokeCognitoRefreshToken(ctx context.Context, refreshToken string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	input := &cognitoidentityprovider.RevokeTokenInput{
+		ClientId: aws.String(cognitoClient.AppClientId),
+		Token:    aws.String(refreshToken),
+	}
+	if clientSecret := utils.GetSecrets().CognitoAppClientSecret; clientSecret != "" {
+		input.ClientSecret = aws.String(clientSecret)
+	}
+	_, err = cognitoClient.Client.RevokeToken(ctx, input)
+	var unsupportedToken *types.UnsupportedTokenTypeException
+	if errors.As(err, &unsupportedToken) {
+		return &AuthError{Code: http.StatusBadRequest, Reason: "invalid_refresh_token", Err: ErrorRefreshFailed}
+	} else if err != nil {
+		return translateTokenError(err)
+	}
+
+	return nil
+}
+
+// Invalidates every refresh token the user has, signing them out on all their devices
+func GlobalSignOutCognitoUser(ctx context.Context, accessToken string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cognitoClient.Client.GlobalSignOut(ctx, &cognitoidentityprovider.GlobalSignOutInput{
+		AccessToken: aws.String(accessToken),
+	}); err != nil {
+		return translateTokenError(err)
+	}
+
+	return nil
+}
+
+// Signs the user out of every device without needing one of their tokens, e.g. from a secure account link
+func AdminGlobalSignOutCognitoUser(ctx context.Context, cognitoUsername string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.AdminUserGlobalSignOut(ctx, &cognitoidentityprovider.AdminUserGlobalSignOutInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Username:   aws.String(cognitoUsername),
+	})
+	return err
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// A Cognito group that unlocks some of the API; users outside every group are regular members
+type Role string
+
+const (
+	RoleAdmin     Role = "admins"
+	RoleModerator Role = "moderators"
+)
+
+// The roles a caller has, from the cognito:groups claim on their access token
+type Roles []Role
+
+// Reads roles from the comma-separated form the authorizer passes them in, since authorizer
+// context values can't be lists
+func ParseRoles(joined string) Roles {
+	var roles Roles
+	for _, role := range strings.Split(joined, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, Role(role))
+		}
+	}
+	return roles
+}
+
+func (roles Roles) String() string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ",")
+}
+
+// Admins can do anything a moderator can
+func (roles Roles) Has(role Role) bool {
+	for _, r := range roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// The 403 for a caller who doesn't have the role
+func MissingRoleError(role Role) *HTTPError {
+	return &HTTPError{Code: http.StatusForbidden, Err: fmt.Errorf("only %s can do this", role)}
+}
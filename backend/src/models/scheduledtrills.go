@@ -0,0 +1,270 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// A trill waiting to be posted at PublishAt. Each one has a one-time EventBridge Scheduler schedule that
+// invokes the trillPublisher Lambda, which posts it and deletes the row, or marks it failed with the
+// reason if it can no longer be posted, e.g. its media was used by another trill in the meantime.
+type ScheduledTrill struct {
+	ScheduledTrillID int64  `gorm:"primarykey;autoIncrement"`
+	Username         string `gorm:"type:varchar(128);index"`
+	PendingTrill     `gorm:"embedded"`
+	PublishAt        time.Time `gorm:"index"`
+	ScheduleName     string    `gorm:"type:varchar(64)"`
+	Status           string    `gorm:"type:varchar(16)"`
+	Error            string    `gorm:"type:varchar(255)"`
+	CreatedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// What the schedule sends the trillPublisher Lambda
+type ScheduledTrillEvent struct {
+	ScheduledTrillID int64 `json:"scheduled_trill_id"`
+}
+
+const (
+	ScheduledTrillStatusPending = "pending"
+	ScheduledTrillStatusFailed  = "failed"
+)
+
+var (
+	MaxScheduledTrills = 100
+	MinScheduleDelay   = time.Minute
+	MaxScheduleDelay   = 365 * 24 * time.Hour
+)
+
+var (
+	ErrorScheduledTrillNotFound error = errors.New("scheduled trill does not exist")
+	ErrorTooManyScheduled       error = errors.New("at most 100 trills can be scheduled")
+	ErrorPublishAtInvalid       error = errors.New("publish_at must be between a minute and a year from now")
+	ErrorAuthorDeactivated      error = errors.New("the account was deactivated before the trill was posted")
+)
+
+// Saves the trill and schedules it to be posted at publishAt. Fails with a 400 HTTPError if publishAt
+// is too soon or too far off, or a 409 if the user already has MaxScheduledTrills waiting.
+func CreateScheduledTrill(ctx context.Context, username string, pending *PendingTrill, publishAt time.Time) (*ScheduledTrill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if delay := time.Until(publishAt); delay < MinScheduleDelay || delay > MaxScheduleDelay {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorPublishAtInvalid}
+	}
+
+	scheduled := ScheduledTrill{
+		Username:     username,
+		PendingTrill: *pending,
+		// the schedule fires to the second
+		PublishAt:    publishAt.UTC().Truncate(time.Second),
+		ScheduleName: "trill-" + uuid.NewString(),
+		Status:       ScheduledTrillStatusPending,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&ScheduledTrill{}).Where("username = ? AND status = ?", username, ScheduledTrillStatusPending).
+			Count(&count).Error; err != nil {
+			return err
+		} else if count >= int64(MaxScheduledTrills) {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorTooManyScheduled}
+		}
+
+		if err := tx.Create(&scheduled).Error; err != nil {
+			return err
+		}
+		// made last, so a failure here leaves nothing behind
+		return createSchedule(ctx, &scheduled)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// The user's scheduled trills, soonest first, including ones that failed to post
+func GetScheduledTrills(ctx context.Context, username string) (*[]ScheduledTrill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []ScheduledTrill
+	if err := db.Where("username = ?", username).Order("publish_at, scheduled_trill_id").Find(&scheduled).Error; err != nil {
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// Cancels one of the user's scheduled trills, or clears one that failed. Fails with a 404 HTTPError if
+// they don't have it.
+func DeleteScheduledTrill(ctx context.Context, username string, scheduledTrillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var scheduled ScheduledTrill
+	if result := db.Where("scheduled_trill_id = ? AND username = ?", scheduledTrillID, username).Limit(1).Find(&scheduled); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorScheduledTrillNotFound}
+	}
+
+	// a failed trill's schedule already ran and deleted itself
+	if scheduled.Status == ScheduledTrillStatusPending {
+		if err := deleteSchedule(ctx, scheduled.ScheduleName); err != nil {
+			return err
+		}
+	}
+
+	return db.Delete(&scheduled).Error
+}
+
+// Posts a scheduled trill with the same checks it had when it was scheduled, since its media, the trills
+// it points at, and who can see them may have changed. A trill that can't be posted is marked failed
+// rather than returning an error, so the invocation isn't retried.
+func PublishScheduledTrill(ctx context.Context, scheduledTrillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var scheduled ScheduledTrill
+	if result := db.Where("scheduled_trill_id = ?", scheduledTrillID).Limit(1).Find(&scheduled); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || scheduled.Status != ScheduledTrillStatusPending {
+		// canceled after the schedule fired
+		return nil
+	}
+
+	err = publishScheduledTrill(ctx, &scheduled)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return db.Model(&scheduled).Updates(map[string]interface{}{"status": ScheduledTrillStatusFailed, "error": httpErr.Error()}).Error
+	} else if err != nil {
+		return err
+	}
+
+	return db.Delete(&scheduled).Error
+}
+
+func publishScheduledTrill(ctx context.Context, scheduled *ScheduledTrill) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the author deactivated in the meantime
+	var user User
+	if result := db.Where("username = ? AND deactivated_at IS NULL", scheduled.Username).Limit(1).Find(&user); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusForbidden, Err: ErrorAuthorDeactivated}
+	}
+
+	if scheduled.ParentID != nil {
+		parent, err := getVisibleTrill(ctx, scheduled.Username, *scheduled.ParentID)
+		if err != nil {
+			return err
+		}
+		if canReply, err := CanReply(ctx, scheduled.Username, parent); err != nil {
+			return err
+		} else if !canReply {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorRepliesRestricted}
+		}
+	}
+	if scheduled.QuoteOfID != nil {
+		quoted, err := getVisibleTrill(ctx, scheduled.Username, *scheduled.QuoteOfID)
+		if err != nil {
+			return err
+		} else if quoted.User.IsPrivate {
+			return &HTTPError{Code: http.StatusForbidden, Err: ErrorQuotePrivate}
+		}
+	}
+
+	trill, err := PrepareTrill(ctx, scheduled.Username, &scheduled.PendingTrill)
+	if err != nil {
+		return err
+	}
+
+	return CreateTrill(ctx, trill)
+}
+
+// A trill the user can still see, failing with the same HTTPErrors the trills API gives
+func getVisibleTrill(ctx context.Context, username string, trillID int64) (*Trill, error) {
+	trill, err := GetTrill(ctx, trillID)
+	if err != nil {
+		return nil, err
+	}
+
+	if blocked, err := IsBlocked(ctx, username, trill.Username); err != nil {
+		return nil, err
+	} else if blocked {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorBlocked}
+	}
+	if canView, err := CanViewUser(ctx, username, &trill.User); err != nil {
+		return nil, err
+	} else if !canView {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorPrivateAccount}
+	}
+
+	return trill, nil
+}
+
+// A one-time schedule that invokes the trillPublisher Lambda with the trill's ID, then deletes itself
+func createSchedule(ctx context.Context, scheduled *ScheduledTrill) error {
+	schedulerClient, err := InitSchedulerClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	secrets := utils.GetSecrets()
+	input, err := json.Marshal(ScheduledTrillEvent{ScheduledTrillID: scheduled.ScheduledTrillID})
+	if err != nil {
+		return err
+	}
+
+	_, err = schedulerClient.CreateSchedule(ctx, &scheduler.CreateScheduleInput{
+		Name:                       aws.String(scheduled.ScheduleName),
+		ScheduleExpression:         aws.String(fmt.Sprintf("at(%s)", scheduled.PublishAt.Format("2006-01-02T15:04:05"))),
+		ScheduleExpressionTimezone: aws.String("UTC"),
+		FlexibleTimeWindow:         &types.FlexibleTimeWindow{Mode: types.FlexibleTimeWindowModeOff},
+		ActionAfterCompletion:      types.ActionAfterCompletionDelete,
+		Target: &types.Target{
+			Arn:     aws.String(secrets.TrillPublisherARN),
+			RoleArn: aws.String(secrets.SchedulerRoleARN),
+			Input:   aws.String(string(input)),
+		},
+	})
+	return err
+}
+
+// Deleting a schedule that's already gone, because it ran, isn't an error
+func deleteSchedule(ctx context.Context, name string) error {
+	schedulerClient, err := InitSchedulerClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = schedulerClient.DeleteSchedule(ctx, &scheduler.DeleteScheduleInput{Name: aws.String(name)})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
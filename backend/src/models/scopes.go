@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// What an API key or OAuth token can be allowed to do: read:<resource> for GET requests and
+// write:<resource> for anything else, where the resource is the first part of the path
+var ResourceScopes = map[string]bool{
+	"read:users":              true,
+	"write:users":             true,
+	"read:trills":             true,
+	"write:trills":            true,
+	"read:hashtags":           true,
+	"read:gifs":               true,
+	"read:notifications":      true,
+	"write:notifications":     true,
+	"read:reviews":            true,
+	"write:reviews":           true,
+	"read:follows":            true,
+	"write:follows":           true,
+	"read:likes":              true,
+	"write:likes":             true,
+	"read:albums":             true,
+	"read:favoritealbums":     true,
+	"write:favoritealbums":    true,
+	"read:listenlateralbums":  true,
+	"write:listenlateralbums": true,
+}
+
+var (
+	ErrorNoScopes error = errors.New("at least one scope is required")
+)
+
+// Rejects unknown scopes, and drops duplicates from the rest
+func ValidateScopes(scopes []string) ([]string, error) {
+	var valid []string
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !ResourceScopes[scope] {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Err: fmt.Errorf("unknown scope '%s'", scope)}
+		} else if !seen[scope] {
+			seen[scope] = true
+			valid = append(valid, scope)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorNoScopes}
+	}
+
+	return valid, nil
+}
+
+// Scopes are stored comma-separated
+func splitScopes(joined string) []string {
+	if joined == "" {
+		return []string{}
+	}
+	return strings.Split(joined, ",")
+}
+
+func hasScope(joined string, scope string) bool {
+	for _, s := range splitScopes(joined) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	// Lambda images don't always ship a zoneinfo database
+	_ "time/tzdata"
+
+	"gorm.io/gorm/clause"
+)
+
+// Private per-user preferences, kept out of the public User row
+type UserSettings struct {
+	Username string `json:"-" gorm:"type:varchar(128);primarykey"`
+
+	NotifyNewFollowers     bool `json:"notify_new_followers"`
+	NotifyReviewLikes      bool `json:"notify_review_likes"`
+	NotifyFollowingReviews bool `json:"notify_following_reviews"`
+	NotifyMentions         bool `json:"notify_mentions"`
+	EmailNotifications     bool `json:"email_notifications"`
+
+	Discoverable       bool `json:"discoverable"`
+	RequireAltText     bool `json:"require_alt_text"`
+	ShowLikedReviews   bool `json:"show_liked_reviews"`
+	ShowLikedTrills    bool `json:"show_liked_trills"`
+	ShowActivityStatus bool `json:"show_activity_status"`
+	ShowSensitiveMedia bool `json:"show_sensitive_media"`
+
+	Language   string   `json:"language" gorm:"type:varchar(16)"`
+	Locale     string   `json:"locale" gorm:"type:varchar(16)"`
+	Timezone   string   `json:"timezone" gorm:"type:varchar(64)"`
+	MutedWords []string `json:"muted_words" gorm:"type:text;serializer:json"`
+
+	TimelineRanking string `json:"timeline_ranking" gorm:"type:varchar(16)"`
+}
+
+var (
+	MaxMutedWords      = 100
+	MaxMutedWordLength = 64
+)
+
+var (
+	languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+	localePattern   = regexp.MustCompile(`^[a-z]{2}-[A-Z]{2}$`)
+)
+
+// No gorm defaults on purpose: gorm skips zero values for columns with defaults, so false could never be saved
+func DefaultUserSettings(username string) *UserSettings {
+	return &UserSettings{
+		Username:               username,
+		NotifyNewFollowers:     true,
+		NotifyReviewLikes:      true,
+		NotifyFollowingReviews: true,
+		NotifyMentions:         true,
+		EmailNotifications:     false,
+		Discoverable:           true,
+		RequireAltText:         false,
+		ShowLikedReviews:       true,
+		ShowLikedTrills:        true,
+		ShowActivityStatus:     true,
+		ShowSensitiveMedia:     false,
+		Language:               "en",
+		Locale:                 "en-US",
+		Timezone:               "UTC",
+		MutedWords:             []string{},
+		TimelineRanking:        RankingLatest,
+	}
+}
+
+// Returns the defaults for users who have never saved their settings
+func GetUserSettings(ctx context.Context, username string) (*UserSettings, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := DefaultUserSettings(username)
+	if err := db.Where("username = ?", username).Limit(1).Find(settings).Error; err != nil {
+		return nil, err
+	}
+	// rows saved before locale, timezone, and timeline ranking existed
+	if settings.Locale == "" {
+		settings.Locale = "en-US"
+	}
+	if settings.Timezone == "" {
+		settings.Timezone = "UTC"
+	}
+	if settings.TimelineRanking == "" {
+		settings.TimelineRanking = RankingLatest
+	}
+
+	return settings, nil
+}
+
+// Normalizes the settings in place, returning a 400 HTTPError if they can't be saved
+func ValidateUserSettings(settings *UserSettings) error {
+	if !languagePattern.MatchString(settings.Language) {
+		return &HTTPError{Code: http.StatusBadRequest, Err: errors.New("language must be a code like 'en' or 'pt-BR'")}
+	}
+	if !localePattern.MatchString(settings.Locale) {
+		return &HTTPError{Code: http.StatusBadRequest, Err: errors.New("locale must be a code like 'en-US' or 'pt-BR'")}
+	}
+	// LoadLocation also accepts "" and "Local", which would mean the server's zone
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" || settings.Timezone == "Local" {
+		return &HTTPError{Code: http.StatusBadRequest, Err: errors.New("timezone must be an IANA zone like 'America/New_York'")}
+	}
+
+	seen := make(map[string]bool)
+	mutedWords := make([]string, 0, len(settings.MutedWords))
+	for _, word := range settings.MutedWords {
+		// a phrase matches across any whitespace, so it's stored with single spaces
+		word = strings.ToLower(strings.Join(strings.Fields(word), " "))
+		if len(word) == 0 || seen[word] {
+			continue
+		} else if len(word) > MaxMutedWordLength {
+			return &HTTPError{Code: http.StatusBadRequest, Err: errors.New("muted words must be at most 64 characters")}
+		}
+		seen[word] = true
+		mutedWords = append(mutedWords, word)
+	}
+	if len(mutedWords) > MaxMutedWords {
+		return &HTTPError{Code: http.StatusBadRequest, Err: errors.New("at most 100 muted words are allowed")}
+	}
+	settings.MutedWords = mutedWords
+
+	if !ValidRanking(settings.TimelineRanking) {
+		return &HTTPError{Code: http.StatusBadRequest, Err: ErrorInvalidRanking}
+	}
+
+	return nil
+}
+
+// The user's timezone for anything sent on their clock, e.g. email timestamps and digest send times
+func (settings *UserSettings) Location() *time.Location {
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil || settings.Timezone == "" {
+		return time.UTC
+	}
+
+	return location
+}
+
+func UpdateUserSettings(ctx context.Context, settings *UserSettings) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(settings).Error
+}
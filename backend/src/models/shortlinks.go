@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"trill/src/utils"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A link in a trill's text, shortened when the trill is posted so clicks on it go through the shortLinks
+// redirect and can be counted. The text keeps the original URL; clients link it to the short one. Each
+// distinct URL in a trill gets its own code, kept when the trill is edited so its clicks aren't lost. The
+// linkScanner worker checks each one after it's made; a link flagged as unsafe keeps its threat and stops
+// redirecting.
+type ShortLink struct {
+	Code       string    `gorm:"type:varchar(16);primarykey"`
+	TrillID    int64     `gorm:"uniqueIndex:idx_short_links_trill_url"`
+	URLHash    string    `gorm:"type:char(64);uniqueIndex:idx_short_links_trill_url;index"`
+	URL        string    `gorm:"type:varchar(2048)"`
+	ClickCount int64     `gorm:"not null;default:0"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Threat     string    `gorm:"type:varchar(32)"`
+	ScannedAt  *time.Time
+}
+
+// One click through a short link, with the host of the page it came from if the browser said
+type LinkClick struct {
+	ClickID   int64     `gorm:"primarykey;autoIncrement"`
+	Code      string    `gorm:"type:varchar(16);index"`
+	Referrer  string    `gorm:"type:varchar(255)"`
+	ClickedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// A link in a trill's text. Start and End count characters rather than bytes, with End exclusive.
+type LinkMatch struct {
+	URL   string
+	Start int
+	End   int
+}
+
+const (
+	DefaultShortLinkURL = "https://t.trill"
+	shortCodeAlphabet   = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	ShortCodeLength = 7
+)
+
+var (
+	ErrorShortLinkNotFound error = errors.New("link does not exist")
+	ErrorNotTrillAnalyst   error = errors.New("only the author can see a trill's analytics")
+)
+
+// Where short links live, from the SHORT_LINK_URL setting, without a trailing slash
+func ShortLinkURL() string {
+	base := utils.GetSecrets().ShortLinkURL
+	if base == "" {
+		base = DefaultShortLinkURL
+	}
+	return strings.TrimRight(base, "/")
+}
+
+// The full short URL for a code
+func (link *ShortLink) ShortURL() string {
+	return ShortLinkURL() + "/" + link.Code
+}
+
+// Drops punctuation that most likely ends the sentence rather than the link
+func trimLink(link string) string {
+	link = strings.TrimRight(link, `.,!?:;'"`)
+	if !strings.Contains(link, "(") {
+		link = strings.TrimRight(link, ")")
+	}
+	return link
+}
+
+// Every link in the text, in order, including repeats. Links too long to store are left out.
+func FindLinks(text string) []LinkMatch {
+	var matches []LinkMatch
+	for _, loc := range linkPattern.FindAllStringIndex(text, -1) {
+		link := trimLink(text[loc[0]:loc[1]])
+		if len(link) > 2048 {
+			continue
+		}
+		start := utf8.RuneCountInString(text[:loc[0]])
+		matches = append(matches, LinkMatch{URL: link, Start: start, End: start + utf8.RuneCountInString(link)})
+	}
+	return matches
+}
+
+// Makes a short link for each distinct URL in the trill's text that doesn't have one yet. Links that are
+// already short links are left alone.
+func shortenLinks(tx *gorm.DB, trill *Trill) error {
+	prefix := ShortLinkURL() + "/"
+	seen := map[string]bool{}
+	for _, match := range FindLinks(trill.Text) {
+		hash := linkHash(match.URL)
+		if seen[hash] || strings.HasPrefix(match.URL, prefix) {
+			continue
+		}
+		seen[hash] = true
+
+		code, err := newShortCode(tx)
+		if err != nil {
+			return err
+		}
+		link := ShortLink{Code: code, TrillID: trill.TrillID, URLHash: hash, URL: match.URL}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A random code no short link has yet
+func newShortCode(tx *gorm.DB) (string, error) {
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	for {
+		code := make([]byte, ShortCodeLength)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			code[i] = shortCodeAlphabet[n.Int64()]
+		}
+
+		var count int64
+		if err := tx.Model(&ShortLink{}).Where("code = ?", string(code)).Count(&count).Error; err != nil {
+			return "", err
+		} else if count == 0 {
+			return string(code), nil
+		}
+	}
+}
+
+// Looks up a short link and logs a click on it. Links in deleted trills, or trills by deactivated users,
+// are a 404 HTTPError, and links flagged as unsafe a 403.
+func FollowShortLink(ctx context.Context, code string, referrer string) (*ShortLink, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var link ShortLink
+	activeTrills := db.Model(&Trill{}).Select("trill_id").Where("username NOT IN (?)", deactivatedUsers(db))
+	if result := db.Where("code = ? AND trill_id IN (?)", code, activeTrills).Limit(1).Find(&link); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorShortLinkNotFound}
+	} else if link.Threat != "" {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorLinkFlagged}
+	}
+
+	// only the host, so the log doesn't keep whatever was in the referring page's URL
+	if parsed, err := url.Parse(referrer); err == nil && len(parsed.Host) <= 255 {
+		referrer = parsed.Host
+	} else {
+		referrer = ""
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&LinkClick{Code: link.Code, Referrer: referrer}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&link).UpdateColumn("click_count", gorm.Expr("click_count + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	link.ClickCount++
+	return &link, nil
+}
+
+// One of the requestor's trills with its short links, including any for URLs since edited out of the
+// text. Fails with a 404 HTTPError if the trill doesn't exist or a 403 if it's someone else's.
+func GetTrillAnalytics(ctx context.Context, trillID int64, requestor string) (*Trill, error) {
+	trill, err := GetTrill(ctx, trillID)
+	if err != nil {
+		return nil, err
+	} else if trill.Username != requestor {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorNotTrillAnalyst}
+	}
+	return trill, nil
+}
+
+// Deletes the short links in the given trills and the clicks logged on them
+func deleteShortLinks(tx *gorm.DB, trillIDs interface{}) error {
+	codes := tx.Model(&ShortLink{}).Select("code").Where("trill_id IN (?)", trillIDs)
+	if err := tx.Where("code IN (?)", codes).Delete(&LinkClick{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("trill_id IN (?)", trillIDs).Delete(&ShortLink{}).Error
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A post that disappears StoryTTL after it goes up: one image or video from POST /trills/media, with an
+// optional caption. Only the author can see who viewed it. Expired stories are hidden right away and
+// deleted for good, media and all, by the storyCleanup lambda.
+type Story struct {
+	StoryID   int64     `gorm:"primarykey;autoIncrement"`
+	Username  string    `gorm:"type:varchar(128);index"`
+	Caption   string    `gorm:"type:varchar(200)"`
+	ViewCount int64     `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ExpiresAt time.Time `gorm:"index"`
+	User      User      `gorm:"foreignKey:Username;references:Username"`
+	Media     Media     `gorm:"foreignKey:StoryID"`
+}
+
+// Someone who saw a story; the author's own views aren't recorded
+type StoryView struct {
+	StoryID  int64     `gorm:"primarykey"`
+	Username string    `gorm:"type:varchar(128);primarykey;index"`
+	ViewedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	User     User      `gorm:"foreignKey:Username;references:Username"`
+}
+
+var (
+	StoryTTL = 24 * time.Hour
+	// stories the cleanup deletes per batch, keeping each batch's S3 calls small
+	StoryCleanupBatch = 100
+)
+
+var (
+	ErrorStoryNotFound  error = errors.New("story does not exist or has expired")
+	ErrorNotStoryAuthor error = errors.New("only the author can see who viewed a story")
+	ErrorStoryVoiceNote error = errors.New("stories need an image or video, not a voice note")
+)
+
+// Posts the story with its media, which must be one of the author's unattached uploads as checked by
+// ValidateTrillMedia. Voice notes only go on trills, and are a 400 HTTPError.
+func CreateStory(ctx context.Context, story *Story) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if story.Media.Kind == MediaKindAudio {
+		return &HTTPError{Code: http.StatusBadRequest, Err: ErrorStoryVoiceNote}
+	}
+
+	story.CreatedAt = time.Now()
+	story.ExpiresAt = story.CreatedAt.Add(StoryTTL)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(story).Error; err != nil {
+			return err
+		}
+
+		story.Media.StoryID = &story.StoryID
+		updates := map[string]interface{}{"story_id": story.StoryID, "alt_text": story.Media.AltText}
+		if story.Media.Kind != MediaKindVideo {
+			updates["width"], updates["height"] = story.Media.Width, story.Media.Height
+		}
+		result := tx.Model(&Media{}).Where("media_id = ? AND trill_id IS NULL AND story_id IS NULL", story.Media.MediaID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorTrillMediaUsed}
+		}
+		return nil
+	})
+}
+
+// Stories that haven't expired, by authors the requestor can see
+func activeStories(db *gorm.DB, requestor string) *gorm.DB {
+	query := db.Preload("User").Preload("Media").Where("expires_at > ?", time.Now()).
+		Where("username NOT IN (?) AND username NOT IN (?)", hiddenPrivateUsers(db, requestor), deactivatedUsers(db))
+	return excludeBlocked(query, db, "username", requestor)
+}
+
+// A story the requestor can see. One that expired, or whose author is private, deactivated, or has a block
+// with the requestor, is a 404 HTTPError the same as one that doesn't exist.
+func GetStory(ctx context.Context, storyID int64, requestor string) (*Story, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var story Story
+	if result := activeStories(db, requestor).Where("story_id = ?", storyID).Limit(1).Find(&story); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorStoryNotFound}
+	}
+
+	return &story, nil
+}
+
+// The user's stories that haven't expired, oldest first so they play in order. There can only be a day's
+// worth, so they come back all at once.
+func GetUserStories(ctx context.Context, username string, requestor string) (*[]Story, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stories []Story
+	if err := activeStories(db, requestor).Where("username = ?", username).Order("story_id ASC").Find(&stories).Error; err != nil {
+		return nil, err
+	}
+
+	return &stories, nil
+}
+
+// Records that the viewer saw the story; seeing it again, or seeing one's own, changes nothing
+func RecordStoryView(ctx context.Context, story *Story, viewer string) error {
+	if viewer == story.Username {
+		return nil
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		view := StoryView{StoryID: story.StoryID, Username: viewer}
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&view)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&Story{}).Where("story_id = ?", story.StoryID).
+			UpdateColumn("view_count", gorm.Expr("view_count + ?", result.RowsAffected)).Error
+	})
+}
+
+// Who viewed one of the requestor's stories, most recent first, keyset paginated in either direction on
+// when they viewed it. Fails with a 404 HTTPError if the story has expired or a 403 if it's someone else's.
+func GetStoryViewers(ctx context.Context, storyID int64, requestor string, limit int, cursor *Cursor) (*[]StoryView, Cursors, error) {
+	story, err := GetStory(ctx, storyID, requestor)
+	if err != nil {
+		return nil, Cursors{}, err
+	} else if story.Username != requestor {
+		return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorNotStoryAuthor}
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Preload("User").Where("story_id = ? AND username NOT IN (?)", story.StoryID, deactivatedUsers(db))
+	keyset := pagination.Keyset{Value: "viewed_at", Key: "username", KeyAscending: true, Time: true}
+
+	var views []StoryView
+	if err := keyset.Apply(query, cursor, limit).Find(&views).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	views, cursors := pagination.Trim(views, cursor, limit, func(view *StoryView) Cursor {
+		return pagination.TimeCursor(view.ViewedAt, view.Username)
+	})
+	return &views, cursors, nil
+}
+
+// Deletes one of the requestor's stories before it expires, failing with a 404 HTTPError if it isn't theirs
+func DeleteStory(ctx context.Context, storyID int64, requestor string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stories []Story
+	if err := db.Preload("Media").Where("story_id = ? AND username = ?", storyID, requestor).Find(&stories).Error; err != nil {
+		return err
+	} else if len(stories) == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorStoryNotFound}
+	}
+
+	return purgeStories(ctx, db, stories)
+}
+
+// Hard deletes every expired story in batches, returning how many went
+func DeleteExpiredStories(ctx context.Context) (int, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for {
+		var stories []Story
+		if err := db.Preload("Media").Where("expires_at <= ?", time.Now()).Order("story_id").
+			Limit(StoryCleanupBatch).Find(&stories).Error; err != nil {
+			return deleted, err
+		}
+		if len(stories) == 0 {
+			return deleted, nil
+		}
+
+		if err := purgeStories(ctx, db, stories); err != nil {
+			return deleted, err
+		}
+		deleted += len(stories)
+	}
+}
+
+// Deletes the stories' rows, then their uploads and renditions from the bucket. The rows go first so
+// nothing points at a missing object; an object left behind by a failed S3 call is just unreachable.
+func purgeStories(ctx context.Context, db *gorm.DB, stories []Story) error {
+	storyIDs := make([]int64, len(stories))
+	var keys, prefixes []string
+	for i, story := range stories {
+		storyIDs[i] = story.StoryID
+		if story.Media.ObjectKey == "" {
+			continue
+		}
+		keys = append(keys, story.Media.ObjectKey)
+		// a video's renditions all sit under trill-video/<upload name>/
+		if story.Media.Kind == MediaKindVideo {
+			name := strings.TrimSuffix(path.Base(story.Media.ObjectKey), path.Ext(story.Media.ObjectKey))
+			prefixes = append(prefixes, fmt.Sprintf("%s%s/", TranscodedVideoPrefix, name))
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("story_id IN ?", storyIDs).Delete(&StoryView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("story_id IN ?", storyIDs).Delete(&Media{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("story_id IN ?", storyIDs).Delete(&Story{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return DeleteContentObjects(ctx, keys, prefixes)
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// An account suggested for the user to follow. The suggestionAggregator worker replaces each user's
+// suggestions every run: a candidate scores for every account the user follows that follows them, and
+// for every hashtag both of them used lately. Accounts the user already follows or asked to follow, and
+// anyone with a block or mute between them, aren't stored, and are checked again when suggestions are
+// read since those can change between runs.
+type FollowSuggestion struct {
+	Username       string    `gorm:"type:varchar(128);primarykey"`
+	Suggested      string    `gorm:"type:varchar(128);primarykey;index"`
+	Score          float64   `gorm:"not null;default:0"`
+	MutualFollows  int64     `gorm:"not null;default:0"`
+	SharedHashtags int64     `gorm:"not null;default:0"`
+	ComputedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	SuggestedUser  User      `gorm:"foreignKey:Suggested;references:Username"`
+}
+
+// How many of a user's follows follow a candidate, or how many recent hashtags they share
+type suggestionOverlap struct {
+	Username  string
+	Suggested string
+	Overlap   int64
+}
+
+const (
+	MaxSuggestions      = 30
+	suggestionBatchSize = 500
+	// a shared hashtag counts for this much of a mutual follow
+	SharedHashtagWeight = 0.5
+)
+
+var (
+	// how far back hashtags count as shared
+	SuggestionHashtagWindow = 30 * 24 * time.Hour
+)
+
+// The requestor's best suggestions, leaving out anyone they've followed, asked to follow, muted, or have
+// a block with since the suggestions were computed, and accounts since deactivated
+func GetSuggestions(ctx context.Context, requestor string, limit int) (*[]FollowSuggestion, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	requested := db.Model(&FollowRequest{}).Select("target").Where("requester = ?", requestor)
+	query := db.Preload("SuggestedUser").Where("username = ?", requestor).
+		Where("suggested NOT IN (?) AND suggested NOT IN (?) AND suggested NOT IN (?)", following, requested, deactivatedUsers(db))
+	query = excludeMuted(excludeBlocked(query, db, "suggested", requestor), db, "suggested", requestor)
+
+	var suggestions []FollowSuggestion
+	if err := query.Order("score DESC, suggested").Limit(limit).Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+
+	return &suggestions, nil
+}
+
+// Recomputes every active user's suggestions, suggestionBatchSize users at a time. Returns how many
+// suggestions were stored.
+func ComputeSuggestions(ctx context.Context) (int, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	stored := 0
+	last := ""
+	for {
+		var usernames []string
+		if err := db.Model(&User{}).Where("deactivated_at IS NULL AND username > ?", last).
+			Order("username").Limit(suggestionBatchSize).Pluck("username", &usernames).Error; err != nil {
+			return 0, err
+		} else if len(usernames) == 0 {
+			return stored, nil
+		}
+		last = usernames[len(usernames)-1]
+
+		suggestions, err := suggestFollows(db, usernames, now)
+		if err != nil {
+			return 0, err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("username IN ?", usernames).Delete(&FollowSuggestion{}).Error; err != nil {
+				return err
+			}
+			if len(suggestions) == 0 {
+				return nil
+			}
+			return tx.Omit(clause.Associations).CreateInBatches(&suggestions, 500).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+		stored += len(suggestions)
+	}
+}
+
+// Scores the candidates for each of the users, keeping their top MaxSuggestions
+func suggestFollows(db *gorm.DB, usernames []string, now time.Time) ([]FollowSuggestion, error) {
+	// friends of friends: the accounts followed by the accounts each user follows
+	var mutuals []suggestionOverlap
+	if err := db.Table("follows AS mine").Select("mine.followee AS username, theirs.following AS suggested, COUNT(*) AS overlap").
+		Joins("JOIN follows AS theirs ON theirs.followee = mine.following").
+		Where("mine.followee IN ?", usernames).
+		Group("mine.followee, theirs.following").Scan(&mutuals).Error; err != nil {
+		return nil, err
+	}
+
+	// the hashtags each user used lately against the ones public accounts did
+	since := now.Add(-SuggestionHashtagWindow)
+	used := func() *gorm.DB {
+		return db.Table("trill_hashtags").Select("DISTINCT trills.username, trill_hashtags.hashtag_id").
+			Joins("JOIN trills ON trills.trill_id = trill_hashtags.trill_id").Where("trills.created_at >= ?", since)
+	}
+	public := db.Model(&User{}).Select("username").Where("is_private = ?", false)
+	var shared []suggestionOverlap
+	if err := db.Table("(?) AS mine", used().Where("trills.username IN ?", usernames)).
+		Select("mine.username, theirs.username AS suggested, COUNT(*) AS overlap").
+		Joins("JOIN (?) AS theirs ON theirs.hashtag_id = mine.hashtag_id", used().Where("trills.username IN (?)", public)).
+		Group("mine.username, theirs.username").Scan(&shared).Error; err != nil {
+		return nil, err
+	}
+
+	candidates := map[string]map[string]*FollowSuggestion{}
+	candidate := func(overlap *suggestionOverlap) *FollowSuggestion {
+		if candidates[overlap.Username] == nil {
+			candidates[overlap.Username] = map[string]*FollowSuggestion{}
+		}
+		suggestion := candidates[overlap.Username][overlap.Suggested]
+		if suggestion == nil {
+			suggestion = &FollowSuggestion{Username: overlap.Username, Suggested: overlap.Suggested, ComputedAt: now}
+			candidates[overlap.Username][overlap.Suggested] = suggestion
+		}
+		return suggestion
+	}
+	for i := range mutuals {
+		candidate(&mutuals[i]).MutualFollows = mutuals[i].Overlap
+	}
+	for i := range shared {
+		candidate(&shared[i]).SharedHashtags = shared[i].Overlap
+	}
+
+	excluded, err := suggestionExclusions(db, usernames)
+	if err != nil {
+		return nil, err
+	}
+	ineligible, err := ineligibleSuggestions(db, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []FollowSuggestion
+	for username, byCandidate := range candidates {
+		var ranked []FollowSuggestion
+		for suggested, suggestion := range byCandidate {
+			if suggested == username || excluded[[2]string{username, suggested}] || ineligible[suggested] {
+				continue
+			}
+			suggestion.Score = float64(suggestion.MutualFollows) + SharedHashtagWeight*float64(suggestion.SharedHashtags)
+			ranked = append(ranked, *suggestion)
+		}
+
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Score != ranked[j].Score {
+				return ranked[i].Score > ranked[j].Score
+			}
+			return ranked[i].Suggested < ranked[j].Suggested
+		})
+		if len(ranked) > MaxSuggestions {
+			ranked = ranked[:MaxSuggestions]
+		}
+		suggestions = append(suggestions, ranked...)
+	}
+	return suggestions, nil
+}
+
+// The pairs of user and account that can't be suggested: accounts the user follows, asked to follow, or
+// muted, and anyone with a block either way
+func suggestionExclusions(db *gorm.DB, usernames []string) (map[[2]string]bool, error) {
+	excluded := map[[2]string]bool{}
+	var pairs []suggestionOverlap
+	queries := []*gorm.DB{
+		db.Model(&Follows{}).Select("followee AS username, following AS suggested").Where("followee IN ?", usernames),
+		db.Model(&FollowRequest{}).Select("requester AS username, target AS suggested").Where("requester IN ?", usernames),
+		db.Model(&Mute{}).Select("muter AS username, muted AS suggested").Where("muter IN ?", usernames),
+		db.Model(&Block{}).Select("blocker AS username, blocked AS suggested").Where("blocker IN ?", usernames),
+		db.Model(&Block{}).Select("blocked AS username, blocker AS suggested").Where("blocked IN ?", usernames),
+	}
+	for _, query := range queries {
+		pairs = nil
+		if err := query.Scan(&pairs).Error; err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			excluded[[2]string{pair.Username, pair.Suggested}] = true
+		}
+	}
+	return excluded, nil
+}
+
+// The candidates that can't be suggested to anyone: deactivated accounts and those that opted out of
+// being discovered
+func ineligibleSuggestions(db *gorm.DB, candidates map[string]map[string]*FollowSuggestion) (map[string]bool, error) {
+	seen := map[string]bool{}
+	var suggested []string
+	for _, byCandidate := range candidates {
+		for username := range byCandidate {
+			if !seen[username] {
+				seen[username] = true
+				suggested = append(suggested, username)
+			}
+		}
+	}
+
+	ineligible := map[string]bool{}
+	if len(suggested) == 0 {
+		return ineligible, nil
+	}
+
+	// users without a settings row are discoverable by default
+	hidden := db.Model(&UserSettings{}).Select("username").Where("discoverable = ?", false)
+	var names []string
+	if err := db.Model(&User{}).Where("username IN ?", suggested).
+		Where("deactivated_at IS NOT NULL OR username IN (?)", hidden).Pluck("username", &names).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		ineligible[name] = true
+	}
+	return ineligible, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	MaxThreadLength = 25
+)
+
+var (
+	ErrorThreadScheduled error = errors.New("trills in a thread are posted together and can't be scheduled")
+)
+
+// Posts the trills as a thread in one transaction, each replying to the one before it; if any of them
+// fails, none are posted. The first starts a new conversation unless it already has a parent.
+func CreateThread(ctx context.Context, trills []*Trill) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i, trill := range trills {
+			if i > 0 {
+				trill.ParentID = &trills[i-1].TrillID
+			}
+			if err := createTrill(tx, trill); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, trill := range trills {
+		queueLinkPreview(ctx, trill)
+		queueLinkScan(ctx, trill)
+		queueTimelineFanout(ctx, trill)
+	}
+	return nil
+}
+
+// The trills with everything GetTrill loads, in the order of the IDs; ones that don't exist are left out
+func GetTrillsByID(ctx context.Context, trillIDs []int64) (*[]Trill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Trill
+	if err := preloadTrills(db).Where("trill_id IN ? AND username NOT IN (?)", trillIDs, deactivatedUsers(db)).
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]Trill, len(found))
+	for _, trill := range found {
+		byID[trill.TrillID] = trill
+	}
+	trills := make([]Trill, 0, len(found))
+	for _, trillID := range trillIDs {
+		if trill, ok := byID[trillID]; ok {
+			trills = append(trills, trill)
+		}
+	}
+
+	return &trills, nil
+}
@@ -0,0 +1,548 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"trill/src/pagination"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A trill in someone's materialized home timeline. The timelineFanout worker writes one for the author and
+// each of their followers when a trill or retrill is posted, so reading a home timeline is a range scan on
+// the primary key. Authors with CelebrityFollowers or more followers only get their own entry; their trills
+// are pulled in when the timeline is read instead, since writing to every follower would take too long.
+type TimelineEntry struct {
+	Username  string    `gorm:"type:varchar(128);primarykey"`
+	TrillID   int64     `gorm:"primarykey;autoIncrement:false;index"`
+	Author    string    `gorm:"type:varchar(128);index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// What gets queued for the timelineFanout worker
+type TimelineFanoutEvent struct {
+	TrillID int64 `json:"trill_id"`
+}
+
+// A trill's place in the explore timeline
+type hotTrill struct {
+	TrillID int64
+	Hot     int64
+}
+
+// What the top home timeline needs to score a trill
+type rankedCandidate struct {
+	TrillID      int64
+	Username     string
+	LikeCount    int64
+	ReplyCount   int64
+	RetrillCount int64
+	QuoteCount   int64
+	CreatedAt    time.Time
+	score        int64
+}
+
+// How often the requestor has liked or replied to an author lately
+type authorAffinity struct {
+	Username     string
+	Interactions int64
+}
+
+// What a user timeline shows besides the user's own top-level trills
+type UserTimelineOptions struct {
+	IncludeReplies  bool
+	IncludeRetrills bool
+}
+
+const (
+	CelebrityFollowers = 10000
+	// how many of an account's latest trills land in a new follower's home timeline
+	TimelineBackfillSize = 50
+	fanoutBatchSize      = 1000
+)
+
+// How the home timeline is ordered: newest first, or by how likely the requestor is to care
+const (
+	RankingLatest = "latest"
+	RankingTop    = "top"
+	// how many of the latest home timeline trills the top ranking chooses from
+	MaxRankedCandidates = 500
+)
+
+var (
+	// how far back the top ranking looks
+	RankingWindow = 48 * time.Hour
+	// how much newer a trill has to be to rank with one that has ten times the engagement
+	RankingDecay = 6 * time.Hour
+	// how far back likes and replies count toward the requestor's affinity for an author
+	AffinityWindow = 30 * 24 * time.Hour
+)
+
+var (
+	ErrorInvalidRanking error = errors.New("ranking must be 'top' or 'latest'")
+)
+
+var (
+	// how far back the explore timeline looks
+	ExploreWindow = 72 * time.Hour
+	// how much newer a trill has to be to rank with one that has ten times the engagement
+	ExploreDecay = 12 * time.Hour
+)
+
+// Engagement on a log scale plus recency on a linear one, scaled up to an integer so it can be a cursor.
+// It doesn't depend on the time it's computed, so pages don't shift as the clock moves; only new
+// engagement moves a trill.
+const hotScore = "FLOOR((LOG10(GREATEST(like_count + reply_count + 2 * (retrill_count + quote_count), 1)) + " +
+	"UNIX_TIMESTAMP(created_at) / ?) * 1000000)"
+
+// The requestor's home timeline newest first, keyset paginated in either direction on the trill ID. A page
+// can come back short when hydrating it drops trills that were deleted or hidden since.
+func GetHomeTimeline(ctx context.Context, requestor string, idRange IDRange, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trillIDs, err := homeTimelineIDs(db, requestor, idRange, cursor, time.Time{}, limit)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	trillIDs, cursors := pagination.Trim(trillIDs, cursor, limit, func(trillID *int64) Cursor {
+		return Cursor{Value: *trillID}
+	})
+	trills := []Trill{}
+	if len(trillIDs) == 0 {
+		return &trills, cursors, nil
+	}
+
+	// hydrating drops trills since deleted, accounts since unfollowed or muted, and anything now hidden
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	query := visibleTrills(preloadTrills(db), db, requestor).Where("trill_id IN ?", trillIDs).
+		Where("username IN (?) OR username = ? OR trill_id IN (?)", following, requestor, followedHashtagTrills(db, requestor).Select("trill_id"))
+	// a retrill only shows when the requestor could see the original themselves
+	originals := visibleTrills(db.Model(&Trill{}).Select("trill_id"), db, requestor)
+	query = query.Where("retrill_of_id IS NULL OR retrill_of_id IN (?)", originals)
+	query = excludeMuted(query, db, "username", requestor)
+	settings, err := GetUserSettings(ctx, requestor)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	query = excludeMutedWords(query, db, requestor, settings.MutedWords)
+	if err := query.Order("trill_id DESC").Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	return &trills, cursors, nil
+}
+
+// The trill IDs in the requestor's home timeline from their materialized entries merged with the trills
+// of celebrities they follow and of hashtags they follow, within the ID range and no older than since
+// when it's set. Like trillKeyset.Apply, it reads the limit past the cursor and one more, for Trim.
+func homeTimelineIDs(db *gorm.DB, requestor string, idRange IDRange, cursor *Cursor, since time.Time, limit int) ([]int64, error) {
+	entries := idRange.Apply(db.Model(&TimelineEntry{}).Where("username = ?", requestor), "trill_id")
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	celebrities := db.Model(&User{}).Select("username").Where("follower_count >= ? AND username IN (?)", CelebrityFollowers, following)
+	fanIn := idRange.Apply(db.Model(&Trill{}).Where("username IN (?)", celebrities), "trill_id")
+	tagged := idRange.Apply(followedHashtagTrills(db, requestor), "trill_id")
+	if !since.IsZero() {
+		entries = entries.Where("created_at >= ?", since)
+		fanIn = fanIn.Where("created_at >= ?", since)
+		tagged = tagged.Where("created_at >= ?", since)
+	}
+
+	var materialized []int64
+	if err := trillKeyset.Apply(entries, cursor, limit).Pluck("trill_id", &materialized).Error; err != nil {
+		return nil, err
+	}
+	var pulled []int64
+	if err := trillKeyset.Apply(fanIn, cursor, limit).Pluck("trill_id", &pulled).Error; err != nil {
+		return nil, err
+	}
+
+	var topical []int64
+	if err := trillKeyset.Apply(tagged, cursor, limit).Pluck("trill_id", &topical).Error; err != nil {
+		return nil, err
+	}
+
+	// a backward page is read oldest first
+	ascending := cursor != nil && cursor.Backward
+	return mergeTrillIDs(mergeTrillIDs(materialized, pulled, limit+1, ascending), topical, limit+1, ascending), nil
+}
+
+func ValidRanking(ranking string) bool {
+	return ranking == RankingLatest || ranking == RankingTop
+}
+
+// The requestor's home timeline from the last RankingWindow, best first: each trill is scored on its
+// engagement, how often the requestor has liked or replied to its author lately, and how recent it is.
+// Scores don't depend on the time they're computed, so the cursor holds the last score and trill ID.
+// The ranking runs out with the window; older trills are only in the latest ordering. The ID range
+// narrows the candidates, so a refresh can rank just what's new. Trills using the requestor's muted words
+// never become candidates.
+func GetTopHomeTimeline(ctx context.Context, requestor string, idRange IDRange, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var last rankedCandidate
+	if cursor != nil {
+		last.score = cursor.Value
+		if last.TrillID, err = strconv.ParseInt(cursor.Key, 10, 64); err != nil {
+			return nil, Cursors{}, &HTTPError{Code: http.StatusBadRequest, Err: ErrorCursorInvalid}
+		}
+	}
+
+	candidateIDs, err := homeTimelineIDs(db, requestor, idRange, nil, time.Now().Add(-RankingWindow), MaxRankedCandidates)
+	if err != nil {
+		return nil, Cursors{}, err
+	} else if len(candidateIDs) == 0 {
+		return &[]Trill{}, Cursors{}, nil
+	} else if len(candidateIDs) > MaxRankedCandidates {
+		candidateIDs = candidateIDs[:MaxRankedCandidates]
+	}
+
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	query := visibleTrills(db.Model(&Trill{}).Select("trill_id, username, like_count, reply_count, retrill_count, quote_count, created_at"), db, requestor).
+		Where("trill_id IN ?", candidateIDs).
+		Where("username IN (?) OR username = ? OR trill_id IN (?)", following, requestor, followedHashtagTrills(db, requestor).Select("trill_id"))
+	originals := visibleTrills(db.Model(&Trill{}).Select("trill_id"), db, requestor)
+	query = query.Where("retrill_of_id IS NULL OR retrill_of_id IN (?)", originals)
+	query = excludeMuted(query, db, "username", requestor)
+	settings, err := GetUserSettings(ctx, requestor)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	query = excludeMutedWords(query, db, requestor, settings.MutedWords)
+	var candidates []rankedCandidate
+	if err := query.Scan(&candidates).Error; err != nil {
+		return nil, Cursors{}, err
+	} else if len(candidates) == 0 {
+		return &[]Trill{}, Cursors{}, nil
+	}
+
+	authors := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		authors[i] = candidate.Username
+	}
+	affinity, err := getAffinity(db, requestor, authors)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	// ranked in the order Trim expects, which is reversed going backward
+	backward := cursor != nil && cursor.Backward
+	ranked := make([]rankedCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate.score = topScore(&candidate, affinity[candidate.Username])
+		if cursor == nil || (backward && candidate.ranksAbove(&last)) || (!backward && last.ranksAbove(&candidate)) {
+			ranked = append(ranked, candidate)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if backward {
+			return ranked[j].ranksAbove(&ranked[i])
+		}
+		return ranked[i].ranksAbove(&ranked[j])
+	})
+
+	ranked, cursors := pagination.Trim(ranked, cursor, limit, func(candidate *rankedCandidate) Cursor {
+		return Cursor{Value: candidate.score, Key: strconv.FormatInt(candidate.TrillID, 10)}
+	})
+	trillIDs := make([]int64, len(ranked))
+	for i, candidate := range ranked {
+		trillIDs[i] = candidate.TrillID
+	}
+	if len(trillIDs) == 0 {
+		return &[]Trill{}, cursors, nil
+	}
+	trills, err := GetTrillsByID(ctx, trillIDs)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	return trills, cursors, nil
+}
+
+// Whether the candidate ranks above the other one: it scored higher, or the same and it's newer
+func (candidate *rankedCandidate) ranksAbove(other *rankedCandidate) bool {
+	if candidate.score != other.score {
+		return candidate.score > other.score
+	}
+	return candidate.TrillID > other.TrillID
+}
+
+// Engagement and affinity on log scales plus recency on a linear one, scaled up to an integer so it can
+// be a cursor. Ten times the interactions with an author counts the same as ten times the engagement.
+func topScore(candidate *rankedCandidate, interactions int64) int64 {
+	engagement := candidate.LikeCount + candidate.ReplyCount + 2*(candidate.RetrillCount+candidate.QuoteCount)
+	score := math.Log10(math.Max(float64(engagement), 1)) + math.Log10(float64(1+interactions)) +
+		float64(candidate.CreatedAt.Unix())/RankingDecay.Seconds()
+	return int64(math.Floor(score * 1000000))
+}
+
+// How many times lately the requestor has liked or replied to each of the authors' trills
+func getAffinity(db *gorm.DB, requestor string, authors []string) (map[string]int64, error) {
+	since := time.Now().Add(-AffinityWindow)
+
+	var likes []authorAffinity
+	if err := db.Model(&TrillLike{}).Select("trills.username, COUNT(*) AS interactions").
+		Joins("JOIN trills ON trills.trill_id = trill_likes.trill_id").
+		Where("trill_likes.username = ? AND trill_likes.created_at >= ? AND trills.username IN ?", requestor, since, authors).
+		Group("trills.username").Scan(&likes).Error; err != nil {
+		return nil, err
+	}
+
+	var replies []authorAffinity
+	if err := db.Table("trills AS replies").Select("parents.username, COUNT(*) AS interactions").
+		Joins("JOIN trills AS parents ON parents.trill_id = replies.parent_id").
+		Where("replies.username = ? AND replies.created_at >= ? AND parents.username IN ?", requestor, since, authors).
+		Group("parents.username").Scan(&replies).Error; err != nil {
+		return nil, err
+	}
+
+	affinity := make(map[string]int64, len(likes)+len(replies))
+	for _, a := range append(likes, replies...) {
+		affinity[a.Username] += a.Interactions
+	}
+	return affinity, nil
+}
+
+// Popular and recent trills from public accounts the requestor doesn't follow, hottest first, for anyone
+// whose home timeline is quiet. Replies and retrills are left out, as are muted accounts and anyone
+// the requestor has a block with. Keyset paginated in either direction on the hot score, then the trill ID.
+func GetExploreTimeline(ctx context.Context, requestor string, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	decay := ExploreDecay.Seconds()
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	public := db.Model(&User{}).Select("username").Where("is_private = ? AND deactivated_at IS NULL", false)
+	query := db.Model(&Trill{}).Select("trill_id, "+hotScore+" AS hot", decay).
+		Where("created_at >= ? AND parent_id IS NULL AND retrill_of_id IS NULL", time.Now().Add(-ExploreWindow)).
+		Where("username IN (?) AND username NOT IN (?) AND username <> ?", public, following, requestor)
+	query = excludeBlocked(excludeMuted(query, db, "username", requestor), db, "username", requestor)
+	if cursor != nil {
+		if _, err := strconv.ParseInt(cursor.Key, 10, 64); err != nil {
+			return nil, Cursors{}, &HTTPError{Code: http.StatusBadRequest, Err: ErrorCursorInvalid}
+		}
+	}
+	keyset := pagination.Keyset{Value: hotScore, ValueArgs: []interface{}{decay}, Key: "trill_id"}
+
+	var ranked []hotTrill
+	if err := keyset.Apply(query, cursor, limit).Scan(&ranked).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	ranked, cursors := pagination.Trim(ranked, cursor, limit, func(trill *hotTrill) Cursor {
+		return Cursor{Value: trill.Hot, Key: strconv.FormatInt(trill.TrillID, 10)}
+	})
+	trillIDs := make([]int64, len(ranked))
+	for i, trill := range ranked {
+		trillIDs[i] = trill.TrillID
+	}
+	if len(trillIDs) == 0 {
+		return &[]Trill{}, cursors, nil
+	}
+	trills, err := GetTrillsByID(ctx, trillIDs)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	return trills, cursors, nil
+}
+
+// Merges two lists of trill IDs that are each newest first, or oldest first when ascending is set, into
+// one in the same order, without repeats, keeping at most n
+func mergeTrillIDs(a []int64, b []int64, n int, ascending bool) []int64 {
+	merged := make([]int64, 0, n)
+	for len(merged) < n && (len(a) > 0 || len(b) > 0) {
+		var id int64
+		if len(b) == 0 || (len(a) > 0 && (a[0] == b[0] || (a[0] > b[0]) != ascending)) {
+			id, a = a[0], a[1:]
+		} else {
+			id, b = b[0], b[1:]
+		}
+		if len(merged) == 0 || merged[len(merged)-1] != id {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// Queues the trill to be written into its author's and followers' home timelines. Failing to queue it
+// only keeps it off those timelines, so it's logged rather than failing whatever posted the trill.
+func queueTimelineFanout(ctx context.Context, trill *Trill) {
+	if err := enqueueTimelineFanout(ctx, &TimelineFanoutEvent{TrillID: trill.TrillID}); err != nil {
+		fmt.Printf("failed to queue timeline fanout for trill %d: %s\n", trill.TrillID, err.Error())
+	}
+}
+
+func enqueueTimelineFanout(ctx context.Context, event *TimelineFanoutEvent) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().TimelineFanoutQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Writes the trill into its author's home timeline and, unless the author is a celebrity, each of their
+// followers'. Entries that are already there are left alone, so a redelivered event is harmless, and a
+// trill deleted since it was queued is skipped.
+func FanoutTrill(ctx context.Context, event *TimelineFanoutEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trill Trill
+	if result := db.Preload("User").Where("trill_id = ?", event.TrillID).Limit(1).Find(&trill); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return nil
+	}
+
+	recipients := []string{trill.Username}
+	if trill.User.FollowerCount < CelebrityFollowers {
+		var followers []string
+		if err := db.Model(&Follows{}).Where("following = ?", trill.Username).Pluck("followee", &followers).Error; err != nil {
+			return err
+		}
+		recipients = append(recipients, followers...)
+	}
+
+	entries := make([]TimelineEntry, len(recipients))
+	for i, username := range recipients {
+		entries[i] = TimelineEntry{Username: username, TrillID: trill.TrillID, Author: trill.Username, CreatedAt: trill.CreatedAt}
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&entries, fanoutBatchSize).Error
+}
+
+// Copies the account's latest trills into its new followers' home timelines, so following someone doesn't
+// start from an empty slate. Celebrities' trills are pulled in when the timeline is read, so they're skipped.
+func backfillTimeline(tx *gorm.DB, following string, followers ...string) error {
+	var author User
+	if result := tx.Where("username = ?", following).Limit(1).Find(&author); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || author.FollowerCount >= CelebrityFollowers {
+		return nil
+	}
+
+	var trills []Trill
+	if err := tx.Select("trill_id", "created_at").Where("username = ?", following).
+		Order("trill_id DESC").Limit(TimelineBackfillSize).Find(&trills).Error; err != nil {
+		return err
+	}
+
+	entries := make([]TimelineEntry, 0, len(trills)*len(followers))
+	for _, follower := range followers {
+		for _, trill := range trills {
+			entries = append(entries, TimelineEntry{Username: follower, TrillID: trill.TrillID, Author: following, CreatedAt: trill.CreatedAt})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&entries, fanoutBatchSize).Error
+}
+
+// Takes an account's trills back out of a former follower's home timeline
+func clearTimeline(tx *gorm.DB, follower string, following string) error {
+	return tx.Where("username = ? AND author = ?", follower, following).Delete(&TimelineEntry{}).Error
+}
+
+// A user's trills newest first, keyset paginated on the trill ID in either direction, with their replies
+// and retrills if the options ask for them, within the ID range. Fails with a 403 HTTPError if there's a block between the users or the account is
+// private and the requestor doesn't follow it. Retrills of trills the requestor can't see are left out.
+func GetUserTimeline(ctx context.Context, requestor string, username string, options UserTimelineOptions, idRange IDRange, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	user, err := GetUser(ctx, username)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	if blocked, err := IsBlocked(ctx, requestor, user.Username); err != nil {
+		return nil, Cursors{}, err
+	} else if blocked {
+		return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorBlocked}
+	}
+	if canView, err := CanViewUser(ctx, requestor, user); err != nil {
+		return nil, Cursors{}, err
+	} else if !canView {
+		return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorPrivateAccount}
+	}
+
+	query := preloadTrills(db).Where("username = ?", user.Username)
+	if !options.IncludeReplies {
+		query = query.Where("parent_id IS NULL")
+	}
+	if options.IncludeRetrills {
+		originals := visibleTrills(db.Model(&Trill{}).Select("trill_id"), db, requestor)
+		query = query.Where("retrill_of_id IS NULL OR retrill_of_id IN (?)", originals)
+	} else {
+		query = query.Where("retrill_of_id IS NULL")
+	}
+	query = idRange.Apply(query, "trill_id")
+
+	var trills []Trill
+	if err := trillKeyset.Apply(query, cursor, limit).Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trills, cursors := pagination.Trim(trills, cursor, limit, trillPosition)
+	return &trills, cursors, nil
+}
+
+// Trills that mention the requestor newest first, keyset paginated on the trill ID in either direction,
+// within the ID range. Their own trills are left out, along with trills they can't see, anyone they've
+// muted, and trills using their muted words, the same as their mention notifications.
+func GetMentionsTimeline(ctx context.Context, requestor string, idRange IDRange, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	mentioned := db.Model(&Mention{}).Select("trill_id").Where("username = ?", requestor)
+	query := visibleTrills(preloadTrills(db), db, requestor).Where("trill_id IN (?) AND username <> ?", mentioned, requestor)
+	query = excludeMuted(query, db, "username", requestor)
+	settings, err := GetUserSettings(ctx, requestor)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	query = excludeMutedWords(query, db, requestor, settings.MutedWords)
+	query = idRange.Apply(query, "trill_id")
+
+	var trills []Trill
+	if err := trillKeyset.Apply(query, cursor, limit).Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trills, cursors := pagination.Trim(trills, cursor, limit, trillPosition)
+	return &trills, cursors, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// A random secret with the given prefix, along with the hash that gets stored in its place. The
+// secrets are long enough that a plain SHA-256 is all the hashing they need.
+func randomToken(prefix string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	raw := prefix + hex.EncodeToString(secret)
+	return raw, hashSecret(raw), nil
+}
+
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// What's left of a deleted trill that had replies: where it sat in its conversation, so the replies
+// under it stay attached to the thread. Nothing the author wrote is kept, not even who they were.
+// ReplyCount is kept up as the replies under it are deleted.
+type TrillTombstone struct {
+	TrillID        int64     `gorm:"primarykey;autoIncrement:false"`
+	ParentID       *int64    `gorm:"index"`
+	ConversationID int64     `gorm:"index"`
+	ReplyCount     int64     `gorm:"not null;default:0"`
+	DeletedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// One place in a thread, holding either the trill or, once it's deleted, its tombstone
+type ThreadEntry struct {
+	Trill     *Trill
+	Tombstone *TrillTombstone
+}
+
+func (entry *ThreadEntry) TrillID() int64 {
+	if entry.Trill != nil {
+		return entry.Trill.TrillID
+	}
+	return entry.Tombstone.TrillID
+}
+
+func (entry *ThreadEntry) ParentID() *int64 {
+	if entry.Trill != nil {
+		return entry.Trill.ParentID
+	}
+	return entry.Tombstone.ParentID
+}
+
+// The trill, or its tombstone if it was deleted with replies under it. Fails with the same 404
+// HTTPError as GetTrill if there's neither.
+func GetThreadEntry(ctx context.Context, trillID int64) (*ThreadEntry, error) {
+	trill, err := GetTrill(ctx, trillID)
+	var httpErr *HTTPError
+	if err == nil {
+		return &ThreadEntry{Trill: trill}, nil
+	} else if !errors.As(err, &httpErr) || httpErr.Code != http.StatusNotFound {
+		return nil, err
+	}
+
+	tombstone, found, err := getTombstone(ctx, trillID)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+	}
+
+	return &ThreadEntry{Tombstone: tombstone}, nil
+}
+
+func getTombstone(ctx context.Context, trillID int64) (*TrillTombstone, bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var tombstone TrillTombstone
+	if result := db.Where("trill_id = ?", trillID).Limit(1).Find(&tombstone); result.Error != nil {
+		return nil, false, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, false, nil
+	}
+
+	return &tombstone, true, nil
+}
+
+// Leaves tombstones for the trills that have replies; trills nobody replied to, and retrills, go without a trace
+func createTombstones(tx *gorm.DB, trills []Trill) error {
+	var tombstones []TrillTombstone
+	for _, trill := range trills {
+		if trill.RetrillOfID == nil && trill.ReplyCount > 0 {
+			tombstones = append(tombstones, TrillTombstone{
+				TrillID:        trill.TrillID,
+				ParentID:       trill.ParentID,
+				ConversationID: trill.ConversationID,
+				ReplyCount:     trill.ReplyCount,
+				DeletedAt:      time.Now(),
+			})
+		}
+	}
+	if len(tombstones) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tombstones).Error
+}
+
+// A reply under a deleted trill counts against its tombstone instead
+func decrementTombstoneReplies(tx *gorm.DB, delta int64, trillID int64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	return tx.Model(&TrillTombstone{}).Where("trill_id = ?", trillID).
+		UpdateColumn("reply_count", gorm.Expr("reply_count - ?", delta)).Error
+}
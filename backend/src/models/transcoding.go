@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/mediaconvert"
+	"github.com/aws/aws-sdk-go-v2/service/mediaconvert/types"
+)
+
+const (
+	TranscodedVideoPrefix = "trill-video/"
+)
+
+var (
+	// renditions are scaled to this height, keeping the aspect ratio
+	TranscodeHeight         int32 = 720
+	TranscodeMaxBitrate     int32 = 5_000_000
+	TranscodeSegmentSeconds int32 = 6
+)
+
+// The detail of a MediaConvert Job State Change event, for the parts we read
+type TranscodeJobEvent struct {
+	JobID              string `json:"jobId"`
+	Status             string `json:"status"`
+	ErrorMessage       string `json:"errorMessage"`
+	OutputGroupDetails []struct {
+		Type              string   `json:"type"`
+		PlaylistFilePaths []string `json:"playlistFilePaths"`
+		OutputDetails     []struct {
+			OutputFilePaths []string `json:"outputFilePaths"`
+			DurationInMs    int64    `json:"durationInMs"`
+			VideoDetails    struct {
+				WidthInPx  int `json:"widthInPx"`
+				HeightInPx int `json:"heightInPx"`
+			} `json:"videoDetails"`
+		} `json:"outputDetails"`
+	} `json:"outputGroupDetails"`
+}
+
+// Starts transcoding a video that was just uploaded. Uploads that aren't videos for a trill, or that
+// were already started, are skipped; ones over MaxTrillVideoBytes are marked failed.
+func StartTranscode(ctx context.Context, key string, size int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var media Media
+	if result := db.Where("object_key = ?", key).Limit(1).Find(&media); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || media.Kind != MediaKindVideo || media.Status != MediaStatusPending {
+		return nil
+	}
+
+	if size > MaxTrillVideoBytes {
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	jobID, err := createTranscodeJob(ctx, &media)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&media).Updates(map[string]interface{}{"status": MediaStatusProcessing, "job_id": jobID}).Error
+}
+
+// Records the renditions, dimensions, and duration of a finished job, or marks the video failed.
+// Jobs for media that's since been deleted, and voice notes' jobs, are ignored.
+func FinishTranscode(ctx context.Context, event *TranscodeJobEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var media Media
+	if result := db.Where("job_id = ?", event.JobID).Limit(1).Find(&media); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || media.Kind != MediaKindVideo {
+		return nil
+	}
+
+	if event.Status != "COMPLETE" {
+		fmt.Printf("transcode job %s for %s failed: %s\n", event.JobID, media.ObjectKey, event.ErrorMessage)
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	updates := map[string]interface{}{"status": MediaStatusReady}
+	for _, group := range event.OutputGroupDetails {
+		if group.Type == "HLS_GROUP" && len(group.PlaylistFilePaths) > 0 {
+			updates["hls_key"] = contentKeyFromURI(group.PlaylistFilePaths[0])
+		}
+		for _, output := range group.OutputDetails {
+			if group.Type == "FILE_GROUP" && len(output.OutputFilePaths) > 0 {
+				updates["mp4_key"] = contentKeyFromURI(output.OutputFilePaths[0])
+			}
+			updates["width"] = output.VideoDetails.WidthInPx
+			updates["height"] = output.VideoDetails.HeightInPx
+			updates["duration_ms"] = output.DurationInMs
+		}
+	}
+
+	return db.Model(&media).Updates(updates).Error
+}
+
+// s3://trill-content/trill-video/x.mp4 -> trill-video/x.mp4
+func contentKeyFromURI(uri string) string {
+	return strings.TrimPrefix(uri, "s3://"+ContentBucket+"/")
+}
+
+// Submits a job that turns the upload into an HLS stream and an MP4, both H.264/AAC, under
+// trill-video/<upload name>/
+func createTranscodeJob(ctx context.Context, media *Media) (string, error) {
+	name := strings.TrimSuffix(path.Base(media.ObjectKey), path.Ext(media.ObjectKey))
+	destination := fmt.Sprintf("s3://%s/%s%s/", ContentBucket, TranscodedVideoPrefix, name)
+	videoDescription := &types.VideoDescription{
+		Height: aws.Int32(TranscodeHeight),
+		CodecSettings: &types.VideoCodecSettings{
+			Codec: types.VideoCodecH264,
+			H264Settings: &types.H264Settings{
+				RateControlMode:   types.H264RateControlModeQvbr,
+				MaxBitrate:        aws.Int32(TranscodeMaxBitrate),
+				SceneChangeDetect: types.H264SceneChangeDetectTransitionDetection,
+			},
+		},
+	}
+	audioDescriptions := []types.AudioDescription{{
+		CodecSettings: &types.AudioCodecSettings{
+			Codec: types.AudioCodecAac,
+			AacSettings: &types.AacSettings{
+				Bitrate:    aws.Int32(96000),
+				CodingMode: types.AacCodingModeCodingMode20,
+				SampleRate: aws.Int32(48000),
+			},
+		},
+	}}
+	return submitTranscodeJob(ctx, media, []types.OutputGroup{
+		{
+			OutputGroupSettings: &types.OutputGroupSettings{
+				Type: types.OutputGroupTypeHlsGroupSettings,
+				HlsGroupSettings: &types.HlsGroupSettings{
+					Destination:      aws.String(destination + "hls/" + name),
+					SegmentLength:    aws.Int32(TranscodeSegmentSeconds),
+					MinSegmentLength: aws.Int32(0),
+				},
+			},
+			Outputs: []types.Output{{
+				NameModifier:      aws.String("_720p"),
+				ContainerSettings: &types.ContainerSettings{Container: types.ContainerTypeM3u8},
+				VideoDescription:  videoDescription,
+				AudioDescriptions: audioDescriptions,
+			}},
+		},
+		{
+			OutputGroupSettings: &types.OutputGroupSettings{
+				Type:              types.OutputGroupTypeFileGroupSettings,
+				FileGroupSettings: &types.FileGroupSettings{Destination: aws.String(destination + name)},
+			},
+			Outputs: []types.Output{{
+				NameModifier:      aws.String("_720p"),
+				ContainerSettings: &types.ContainerSettings{Container: types.ContainerTypeMp4},
+				VideoDescription:  videoDescription,
+				AudioDescriptions: audioDescriptions,
+			}},
+		},
+	})
+}
+
+// Submits a job making the output groups from the upload. The media's ID and kind go in the job's
+// metadata, so its events can be routed by kind.
+func submitTranscodeJob(ctx context.Context, media *Media, outputGroups []types.OutputGroup) (string, error) {
+	mediaConvertClient, err := InitMediaConvertClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	job, err := mediaConvertClient.CreateJob(ctx, &mediaconvert.CreateJobInput{
+		Role:         aws.String(utils.GetSecrets().MediaConvertRole),
+		UserMetadata: map[string]string{"media_id": strconv.FormatInt(media.MediaID, 10), "kind": media.Kind},
+		Settings: &types.JobSettings{
+			Inputs: []types.Input{{
+				FileInput:      aws.String(fmt.Sprintf("s3://%s/%s", ContentBucket, media.ObjectKey)),
+				AudioSelectors: map[string]types.AudioSelector{"Audio Selector 1": {DefaultSelection: types.AudioDefaultSelectionDefault}},
+			}},
+			OutputGroups: outputGroups,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(job.Job.Id), nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/translate"
+	"github.com/aws/aws-sdk-go-v2/service/translate/types"
+	"gorm.io/gorm/clause"
+)
+
+// A trill's text translated into a language by Amazon Translate, cached so each trill is only translated
+// into each language once. Editing the trill deletes its translations.
+type TrillTranslation struct {
+	TrillID        int64     `gorm:"primarykey"`
+	Language       string    `gorm:"type:varchar(16);primarykey"`
+	SourceLanguage string    `gorm:"type:varchar(16)"`
+	Text           string    `gorm:"type:text"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	// a language code Amazon Translate takes, e.g. "fr" or "zh-TW"
+	translationLanguagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2,4})?$`)
+)
+
+var (
+	ErrorTranslationLanguage    error = errors.New("lang must be a language code like fr or zh-TW")
+	ErrorTranslationNoText      error = errors.New("trill has no text to translate")
+	ErrorTranslationUnsupported error = errors.New("the trill can't be translated into that language")
+)
+
+// The language a user's settings ask for, as a translation target; the region is dropped, since
+// Translate only takes it for a few languages
+func TranslationLanguage(settings *UserSettings) string {
+	language, _, _ := strings.Cut(settings.Language, "-")
+	return language
+}
+
+// The trill's text in the language, translating it unless it's cached. Fails with a 400 HTTPError if the
+// language code is malformed, the trill has no text, or Translate can't translate it into the language.
+func GetTrillTranslation(ctx context.Context, trill *Trill, language string) (*TrillTranslation, error) {
+	if !translationLanguagePattern.MatchString(language) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTranslationLanguage}
+	} else if strings.TrimSpace(trill.Text) == "" {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTranslationNoText}
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached TrillTranslation
+	if result := db.Where("trill_id = ? AND language = ?", trill.TrillID, language).Limit(1).Find(&cached); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected > 0 {
+		return &cached, nil
+	}
+
+	translation, err := translateText(ctx, trill.Text, language)
+	if err != nil {
+		return nil, err
+	}
+	translation.TrillID = trill.TrillID
+
+	// the text is checked again in case the trill was edited while it was being translated
+	var current int64
+	if err := db.Model(&Trill{}).Where("trill_id = ? AND text = ?", trill.TrillID, trill.Text).Count(&current).Error; err != nil {
+		return nil, err
+	} else if current == 0 {
+		return translation, nil
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(translation).Error; err != nil {
+		return nil, err
+	}
+	return translation, nil
+}
+
+// Asks Amazon Translate for the text in the language, letting it detect the language it's in
+func translateText(ctx context.Context, text string, language string) (*TrillTranslation, error) {
+	translateClient, err := InitTranslateClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := translateClient.TranslateText(ctx, &translate.TranslateTextInput{
+		Text:               aws.String(text),
+		SourceLanguageCode: aws.String("auto"),
+		TargetLanguageCode: aws.String(language),
+	})
+	var unsupported *types.UnsupportedLanguagePairException
+	var undetected *types.DetectedLanguageLowConfidenceException
+	if errors.As(err, &unsupported) || errors.As(err, &undetected) {
+		return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTranslationUnsupported}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &TrillTranslation{
+		Language:       language,
+		SourceLanguage: aws.ToString(resp.SourceLanguageCode),
+		Text:           aws.ToString(resp.TranslatedText),
+		CreatedAt:      time.Now(),
+	}, nil
+}
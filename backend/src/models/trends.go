@@ -0,0 +1,203 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// A hashtag trending worldwide, or in a region when Region is set. The trendAggregator worker replaces the
+// whole table every run: a tag's score is the number of people who used it over the last TrendWindow, each
+// use counting for half as much every TrendHalfLife, so a tag that's picking up now outranks one that was
+// busier this morning. Only public accounts count, each once per tag, and a region is the country in the
+// author's locale setting.
+type Trend struct {
+	Region     string    `gorm:"type:varchar(2);primarykey"`
+	Position   int       `gorm:"primarykey;autoIncrement:false"`
+	HashtagID  int64     `gorm:"index"`
+	Tag        string    `gorm:"type:varchar(100)"`
+	Score      float64   `gorm:"not null;default:0"`
+	TrillCount int64     `gorm:"not null;default:0"`
+	ComputedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// One use of a hashtag inside the trend window
+type trendUse struct {
+	HashtagID int64
+	Tag       string
+	Username  string
+	Locale    string
+	CreatedAt time.Time
+}
+
+// A tag's running score while trends are being computed
+type trendTally struct {
+	hashtagID int64
+	tag       string
+	latest    map[string]time.Time
+	uses      int64
+}
+
+const (
+	// worldwide trends are stored under no region
+	WorldwideRegion = ""
+	MaxTrends       = 30
+	// fewer people than this using a tag is a conversation, not a trend
+	MinTrendAuthors = 3
+)
+
+var (
+	TrendWindow   = 24 * time.Hour
+	TrendHalfLife = 2 * time.Hour
+)
+
+var (
+	ErrorInvalidRegion error = errors.New("region must be a two-letter country code")
+)
+
+var (
+	regionPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// Uppercases the two-letter country code, failing with a 400 HTTPError if it isn't one. An empty
+// region means worldwide.
+func NormalizeRegion(region string) (string, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if region != WorldwideRegion && !regionPattern.MatchString(region) {
+		return "", &HTTPError{Code: http.StatusBadRequest, Err: ErrorInvalidRegion}
+	}
+	return region, nil
+}
+
+// The country part of a locale like en-US, or no region for a locale without one
+func localeRegion(locale string) string {
+	if _, country, found := strings.Cut(locale, "-"); found && regionPattern.MatchString(country) {
+		return country
+	}
+	return WorldwideRegion
+}
+
+// The region's trends, best first. A region with nothing trending falls back to the worldwide trends,
+// which is reported by the region that comes back.
+func GetTrends(ctx context.Context, region string) (string, []Trend, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var trends []Trend
+	if err := db.Where("region = ?", region).Order("position").Find(&trends).Error; err != nil {
+		return "", nil, err
+	}
+	if len(trends) > 0 || region == WorldwideRegion {
+		return region, trends, nil
+	}
+
+	if err := db.Where("region = ?", WorldwideRegion).Order("position").Find(&trends).Error; err != nil {
+		return "", nil, err
+	}
+	return WorldwideRegion, trends, nil
+}
+
+// Scores every hashtag used in the trend window and replaces the stored trends with the top MaxTrends
+// worldwide and in each region. Returns how many trends were stored.
+func ComputeTrends(ctx context.Context) (int, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var uses []trendUse
+	if err := db.Table("trill_hashtags").
+		Select("trill_hashtags.hashtag_id, hashtags.tag, trills.username, user_settings.locale, trills.created_at").
+		Joins("JOIN hashtags ON hashtags.hashtag_id = trill_hashtags.hashtag_id").
+		Joins("JOIN trills ON trills.trill_id = trill_hashtags.trill_id").
+		Joins("JOIN users ON users.username = trills.username").
+		Joins("LEFT JOIN user_settings ON user_settings.username = trills.username").
+		Where("trills.created_at >= ? AND users.is_private = ? AND users.deactivated_at IS NULL", now.Add(-TrendWindow), false).
+		Scan(&uses).Error; err != nil {
+		return 0, err
+	}
+
+	tallies := map[string]map[int64]*trendTally{}
+	tally := func(region string, use *trendUse) {
+		if tallies[region] == nil {
+			tallies[region] = map[int64]*trendTally{}
+		}
+		t := tallies[region][use.HashtagID]
+		if t == nil {
+			t = &trendTally{hashtagID: use.HashtagID, tag: use.Tag, latest: map[string]time.Time{}}
+			tallies[region][use.HashtagID] = t
+		}
+		// each person counts once, as of their latest use
+		if latest, ok := t.latest[use.Username]; !ok || use.CreatedAt.After(latest) {
+			t.latest[use.Username] = use.CreatedAt
+		}
+		t.uses++
+	}
+	for i := range uses {
+		tally(WorldwideRegion, &uses[i])
+		if region := localeRegion(uses[i].Locale); region != WorldwideRegion {
+			tally(region, &uses[i])
+		}
+	}
+
+	var trends []Trend
+	for region, byTag := range tallies {
+		trends = append(trends, rankTrends(region, byTag, now)...)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&Trend{}).Error; err != nil {
+			return err
+		}
+		if len(trends) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&trends, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(trends), nil
+}
+
+// The region's top MaxTrends tags by decayed score, ties going to the tag used more
+func rankTrends(region string, byTag map[int64]*trendTally, now time.Time) []Trend {
+	var trends []Trend
+	for _, t := range byTag {
+		if len(t.latest) < MinTrendAuthors {
+			continue
+		}
+		var score float64
+		for _, usedAt := range t.latest {
+			score += math.Pow(0.5, float64(now.Sub(usedAt))/float64(TrendHalfLife))
+		}
+		trends = append(trends, Trend{Region: region, HashtagID: t.hashtagID, Tag: t.tag, Score: score, TrillCount: t.uses, ComputedAt: now})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Score != trends[j].Score {
+			return trends[i].Score > trends[j].Score
+		}
+		if trends[i].TrillCount != trends[j].TrillCount {
+			return trends[i].TrillCount > trends[j].TrillCount
+		}
+		return trends[i].Tag < trends[j].Tag
+	})
+	if len(trends) > MaxTrends {
+		trends = trends[:MaxTrends]
+	}
+	for i := range trends {
+		trends[i].Position = i + 1
+	}
+	return trends
+}
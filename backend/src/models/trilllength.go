@@ -0,0 +1,44 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"trill/src/utils"
+)
+
+var (
+	DefaultMaxTrillLength = 280
+	// every link counts the same however long it is, like the shortened links other sites show
+	TrillURLWeight = 23
+	// a backstop for text that's short in characters but not in bytes, e.g. stacked combining marks
+	MaxTrillTextBytes = 8192
+)
+
+// a link runs from its scheme to the next whitespace
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://\S+`)
+
+// The most a trill's text can count as, from the TRILL_MAX_LENGTH setting
+func MaxTrillLength() int {
+	length, err := strconv.Atoi(utils.GetSecrets().TrillMaxLength)
+	if err != nil || length <= 0 {
+		return DefaultMaxTrillLength
+	}
+	return length
+}
+
+// What the text counts as against MaxTrillLength: one per user-perceived character, so an emoji with a
+// skin tone or a letter with an accent counts once, plus TrillURLWeight for each link
+func TrillLength(text string) int {
+	length := 0
+	rest := 0
+	for _, loc := range linkPattern.FindAllStringIndex(text, -1) {
+		length += utils.GraphemeCount(text[rest:loc[0]]) + TrillURLWeight
+		rest = loc[1]
+	}
+	return length + utils.GraphemeCount(text[rest:])
+}
+
+// Whether the text fits in a trill
+func TrillTextFits(text string) bool {
+	return len(text) <= MaxTrillTextBytes && TrillLength(text) <= MaxTrillLength()
+}
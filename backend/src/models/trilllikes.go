@@ -0,0 +1,297 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"trill/src/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A user liking a trill. The composite key keeps it to one like per user per trill; the review likes
+// in likes predate trills and stay separate.
+type TrillLike struct {
+	Username  string    `gorm:"type:varchar(128);primarykey"`
+	TrillID   int64     `gorm:"primarykey;index"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	ErrorLikesHidden error = errors.New("this user's likes are hidden")
+)
+
+// What the requestor has done to the trills in a response, by trill ID. Votes holds the option the
+// requestor picked in each poll they voted in, Reacted the emoji they reacted with, and CanReply whether
+// the author lets them reply.
+// ShowSensitiveMedia is their setting for whether sensitive media should come unblurred. HashtagReasons
+// is only filled in for the home timeline, with the followed tag behind each trill that's there for one.
+type TrillViewer struct {
+	Liked              map[int64]bool
+	Retrilled          map[int64]bool
+	Bookmarked         map[int64]bool
+	Votes              map[int64]int
+	Reacted            map[int64][]string
+	CanReply           map[int64]bool
+	ShowSensitiveMedia bool
+	HashtagReasons     map[int64]string
+	// quoted trills the requestor can't see: their author blocked them or was blocked, is private and not
+	// followed, or has deactivated
+	HiddenQuotes map[int64]bool
+}
+
+// Liking a trill that's already liked does nothing
+func LikeTrill(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		like := TrillLike{Username: username, TrillID: trillID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return incrementTrillCounter(tx, "like_count", result.RowsAffected, trillID)
+	})
+}
+
+// Unliking a trill that isn't liked does nothing
+func UnlikeTrill(ctx context.Context, username string, trillID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("username = ? AND trill_id = ?", username, trillID).Delete(&TrillLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return incrementTrillCounter(tx, "like_count", -result.RowsAffected, trillID)
+	})
+}
+
+// Looks up the requestor's likes, retrills, bookmarks, poll votes, reactions, and which they can reply to for the trills, along with the retrilled and quoted trills inside them
+func GetTrillViewer(ctx context.Context, requestor string, trills []Trill) (*TrillViewer, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var trillIDs []int64
+	var quotedIDs []int64
+	var collected []*Trill
+	var collect func(trill *Trill)
+	collect = func(trill *Trill) {
+		trillIDs = append(trillIDs, trill.TrillID)
+		collected = append(collected, trill)
+		if trill.RetrillOf != nil {
+			collect(trill.RetrillOf)
+		}
+		if trill.QuoteOf != nil {
+			quotedIDs = append(quotedIDs, trill.QuoteOf.TrillID)
+			collect(trill.QuoteOf)
+		}
+	}
+	for i := range trills {
+		collect(&trills[i])
+	}
+
+	viewer := &TrillViewer{Liked: make(map[int64]bool), Retrilled: make(map[int64]bool), Bookmarked: make(map[int64]bool),
+		Votes: make(map[int64]int), Reacted: make(map[int64][]string), CanReply: make(map[int64]bool), HiddenQuotes: make(map[int64]bool)}
+	if len(trillIDs) == 0 {
+		return viewer, nil
+	}
+
+	var liked []int64
+	if err := db.Model(&TrillLike{}).Where("username = ? AND trill_id IN ?", requestor, trillIDs).
+		Pluck("trill_id", &liked).Error; err != nil {
+		return nil, err
+	}
+	for _, trillID := range liked {
+		viewer.Liked[trillID] = true
+	}
+
+	var retrilled []int64
+	if err := db.Model(&Trill{}).Where("username = ? AND retrill_of_id IN ?", requestor, trillIDs).
+		Pluck("retrill_of_id", &retrilled).Error; err != nil {
+		return nil, err
+	}
+	for _, trillID := range retrilled {
+		viewer.Retrilled[trillID] = true
+	}
+
+	var bookmarked []int64
+	if err := db.Model(&Bookmark{}).Where("username = ? AND trill_id IN ?", requestor, trillIDs).
+		Pluck("trill_id", &bookmarked).Error; err != nil {
+		return nil, err
+	}
+	for _, trillID := range bookmarked {
+		viewer.Bookmarked[trillID] = true
+	}
+
+	var votes []PollVote
+	if err := db.Where("username = ? AND trill_id IN ?", requestor, trillIDs).Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	for _, vote := range votes {
+		viewer.Votes[vote.TrillID] = vote.Position
+	}
+
+	if viewer.Reacted, err = getReacted(db, requestor, trillIDs); err != nil {
+		return nil, err
+	}
+
+	if viewer.CanReply, err = repliableTrills(db, requestor, collected); err != nil {
+		return nil, err
+	}
+
+	if len(quotedIDs) > 0 {
+		var visible []int64
+		if err := visibleTrills(db.Model(&Trill{}), db, requestor).Where("trill_id IN ?", quotedIDs).
+			Pluck("trill_id", &visible).Error; err != nil {
+			return nil, err
+		}
+		shown := make(map[int64]bool, len(visible))
+		for _, trillID := range visible {
+			shown[trillID] = true
+		}
+		for _, trillID := range quotedIDs {
+			viewer.HiddenQuotes[trillID] = !shown[trillID]
+		}
+	}
+
+	settings, err := GetUserSettings(ctx, requestor)
+	if err != nil {
+		return nil, err
+	}
+	viewer.ShowSensitiveMedia = settings.ShowSensitiveMedia
+
+	return viewer, nil
+}
+
+// Someone who liked or retrilled a trill, with when they did, which is also the keyset sort value
+type Engager struct {
+	User      `gorm:"embedded"`
+	EngagedAt time.Time
+}
+
+// Who liked the trill, most recent first, keyset paginated on when they liked it
+func GetTrillLikers(ctx context.Context, trillID int64, requestor string, limit int, cursor *Cursor) (*[]Engager, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Model(&User{}).Select("users.*, trill_likes.created_at AS engaged_at").
+		Joins("JOIN trill_likes ON trill_likes.username = users.username").Where("trill_likes.trill_id = ?", trillID)
+	return getEngagers(query, db, "trill_likes.created_at", requestor, limit, cursor)
+}
+
+// Who retrilled the trill, most recent first, keyset paginated on when they retrilled it
+func GetRetrillers(ctx context.Context, trillID int64, requestor string, limit int, cursor *Cursor) (*[]Engager, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	query := db.Model(&User{}).Select("users.*, trills.created_at AS engaged_at").
+		Joins("JOIN trills ON trills.username = users.username").Where("trills.retrill_of_id = ?", trillID)
+	return getEngagers(query, db, "trills.created_at", requestor, limit, cursor)
+}
+
+// Pages through the users the query finds in either direction, newest engagedColumn first with username
+// breaking ties. Only users the requestor can see are listed.
+func getEngagers(query *gorm.DB, db *gorm.DB, engagedColumn string, requestor string, limit int, cursor *Cursor) (*[]Engager, Cursors, error) {
+	keyset := pagination.Keyset{Value: engagedColumn, Key: "users.username", KeyAscending: true, Time: true}
+
+	var engagers []Engager
+	if err := keyset.Apply(visibleUsers(query, db, requestor), cursor, limit).Find(&engagers).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	engagers, cursors := pagination.Trim(engagers, cursor, limit, func(engager *Engager) Cursor {
+		return pagination.TimeCursor(engager.EngagedAt, engager.Username)
+	})
+	return &engagers, cursors, nil
+}
+
+// The trills the user has liked, most recently liked first, keyset paginated in either direction on when
+// they liked each one. Fails with a 403 HTTPError if there's a block between the users, the account is
+// private and the requestor doesn't follow it, or the user hides their likes from everyone else. Trills the
+// requestor can't see and trills by accounts they've muted are left out.
+func GetLikedTrills(ctx context.Context, requestor string, username string, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	user, err := GetUser(ctx, username)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	if blocked, err := IsBlocked(ctx, requestor, user.Username); err != nil {
+		return nil, Cursors{}, err
+	} else if blocked {
+		return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorBlocked}
+	}
+	if canView, err := CanViewUser(ctx, requestor, user); err != nil {
+		return nil, Cursors{}, err
+	} else if !canView {
+		return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorPrivateAccount}
+	}
+	if requestor != user.Username {
+		settings, err := GetUserSettings(ctx, user.Username)
+		if err != nil {
+			return nil, Cursors{}, err
+		} else if !settings.ShowLikedTrills {
+			return nil, Cursors{}, &HTTPError{Code: http.StatusForbidden, Err: ErrorLikesHidden}
+		}
+	}
+
+	visible := excludeMuted(visibleTrills(db.Model(&Trill{}).Select("trill_id"), db, requestor), db, "username", requestor)
+	query := db.Where("username = ? AND trill_id IN (?)", user.Username, visible)
+	keyset := pagination.Keyset{Value: "created_at", Key: "trill_id", Time: true}
+
+	var likes []TrillLike
+	if err := keyset.Apply(query, cursor, limit).Find(&likes).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	likes, cursors := pagination.Trim(likes, cursor, limit, func(like *TrillLike) Cursor {
+		return pagination.TimeCursor(like.CreatedAt, strconv.FormatInt(like.TrillID, 10))
+	})
+	trills := []Trill{}
+	if len(likes) == 0 {
+		return &trills, cursors, nil
+	}
+
+	trillIDs := make([]int64, len(likes))
+	for i, like := range likes {
+		trillIDs[i] = like.TrillID
+	}
+	var found []Trill
+	if err := preloadTrills(db).Where("trill_id IN ?", trillIDs).Find(&found).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	// back into the order they were liked in
+	byID := make(map[int64]*Trill, len(found))
+	for i := range found {
+		byID[found[i].TrillID] = &found[i]
+	}
+	for _, trillID := range trillIDs {
+		if trill, ok := byID[trillID]; ok {
+			trills = append(trills, *trill)
+		}
+	}
+
+	return &trills, cursors, nil
+}
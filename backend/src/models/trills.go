@@ -0,0 +1,410 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"trill/src/pagination"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A post. A retrill is a row of its own with no content that points at the original, so it shows up in
+// the retrilling user's timeline in the order it was made.
+type Trill struct {
+	TrillID        int64     `gorm:"primarykey;autoIncrement"`
+	Username       string    `gorm:"type:varchar(128);index"`
+	Text           string    `gorm:"type:text"` // can be edited for a while, with earlier versions kept as revisions
+	ParentID       *int64    `gorm:"index"`     // the trill a reply answers
+	ConversationID int64     `gorm:"index"`     // the ID of the trill that started the thread
+	RetrillOfID    *int64    `gorm:"index"`
+	QuoteOfID      *int64    `gorm:"index"` // the trill a quote embeds under its own content
+	ReplyCount     int64     `gorm:"not null;default:0"`
+	RetrillCount   int64     `gorm:"not null;default:0"`
+	QuoteCount     int64     `gorm:"not null;default:0"`
+	LikeCount      int64     `gorm:"not null;default:0"`
+	ViewCount      int64     `gorm:"not null;default:0"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"` // for an import, when it was first posted elsewhere
+	UpdatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	Sensitive      bool      `gorm:"not null;default:false"` // covers the text and media; Media can be marked on its own
+	ContentWarning string    `gorm:"type:varchar(100)"`
+	ReplyAudience  string    `gorm:"type:varchar(16);not null;default:everyone"`
+	EditedAt       *time.Time
+	LinkPreviewID  *int64
+	LinkWarning    string          `gorm:"type:varchar(32)"` // set when a scan flags one of Links as malware or phishing
+	ImportedFrom   string          `gorm:"type:varchar(16)"`
+	User           User            `gorm:"foreignKey:Username;references:Username"`
+	RetrillOf      *Trill          `gorm:"foreignKey:RetrillOfID;references:TrillID"`
+	QuoteOf        *Trill          `gorm:"foreignKey:QuoteOfID;references:TrillID"`
+	Mentions       []Mention       `gorm:"foreignKey:TrillID;references:TrillID"`
+	Media          []Media         `gorm:"foreignKey:TrillID;references:TrillID"`             // uploads in the content bucket
+	Poll           *Poll           `gorm:"foreignKey:TrillID;references:TrillID"`             // in place of media
+	LinkPreview    *LinkPreview    `gorm:"foreignKey:LinkPreviewID;references:LinkPreviewID"` // the first link's card, once the linkPreviews worker has fetched it
+	Links          []ShortLink     `gorm:"foreignKey:TrillID;references:TrillID"`             // every link in the text, so clicks can be counted
+	Reactions      []ReactionCount `gorm:"foreignKey:TrillID;references:TrillID"`             // emoji reactions, besides likes
+}
+
+const (
+	TrillMediaUploadPrefix = "trill-media/"
+)
+
+var (
+	ErrorTrillNotFound    error = errors.New("trill does not exist")
+	ErrorNotTrillAuthor   error = errors.New("only the author can delete a trill")
+	ErrorTrillMediaOwner  error = errors.New("media does not belong to user")
+	ErrorTrillMediaAbsent error = errors.New("media upload not found")
+	ErrorAlreadyRetrilled error = errors.New("you already retrilled this trill")
+	ErrorNotRetrilled     error = errors.New("you haven't retrilled this trill")
+	ErrorRetrillPrivate   error = errors.New("trills from private accounts can't be retrilled")
+	ErrorQuotePrivate     error = errors.New("trills from private accounts can't be quoted")
+)
+
+// A fresh key for the user to upload an image for a trill to
+func NewTrillMediaKey(username string, ext string) string {
+	return fmt.Sprintf("%s%s-%s%s", TrillMediaUploadPrefix, username, uuid.NewString(), ext)
+}
+
+// Looks up a pending trill's uploads and GIF and builds the trill it would post, failing with an
+// HTTPError if any of it can't be used; see ValidateTrillMedia and ResolveGIF. The trills it replies
+// to or quotes are left to the caller to check.
+func PrepareTrill(ctx context.Context, username string, pending *PendingTrill) (*Trill, error) {
+	media, err := ValidateTrillMedia(ctx, username, pending.Media)
+	if err != nil {
+		return nil, err
+	}
+	if pending.GIF != "" {
+		if len(media) > 0 {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorTrillMediaMixed}
+		}
+		gif, err := ResolveGIF(ctx, username, pending.GIF)
+		if err != nil {
+			return nil, err
+		}
+		media = []Media{*gif}
+	}
+	if err := ApplyAltText(ctx, username, media, pending.AltText); err != nil {
+		return nil, err
+	}
+
+	for i := range media {
+		media[i].Sensitive = pending.SensitiveMedia
+	}
+
+	trill := Trill{
+		Username:       username,
+		Text:           pending.Text,
+		Media:          media,
+		ParentID:       pending.ParentID,
+		QuoteOfID:      pending.QuoteOfID,
+		ContentWarning: strings.TrimSpace(pending.ContentWarning),
+		ReplyAudience:  pending.ReplyAudience,
+	}
+	if trill.ReplyAudience == "" {
+		trill.ReplyAudience = ReplyAudienceEveryone
+	}
+	// a content warning only makes sense on a sensitive trill
+	trill.Sensitive = pending.Sensitive || trill.ContentWarning != ""
+	if len(pending.PollOptions) > 0 {
+		if len(media) > 0 {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Err: ErrorPollWithMedia}
+		}
+		trill.Poll = NewPoll(pending.PollOptions, time.Duration(pending.PollDurationMinutes)*time.Minute)
+	}
+
+	return &trill, nil
+}
+
+func CreateTrill(ctx context.Context, trill *Trill) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return createTrill(tx, trill)
+	})
+	if err != nil {
+		return err
+	}
+
+	queueLinkPreview(ctx, trill)
+	queueLinkScan(ctx, trill)
+	queueTimelineFanout(ctx, trill)
+	return nil
+}
+
+func createTrill(tx *gorm.DB, trill *Trill) error {
+	// a reply joins its parent's conversation, anything else starts its own
+	if trill.ParentID != nil {
+		var parent Trill
+		if result := tx.Where("trill_id = ?", *trill.ParentID).Limit(1).Find(&parent); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+		}
+		trill.ConversationID = parent.ConversationID
+	}
+
+	if err := tx.Omit(clause.Associations).Create(trill).Error; err != nil {
+		return err
+	}
+	if err := attachMedia(tx, trill); err != nil {
+		return err
+	}
+	if err := createPoll(tx, trill); err != nil {
+		return err
+	}
+
+	if trill.ParentID == nil {
+		trill.ConversationID = trill.TrillID
+		if err := tx.Model(trill).UpdateColumn("conversation_id", trill.TrillID).Error; err != nil {
+			return err
+		}
+	} else if err := incrementTrillCounter(tx, "reply_count", 1, *trill.ParentID); err != nil {
+		return err
+	}
+	if trill.QuoteOfID != nil {
+		if err := incrementTrillCounter(tx, "quote_count", 1, *trill.QuoteOfID); err != nil {
+			return err
+		}
+	}
+	if err := tagTrill(tx, trill.TrillID, ParseHashtags(trill.Text)); err != nil {
+		return err
+	}
+	// handles from elsewhere aren't usernames here
+	if trill.ImportedFrom == "" {
+		if err := mentionUsers(tx, trill); err != nil {
+			return err
+		}
+	}
+	if err := shortenLinks(tx, trill); err != nil {
+		return err
+	}
+	if err := notifyMentions(tx, trill); err != nil {
+		return err
+	}
+
+	return incrementUserCounter(tx, "trill_count", 1, trill.Username)
+}
+
+// Loads the author, mentions, media, poll, link preview, short links, and reaction counts, and the trill a retrill or quote points at, for each trill the query finds
+func preloadTrills(db *gorm.DB) *gorm.DB {
+	for _, prefix := range []string{"", "RetrillOf.", "RetrillOf.QuoteOf.", "QuoteOf."} {
+		db = db.Preload(prefix+"User").Preload(prefix+"Mentions").Preload(prefix+"Media", orderMedia).
+			Preload(prefix+"Poll.Options", orderPollOptions).Preload(prefix+"LinkPreview").Preload(prefix+"Links").
+			Preload(prefix+"Reactions", orderReactionCounts)
+	}
+	return db
+}
+
+// Like incrementUserCounter, for the counters on a trill
+func incrementTrillCounter(tx *gorm.DB, column string, delta int64, trillID int64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	return tx.Model(&Trill{}).Where("trill_id = ?", trillID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// Trills by deactivated accounts count as not existing
+func GetTrill(ctx context.Context, trillID int64) (*Trill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var trill Trill
+	if result := preloadTrills(db).Where("trill_id = ? AND username NOT IN (?)", trillID, deactivatedUsers(db)).
+		Limit(1).Find(&trill); result.Error != nil {
+		return nil, result.Error
+	} else if result.RowsAffected == 0 {
+		return nil, &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+	}
+
+	return &trill, nil
+}
+
+// Trills sort newest first on their ID alone, since IDs only go up
+var trillKeyset = pagination.Keyset{Value: "trill_id"}
+
+func trillPosition(trill *Trill) Cursor {
+	return Cursor{Value: trill.TrillID}
+}
+
+// The user's trills and retrills newest first, keyset paginated on the trill ID in either direction
+func GetUserTrills(ctx context.Context, username string, limit int, cursor *Cursor) (*[]Trill, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	var trills []Trill
+	query := preloadTrills(db).Where("username = ?", username)
+	if err := trillKeyset.Apply(query, cursor, limit).Find(&trills).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	trills, cursors := pagination.Trim(trills, cursor, limit, trillPosition)
+	return &trills, cursors, nil
+}
+
+// Fails with a 404 HTTPError if the trill doesn't exist, or a 403 if the requestor didn't write it
+func DeleteTrill(ctx context.Context, trillID int64, requestor string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var trill Trill
+	if result := db.Where("trill_id = ?", trillID).Limit(1).Find(&trill); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorTrillNotFound}
+	} else if trill.Username != requestor {
+		return &HTTPError{Code: http.StatusForbidden, Err: ErrorNotTrillAuthor}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteTrill(tx, &trill)
+	})
+}
+
+// Replies stay in the conversation under the trill's tombstone, but retrills go with the original
+func deleteTrill(tx *gorm.DB, trill *Trill) error {
+	result := tx.Where("trill_id = ?", trill.TrillID).Delete(&Trill{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if trill.RetrillOfID != nil {
+		return incrementTrillCounter(tx, "retrill_count", -result.RowsAffected, *trill.RetrillOfID)
+	}
+	if result.RowsAffected > 0 {
+		if err := createTombstones(tx, []Trill{*trill}); err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&TrillLike{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&Bookmark{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&TrillHashtag{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&Mention{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&Media{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&Notification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&TimelineEntry{}).Error; err != nil {
+		return err
+	}
+	if err := deletePolls(tx, []int64{trill.TrillID}); err != nil {
+		return err
+	}
+	if err := deleteShortLinks(tx, []int64{trill.TrillID}); err != nil {
+		return err
+	}
+	if err := deleteReactions(tx, []int64{trill.TrillID}); err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&TrillTranslation{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&TrillRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("trill_id = ?", trill.TrillID).Delete(&ImportedTweet{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&User{}).Where("pinned_trill_id = ?", trill.TrillID).UpdateColumn("pinned_trill_id", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("retrill_of_id = ?", trill.TrillID).Delete(&Trill{}).Error; err != nil {
+		return err
+	}
+	if trill.ParentID != nil {
+		if err := incrementTrillCounter(tx, "reply_count", -result.RowsAffected, *trill.ParentID); err != nil {
+			return err
+		}
+		if err := decrementTombstoneReplies(tx, result.RowsAffected, *trill.ParentID); err != nil {
+			return err
+		}
+	}
+	if trill.QuoteOfID != nil {
+		if err := incrementTrillCounter(tx, "quote_count", -result.RowsAffected, *trill.QuoteOfID); err != nil {
+			return err
+		}
+	}
+
+	return incrementUserCounter(tx, "trill_count", -result.RowsAffected, trill.Username)
+}
+
+// Reposts the original to the user's timeline, failing with a 409 HTTPError if they already have
+func CreateRetrill(ctx context.Context, username string, original *Trill) (*Trill, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.User.IsPrivate {
+		return nil, &HTTPError{Code: http.StatusForbidden, Err: ErrorRetrillPrivate}
+	}
+
+	retrill := Trill{
+		Username:       username,
+		RetrillOfID:    &original.TrillID,
+		ConversationID: original.ConversationID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Trill{}).Where("username = ? AND retrill_of_id = ?", username, original.TrillID).Count(&count).Error; err != nil {
+			return err
+		} else if count > 0 {
+			return &HTTPError{Code: http.StatusConflict, Err: ErrorAlreadyRetrilled}
+		}
+
+		if err := tx.Omit(clause.Associations).Create(&retrill).Error; err != nil {
+			return err
+		}
+
+		return incrementTrillCounter(tx, "retrill_count", 1, original.TrillID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queueTimelineFanout(ctx, &retrill)
+	return &retrill, nil
+}
+
+// Takes the user's retrill of the original back off their timeline, failing with a 404 HTTPError if there isn't one
+func DeleteRetrill(ctx context.Context, username string, originalID int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var retrill Trill
+	if result := db.Where("username = ? AND retrill_of_id = ?", username, originalID).Limit(1).Find(&retrill); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return &HTTPError{Code: http.StatusNotFound, Err: ErrorNotRetrilled}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteTrill(tx, &retrill)
+	})
+}
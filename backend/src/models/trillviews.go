@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"gorm.io/gorm"
+)
+
+// What gets queued for the trillViews worker: the trills one response showed the viewer
+type TrillImpressionEvent struct {
+	Viewer   string  `json:"viewer"`
+	TrillIDs []int64 `json:"trill_ids"`
+}
+
+// Queues an impression for each trill the viewer was shown, instead of writing them, so reading trills
+// doesn't wait on an update per trill. Authors seeing their own trills don't count, and seeing a retrill
+// counts for the original.
+func EnqueueTrillImpressions(ctx context.Context, viewer string, trills []Trill) error {
+	seen := map[int64]bool{}
+	var trillIDs []int64
+	for _, trill := range trills {
+		if trill.RetrillOf != nil {
+			trill = *trill.RetrillOf
+		}
+		if trill.Username != viewer && !seen[trill.TrillID] {
+			seen[trill.TrillID] = true
+			trillIDs = append(trillIDs, trill.TrillID)
+		}
+	}
+	if len(trillIDs) == 0 {
+		return nil
+	}
+
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(TrillImpressionEvent{Viewer: viewer, TrillIDs: trillIDs})
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().TrillViewQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Adds up a batch of impressions and writes each trill's total once, so a trill that's being read a lot
+// costs one update per batch rather than one per view. Counts are approximate: a batch that fails part
+// way is retried whole.
+func RecordTrillImpressions(ctx context.Context, events []TrillImpressionEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	totals := map[int64]int64{}
+	for _, event := range events {
+		for _, trillID := range event.TrillIDs {
+			totals[trillID]++
+		}
+	}
+
+	// updated in ID order so concurrent batches lock rows in the same order
+	trillIDs := make([]int64, 0, len(totals))
+	for trillID := range totals {
+		trillIDs = append(trillIDs, trillID)
+	}
+	sort.Slice(trillIDs, func(i, j int) bool { return trillIDs[i] < trillIDs[j] })
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, trillID := range trillIDs {
+			if err := incrementTrillCounter(tx, "view_count", totals[trillID], trillID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
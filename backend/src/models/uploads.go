@@ -0,0 +1,243 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// What gets queued for the avatarProcessor worker when a user confirms a profile picture upload
+type AvatarEvent struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+}
+
+const (
+	ContentBucket    = "trill-content"
+	ContentBucketURL = "https://trill-content.s3.amazonaws.com/"
+
+	AvatarUploadPrefix    = "profile-pictures/"
+	ProcessedAvatarPrefix = "avatars/"
+	BannerUploadPrefix    = "banners/"
+)
+
+var (
+	PresignExpiration         = 15 * time.Minute
+	AvatarSize                = 400
+	AvatarThumbnailSize       = 96
+	MaxAvatarBytes      int64 = 10 << 20
+	MaxBannerBytes      int64 = 5 << 20
+	MaxTrillImageBytes  int64 = 10 << 20
+	// the most keys S3 takes in one DeleteObjects call
+	maxDeleteObjects = 1000
+)
+
+var (
+	ErrorUnsupportedImageType error = errors.New("unsupported image type, expected jpeg, png, gif, or webp")
+	ErrorObjectTooLarge       error = errors.New("uploaded file is too large")
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func GetImageExtension(contentType string) (string, error) {
+	if ext, ok := imageExtensions[contentType]; ok {
+		return ext, nil
+	}
+
+	return "", ErrorUnsupportedImageType
+}
+
+// Returns a URL the client can PUT the object to directly, without going through the lambda
+func PresignUpload(ctx context.Context, key string, contentType string) (string, error) {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	presignClient := s3.NewPresignClient(s3Client)
+	presigned, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(ContentBucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}, s3.WithPresignExpires(PresignExpiration))
+	if err != nil {
+		return "", err
+	}
+
+	return presigned.URL, nil
+}
+
+func ContentObjectExists(ctx context.Context, key string) (bool, error) {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(ContentBucket),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func QueueAvatarProcessing(ctx context.Context, event *AvatarEvent) error {
+	sqsClient, err := InitSQSClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(utils.GetSecrets().AvatarQueueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+// Only writes the two picture columns, so it can't clobber anything else changed on the user meanwhile
+func SetProfilePicture(ctx context.Context, username string, picture string, thumbnail string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ?", username).
+		Updates(map[string]interface{}{"profile_picture": picture, "profile_picture_thumbnail": thumbnail}).Error
+}
+
+// Points the user at the processed variants of their upload, as long as the upload is still their profile
+// picture. Returns false if they've confirmed a different one since.
+func ReplaceProfilePicture(ctx context.Context, username string, upload string, picture string, thumbnail string) (bool, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Model(&User{}).Where("username = ? AND profile_picture = ?", username, upload).
+		Updates(map[string]interface{}{"profile_picture": picture, "profile_picture_thumbnail": thumbnail})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func GetProcessedAvatarKey(key string, size int) string {
+	name := strings.TrimSuffix(path.Base(key), path.Ext(key))
+	return fmt.Sprintf("%s%s-%d.webp", ProcessedAvatarPrefix, name, size)
+}
+
+// Reads at most maxBytes of the object, failing with ErrorObjectTooLarge if there's more
+func GetContentObject(ctx context.Context, key string, maxBytes int64) ([]byte, error) {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ContentBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(object.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(buf)) > maxBytes {
+		return nil, ErrorObjectTooLarge
+	}
+
+	return buf, nil
+}
+
+// Deletes the objects and everything under the prefixes. Keys that don't exist are skipped.
+func DeleteContentObjects(ctx context.Context, keys []string, prefixes []string) error {
+	if len(keys) == 0 && len(prefixes) == 0 {
+		return nil
+	}
+
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range prefixes {
+		paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(ContentBucket),
+			Prefix: aws.String(prefix),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			for _, object := range page.Contents {
+				keys = append(keys, aws.ToString(object.Key))
+			}
+		}
+	}
+
+	for start := 0; start < len(keys); start += maxDeleteObjects {
+		end := start + maxDeleteObjects
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objects := make([]types.ObjectIdentifier, end-start)
+		for i, key := range keys[start:end] {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		if _, err := s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(ContentBucket),
+			Delete: &types.Delete{Objects: objects, Quiet: true},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PutContentObject(ctx context.Context, key string, contentType string, body []byte) error {
+	s3Client, err := InitS3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(ContentBucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        bytes.NewReader(body),
+	})
+	return err
+}
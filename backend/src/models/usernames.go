@@ -0,0 +1,235 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"gorm.io/gorm"
+)
+
+// Maps every handle a user has given up to the handle they currently use
+type UsernameHistory struct {
+	OldUsername string    `gorm:"type:varchar(128);primarykey"`
+	Username    string    `gorm:"type:varchar(128);index"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+var (
+	ErrorUsernameInvalid     error = errors.New("usernames must be 3-32 characters of letters, numbers, or underscores")
+	ErrorUsernameUnavailable error = errors.New("username is already taken")
+	ErrorUsernameReserved    error = errors.New("username is not allowed")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// Handles that could pass for the app itself or for a page on the site
+var reservedUsernames = map[string]bool{
+	"about": true, "abuse": true, "account": true, "admin": true, "administrator": true,
+	"albums": true, "api": true, "app": true, "auth": true, "avatar": true, "banner": true,
+	"batch": true, "billing": true, "blog": true, "contact": true, "deactivate": true, "dev": true,
+	"explore": true, "export": true, "favoritealbums": true, "feedback": true, "follows": true,
+	"help": true, "helpdesk": true, "home": true, "info": true, "likes": true,
+	"listenlateralbums": true, "login": true, "logout": true, "mail": true, "me": true, "mod": true,
+	"moderator": true, "news": true, "noreply": true, "null": true, "official": true,
+	"postmaster": true, "privacy": true, "reactivate": true, "reports": true, "reviews": true,
+	"root": true, "search": true, "security": true, "settings": true, "signin": true, "signup": true,
+	"staff": true, "status": true, "support": true, "system": true, "team": true, "terms": true,
+	"trill": true, "trillapp": true, "trillofficial": true, "trillsupport": true, "undefined": true,
+	"user": true, "username": true, "users": true, "verified": true, "webmaster": true, "www": true,
+}
+
+// Undoes the usual tricks for slipping a word past the denylist, e.g. "b4d_w0rd" -> "badword"
+var usernameNormalizer = strings.NewReplacer("_", "", "0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b")
+
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return &HTTPError{Code: http.StatusBadRequest, Err: ErrorUsernameInvalid}
+	} else if IsUsernameReserved(username) {
+		return &HTTPError{Code: http.StatusBadRequest, Err: ErrorUsernameReserved}
+	}
+
+	return nil
+}
+
+// The form usernames are compared in, so @Alice and @alice are the same account
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Keeps normalized_username in step with every create and save
+func (user *User) BeforeSave(tx *gorm.DB) error {
+	user.NormalizedUsername = NormalizeUsername(user.Username)
+	return nil
+}
+
+// true if the handle is reserved for the app, or contains a word from the USERNAME_DENYLIST setting
+func IsUsernameReserved(username string) bool {
+	lower := strings.ToLower(username)
+	if reservedUsernames[strings.ReplaceAll(lower, "_", "")] {
+		return true
+	}
+
+	normalized := usernameNormalizer.Replace(lower)
+	for _, word := range strings.Split(utils.GetSecrets().UsernameDenylist, ",") {
+		word = usernameNormalizer.Replace(strings.ToLower(strings.TrimSpace(word)))
+		if word != "" && strings.Contains(normalized, word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the current handle for a username in its stored casing, following any renames
+func ResolveUsername(ctx context.Context, username string) (string, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := NormalizeUsername(username)
+	var user User
+	if result := db.Unscoped().Select("username").Where("normalized_username = ?", normalized).Limit(1).Find(&user); result.Error != nil {
+		return "", result.Error
+	} else if result.RowsAffected > 0 {
+		return user.Username, nil
+	}
+
+	var history UsernameHistory
+	if result := db.Where("LOWER(old_username) = ?", normalized).Limit(1).Find(&history); result.Error != nil {
+		return "", result.Error
+	} else if result.RowsAffected == 0 {
+		return username, nil
+	}
+
+	return history.Username, nil
+}
+
+// A username is available if it isn't reserved, no user (even a deleted one) has it in any casing, and nobody else used to have it
+func IsUsernameAvailable(ctx context.Context, username string, requestor string) (bool, error) {
+	if IsUsernameReserved(username) {
+		return false, nil
+	}
+
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var userCount int64
+	normalized := NormalizeUsername(username)
+	if err := db.Unscoped().Model(&User{}).Where("normalized_username = ?", normalized).Count(&userCount).Error; err != nil {
+		return false, err
+	} else if userCount > 0 {
+		return false, nil
+	}
+
+	var historyCount int64
+	if err := db.Model(&UsernameHistory{}).Where("LOWER(old_username) = ? AND username <> ?", normalized, requestor).Count(&historyCount).Error; err != nil {
+		return false, err
+	}
+
+	return historyCount == 0, nil
+}
+
+// Cognito usernames are immutable, so a handle also has to be free there to be claimed
+func IsCognitoUsernameAvailable(ctx context.Context, username string) (bool, error) {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = cognitoClient.Client.AdminGetUser(ctx, &cognitoidentityprovider.AdminGetUserInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Username:   aws.String(username),
+	})
+	var notFound *types.UserNotFoundException
+	if errors.As(err, &notFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// Moves the user and everything that references them to the new handle, records the old one, and sets
+// the new handle as their preferred_username in Cognito. Cognito is updated last, inside the transaction,
+// so a failure there rolls the rename back; if the commit itself fails afterwards, Cognito is put back.
+func ChangeUsername(ctx context.Context, cognitoUsername string, oldUsername string, newUsername string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	cognitoUpdated := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// the row keeps its id, and every table with a foreign key on users.username picks the new handle
+		// up through ON UPDATE CASCADE
+		result := tx.Model(&User{}).Where("username = ?", oldUsername).
+			Updates(map[string]interface{}{"username": newUsername, "normalized_username": NormalizeUsername(newUsername)})
+		if result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// tables that store a handle without a foreign key
+		for _, model := range []interface{}{&Reaction{}, &ListMember{}, &Story{}, &StoryView{}, &TrillDeletion{}, &TwitterImport{}, &ImportedTweet{}, &RevokedToken{}, &DeviceName{}, &TimelineEntry{}} {
+			if err := tx.Model(model).Where("username = ?", oldUsername).Update("username", newUsername).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&List{}).Where("owner = ?", oldUsername).Update("owner", newUsername).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&TimelineEntry{}).Where("author = ?", oldUsername).Update("author", newUsername).Error; err != nil {
+			return err
+		}
+
+		// reclaiming one of the user's own old handles
+		if err := tx.Where("LOWER(old_username) = ?", NormalizeUsername(newUsername)).Delete(&UsernameHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&UsernameHistory{OldUsername: oldUsername, Username: newUsername}).Error; err != nil {
+			return err
+		}
+
+		if err := UpdateCognitoPreferredUsername(ctx, cognitoUsername, newUsername); err != nil {
+			return err
+		}
+		cognitoUpdated = true
+		return nil
+	})
+	if err != nil && cognitoUpdated {
+		if revertErr := UpdateCognitoPreferredUsername(ctx, cognitoUsername, oldUsername); revertErr != nil {
+			fmt.Printf("failed to restore preferred_username %s for %s: %s\n", oldUsername, cognitoUsername, revertErr.Error())
+		}
+	}
+	return err
+}
+
+func UpdateCognitoPreferredUsername(ctx context.Context, cognitoUsername string, preferredUsername string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.AdminUpdateUserAttributes(ctx, &cognitoidentityprovider.AdminUpdateUserAttributesInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Username:   aws.String(cognitoUsername),
+		UserAttributes: []types.AttributeType{
+			{Name: aws.String("preferred_username"), Value: aws.String(preferredUsername)},
+		},
+	})
+	return err
+}
@@ -5,54 +5,59 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"time"
+	"trill/src/pagination"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type PrivateCognitoUser struct {
-	Email string
-}
-
 type User struct {
-	Username       string `json:"username" gorm:"varchar(128);primarykey"`
-	Nickname       string `json:"nickname" gorm:"varchar(128)"`
-	Bio            string `json:"bio" gorm:"varchar(1024)"`
-	ProfilePicture string `json:"profile_picture" gorm:"varchar(512)"`
+	ID                      string         `json:"id" gorm:"type:char(36);primarykey"`
+	Username                string         `json:"username" gorm:"type:varchar(128);uniqueIndex;not null"`
+	NormalizedUsername      string         `json:"-" gorm:"type:varchar(128);uniqueIndex"`
+	Nickname                string         `json:"nickname" gorm:"varchar(128)"`
+	DisplayName             string         `json:"display_name" gorm:"varchar(128)"`
+	Pronouns                string         `json:"pronouns" gorm:"varchar(64)"`
+	Bio                     string         `json:"bio" gorm:"varchar(1024)"`
+	ProfilePicture          string         `json:"profile_picture" gorm:"varchar(512)"`
+	ProfilePictureThumbnail string         `json:"profile_picture_thumbnail" gorm:"varchar(512)"`
+	BannerImage             string         `json:"banner_image" gorm:"varchar(512)"`
+	Location                string         `json:"location" gorm:"varchar(128)"`
+	Website                 string         `json:"website" gorm:"varchar(255)"`
+	Birthday                *time.Time     `json:"-" gorm:"type:date"`
+	Email                   string         `json:"-" gorm:"type:varchar(320)"`
+	EmailVerified           bool           `json:"-"`
+	IsPrivate               bool           `json:"is_private"`
+	Verified                bool           `json:"verified"`
+	FollowerCount           int64          `json:"follower_count" gorm:"not null;default:0"`
+	FollowingCount          int64          `json:"following_count" gorm:"not null;default:0"`
+	ReviewCount             int64          `json:"review_count" gorm:"not null;default:0"`
+	TrillCount              int64          `json:"trill_count" gorm:"not null;default:0"`
+	PinnedTrillID           *int64         `json:"-"`
+	ViewCount               int64          `json:"-" gorm:"not null;default:0"`
+	LastActiveAt            *time.Time     `json:"-"`
+	DeactivatedAt           *time.Time     `json:"-" gorm:"index"`
+	TokensRevokedAt         *time.Time     `json:"-"`
+	DeletedAt               gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
-func GetPrivateCognitoUser(ctx context.Context, authToken string) (*PrivateCognitoUser, error) {
-	cognitoClient, err := InitCognitoClient(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	cognitoUserInput := cognitoidentityprovider.GetUserInput{
-		AccessToken: &authToken,
-	}
-
-	cogInfo, err := cognitoClient.Client.GetUser(ctx, &cognitoUserInput)
-	if err != nil {
-		return nil, err
-	}
-
-	// get email from user attributes
-	email := ""
-	for _, v := range cogInfo.UserAttributes {
-		if *v.Name == "email" {
-			email = *v.Value
-		}
-	}
-
-	if len(email) == 0 {
-		return nil, errors.New("could not find user email")
+// Handles can change, so rows are keyed by an id that never does
+func (user *User) BeforeCreate(tx *gorm.DB) error {
+	if user.ID == "" {
+		user.ID = uuid.NewString()
 	}
-
-	return &PrivateCognitoUser{
-		Email: email,
-	}, nil
+	return nil
 }
 
+var (
+	ErrorPrivateAccount error = errors.New("this account is private")
+)
+
 func GetUser(ctx context.Context, username string) (*User, error) {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
@@ -61,7 +66,7 @@ func GetUser(ctx context.Context, username string) (*User, error) {
 
 	// find and get user info from db
 	var user User
-	result := db.Where("username = ?", username).First(&user)
+	result := db.Where("normalized_username = ? AND deactivated_at IS NULL", NormalizeUsername(username)).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, &HTTPError{Code: http.StatusNotFound, Err: errors.New("User not found in RDS")}
@@ -73,21 +78,28 @@ func GetUser(ctx context.Context, username string) (*User, error) {
 	return &user, nil
 }
 
-func GetUsers(ctx context.Context, usernames []string) (*[]User, error) {
+// Leaves out anyone the requestor has blocked or been blocked by
+func GetUsers(ctx context.Context, usernames []string, requestor string) (*[]User, error) {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	normalized := make([]string, len(usernames))
+	for i, username := range usernames {
+		normalized[i] = NormalizeUsername(username)
+	}
+
 	var users []User
-	if err := db.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+	query := db.Where("normalized_username IN ? AND deactivated_at IS NULL", normalized)
+	if err := excludeBlocked(query, db, "username", requestor).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
 	return &users, nil
 }
 
-func SearchUser(ctx context.Context, username string) (*[]User, error) {
+func SearchUser(ctx context.Context, username string, requestor string) (*[]User, error) {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -96,19 +108,106 @@ func SearchUser(ctx context.Context, username string) (*[]User, error) {
 	var users []User
 	// if err := db.Where("SOUNDEX(username) = SOUNDEX(?)", username).Limit(50).Find(&users).Error; err != nil {
 	// if err := db.Where("MATCH(username) AGAINST(? IN BOOLEAN MODE)", searchTerm).Limit(50).Find(&users).Error; err != nil {
-	if err := db.Where("username LIKE ?", "%"+username+"%").Find(&users).Error; err != nil {
+	// users without a settings row are discoverable by default
+	hidden := db.Model(&UserSettings{}).Select("username").Where("discoverable = ?", false)
+	query := db.Where("username LIKE ? AND username NOT IN (?) AND deactivated_at IS NULL", "%"+username+"%", hidden)
+	if err := excludeBlocked(query, db, "username", requestor).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return &users, nil
 }
 
+// Anyone can see a public account; a private one only its owner and approved followers can
+func CanViewUser(ctx context.Context, requestor string, user *User) (bool, error) {
+	if !user.IsPrivate || requestor == user.Username {
+		return true, nil
+	}
+
+	return IsFollowing(ctx, requestor, user.Username)
+}
+
+// Subquery of the private accounts whose content the requestor isn't allowed to see
+func hiddenPrivateUsers(db *gorm.DB, requestor string) *gorm.DB {
+	following := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	return db.Model(&User{}).Select("username").
+		Where("is_private = ? AND username <> ? AND username NOT IN (?)", true, requestor, following)
+}
+
+// Leaves out deactivated users, private accounts the requestor doesn't follow, and anyone they have a
+// block with, from a query on the users table
+func visibleUsers(query *gorm.DB, db *gorm.DB, requestor string) *gorm.DB {
+	query = query.Where("users.deactivated_at IS NULL AND users.username NOT IN (?)", hiddenPrivateUsers(db, requestor))
+	return excludeBlocked(query, db, "users.username", requestor)
+}
+
+// A search hit along with how well it matched, which is also its keyset sort value
+type RankedUser struct {
+	User      `gorm:"embedded"`
+	Relevance int64
+}
+
+// escapes LIKE wildcards, since underscores are valid in usernames
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Prefix and substring search on username and nickname. Users the requestor follows rank first,
+// then exact username matches, then username prefixes, then nickname prefixes, then everything else.
+func SearchUsers(ctx context.Context, search string, requestor string, limit int, cursor *Cursor) (*[]RankedUser, Cursors, error) {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+
+	escaped := likeEscaper.Replace(search)
+	followed := db.Model(&Follows{}).Select("following").Where("followee = ?", requestor)
+	relevance := "(CASE WHEN username IN (?) THEN 8 ELSE 0 END) + " +
+		"(CASE WHEN username = ? THEN 4 WHEN username LIKE ? THEN 2 WHEN nickname LIKE ? THEN 1 ELSE 0 END)"
+	relevanceArgs := []interface{}{followed, search, escaped + "%", escaped + "%"}
+
+	hidden := db.Model(&UserSettings{}).Select("username").Where("discoverable = ?", false)
+	query := db.Model(&User{}).
+		Select("users.*, "+relevance+" AS relevance", relevanceArgs...).
+		Where("(username LIKE ? OR nickname LIKE ?) AND username NOT IN (?) AND deactivated_at IS NULL", "%"+escaped+"%", "%"+escaped+"%", hidden)
+	query = excludeBlocked(query, db, "username", requestor)
+
+	var users []RankedUser
+	keyset := pagination.Keyset{Value: relevance, ValueArgs: relevanceArgs, Key: "username", KeyAscending: true}
+	if err := keyset.Apply(query, cursor, limit).Find(&users).Error; err != nil {
+		return nil, Cursors{}, err
+	}
+
+	users, cursors := pagination.Trim(users, cursor, limit, func(user *RankedUser) Cursor {
+		return Cursor{Value: user.Relevance, Key: user.Username}
+	})
+	return &users, cursors, nil
+}
+
+func SetUserVerified(ctx context.Context, username string, verified bool) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := db.Model(&User{}).Where("username = ?", username).Update("verified", verified)
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		// MySQL doesn't count rows that already had the value, so tell "unchanged" apart from "missing"
+		if _, err := GetUser(ctx, username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func UpdateUser(ctx context.Context, user *User) error {
 	db, err := GetDBFromContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	updatedUser := db.Save(&user)
+	// the counters and last_active_at are kept up to date elsewhere, so a stale copy mustn't overwrite them
+	updatedUser := db.Omit(append(userCounters, "last_active_at")...).Save(&user)
 	if updatedUser.Error != nil {
 		return updatedUser.Error
 	}
@@ -130,3 +229,259 @@ func CreateUser(ctx context.Context, user *User) error {
 		return nil
 	}
 }
+
+// Cognito can retry triggers, so an existing row is left untouched instead of failing the confirmation
+func CreateUserIfNotExists(ctx context.Context, user *User) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&user); res.Error != nil {
+		log.Println(res.Error.Error())
+		return res.Error
+	}
+
+	return nil
+}
+
+// Copies the email Cognito has for the user onto their row, so profile reads never have to ask
+// Cognito for it. Called from the Cognito triggers, which only know the user by their Cognito username.
+func SyncUserEmail(ctx context.Context, cognitoUsername string, email string, verified bool) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	username, err := ResolveUsername(ctx, cognitoUsername)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("username = ? AND (email <> ? OR email_verified <> ?)", username, email, verified).
+		Updates(map[string]interface{}{"email": email, "email_verified": verified}).Error
+}
+
+// Soft-deletes the user and hard-deletes everything that references them
+func DeleteUser(ctx context.Context, username string) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		followers := tx.Model(&Follows{}).Select("followee").Where("following = ?", username)
+		if err := tx.Model(&User{}).Where("username IN (?)", followers).
+			UpdateColumn("following_count", gorm.Expr("following_count - 1")).Error; err != nil {
+			return err
+		}
+		following := tx.Model(&Follows{}).Select("following").Where("followee = ?", username)
+		if err := tx.Model(&User{}).Where("username IN (?)", following).
+			UpdateColumn("follower_count", gorm.Expr("follower_count - 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("followee = ? OR following = ?", username, username).Delete(&Follows{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR author = ?", username, username).Delete(&TimelineEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&HashtagFollow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR suggested = ?", username, username).Delete(&FollowSuggestion{}).Error; err != nil {
+			return err
+		}
+
+		// likes left by the user, and likes left on the user's reviews
+		userReviews := tx.Model(&Review{}).Select("review_id").Where("username = ?", username)
+		if err := tx.Where("username = ? OR review_id IN (?)", username, userReviews).Delete(&Like{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("username = ?", username).Delete(&Review{}).Error; err != nil {
+			return err
+		}
+		// the user's trill likes, keeping like counts right on trills that stay
+		likedTrills := tx.Model(&TrillLike{}).Select("trill_id").Where("username = ?", username)
+		if err := tx.Model(&Trill{}).Where("trill_id IN (?)", likedTrills).
+			UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error; err != nil {
+			return err
+		}
+		// retrills of the user's trills and everything else pointing at them, the user's media, then the user's own trills and retrills
+		userTrills := tx.Model(&Trill{}).Select("trill_id").Where("username = ?", username)
+		if err := tx.Where("username = ? OR trill_id IN (?)", username, userTrills).Delete(&TrillLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR trill_id IN (?)", username, userTrills).Delete(&Bookmark{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&BookmarkFolder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trill_id IN (?)", userTrills).Delete(&TrillHashtag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR trill_id IN (?)", username, userTrills).Delete(&Mention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&Media{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR actor = ? OR trill_id IN (?)", username, username, userTrills).Delete(&Notification{}).Error; err != nil {
+			return err
+		}
+		if err := deletePollVotes(tx, username); err != nil {
+			return err
+		}
+		if err := deletePolls(tx, userTrills); err != nil {
+			return err
+		}
+		if err := deleteShortLinks(tx, userTrills); err != nil {
+			return err
+		}
+		if err := deleteUserReactions(tx, username); err != nil {
+			return err
+		}
+		if err := deleteReactions(tx, userTrills); err != nil {
+			return err
+		}
+		if err := tx.Where("trill_id IN (?)", userTrills).Delete(&TrillTranslation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("trill_id IN (?)", userTrills).Delete(&TrillRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("retrill_of_id IN (?)", userTrills).Delete(&Trill{}).Error; err != nil {
+			return err
+		}
+		// replies to the user's trills stay in their threads under tombstones
+		var replied []Trill
+		if err := tx.Where("username = ? AND reply_count > 0", username).Find(&replied).Error; err != nil {
+			return err
+		}
+		if err := createTombstones(tx, replied); err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&Trill{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&Draft{}).Error; err != nil {
+			return err
+		}
+		// a deletion still queued finds nothing left to delete
+		if err := tx.Where("username = ?", username).Delete(&TrillDeletion{}).Error; err != nil {
+			return err
+		}
+		// the user's lists, and the user off everyone else's
+		userLists := tx.Model(&List{}).Select("list_id").Where("owner = ?", username)
+		if err := tx.Where("list_id IN (?)", userLists).Delete(&ListMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner = ?", username).Delete(&List{}).Error; err != nil {
+			return err
+		}
+		if err := removeFromLists(tx, username, tx.Model(&List{}).Select("list_id")); err != nil {
+			return err
+		}
+		userStories := tx.Model(&Story{}).Select("story_id").Where("username = ?", username)
+		if err := tx.Where("username = ? OR story_id IN (?)", username, userStories).Delete(&StoryView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&Story{}).Error; err != nil {
+			return err
+		}
+		// any schedules still waiting find nothing to post
+		if err := tx.Where("username = ?", username).Delete(&ScheduledTrill{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&FavoriteAlbum{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&ListenLaterAlbum{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&UserSettings{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("requester = ? OR target = ?", username, username).Delete(&FollowRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("blocker = ? OR blocked = ?", username, username).Delete(&Block{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("muter = ? OR muted = ?", username, username).Delete(&Mute{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&DataExport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&TwitterImport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&ImportedTweet{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&DeviceName{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&LoginLocation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&SecureAccountToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ?", username).Delete(&BackupCode{}).Error; err != nil {
+			return err
+		}
+		// the user's own apps go too, along with everything they were given
+		ownedApps := tx.Model(&OAuthApp{}).Select("client_id").Where("owner = ?", username)
+		if err := tx.Where("username = ? OR client_id IN (?)", username, ownedApps).Delete(&OAuthCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR client_id IN (?)", username, ownedApps).Delete(&OAuthToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner = ?", username).Delete(&OAuthApp{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("username = ? OR viewer = ?", username, username).Delete(&ProfileView{}).Error; err != nil {
+			return err
+		}
+		// reports about the user stay for the moderators' records
+		if err := tx.Where("reporter = ?", username).Delete(&Report{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("username = ?", username).Delete(&User{}).Error
+	})
+}
+
+// Columns that are only ever changed through incrementUserCounter
+var userCounters = []string{"follower_count", "following_count", "review_count", "trill_count", "view_count"}
+
+// Adds delta to one of the denormalized counters on each of the users, so profiles never need a COUNT(*)
+func incrementUserCounter(tx *gorm.DB, column string, delta int64, usernames ...string) error {
+	if delta == 0 || len(usernames) == 0 {
+		return nil
+	}
+
+	return tx.Model(&User{}).Where("username IN ?", usernames).
+		UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+func DeleteCognitoUser(ctx context.Context, username string) error {
+	cognitoClient, err := InitCognitoClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = cognitoClient.Client.AdminDeleteUser(ctx, &cognitoidentityprovider.AdminDeleteUserInput{
+		UserPoolId: aws.String(cognitoClient.UserPoolId),
+		Username:   aws.String(username),
+	})
+	return err
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"trill/src/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/mediaconvert/types"
+)
+
+const (
+	TranscodedAudioPrefix = "trill-audio/"
+	// bars in a voice note's waveform
+	WaveformBars = 64
+	// the transcode also makes a mono WAV at this rate to read the waveform from, deleted once it's read
+	waveformSampleRate = 8000
+)
+
+var (
+	MaxTrillVoiceNotes         = 1
+	MaxVoiceNoteBytes    int64 = 20 << 20
+	MaxVoiceNoteDuration       = 140 * time.Second
+	VoiceNoteBitrate     int32 = 64000
+)
+
+var (
+	ErrorVoiceNoteFailed error = errors.New("voice note could not be processed; voice notes can be up to 2 minutes 20 seconds")
+)
+
+var audioExtensions = map[string]string{
+	"audio/mp4":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/mpeg":  ".mp3",
+	"audio/aac":   ".aac",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/webm":  ".weba",
+}
+
+// Starts transcoding a voice note that was just uploaded. Uploads that aren't voice notes, or that were
+// already started, are skipped; ones over MaxVoiceNoteBytes are marked failed.
+func StartAudioTranscode(ctx context.Context, key string, size int64) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var media Media
+	if result := db.Where("object_key = ?", key).Limit(1).Find(&media); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || media.Kind != MediaKindAudio || media.Status != MediaStatusPending {
+		return nil
+	}
+
+	if size > MaxVoiceNoteBytes {
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	jobID, err := createAudioTranscodeJob(ctx, &media)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&media).Updates(map[string]interface{}{"status": MediaStatusProcessing, "job_id": jobID}).Error
+}
+
+// Records the rendition, duration, and waveform of a finished job, reading the waveform from the job's
+// WAV, or marks the voice note failed; ones over MaxVoiceNoteDuration fail too. Jobs for media that's
+// since been deleted, and videos' jobs, are ignored.
+func FinishAudioTranscode(ctx context.Context, event *TranscodeJobEvent) error {
+	db, err := GetDBFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var media Media
+	if result := db.Where("job_id = ?", event.JobID).Limit(1).Find(&media); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 || media.Kind != MediaKindAudio {
+		return nil
+	}
+
+	if event.Status != "COMPLETE" {
+		fmt.Printf("transcode job %s for %s failed: %s\n", event.JobID, media.ObjectKey, event.ErrorMessage)
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	var audioKey, waveformKey string
+	for _, group := range event.OutputGroupDetails {
+		for _, output := range group.OutputDetails {
+			for _, uri := range output.OutputFilePaths {
+				if strings.HasSuffix(uri, ".wav") {
+					waveformKey = contentKeyFromURI(uri)
+				} else {
+					audioKey = contentKeyFromURI(uri)
+				}
+			}
+		}
+	}
+
+	if audioKey == "" || waveformKey == "" {
+		fmt.Printf("transcode job %s for %s is missing outputs\n", event.JobID, media.ObjectKey)
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	// 16-bit mono samples, plus room for the header; a bigger WAV is too long a voice note
+	maxWAVBytes := int64(MaxVoiceNoteDuration.Seconds())*waveformSampleRate*2 + 1024
+	buf, err := GetContentObject(ctx, waveformKey, maxWAVBytes)
+	usable := err == nil
+	if err != nil && !errors.Is(err, ErrorObjectTooLarge) {
+		return err
+	}
+	var waveform []int
+	var duration time.Duration
+	if usable {
+		if waveform, duration, err = utils.WAVWaveform(buf, WaveformBars); err != nil {
+			fmt.Printf("failed to read the waveform of %s: %s\n", media.ObjectKey, err)
+			usable = false
+		}
+	}
+
+	// the WAV was only for reading the waveform
+	if err := DeleteContentObjects(ctx, []string{waveformKey}, nil); err != nil {
+		return err
+	}
+	if !usable || duration > MaxVoiceNoteDuration {
+		return db.Model(&media).Update("status", MediaStatusFailed).Error
+	}
+
+	return db.Model(&media).Updates(map[string]interface{}{
+		"status":      MediaStatusReady,
+		"mp4_key":     audioKey,
+		"duration_ms": duration.Milliseconds(),
+		"waveform":    formatWaveform(waveform),
+	}).Error
+}
+
+// The waveform's levels, as stored
+func ParseWaveform(stored string) []int {
+	if stored == "" {
+		return nil
+	}
+	fields := strings.Split(stored, ",")
+	levels := make([]int, len(fields))
+	for i, field := range fields {
+		levels[i], _ = strconv.Atoi(field)
+	}
+	return levels
+}
+
+// Levels are stored comma separated, which fits WaveformBars of them in the column
+func formatWaveform(levels []int) string {
+	fields := make([]string, len(levels))
+	for i, level := range levels {
+		fields[i] = strconv.Itoa(level)
+	}
+	return strings.Join(fields, ",")
+}
+
+// Submits a job that turns the upload into a mono AAC M4A for playback, and a small WAV to read the
+// waveform from, both under trill-audio/<upload name>/
+func createAudioTranscodeJob(ctx context.Context, media *Media) (string, error) {
+	name := strings.TrimSuffix(path.Base(media.ObjectKey), path.Ext(media.ObjectKey))
+	destination := fmt.Sprintf("s3://%s/%s%s/%s", ContentBucket, TranscodedAudioPrefix, name, name)
+	return submitTranscodeJob(ctx, media, []types.OutputGroup{
+		{
+			OutputGroupSettings: &types.OutputGroupSettings{
+				Type:              types.OutputGroupTypeFileGroupSettings,
+				FileGroupSettings: &types.FileGroupSettings{Destination: aws.String(destination)},
+			},
+			Outputs: []types.Output{
+				{
+					NameModifier:      aws.String("_audio"),
+					Extension:         aws.String("m4a"),
+					ContainerSettings: &types.ContainerSettings{Container: types.ContainerTypeMp4},
+					AudioDescriptions: []types.AudioDescription{{
+						CodecSettings: &types.AudioCodecSettings{
+							Codec: types.AudioCodecAac,
+							AacSettings: &types.AacSettings{
+								Bitrate:    aws.Int32(VoiceNoteBitrate),
+								CodingMode: types.AacCodingModeCodingMode10,
+								SampleRate: aws.Int32(48000),
+							},
+						},
+					}},
+				},
+				{
+					NameModifier:      aws.String("_waveform"),
+					Extension:         aws.String("wav"),
+					ContainerSettings: &types.ContainerSettings{Container: types.ContainerTypeRaw},
+					AudioDescriptions: []types.AudioDescription{{
+						CodecSettings: &types.AudioCodecSettings{
+							Codec: types.AudioCodecWav,
+							WavSettings: &types.WavSettings{
+								BitDepth:   aws.Int32(16),
+								Channels:   aws.Int32(1),
+								SampleRate: aws.Int32(waveformSampleRate),
+							},
+						},
+					}},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,73 @@
+// Package pagination is how every keyset-paginated endpoint reads its limit and cursor params, pages its
+// query, and hands back cursors for the pages either side.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keyset position of a row at the edge of a page: the value being sorted on, plus the row's key to break
+// ties. A backward cursor asks for the rows just before the position in the sort order, usually newer ones.
+type Cursor struct {
+	Value    int64
+	Key      string
+	Backward bool
+}
+
+// The cursors either side of a page; next is nil on the last page, and prev is nil when the page is empty
+type Cursors struct {
+	Next *Cursor
+	Prev *Cursor
+}
+
+var (
+	ErrorCursorInvalid error = errors.New("invalid cursor")
+)
+
+// backward cursors are marked by a prefix, so cursors from before they existed still decode
+const backwardPrefix = "p"
+
+// A cursor for rows sorted on a timestamp, like created_at, with the row's ID to break ties
+func TimeCursor(t time.Time, key string) Cursor {
+	return Cursor{Value: t.UnixMilli(), Key: key}
+}
+
+// The timestamp a TimeCursor holds
+func (cursor *Cursor) Time() time.Time {
+	return time.UnixMilli(cursor.Value)
+}
+
+// Cursors are opaque to clients so the format can change
+func Encode(cursor *Cursor) string {
+	raw := fmt.Sprintf("%d:%s", cursor.Value, cursor.Key)
+	if cursor.Backward {
+		raw = backwardPrefix + raw
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Decode(encoded string) (*Cursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrorCursorInvalid
+	}
+
+	raw := string(decoded)
+	backward := strings.HasPrefix(raw, backwardPrefix)
+	raw = strings.TrimPrefix(raw, backwardPrefix)
+	rawValue, key, ok := strings.Cut(raw, ":")
+	if !ok {
+		return nil, ErrorCursorInvalid
+	}
+	value, err := strconv.ParseInt(rawValue, 10, 64)
+	if err != nil {
+		return nil, ErrorCursorInvalid
+	}
+
+	return &Cursor{Value: value, Key: key, Backward: backward}, nil
+}
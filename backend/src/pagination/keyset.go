@@ -0,0 +1,99 @@
+package pagination
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The columns a query pages on. Rows sort on Value, descending unless Ascending is set, which can be an
+// expression taking ValueArgs, then on Key, descending unless KeyAscending is set; Key can be left out when
+// Value is unique. A Time keyset's Value is a timestamp column, and its cursors are TimeCursors.
+type Keyset struct {
+	Value        string
+	ValueArgs    []interface{}
+	Ascending    bool
+	Key          string
+	KeyAscending bool
+	Time         bool
+}
+
+// Pages the query from the cursor, or from the start when it's nil. One extra row is asked for, which
+// tells Trim whether there's more in the direction the cursor points.
+func (keyset Keyset) Apply(query *gorm.DB, cursor *Cursor, limit int) *gorm.DB {
+	backward := cursor != nil && cursor.Backward
+	if cursor != nil {
+		var value interface{} = cursor.Value
+		if keyset.Time {
+			value = cursor.Time()
+		}
+
+		past := comparison(keyset.Ascending, backward)
+		if keyset.Key == "" {
+			query = query.Where(fmt.Sprintf("(%s) %s ?", keyset.Value, past), append(keyset.valueArgs(), value)...)
+		} else {
+			keyPast := comparison(keyset.KeyAscending, backward)
+			args := append(append(keyset.valueArgs(), value), keyset.valueArgs()...)
+			args = append(args, value, cursor.Key)
+			query = query.Where(fmt.Sprintf("(%s) %s ? OR ((%s) = ? AND %s %s ?)", keyset.Value, past, keyset.Value, keyset.Key, keyPast), args...)
+		}
+	}
+
+	order := fmt.Sprintf("(%s) %s", keyset.Value, direction(keyset.Ascending, backward))
+	if keyset.Key != "" {
+		order += fmt.Sprintf(", %s %s", keyset.Key, direction(keyset.KeyAscending, backward))
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: keyset.valueArgs(), WithoutParentheses: true}}).
+		Limit(limit + 1)
+}
+
+func (keyset Keyset) valueArgs() []interface{} {
+	return append([]interface{}{}, keyset.ValueArgs...)
+}
+
+func direction(ascending bool, backward bool) string {
+	if ascending != backward {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// How a column compares to the cursor for the rows past it
+func comparison(ascending bool, backward bool) string {
+	if ascending != backward {
+		return ">"
+	}
+	return "<"
+}
+
+// Cuts the rows Apply fetched down to the page, in the keyset's order, and works out the cursors either
+// side from the position of each row. Prev is always there for a page with rows, since newer rows can
+// turn up at any time; polling it is how a client catches up.
+func Trim[T any](rows []T, cursor *Cursor, limit int, position func(*T) Cursor) ([]T, Cursors) {
+	backward := cursor != nil && cursor.Backward
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	// a backward page was read in reverse
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var cursors Cursors
+	if len(rows) == 0 {
+		return rows, cursors
+	}
+	prev := position(&rows[0])
+	prev.Backward = true
+	cursors.Prev = &prev
+	// going backward, there's always the page the cursor came from after this one
+	if more || backward {
+		next := position(&rows[len(rows)-1])
+		cursors.Next = &next
+	}
+	return rows, cursors
+}
@@ -0,0 +1,99 @@
+package pagination
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 20
+)
+
+var (
+	ErrorLimitParse error = errors.New("failed to parse limit")
+)
+
+// The limit param, clamped to between 1 and MaxLimit; DefaultLimit when it's missing
+func ParseLimit(value string) (int, error) {
+	if value == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, ErrorLimitParse
+	}
+
+	if limit < 1 {
+		return 1, nil
+	} else if limit > MaxLimit {
+		return MaxLimit, nil
+	}
+	return limit, nil
+}
+
+// The limit and cursor params from a query string; the cursor is nil for the first page
+func FromParams(params map[string]string) (int, *Cursor, error) {
+	limit, err := ParseLimit(params["limit"])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	value := params["cursor"]
+	if value == "" {
+		return limit, nil, nil
+	}
+	cursor, err := Decode(value)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return limit, cursor, nil
+}
+
+// Bounds on the IDs a page can hold, Twitter style: only rows newer than SinceID, and none newer than
+// MaxID. Either can be nil. A client refreshes with since_id set to the newest ID it has, and fills any
+// gap by adding max_id just below the oldest ID it got back.
+type IDRange struct {
+	SinceID *int64
+	MaxID   *int64
+}
+
+var (
+	ErrorSinceIDParse error = errors.New("failed to parse since_id")
+	ErrorMaxIDParse   error = errors.New("failed to parse max_id")
+)
+
+// The since_id and max_id params from a query string
+func IDRangeFromParams(params map[string]string) (IDRange, error) {
+	var idRange IDRange
+	if value := params["since_id"]; value != "" {
+		sinceID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return IDRange{}, ErrorSinceIDParse
+		}
+		idRange.SinceID = &sinceID
+	}
+	if value := params["max_id"]; value != "" {
+		maxID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return IDRange{}, ErrorMaxIDParse
+		}
+		idRange.MaxID = &maxID
+	}
+
+	return idRange, nil
+}
+
+// Keeps the query's column within the range
+func (idRange IDRange) Apply(query *gorm.DB, column string) *gorm.DB {
+	if idRange.SinceID != nil {
+		query = query.Where(column+" > ?", *idRange.SinceID)
+	}
+	if idRange.MaxID != nil {
+		query = query.Where(column+" <= ?", *idRange.MaxID)
+	}
+	return query
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+var SESSendEmailURL string = "https://email.us-east-1.amazonaws.com/v2/email/outbound-emails"
+
+type sesContent struct {
+	Data string `json:"Data"`
+}
+
+type sesSendEmail struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent `json:"Text"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Sends a plain text email from EMAIL_SENDER through SES's SendEmail API, signed with the Lambda's own credentials
+func SendEmail(ctx context.Context, to string, subject string, text string) error {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-east-1"))
+	if err != nil {
+		return err
+	}
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	var email sesSendEmail
+	email.FromEmailAddress = GetSecrets().EmailSender
+	email.Destination.ToAddresses = []string{to}
+	email.Content.Simple.Subject.Data = subject
+	email.Content.Simple.Body.Text.Data = text
+	payload, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", SESSendEmailURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	payloadHash := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, request, hex.EncodeToString(payloadHash[:]), "ses", "us-east-1", time.Now()); err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		res, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("SES SendEmail failed with status %s: %s", resp.Status, string(res))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// The parts of a GIPHY GIF object the picker uses. GIPHY sends dimensions as strings.
+type GiphyGIF struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Images struct {
+		Original   GiphyRendition `json:"original"`
+		FixedWidth GiphyRendition `json:"fixed_width"`
+	} `json:"images"`
+}
+
+type GiphyRendition struct {
+	URL    string `json:"url"`
+	MP4    string `json:"mp4"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+}
+
+type GiphyPagination struct {
+	TotalCount int `json:"total_count"`
+	Count      int `json:"count"`
+	Offset     int `json:"offset"`
+}
+
+var (
+	ErrorGiphyNotFound error = errors.New("GIF not found")
+)
+
+var (
+	GiphyAPIURL = "https://api.giphy.com/v1/gifs"
+	// the most any GIF the picker shows can be rated
+	GiphyRating = "pg-13"
+)
+
+// Calls a GIPHY GIF endpoint, e.g. "/search" or "/<id>", with the API key and rating added to the params,
+// and decodes the response's data into data
+func DoGiphyRequest(ctx context.Context, endpoint string, params url.Values, data interface{}) (*GiphyPagination, error) {
+	params.Set("api_key", GetSecrets().GiphyAPIKey)
+	params.Set("rating", GiphyRating)
+	request, err := http.NewRequestWithContext(ctx, "GET", GiphyAPIURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return nil, ErrorGiphyNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		res, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GIPHY returned %s: %s", resp.Status, res)
+	}
+
+	body := struct {
+		Data       interface{}      `json:"data"`
+		Pagination *GiphyPagination `json:"pagination"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return body.Pagination, nil
+}
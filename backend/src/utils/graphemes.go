@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"unicode"
+)
+
+const (
+	zeroWidthJoiner = '\u200d'
+)
+
+// The number of user-perceived characters in s, following the main rules for Unicode's extended
+// grapheme clusters (UAX #29): combining marks, variation selectors, skin tones, and tags stay with the
+// character before them, emoji joined by a zero-width joiner count once, and so do CRLF and each pair of
+// regional indicators, which together make a flag. Conjoining Hangul jamo count separately, but
+// precomposed syllables, which is what keyboards produce, are one character anyway.
+func GraphemeCount(s string) int {
+	count := 0
+	prev := rune(-1)
+	// regional indicators in a row, so every second one finishes a flag
+	regional := 0
+	for _, r := range s {
+		switch {
+		case prev == '\r' && r == '\n':
+		case prev != -1 && isGraphemeExtend(r):
+		case prev == zeroWidthJoiner && isPictographic(r):
+		case isRegionalIndicator(r) && regional%2 == 1:
+		default:
+			count++
+		}
+
+		if isRegionalIndicator(r) {
+			regional++
+		} else {
+			regional = 0
+		}
+		prev = r
+	}
+	return count
+}
+
+// Whether s is a single emoji: one user-perceived character that's pictographic, a flag, or a keycap
+// like 1️⃣, with whatever skin tone, variation selector, or joined emoji comes with it
+func IsEmoji(s string) bool {
+	if s == "" || GraphemeCount(s) != 1 {
+		return false
+	}
+	for i, r := range s {
+		if i == 0 && (isPictographic(r) || isRegionalIndicator(r)) {
+			return true
+		} else if r == '\u20e3' {
+			return true
+		}
+	}
+	return false
+}
+
+// Characters that never start a cluster of their own
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zeroWidthJoiner, r == '\u200c':
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // tags, as in subdivision flags
+		return true
+	}
+	return false
+}
+
+// Close enough to Extended_Pictographic for the emoji people actually join
+func isPictographic(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1f000 && r <= 0x1faff)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
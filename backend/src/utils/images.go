@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+
+	// registers decoders for image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	MaxImagePixels = 40_000_000
+)
+
+var (
+	MinBannerWidth  = 600
+	MinBannerHeight = 200
+)
+
+var (
+	ErrorImageTooLarge error = errors.New("image dimensions are too large")
+	ErrorBannerSize    error = errors.New("banners must be landscape and at least 600x200")
+)
+
+// Decodes an image and applies its EXIF orientation, since the EXIF data itself is dropped on re-encode
+func DecodeImage(buf []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	} else if config.Width*config.Height > MaxImagePixels {
+		return nil, ErrorImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	return orient(img, jpegOrientation(buf)), nil
+}
+
+// The width and height an image displays at, after its EXIF orientation, without decoding the pixels
+func ImageSize(buf []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		return 0, 0, err
+	} else if config.Width*config.Height > MaxImagePixels {
+		return 0, 0, ErrorImageTooLarge
+	}
+
+	// orientations 5-8 are rotated a quarter turn
+	if jpegOrientation(buf) >= 5 {
+		return config.Height, config.Width, nil
+	}
+	return config.Width, config.Height, nil
+}
+
+// Checks that the upload really is an image with usable banner dimensions
+func ValidateBanner(buf []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		return err
+	} else if config.Width*config.Height > MaxImagePixels {
+		return ErrorImageTooLarge
+	} else if config.Width < MinBannerWidth || config.Height < MinBannerHeight || config.Width < config.Height {
+		return ErrorBannerSize
+	}
+
+	return nil
+}
+
+// Center-crops the image to a square and scales it down to size x size (never up)
+func CropSquare(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if size > side {
+		size = side
+	}
+
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	// JPEG has no alpha, so transparent pixels end up on white instead of black
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+	return dst
+}
+
+// Encodes a lossless WebP in pure Go, so the Lambdas still build without cgo. Encoding only writes pixel
+// data, so any EXIF/GPS metadata from the original is gone.
+func EncodeWebP(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := nativewebp.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Returns the EXIF orientation (1-8) of a JPEG, or 1 if there is none
+func jpegOrientation(buf []byte) int {
+	if len(buf) < 4 || buf[0] != 0xFF || buf[1] != 0xD8 {
+		return 1
+	}
+
+	// walk the JPEG segments until the APP1 (EXIF) segment
+	for i := 2; i+4 <= len(buf) && buf[i] == 0xFF; {
+		marker := buf[i+1]
+		length := int(binary.BigEndian.Uint16(buf[i+2 : i+4]))
+		if marker == 0xDA || i+2+length > len(buf) { // start of scan, no more metadata
+			return 1
+		}
+		segment := buf[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+func orient(img image.Image, orientation int) image.Image {
+	if orientation == 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 { // rotated a quarter turn, so the dimensions swap
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
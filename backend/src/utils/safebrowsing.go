@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+var (
+	SafeBrowsingAPIURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+	// the most URLs one lookup can take
+	SafeBrowsingBatch = 500
+)
+
+type safeBrowsingEntry struct {
+	URL string `json:"url"`
+}
+
+// Looks the URLs up with the Safe Browsing Lookup API, returning the threat type, e.g. "MALWARE" or
+// "SOCIAL_ENGINEERING", for each one that matched. URLs that didn't match are left out.
+func CheckSafeBrowsing(ctx context.Context, urls []string) (map[string]string, error) {
+	threats := map[string]string{}
+	for start := 0; start < len(urls); start += SafeBrowsingBatch {
+		end := start + SafeBrowsingBatch
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		entries := make([]safeBrowsingEntry, end-start)
+		for i, link := range urls[start:end] {
+			entries[i] = safeBrowsingEntry{URL: link}
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"client": map[string]string{"clientId": "trill", "clientVersion": "1.0"},
+			"threatInfo": map[string]interface{}{
+				"threatTypes":      []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"},
+				"platformTypes":    []string{"ANY_PLATFORM"},
+				"threatEntryTypes": []string{"URL"},
+				"threatEntries":    entries,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint := SafeBrowsingAPIURL + "?key=" + url.QueryEscape(GetSecrets().SafeBrowsingAPIKey)
+		request, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		body := struct {
+			Matches []struct {
+				ThreatType string            `json:"threatType"`
+				Threat     safeBrowsingEntry `json:"threat"`
+			} `json:"matches"`
+		}{}
+		if resp.StatusCode != http.StatusOK {
+			res, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("Safe Browsing returned %s: %s", resp.Status, res)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range body.Matches {
+			threats[match.Threat.URL] = match.ThreatType
+		}
+	}
+
+	return threats, nil
+}
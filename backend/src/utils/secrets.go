@@ -5,16 +5,44 @@ import (
 )
 
 type Secrets struct {
-	Host               string `yaml:"MYSQLHOST"`
-	Port               string `yaml:"MYSQLPORT"`
-	Database           string `yaml:"MYSQLDATABASE"`
-	User               string `yaml:"MYSQLUSER"`
-	Password           string `yaml:"MYSQLPASS"`
-	Region             string `yaml:"AWS_DEFAULT_REGION"`
-	CognitoAppClientId string `yaml:"COGNITO_APP_CLIENT_ID"`
-	CognitoUserPoolId  string `yaml:"COGNITO_USER_POOL_ID"`
-	SpotifyID          string `yaml:"SPOTIFY_CLIENT_ID"`
-	SpotifySecret      string `yaml:"SPOTIFY_CLIENT_SECRET"`
+	Host                   string `yaml:"MYSQLHOST"`
+	Port                   string `yaml:"MYSQLPORT"`
+	Database               string `yaml:"MYSQLDATABASE"`
+	User                   string `yaml:"MYSQLUSER"`
+	Password               string `yaml:"MYSQLPASS"`
+	Region                 string `yaml:"AWS_DEFAULT_REGION"`
+	CognitoAppClientId     string `yaml:"COGNITO_APP_CLIENT_ID"`
+	CognitoUserPoolId      string `yaml:"COGNITO_USER_POOL_ID"`
+	CognitoAppClientSecret string `yaml:"COGNITO_APP_CLIENT_SECRET"`
+	SpotifyID              string `yaml:"SPOTIFY_CLIENT_ID"`
+	SpotifySecret          string `yaml:"SPOTIFY_CLIENT_SECRET"`
+	ExportQueueURL         string `yaml:"EXPORT_QUEUE_URL"`
+	ExportBucket           string `yaml:"EXPORT_BUCKET"`
+	UsernameDenylist       string `yaml:"USERNAME_DENYLIST"`
+	ProfileViewQueueURL    string `yaml:"PROFILE_VIEW_QUEUE_URL"`
+	CaptchaSecret          string `yaml:"CAPTCHA_SECRET_KEY"`
+	CaptchaVerifyURL       string `yaml:"CAPTCHA_VERIFY_URL"`
+	CaptchaPostThreshold   string `yaml:"CAPTCHA_POST_THRESHOLD"`
+	EmailSender            string `yaml:"EMAIL_SENDER"`
+	WebAppURL              string `yaml:"WEB_APP_URL"`
+	MediaConvertEndpoint   string `yaml:"MEDIACONVERT_ENDPOINT"`
+	MediaConvertRole       string `yaml:"MEDIACONVERT_ROLE_ARN"`
+	GiphyAPIKey            string `yaml:"GIPHY_API_KEY"`
+	SchedulerRoleARN       string `yaml:"SCHEDULER_ROLE_ARN"`
+	TrillPublisherARN      string `yaml:"TRILL_PUBLISHER_ARN"`
+	TrillEditWindow        string `yaml:"TRILL_EDIT_WINDOW_MINUTES"`
+	TrillMaxLength         string `yaml:"TRILL_MAX_LENGTH"`
+	LinkPreviewQueueURL    string `yaml:"LINK_PREVIEW_QUEUE_URL"`
+	TrillViewQueueURL      string `yaml:"TRILL_VIEW_QUEUE_URL"`
+	TrillDeletionQueueURL  string `yaml:"TRILL_DELETION_QUEUE_URL"`
+	ShortLinkURL           string `yaml:"SHORT_LINK_URL"`
+	LinkScanQueueURL       string `yaml:"LINK_SCAN_QUEUE_URL"`
+	SafeBrowsingAPIKey     string `yaml:"SAFE_BROWSING_API_KEY"`
+	LinkBlocklist          string `yaml:"LINK_BLOCKLIST"`
+	TwitterImportQueueURL  string `yaml:"TWITTER_IMPORT_QUEUE_URL"`
+	TimelineFanoutQueueURL string `yaml:"TIMELINE_FANOUT_QUEUE_URL"`
+	AvatarQueueURL         string `yaml:"AVATAR_QUEUE_URL"`
+	RateLimitTable         string `yaml:"RATE_LIMIT_TABLE"`
 }
 
 func GetSecrets() Secrets {
@@ -27,7 +55,35 @@ func GetSecrets() Secrets {
 		os.Getenv("AWS_DEFAULT_REGION"),
 		os.Getenv("COGNITO_APP_CLIENT_ID"),
 		os.Getenv("COGNITO_USER_POOL_ID"),
+		os.Getenv("COGNITO_APP_CLIENT_SECRET"),
 		os.Getenv("SPOTIFY_CLIENT_ID"),
 		os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		os.Getenv("EXPORT_QUEUE_URL"),
+		os.Getenv("EXPORT_BUCKET"),
+		os.Getenv("USERNAME_DENYLIST"),
+		os.Getenv("PROFILE_VIEW_QUEUE_URL"),
+		os.Getenv("CAPTCHA_SECRET_KEY"),
+		os.Getenv("CAPTCHA_VERIFY_URL"),
+		os.Getenv("CAPTCHA_POST_THRESHOLD"),
+		os.Getenv("EMAIL_SENDER"),
+		os.Getenv("WEB_APP_URL"),
+		os.Getenv("MEDIACONVERT_ENDPOINT"),
+		os.Getenv("MEDIACONVERT_ROLE_ARN"),
+		os.Getenv("GIPHY_API_KEY"),
+		os.Getenv("SCHEDULER_ROLE_ARN"),
+		os.Getenv("TRILL_PUBLISHER_ARN"),
+		os.Getenv("TRILL_EDIT_WINDOW_MINUTES"),
+		os.Getenv("TRILL_MAX_LENGTH"),
+		os.Getenv("LINK_PREVIEW_QUEUE_URL"),
+		os.Getenv("TRILL_VIEW_QUEUE_URL"),
+		os.Getenv("TRILL_DELETION_QUEUE_URL"),
+		os.Getenv("SHORT_LINK_URL"),
+		os.Getenv("LINK_SCAN_QUEUE_URL"),
+		os.Getenv("SAFE_BROWSING_API_KEY"),
+		os.Getenv("LINK_BLOCKLIST"),
+		os.Getenv("TWITTER_IMPORT_QUEUE_URL"),
+		os.Getenv("TIMELINE_FANOUT_QUEUE_URL"),
+		os.Getenv("AVATAR_QUEUE_URL"),
+		os.Getenv("RATE_LIMIT_TABLE"),
 	}
 }
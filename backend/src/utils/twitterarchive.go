@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A tweet from a Twitter data export, with its text as it should read on Trill
+type ArchivedTweet struct {
+	ID                string
+	Text              string
+	CreatedAt         time.Time
+	InReplyToID       string
+	IsRetweet         bool
+	PossiblySensitive bool
+	Media             []ArchivedMedia
+}
+
+// A photo or video on a tweet. File is its path in the archive, empty when the export left it out.
+type ArchivedMedia struct {
+	Type    string
+	AltText string
+	File    string
+}
+
+const (
+	ArchivedPhoto = "photo"
+	ArchivedVideo = "video"
+	ArchivedGIF   = "animated_gif"
+)
+
+var (
+	ErrorNotTwitterArchive error = errors.New("no tweets found; expected a Twitter data export")
+	ErrorFileTooLarge      error = errors.New("file in archive is too large")
+)
+
+// data/tweets.js, split into data/tweets-part1.js and on for big exports; older exports say tweet
+var tweetsFilePattern = regexp.MustCompile(`^tweets?(?:-part[0-9]+)?\.js$`)
+
+// The export's tweets file(s) are JavaScript assigning a JSON array to a global, and each entry has the
+// tweet under "tweet" in newer exports and bare in older ones
+type archivedTweetEntry struct {
+	Tweet *rawTweet `json:"tweet"`
+}
+
+type rawTweet struct {
+	ID                string `json:"id_str"`
+	FullText          string `json:"full_text"`
+	CreatedAt         string `json:"created_at"`
+	InReplyToID       string `json:"in_reply_to_status_id_str"`
+	PossiblySensitive bool   `json:"possibly_sensitive"`
+	Retweeted         bool   `json:"retweeted"`
+	Entities          struct {
+		URLs []struct {
+			URL         string `json:"url"`
+			ExpandedURL string `json:"expanded_url"`
+		} `json:"urls"`
+	} `json:"entities"`
+	ExtendedEntities struct {
+		Media []struct {
+			URL           string `json:"url"`
+			MediaURLHTTPS string `json:"media_url_https"`
+			Type          string `json:"type"`
+			AltText       string `json:"ext_alt_text"`
+		} `json:"media"`
+	} `json:"extended_entities"`
+}
+
+// Reads every tweet in the export, oldest first, so a thread's tweets come after the ones they reply to
+func ParseTwitterArchive(archive *zip.Reader) ([]ArchivedTweet, error) {
+	// media sit in data/tweets_media/ (data/tweet_media/ in older exports) as <tweet id>-<file name>
+	mediaFiles := map[string][]string{}
+	var tweetFiles []*zip.File
+	for _, file := range archive.File {
+		dir, name := path.Split(file.Name)
+		switch {
+		case strings.HasSuffix(dir, "/tweets_media/") || strings.HasSuffix(dir, "/tweet_media/"):
+			if tweetID, _, ok := strings.Cut(name, "-"); ok {
+				mediaFiles[tweetID] = append(mediaFiles[tweetID], file.Name)
+			}
+		case strings.HasSuffix(dir, "data/") && tweetsFilePattern.MatchString(name):
+			tweetFiles = append(tweetFiles, file)
+		}
+	}
+
+	var tweets []ArchivedTweet
+	for _, file := range tweetFiles {
+		raw, err := readTweetsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range raw {
+			tweet, err := newArchivedTweet(t, mediaFiles[t.ID])
+			if err != nil {
+				return nil, err
+			}
+			tweets = append(tweets, tweet)
+		}
+	}
+	if len(tweets) == 0 {
+		return nil, ErrorNotTwitterArchive
+	}
+
+	sort.SliceStable(tweets, func(i, j int) bool { return tweets[i].CreatedAt.Before(tweets[j].CreatedAt) })
+	return tweets, nil
+}
+
+// Reads the contents of a file in the archive
+func ReadArchiveFile(archive *zip.Reader, name string, maxBytes int64) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(buf)) > maxBytes {
+		return nil, ErrorFileTooLarge
+	}
+	return buf, nil
+}
+
+func readTweetsFile(file *zip.File) ([]rawTweet, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// window.YTD.tweets.part0 = [ ... ]
+	if start := bytes.IndexByte(buf, '['); start >= 0 {
+		buf = buf[start:]
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, err
+	}
+
+	tweets := make([]rawTweet, 0, len(entries))
+	for _, entry := range entries {
+		var wrapped archivedTweetEntry
+		if err := json.Unmarshal(entry, &wrapped); err != nil {
+			return nil, err
+		}
+		if wrapped.Tweet != nil {
+			tweets = append(tweets, *wrapped.Tweet)
+			continue
+		}
+		var bare rawTweet
+		if err := json.Unmarshal(entry, &bare); err != nil {
+			return nil, err
+		}
+		tweets = append(tweets, bare)
+	}
+	return tweets, nil
+}
+
+// Swaps t.co links for where they go, drops the links to the tweet's own media, and undoes the HTML
+// escaping Twitter stores text with. Each photo or video is matched to its file by name; videos and GIFs
+// are stored as the one MP4.
+func newArchivedTweet(raw rawTweet, files []string) (ArchivedTweet, error) {
+	createdAt, err := time.Parse(time.RubyDate, raw.CreatedAt)
+	if err != nil {
+		return ArchivedTweet{}, err
+	}
+
+	text := raw.FullText
+	for _, link := range raw.Entities.URLs {
+		text = strings.ReplaceAll(text, link.URL, link.ExpandedURL)
+	}
+	media := make([]ArchivedMedia, 0, len(raw.ExtendedEntities.Media))
+	for _, m := range raw.ExtendedEntities.Media {
+		text = strings.ReplaceAll(text, m.URL, "")
+		archived := ArchivedMedia{Type: m.Type, AltText: m.AltText}
+		for _, file := range files {
+			_, name, _ := strings.Cut(path.Base(file), "-")
+			if (m.Type == ArchivedPhoto && name == path.Base(m.MediaURLHTTPS)) || (m.Type != ArchivedPhoto && path.Ext(name) == ".mp4") {
+				archived.File = file
+				break
+			}
+		}
+		media = append(media, archived)
+	}
+
+	return ArchivedTweet{
+		ID:                raw.ID,
+		Text:              strings.TrimSpace(html.UnescapeString(text)),
+		CreatedAt:         createdAt,
+		InReplyToID:       raw.InReplyToID,
+		IsRetweet:         raw.Retweeted || strings.HasPrefix(raw.FullText, "RT @"),
+		PossiblySensitive: raw.PossiblySensitive,
+		Media:             media,
+	}, nil
+}
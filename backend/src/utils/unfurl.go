@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// What a page says about itself in its Open Graph tags, falling back to its <title> and description
+type OpenGraph struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+var (
+	UnfurlTimeout      = 5 * time.Second
+	UnfurlMaxRedirects = 3
+	// Open Graph tags belong in the <head>, so there's no need to read the whole page
+	UnfurlMaxBytes  int64 = 512 << 10
+	UnfurlUserAgent       = "TrillBot/1.0 (link previews)"
+)
+
+var (
+	ErrorUnfurlForbidden error = errors.New("link points at an address that can't be previewed")
+	ErrorUnfurlNotHTML   error = errors.New("link is not a web page")
+)
+
+var (
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attributePattern = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	titlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// private, loopback, link-local, and other ranges a link must never make the fetcher reach, which
+// includes the instance metadata endpoint at 169.254.169.254
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+func isPublicIP(ip net.IP) bool {
+	// IPv4-mapped IPv6 addresses are checked as the IPv4 address they carry
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks the address actually being connected to, after DNS, so a hostname that resolves to a private
+// address, or starts to between lookups, is refused too
+func dialControl(network string, address string, conn syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return ErrorUnfurlForbidden
+	} else if port != "80" && port != "443" {
+		return ErrorUnfurlForbidden
+	}
+	return nil
+}
+
+// Only http and https links on their usual ports can be fetched
+func checkUnfurlURL(link *url.URL) error {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return ErrorUnfurlForbidden
+	} else if link.User != nil {
+		return ErrorUnfurlForbidden
+	} else if port := link.Port(); port != "" && port != "80" && port != "443" {
+		return ErrorUnfurlForbidden
+	}
+	return nil
+}
+
+var unfurlClient = &http.Client{
+	Timeout: UnfurlTimeout,
+	Transport: &http.Transport{
+		// never through a proxy, which would make the dial checks meaningless
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: UnfurlTimeout,
+			Control: dialControl,
+		}).DialContext,
+		TLSHandshakeTimeout:   UnfurlTimeout,
+		ResponseHeaderTimeout: UnfurlTimeout,
+		MaxIdleConns:          10,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) > UnfurlMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", UnfurlMaxRedirects)
+		}
+		return checkUnfurlURL(req.URL)
+	},
+}
+
+// Fetches the page at the link and reads its Open Graph tags. Only public addresses are reached, over
+// http or https on the standard ports, and only the start of an HTML response is read.
+func FetchOpenGraph(ctx context.Context, link string) (*OpenGraph, error) {
+	pageURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	} else if err := checkUnfurlURL(pageURL); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", pageURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", UnfurlUserAgent)
+	request.Header.Set("Accept", "text/html")
+
+	resp, err := unfurlClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", pageURL.Host, resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" &&
+		mediaType != "application/xhtml+xml" {
+		return nil, ErrorUnfurlNotHTML
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, UnfurlMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// relative image URLs are relative to wherever the redirects ended up
+	return parseOpenGraph(strings.ToValidUTF8(string(page), ""), resp.Request.URL), nil
+}
+
+func parseOpenGraph(page string, pageURL *url.URL) *OpenGraph {
+	properties := map[string]string{}
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		attributes := map[string]string{}
+		for _, match := range attributePattern.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+		}
+
+		key := attributes["property"]
+		if key == "" {
+			key = attributes["name"]
+		}
+		key = strings.ToLower(key)
+		// the first of a repeated tag wins, as with og:image
+		if _, seen := properties[key]; key != "" && !seen {
+			properties[key] = cleanText(attributes["content"])
+		}
+	}
+
+	graph := OpenGraph{
+		Title:       firstNonEmpty(properties["og:title"], properties["twitter:title"]),
+		Description: firstNonEmpty(properties["og:description"], properties["twitter:description"], properties["description"]),
+		SiteName:    properties["og:site_name"],
+	}
+	if graph.Title == "" {
+		if match := titlePattern.FindStringSubmatch(page); match != nil {
+			graph.Title = cleanText(match[1])
+		}
+	}
+
+	image := firstNonEmpty(properties["og:image:secure_url"], properties["og:image"], properties["og:image:url"], properties["twitter:image"])
+	if imageURL, err := pageURL.Parse(image); image != "" && err == nil && checkUnfurlURL(imageURL) == nil {
+		graph.ImageURL = imageURL.String()
+	}
+
+	return &graph
+}
+
+// Unescapes entities and collapses whitespace
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrorInvalidWAV error = errors.New("not a 16-bit PCM WAV")
+)
+
+// How long a 16-bit PCM WAV plays for, and the loudest sample in each of bars equal slices of it on the
+// first channel, scaled so the loudest slice is 100. Quiet recordings still draw a full waveform that way.
+func WAVWaveform(buf []byte, bars int) ([]int, time.Duration, error) {
+	if len(buf) < 12 || string(buf[:4]) != "RIFF" || string(buf[8:12]) != "WAVE" {
+		return nil, 0, ErrorInvalidWAV
+	}
+
+	// walk the RIFF chunks for the format and the samples
+	var channels, sampleRate, bitDepth int
+	var data []byte
+	for i := 12; i+8 <= len(buf); {
+		id := string(buf[i : i+4])
+		size := int(binary.LittleEndian.Uint32(buf[i+4 : i+8]))
+		// streamed WAVs can leave the data size unset, running to the end of the file
+		if i+8+size > len(buf) {
+			size = len(buf) - i - 8
+		}
+		chunk := buf[i+8 : i+8+size]
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 || binary.LittleEndian.Uint16(chunk[0:2]) != 1 {
+				return nil, 0, ErrorInvalidWAV
+			}
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bitDepth = int(binary.LittleEndian.Uint16(chunk[14:16]))
+		case "data":
+			data = chunk
+		}
+		// chunks are padded to an even length
+		i += 8 + size + size%2
+	}
+	if channels < 1 || sampleRate < 1 || bitDepth != 16 || data == nil {
+		return nil, 0, ErrorInvalidWAV
+	}
+
+	frameSize := 2 * channels
+	frames := len(data) / frameSize
+	duration := time.Duration(frames) * time.Second / time.Duration(sampleRate)
+
+	peaks := make([]int, bars)
+	loudest := 0
+	for bar := 0; bar < bars && frames > 0; bar++ {
+		for frame := bar * frames / bars; frame < (bar+1)*frames/bars; frame++ {
+			sample := int(int16(binary.LittleEndian.Uint16(data[frame*frameSize:])))
+			if sample < 0 {
+				sample = -sample
+			}
+			if sample > peaks[bar] {
+				peaks[bar] = sample
+			}
+		}
+		if peaks[bar] > loudest {
+			loudest = peaks[bar]
+		}
+	}
+	if loudest > 0 {
+		for bar := range peaks {
+			peaks[bar] = peaks[bar] * 100 / loudest
+		}
+	}
+
+	return peaks, duration, nil
+}
@@ -0,0 +1,44 @@
+package views
+
+import (
+	"context"
+	"trill/src/models"
+)
+
+// How one of the requestor's trills is doing. links covers every URL the trill has had, including ones
+// since edited out of the text, and link_clicks is their total.
+type TrillAnalytics struct {
+	TrillID      int64           `json:"trill_id"`
+	ViewCount    int64           `json:"view_count"`
+	LikeCount    int64           `json:"like_count"`
+	RetrillCount int64           `json:"retrill_count"`
+	QuoteCount   int64           `json:"quote_count"`
+	ReplyCount   int64           `json:"reply_count"`
+	LinkClicks   int64           `json:"link_clicks"`
+	Links        []LinkAnalytics `json:"links"`
+}
+
+type LinkAnalytics struct {
+	URL        string `json:"url"`
+	ShortURL   string `json:"short_url"`
+	ClickCount int64  `json:"click_count"`
+}
+
+func MarshalTrillAnalytics(ctx context.Context, trill *models.Trill) (string, error) {
+	analytics := TrillAnalytics{
+		TrillID:      trill.TrillID,
+		ViewCount:    trill.ViewCount,
+		LikeCount:    trill.LikeCount,
+		RetrillCount: trill.RetrillCount,
+		QuoteCount:   trill.QuoteCount,
+		ReplyCount:   trill.ReplyCount,
+		Links:        make([]LinkAnalytics, len(trill.Links)),
+	}
+	for i := range trill.Links {
+		link := &trill.Links[i]
+		analytics.Links[i] = LinkAnalytics{URL: link.URL, ShortURL: link.ShortURL(), ClickCount: link.ClickCount}
+		analytics.LinkClicks += link.ClickCount
+	}
+
+	return Marshal(ctx, analytics)
+}
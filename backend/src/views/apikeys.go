@@ -0,0 +1,54 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+type APIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=64"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// The only response that includes the key itself
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+func newAPIKey(key *models.APIKey) APIKey {
+	return APIKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+func MarshalAPIKeys(ctx context.Context, keys []models.APIKey) (string, error) {
+	keyViews := make([]APIKey, len(keys))
+	for i := range keys {
+		keyViews[i] = newAPIKey(&keys[i])
+	}
+	return Marshal(ctx, keyViews)
+}
+
+func MarshalNewAPIKey(ctx context.Context, key *models.APIKey, rawKey string) (string, error) {
+	return Marshal(ctx, NewAPIKey{APIKey: newAPIKey(key), Key: rawKey})
+}
+
+func UnmarshalAPIKeyRequest(ctx context.Context, marshalledKey string, key *APIKeyRequest) error {
+	return UnmarshalRequest(ctx, marshalledKey, key)
+}
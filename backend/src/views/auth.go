@@ -0,0 +1,254 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"trill/src/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+type SignUpRequest struct {
+	Username string `json:"username" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Nickname string `json:"nickname" validate:"max=50"`
+}
+
+type SignUpResult struct {
+	Username     string        `json:"username"`
+	Confirmed    bool          `json:"confirmed"`
+	CodeDelivery *CodeDelivery `json:"code_delivery,omitempty"`
+}
+
+type ConfirmSignUpRequest struct {
+	Username string `json:"username" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type ResendCodeRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// Answers whichever challenge login came back with; challenge defaults to NEW_PASSWORD_REQUIRED
+type ChallengeResponse struct {
+	Username    string `json:"username" validate:"required"`
+	Session     string `json:"session" validate:"required"`
+	Challenge   string `json:"challenge" validate:"omitempty,oneof=NEW_PASSWORD_REQUIRED SOFTWARE_TOKEN_MFA"`
+	NewPassword string `json:"new_password" validate:"required_unless=Challenge SOFTWARE_TOKEN_MFA"`
+	Code        string `json:"code" validate:"required_if=Challenge SOFTWARE_TOKEN_MFA"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	Username     string `json:"username"`
+	DeviceKey    string `json:"device_key"`
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Username    string `json:"username" validate:"required"`
+	Code        string `json:"code" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// Logs out this device, or every device with global. refresh_token is optional, but without it the
+// device's refresh token keeps working until the next global logout.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	Global       bool   `json:"global"`
+}
+
+type VerifyEmailRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// The token from the link in a new login email
+type SecureAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// What an authenticator app needs to start generating codes; most apps can scan otpauth_uri as a QR code
+type MFASetup struct {
+	SecretCode string `json:"secret_code"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type VerifyMFARequest struct {
+	Code       string `json:"code" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=64"`
+}
+
+type MFAPreferenceRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+type MFAStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Shown once, when the codes are issued; each one logs in a single time without the authenticator app
+type BackupCodes struct {
+	Codes []string `json:"codes"`
+}
+
+type BackupCodeStatus struct {
+	Remaining int64 `json:"remaining"`
+}
+
+// Logs in with a backup code in place of an authenticator app code
+type RecoverRequest struct {
+	Username   string `json:"username" validate:"required"`
+	Password   string `json:"password" validate:"required"`
+	BackupCode string `json:"backup_code" validate:"required"`
+}
+
+// Sent instead of tokens when Cognito needs something else from the user before they can log in
+type AuthChallenge struct {
+	Challenge string `json:"challenge"`
+	Session   string `json:"session"`
+}
+
+// Where Cognito sent the confirmation code, e.g. a masked email address
+type CodeDelivery struct {
+	Destination string `json:"destination"`
+	Medium      string `json:"medium"`
+}
+
+// The body of every failed auth request that Cognito turned down for a reason the client can act on
+type AuthError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func newCodeDelivery(details *types.CodeDeliveryDetailsType) *CodeDelivery {
+	if details == nil {
+		return nil
+	}
+
+	return &CodeDelivery{
+		Destination: aws.ToString(details.Destination),
+		Medium:      string(details.DeliveryMedium),
+	}
+}
+
+func UnmarshalSignUpRequest(ctx context.Context, marshalledSignUp string, signUp *SignUpRequest) error {
+	return UnmarshalRequest(ctx, marshalledSignUp, signUp)
+}
+
+func UnmarshalConfirmSignUpRequest(ctx context.Context, marshalledConfirm string, confirm *ConfirmSignUpRequest) error {
+	return UnmarshalRequest(ctx, marshalledConfirm, confirm)
+}
+
+func UnmarshalResendCodeRequest(ctx context.Context, marshalledResend string, resend *ResendCodeRequest) error {
+	return UnmarshalRequest(ctx, marshalledResend, resend)
+}
+
+func UnmarshalLoginRequest(ctx context.Context, marshalledLogin string, login *LoginRequest) error {
+	return UnmarshalRequest(ctx, marshalledLogin, login)
+}
+
+func UnmarshalChallengeResponse(ctx context.Context, marshalledResponse string, response *ChallengeResponse) error {
+	return UnmarshalRequest(ctx, marshalledResponse, response)
+}
+
+func UnmarshalRefreshRequest(ctx context.Context, marshalledRefresh string, refresh *RefreshRequest) error {
+	return UnmarshalRequest(ctx, marshalledRefresh, refresh)
+}
+
+func UnmarshalForgotPasswordRequest(ctx context.Context, marshalledForgot string, forgot *ForgotPasswordRequest) error {
+	return UnmarshalRequest(ctx, marshalledForgot, forgot)
+}
+
+func UnmarshalResetPasswordRequest(ctx context.Context, marshalledReset string, reset *ResetPasswordRequest) error {
+	return UnmarshalRequest(ctx, marshalledReset, reset)
+}
+
+func UnmarshalChangePasswordRequest(ctx context.Context, marshalledChange string, change *ChangePasswordRequest) error {
+	return UnmarshalRequest(ctx, marshalledChange, change)
+}
+
+func UnmarshalLogoutRequest(ctx context.Context, marshalledLogout string, logout *LogoutRequest) error {
+	return UnmarshalRequest(ctx, marshalledLogout, logout)
+}
+
+func UnmarshalVerifyEmailRequest(ctx context.Context, marshalledVerify string, verify *VerifyEmailRequest) error {
+	return UnmarshalRequest(ctx, marshalledVerify, verify)
+}
+
+func UnmarshalSecureAccountRequest(ctx context.Context, marshalledSecure string, secure *SecureAccountRequest) error {
+	return UnmarshalRequest(ctx, marshalledSecure, secure)
+}
+
+func UnmarshalVerifyMFARequest(ctx context.Context, marshalledVerify string, verify *VerifyMFARequest) error {
+	return UnmarshalRequest(ctx, marshalledVerify, verify)
+}
+
+func UnmarshalMFAPreferenceRequest(ctx context.Context, marshalledPreference string, preference *MFAPreferenceRequest) error {
+	return UnmarshalRequest(ctx, marshalledPreference, preference)
+}
+
+func UnmarshalRecoverRequest(ctx context.Context, marshalledRecover string, recover *RecoverRequest) error {
+	return UnmarshalRequest(ctx, marshalledRecover, recover)
+}
+
+func MarshalSignUpResult(ctx context.Context, username string, confirmed bool, details *types.CodeDeliveryDetailsType) (string, error) {
+	return Marshal(ctx, SignUpResult{
+		Username:     username,
+		Confirmed:    confirmed,
+		CodeDelivery: newCodeDelivery(details),
+	})
+}
+
+func MarshalCodeDelivery(ctx context.Context, details *types.CodeDeliveryDetailsType) (string, error) {
+	return Marshal(ctx, newCodeDelivery(details))
+}
+
+func MarshalAuthChallenge(ctx context.Context, challenge string, session string) (string, error) {
+	return Marshal(ctx, AuthChallenge{
+		Challenge: challenge,
+		Session:   session,
+	})
+}
+
+func MarshalMFASetup(ctx context.Context, username string, secretCode string) (string, error) {
+	label := url.PathEscape(models.MFAIssuer + ":" + username)
+	return Marshal(ctx, MFASetup{
+		SecretCode: secretCode,
+		OTPAuthURI: fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=%s", label, secretCode, url.QueryEscape(models.MFAIssuer)),
+	})
+}
+
+func MarshalMFAStatus(ctx context.Context, enabled bool) (string, error) {
+	return Marshal(ctx, MFAStatus{Enabled: enabled})
+}
+
+func MarshalBackupCodes(ctx context.Context, codes []string) (string, error) {
+	return Marshal(ctx, BackupCodes{Codes: codes})
+}
+
+func MarshalBackupCodeStatus(ctx context.Context, remaining int64) (string, error) {
+	return Marshal(ctx, BackupCodeStatus{Remaining: remaining})
+}
+
+func MarshalAuthError(ctx context.Context, authErr *models.AuthError) (string, error) {
+	return Marshal(ctx, AuthError{
+		Error:   authErr.Reason,
+		Message: authErr.Error(),
+	})
+}
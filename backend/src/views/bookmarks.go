@@ -0,0 +1,57 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"time"
+	"trill/src/models"
+)
+
+type BookmarkFolderRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+// A null or missing folder_id takes the bookmark out of its folder
+type MoveBookmarkRequest struct {
+	FolderID *int64 `json:"folder_id"`
+}
+
+type BookmarkFolder struct {
+	FolderID  int64     `json:"folder_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BookmarkFolders struct {
+	Folders []BookmarkFolder `json:"folders"`
+}
+
+// The folder the request describes, for the user
+func (request *BookmarkFolderRequest) Folder(username string) *models.BookmarkFolder {
+	return &models.BookmarkFolder{Username: username, Name: strings.TrimSpace(request.Name)}
+}
+
+func newBookmarkFolder(folder *models.BookmarkFolder) BookmarkFolder {
+	return BookmarkFolder{FolderID: folder.FolderID, Name: folder.Name, CreatedAt: folder.CreatedAt}
+}
+
+func MarshalBookmarkFolder(ctx context.Context, folder *models.BookmarkFolder) (string, error) {
+	return Marshal(ctx, newBookmarkFolder(folder))
+}
+
+func MarshalBookmarkFolders(ctx context.Context, folders *[]models.BookmarkFolder) (string, error) {
+	page := BookmarkFolders{Folders: make([]BookmarkFolder, len(*folders))}
+	for i := range *folders {
+		page.Folders[i] = newBookmarkFolder(&(*folders)[i])
+	}
+
+	return Marshal(ctx, page)
+}
+
+func UnmarshalBookmarkFolderRequest(ctx context.Context, marshalledFolder string, folder *BookmarkFolderRequest) error {
+	return UnmarshalRequest(ctx, marshalledFolder, folder)
+}
+
+func UnmarshalMoveBookmarkRequest(ctx context.Context, marshalledMove string, move *MoveBookmarkRequest) error {
+	return UnmarshalRequest(ctx, marshalledMove, move)
+}
@@ -0,0 +1,43 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+// Either trill_ids, deleted right away, or a from and until range of when trills were posted, deleted in
+// the background; from is inclusive, until exclusive, and either can be left off
+type TrillDeletionRequest struct {
+	TrillIDs []int64    `json:"trill_ids" validate:"max=100,unique"`
+	From     *time.Time `json:"from"`
+	Until    *time.Time `json:"until"`
+}
+
+type TrillDeletion struct {
+	DeletionID   string     `json:"deletion_id"`
+	Status       string     `json:"status"`
+	From         *time.Time `json:"from,omitempty"`
+	Until        *time.Time `json:"until,omitempty"`
+	DeletedCount int64      `json:"deleted_count"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+func MarshalTrillDeletion(ctx context.Context, deletion *models.TrillDeletion) (string, error) {
+	return Marshal(ctx, TrillDeletion{
+		DeletionID:   deletion.DeletionID,
+		Status:       deletion.Status,
+		From:         deletion.From,
+		Until:        deletion.Until,
+		DeletedCount: deletion.DeletedCount,
+		RequestedAt:  deletion.CreatedAt,
+		CompletedAt:  deletion.CompletedAt,
+		Error:        deletion.Error,
+	})
+}
+
+func UnmarshalTrillDeletionRequest(ctx context.Context, marshalledDeletion string, deletion *TrillDeletionRequest) error {
+	return UnmarshalRequest(ctx, marshalledDeletion, deletion)
+}
@@ -0,0 +1,142 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trill/src/models"
+	"unicode/utf8"
+)
+
+const (
+	// longest description a card gets; crawlers cut off longer ones anyway
+	MaxCardDescription = 200
+	// seconds a card can be cached for, kept short so edits and profile changes show up soon
+	CardCacheAge = 300
+)
+
+// A meta tag for the page's head; Open Graph tags go by property and Twitter's by name, but both
+// read either, so everything goes under property
+type CardMeta struct {
+	Property string `json:"property"`
+	Content  string `json:"content"`
+}
+
+// Link preview metadata for a trill or profile page, for the web app to render into the page's head
+// when it's served to a crawler. Meta has every tag ready to render, and the rest is there for anything
+// that wants to lay out its own.
+type Card struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	URL         string     `json:"url"`
+	Image       string     `json:"image,omitempty"`
+	ImageAlt    string     `json:"image_alt,omitempty"`
+	Meta        []CardMeta `json:"meta"`
+}
+
+// A trill's card. Only public trills show their text and media; a sensitive one shows its content warning
+// instead, and a private author's trills show just who posted them.
+func MarshalTrillCard(ctx context.Context, trill *models.Trill) (string, error) {
+	card := Card{
+		Title: cardTitle(&trill.User),
+		URL:   models.TrillURL(trill),
+		Image: trill.User.ProfilePicture,
+	}
+	twitterCard := "summary"
+	switch {
+	case trill.User.IsPrivate:
+		card.Description = "This account's trills are protected."
+	case trill.Sensitive:
+		card.Description = trill.ContentWarning
+		if card.Description == "" {
+			card.Description = "This trill may contain sensitive content."
+		}
+	default:
+		card.Description = cardDescription(trill.Text)
+		if image := cardImage(trill); image != nil {
+			card.Image = image.URL
+			card.ImageAlt = image.AltText
+			twitterCard = "summary_large_image"
+		}
+	}
+
+	card.Meta = cardMeta(&card, "article", twitterCard)
+	card.Meta = append(card.Meta,
+		CardMeta{Property: "article:author", Content: models.ProfileURL(trill.Username)},
+		CardMeta{Property: "article:published_time", Content: trill.CreatedAt.UTC().Format(time.RFC3339)},
+	)
+	return Marshal(ctx, card)
+}
+
+// A profile's card. A private account's bio isn't shown, as it isn't to anyone who doesn't follow them.
+func MarshalProfileCard(ctx context.Context, user *models.User) (string, error) {
+	card := Card{
+		Title: cardTitle(user),
+		URL:   models.ProfileURL(user.Username),
+		Image: user.ProfilePicture,
+	}
+	if user.IsPrivate {
+		card.Description = "This account is private."
+	} else {
+		card.Description = cardDescription(user.Bio)
+	}
+
+	card.Meta = cardMeta(&card, "profile", "summary")
+	card.Meta = append(card.Meta, CardMeta{Property: "profile:username", Content: user.Username})
+	return Marshal(ctx, card)
+}
+
+func cardTitle(user *models.User) string {
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+	return fmt.Sprintf("%s (@%s) on Trill", name, user.Username)
+}
+
+func cardDescription(text string) string {
+	if utf8.RuneCountInString(text) <= MaxCardDescription {
+		return text
+	}
+	return string([]rune(text)[:MaxCardDescription-1]) + "…"
+}
+
+// The first of the trill's images that's safe to show in a preview, if any; videos that are still
+// processing have nothing to show yet
+func cardImage(trill *models.Trill) *Media {
+	for i := range trill.Media {
+		media := newMedia(&trill.Media[i], trill.Sensitive)
+		if media.Sensitive || media.URL == "" || media.Type == models.MediaKindVideo || media.Type == models.MediaKindAudio {
+			continue
+		}
+		return &media
+	}
+	return nil
+}
+
+// The Open Graph and Twitter tags the card's fields make up
+func cardMeta(card *Card, ogType string, twitterCard string) []CardMeta {
+	meta := []CardMeta{
+		{Property: "og:type", Content: ogType},
+		{Property: "og:site_name", Content: "Trill"},
+		{Property: "og:title", Content: card.Title},
+		{Property: "og:description", Content: card.Description},
+		{Property: "og:url", Content: card.URL},
+		{Property: "twitter:card", Content: twitterCard},
+		{Property: "twitter:title", Content: card.Title},
+		{Property: "twitter:description", Content: card.Description},
+	}
+	if card.Image != "" {
+		meta = append(meta,
+			CardMeta{Property: "og:image", Content: card.Image},
+			CardMeta{Property: "twitter:image", Content: card.Image},
+		)
+		if card.ImageAlt != "" {
+			meta = append(meta,
+				CardMeta{Property: "og:image:alt", Content: card.ImageAlt},
+				CardMeta{Property: "twitter:image:alt", Content: card.ImageAlt},
+			)
+		}
+	}
+	return meta
+}
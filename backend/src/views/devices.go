@@ -0,0 +1,58 @@
+package views
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// A device Cognito remembers for the user, e.g. "iPhone" or "Chrome on Windows"
+type Device struct {
+	DeviceKey           string     `json:"device_key"`
+	Name                string     `json:"name"`
+	LastIP              string     `json:"last_ip,omitempty"`
+	CreatedAt           *time.Time `json:"created_at,omitempty"`
+	LastAuthenticatedAt *time.Time `json:"last_authenticated_at,omitempty"`
+	Current             bool       `json:"current"`
+}
+
+type DeviceNameRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// The name the user gave the device wins over the one the device reported when it was remembered
+func newDevice(device types.DeviceType, names map[string]string, currentDeviceKey string) Device {
+	deviceKey := aws.ToString(device.DeviceKey)
+	view := Device{
+		DeviceKey:           deviceKey,
+		Name:                names[deviceKey],
+		CreatedAt:           device.DeviceCreateDate,
+		LastAuthenticatedAt: device.DeviceLastAuthenticatedDate,
+		Current:             currentDeviceKey != "" && deviceKey == currentDeviceKey,
+	}
+	for _, attribute := range device.DeviceAttributes {
+		switch aws.ToString(attribute.Name) {
+		case "device_name":
+			if view.Name == "" {
+				view.Name = aws.ToString(attribute.Value)
+			}
+		case "last_ip_used":
+			view.LastIP = aws.ToString(attribute.Value)
+		}
+	}
+	return view
+}
+
+func MarshalDevices(ctx context.Context, devices []types.DeviceType, names map[string]string, currentDeviceKey string) (string, error) {
+	deviceViews := make([]Device, len(devices))
+	for i, device := range devices {
+		deviceViews[i] = newDevice(device, names, currentDeviceKey)
+	}
+	return Marshal(ctx, deviceViews)
+}
+
+func UnmarshalDeviceNameRequest(ctx context.Context, marshalledName string, name *DeviceNameRequest) error {
+	return UnmarshalRequest(ctx, marshalledName, name)
+}
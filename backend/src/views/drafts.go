@@ -0,0 +1,158 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+// The same fields as a trill request, any of which can be left empty until the draft is published.
+// reply_to makes it a reply to that trill.
+type DraftRequest struct {
+	Text           string            `json:"text" validate:"trill_length"`
+	Media          []string          `json:"media" validate:"max=4,unique,dive,required"`
+	GIF            string            `json:"gif" validate:"omitempty,url"`
+	AltText        map[string]string `json:"alt_text" validate:"dive,max=1000"`
+	Poll           *PollRequest      `json:"poll"`
+	ReplyTo        *int64            `json:"reply_to"`
+	QuoteOf        *int64            `json:"quote_of"`
+	Sensitive      bool              `json:"sensitive"`
+	ContentWarning string            `json:"content_warning" validate:"max=100"`
+	SensitiveMedia bool              `json:"sensitive_media"`
+	ReplyAudience  string            `json:"reply_audience" validate:"omitempty,oneof=everyone followers mentioned"`
+}
+
+// The contents of a trill that hasn't been posted yet, as they were sent
+type PendingTrill struct {
+	Text           string            `json:"text"`
+	Media          []string          `json:"media"`
+	GIF            string            `json:"gif,omitempty"`
+	AltText        map[string]string `json:"alt_text,omitempty"`
+	Poll           *PollRequest      `json:"poll,omitempty"`
+	ReplyTo        *int64            `json:"reply_to,omitempty"`
+	QuoteOf        *int64            `json:"quote_of,omitempty"`
+	Sensitive      bool              `json:"sensitive"`
+	ContentWarning string            `json:"content_warning,omitempty"`
+	SensitiveMedia bool              `json:"sensitive_media"`
+	ReplyAudience  string            `json:"reply_audience,omitempty"`
+}
+
+type Draft struct {
+	DraftID int64 `json:"draft_id"`
+	PendingTrill
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DraftPage struct {
+	Drafts     []Draft `json:"drafts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// The draft the request describes, for the user
+func (request *DraftRequest) Draft(username string) *models.Draft {
+	pending := newPendingTrillModel(request.Text, request.Media, request.GIF, request.AltText, request.Poll, request.ReplyTo, request.QuoteOf,
+		request.Sensitive, request.ContentWarning, request.SensitiveMedia, request.ReplyAudience)
+	return &models.Draft{
+		Username:     username,
+		PendingTrill: pending,
+	}
+}
+
+func newPendingTrillModel(text string, media []string, gif string, altText map[string]string, poll *PollRequest,
+	parentID *int64, quoteOf *int64, sensitive bool, contentWarning string, sensitiveMedia bool, replyAudience string) models.PendingTrill {
+	pending := models.PendingTrill{
+		Text:           text,
+		Media:          media,
+		GIF:            gif,
+		AltText:        altText,
+		ParentID:       parentID,
+		QuoteOfID:      quoteOf,
+		Sensitive:      sensitive,
+		ContentWarning: contentWarning,
+		SensitiveMedia: sensitiveMedia,
+		ReplyAudience:  replyAudience,
+	}
+	if poll != nil {
+		pending.PollOptions = poll.Options
+		pending.PollDurationMinutes = poll.DurationMinutes
+	}
+	return pending
+}
+
+// The trill request publishing the draft makes, which still has to pass validation
+func NewDraftTrillRequest(draft *models.Draft) *TrillRequest {
+	return &TrillRequest{
+		Text:           draft.Text,
+		Media:          draft.Media,
+		GIF:            draft.GIF,
+		AltText:        draft.AltText,
+		Poll:           pendingPoll(&draft.PendingTrill),
+		QuoteOf:        draft.QuoteOfID,
+		Sensitive:      draft.Sensitive,
+		ContentWarning: draft.ContentWarning,
+		SensitiveMedia: draft.SensitiveMedia,
+		ReplyAudience:  draft.ReplyAudience,
+	}
+}
+
+func pendingPoll(pending *models.PendingTrill) *PollRequest {
+	if len(pending.PollOptions) == 0 {
+		return nil
+	}
+	return &PollRequest{Options: pending.PollOptions, DurationMinutes: pending.PollDurationMinutes}
+}
+
+func newPendingTrill(pending *models.PendingTrill) PendingTrill {
+	media := pending.Media
+	if media == nil {
+		media = []string{}
+	}
+	return PendingTrill{
+		Text:           pending.Text,
+		Media:          media,
+		GIF:            pending.GIF,
+		AltText:        pending.AltText,
+		Poll:           pendingPoll(pending),
+		ReplyTo:        pending.ParentID,
+		QuoteOf:        pending.QuoteOfID,
+		Sensitive:      pending.Sensitive,
+		ContentWarning: pending.ContentWarning,
+		SensitiveMedia: pending.SensitiveMedia,
+		ReplyAudience:  pending.ReplyAudience,
+	}
+}
+
+func newDraft(draft *models.Draft) Draft {
+	return Draft{
+		DraftID:      draft.DraftID,
+		PendingTrill: newPendingTrill(&draft.PendingTrill),
+		CreatedAt:    draft.CreatedAt,
+		UpdatedAt:    draft.UpdatedAt,
+	}
+}
+
+func MarshalDraft(ctx context.Context, draft *models.Draft) (string, error) {
+	return Marshal(ctx, newDraft(draft))
+}
+
+func MarshalDraftPage(ctx context.Context, drafts *[]models.Draft, cursors models.Cursors) (string, error) {
+	page := DraftPage{Drafts: make([]Draft, len(*drafts))}
+	for i := range *drafts {
+		page.Drafts[i] = newDraft(&(*drafts)[i])
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func UnmarshalDraftRequest(ctx context.Context, marshalledDraft string, draft *DraftRequest) error {
+	return UnmarshalRequest(ctx, marshalledDraft, draft)
+}
@@ -0,0 +1,188 @@
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"trill/src/models"
+)
+
+type DataExport struct {
+	ExportID    string     `json:"export_id"`
+	Status      string     `json:"status"`
+	RequestedAt time.Time  `json:"requested_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresIn   int        `json:"expires_in,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type ExportProfile struct {
+	ID                string   `json:"id"`
+	Username          string   `json:"username"`
+	Nickname          string   `json:"nickname"`
+	DisplayName       string   `json:"display_name"`
+	Pronouns          string   `json:"pronouns"`
+	Bio               string   `json:"bio"`
+	ProfilePicture    string   `json:"profile_picture"`
+	BannerImage       string   `json:"banner_image"`
+	Location          string   `json:"location"`
+	Website           string   `json:"website"`
+	Birthday          string   `json:"birthday"`
+	IsPrivate         bool     `json:"is_private"`
+	Verified          bool     `json:"verified"`
+	Deactivated       bool     `json:"deactivated"`
+	PreviousUsernames []string `json:"previous_usernames"`
+}
+
+type ExportReview struct {
+	ReviewID   int       `json:"review_id"`
+	AlbumID    string    `json:"album_id"`
+	Rating     int       `json:"rating"`
+	ReviewText string    `json:"review_text"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ExportTrill struct {
+	TrillID     int64     `json:"trill_id"`
+	Text        string    `json:"text"`
+	Media       []Media   `json:"media"`
+	ParentID    *int64    `json:"parent_id,omitempty"`
+	RetrillOfID *int64    `json:"retrill_of_id,omitempty"`
+	QuoteOfID   *int64    `json:"quote_of_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportReaction struct {
+	TrillID   int64     `json:"trill_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportFollows struct {
+	Following              []string `json:"following"`
+	Followers              []string `json:"followers"`
+	FollowRequestsSent     []string `json:"follow_requests_sent"`
+	FollowRequestsReceived []string `json:"follow_requests_received"`
+}
+
+type ExportAlbums struct {
+	FavoriteAlbums    []string `json:"favorite_albums"`
+	ListenLaterAlbums []string `json:"listen_later_albums"`
+}
+
+type ExportBlocksAndMutes struct {
+	Blocked []string `json:"blocked"`
+	Muted   []string `json:"muted"`
+}
+
+// downloadURL is empty until the export is complete
+func MarshalDataExport(ctx context.Context, exportModel *models.DataExport, downloadURL string) (string, error) {
+	export := DataExport{
+		ExportID:    exportModel.ExportID,
+		Status:      exportModel.Status,
+		RequestedAt: exportModel.CreatedAt,
+		CompletedAt: exportModel.CompletedAt,
+		Error:       exportModel.Error,
+	}
+	if len(downloadURL) > 0 {
+		export.DownloadURL = downloadURL
+		export.ExpiresIn = int(models.DownloadExpiration.Seconds())
+	}
+
+	return Marshal(ctx, export)
+}
+
+// Lays the user's data out as the files of the export archive, keyed by file name
+func MarshalUserDataFiles(ctx context.Context, data *models.UserData) (map[string][]byte, error) {
+	previousUsernames := make([]string, len(data.PreviousUsernames))
+	for i, h := range data.PreviousUsernames {
+		previousUsernames[i] = h.OldUsername
+	}
+	reviews := make([]ExportReview, len(data.Reviews))
+	for i, r := range data.Reviews {
+		reviews[i] = ExportReview{r.ReviewID, r.AlbumID, r.Rating, r.ReviewText, r.CreatedAt, r.UpdatedAt}
+	}
+	trills := make([]ExportTrill, len(data.Trills))
+	for i, t := range data.Trills {
+		trills[i] = ExportTrill{t.TrillID, t.Text, trillMedia(&t), t.ParentID, t.RetrillOfID, t.QuoteOfID, t.CreatedAt}
+	}
+	likes := make([]int, len(data.Likes))
+	for i, l := range data.Likes {
+		likes[i] = l.ReviewID
+	}
+	trillLikes := make([]int64, len(data.TrillLikes))
+	for i, l := range data.TrillLikes {
+		trillLikes[i] = l.TrillID
+	}
+	reactions := make([]ExportReaction, len(data.Reactions))
+	for i, r := range data.Reactions {
+		reactions[i] = ExportReaction{r.TrillID, r.Emoji, r.CreatedAt}
+	}
+	bookmarks := make([]int64, len(data.Bookmarks))
+	for i, b := range data.Bookmarks {
+		bookmarks[i] = b.TrillID
+	}
+	albums := ExportAlbums{make([]string, len(data.FavoriteAlbums)), make([]string, len(data.ListenLaterAlbums))}
+	for i, a := range data.FavoriteAlbums {
+		albums.FavoriteAlbums[i] = a.AlbumID
+	}
+	for i, a := range data.ListenLaterAlbums {
+		albums.ListenLaterAlbums[i] = a.AlbumID
+	}
+	blocksAndMutes := ExportBlocksAndMutes{make([]string, len(data.Blocks)), make([]string, len(data.Mutes))}
+	for i, b := range data.Blocks {
+		blocksAndMutes.Blocked[i] = b.Blocked
+	}
+	for i, m := range data.Mutes {
+		blocksAndMutes.Muted[i] = m.Muted
+	}
+
+	sections := map[string]interface{}{
+		"profile.json": ExportProfile{
+			ID:                data.User.ID,
+			Username:          data.User.Username,
+			Nickname:          data.User.Nickname,
+			DisplayName:       data.User.DisplayName,
+			Pronouns:          data.User.Pronouns,
+			Bio:               data.User.Bio,
+			ProfilePicture:    data.User.ProfilePicture,
+			BannerImage:       data.User.BannerImage,
+			Location:          data.User.Location,
+			Website:           data.User.Website,
+			Birthday:          models.FormatBirthday(data.User.Birthday),
+			IsPrivate:         data.User.IsPrivate,
+			Verified:          data.User.Verified,
+			Deactivated:       data.User.DeactivatedAt != nil,
+			PreviousUsernames: previousUsernames,
+		},
+		"settings.json":    data.Settings,
+		"reviews.json":     reviews,
+		"trills.json":      trills,
+		"likes.json":       likes,
+		"trill_likes.json": trillLikes,
+		"reactions.json":   reactions,
+		"bookmarks.json":   bookmarks,
+		"follows.json": ExportFollows{
+			Following:              data.Following,
+			Followers:              data.Followers,
+			FollowRequestsSent:     data.FollowRequestsSent,
+			FollowRequestsReceived: data.FollowRequestsGot,
+		},
+		"albums.json":           albums,
+		"blocks_and_mutes.json": blocksAndMutes,
+	}
+
+	files := make(map[string][]byte, len(sections))
+	for name, section := range sections {
+		// indented since people are meant to read these
+		buf, err := json.MarshalIndent(section, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files[name] = buf
+	}
+
+	return files, nil
+}
@@ -0,0 +1,179 @@
+package views
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"trill/src/models"
+	"unicode/utf8"
+)
+
+const (
+	// seconds a feed can be cached for; readers poll on their own schedule, so there's no need to be fresher
+	FeedCacheAge = 900
+	// how many characters of a trill make an item's title
+	MaxFeedTitle = 80
+)
+
+// An RSS 2.0 document, with an atom:link to itself as the spec's validators ask
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"`
+	Self          rssLink   `xml:"atom:link"`
+	Image         *rssImage `xml:"image,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// The user's feed; self is the feed's own URL
+func MarshalUserFeed(ctx context.Context, user *models.User, trills []models.Trill, self string) (string, error) {
+	channel := rssChannel{
+		Title:       cardTitle(user),
+		Link:        models.ProfileURL(user.Username),
+		Description: user.Bio,
+	}
+	if channel.Description == "" {
+		channel.Description = fmt.Sprintf("The latest trills from @%s", user.Username)
+	}
+	if user.ProfilePicture != "" {
+		channel.Image = &rssImage{URL: user.ProfilePicture, Title: channel.Title, Link: channel.Link}
+	}
+	return marshalFeed(channel, trills, self)
+}
+
+// The hashtag's feed; self is the feed's own URL
+func MarshalHashtagFeed(ctx context.Context, tag string, trills []models.Trill, self string) (string, error) {
+	channel := rssChannel{
+		Title:       fmt.Sprintf("#%s on Trill", tag),
+		Link:        models.HashtagURL(tag),
+		Description: fmt.Sprintf("The latest trills tagged #%s", tag),
+	}
+	return marshalFeed(channel, trills, self)
+}
+
+// The newest trill's time, for Last-Modified; the zero time for an empty feed
+func FeedUpdatedAt(trills []models.Trill) time.Time {
+	var updated time.Time
+	for i := range trills {
+		if trills[i].UpdatedAt.After(updated) {
+			updated = trills[i].UpdatedAt
+		}
+	}
+	return updated
+}
+
+func marshalFeed(channel rssChannel, trills []models.Trill, self string) (string, error) {
+	channel.Language = "en"
+	channel.TTL = FeedCacheAge / 60
+	channel.Self = rssLink{Href: self, Rel: "self", Type: "application/rss+xml"}
+	if updated := FeedUpdatedAt(trills); !updated.IsZero() {
+		channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+	channel.Items = make([]rssItem, len(trills))
+	for i := range trills {
+		channel.Items[i] = newFeedItem(&trills[i])
+	}
+
+	body, err := xml.MarshalIndent(rssFeed{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(body), nil
+}
+
+// A retrill's item is the original, linked to where it was first posted, under the retrill's date.
+// Sensitive trills show only their content warning, since readers have no way to blur them.
+func newFeedItem(trill *models.Trill) rssItem {
+	shown := trill
+	prefix := ""
+	if trill.RetrillOf != nil {
+		shown = trill.RetrillOf
+		prefix = fmt.Sprintf("Retrilled @%s: ", shown.Username)
+	}
+
+	link := models.TrillURL(shown)
+	item := rssItem{
+		Link:    link,
+		PubDate: trill.CreatedAt.UTC().Format(time.RFC1123Z),
+		GUID:    rssGUID{Value: models.TrillURL(trill), IsPermaLink: true},
+	}
+
+	if shown.Sensitive {
+		warning := shown.ContentWarning
+		if warning == "" {
+			warning = "This trill may contain sensitive content."
+		}
+		item.Title = prefix + warning
+		item.Description = fmt.Sprintf(`<p>%s</p><p><a href="%s">View on Trill</a></p>`, html.EscapeString(warning), html.EscapeString(link))
+		return item
+	}
+
+	item.Title = prefix + feedTitle(shown.Text)
+	var description strings.Builder
+	fmt.Fprintf(&description, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(shown.Text), "\n", "<br>"))
+	for _, media := range trillMedia(shown) {
+		if media.URL == "" || media.Type == models.MediaKindVideo {
+			continue
+		} else if media.Type == models.MediaKindAudio {
+			fmt.Fprintf(&description, `<p><audio controls src="%s" title="%s"></audio></p>`, html.EscapeString(media.URL), html.EscapeString(media.AltText))
+			continue
+		}
+		fmt.Fprintf(&description, `<p><img src="%s" alt="%s"></p>`, html.EscapeString(media.URL), html.EscapeString(media.AltText))
+	}
+	if shown.QuoteOf != nil && !shown.QuoteOf.User.IsPrivate {
+		fmt.Fprintf(&description, `<blockquote><p>%s</p>&mdash; @%s</blockquote>`,
+			strings.ReplaceAll(html.EscapeString(shown.QuoteOf.Text), "\n", "<br>"), html.EscapeString(shown.QuoteOf.Username))
+	}
+	item.Description = description.String()
+	return item
+}
+
+// The first line of the text, cut down to MaxFeedTitle characters
+func feedTitle(text string) string {
+	title := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	if title == "" {
+		return "Trill"
+	}
+	if utf8.RuneCountInString(title) > MaxFeedTitle {
+		return string([]rune(title)[:MaxFeedTitle-1]) + "…"
+	}
+	return title
+}
@@ -0,0 +1,21 @@
+package views
+
+import (
+	"context"
+	"trill/src/models"
+)
+
+// next_page is left out once the provider runs out of results
+type GIFPage struct {
+	GIFs     []models.GIF `json:"gifs"`
+	NextPage int          `json:"next_page,omitempty"`
+}
+
+func MarshalGIFPage(ctx context.Context, gifs []models.GIF, paginate *models.Paginate) (string, error) {
+	page := GIFPage{GIFs: gifs}
+	if len(gifs) == paginate.Limit {
+		page.NextPage = paginate.Page + 1
+	}
+
+	return Marshal(ctx, page)
+}
@@ -0,0 +1,25 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+type FollowedHashtag struct {
+	Tag        string    `json:"tag"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowedHashtags struct {
+	Hashtags []FollowedHashtag `json:"hashtags"`
+}
+
+func MarshalFollowedHashtags(ctx context.Context, follows *[]models.HashtagFollow) (string, error) {
+	page := FollowedHashtags{Hashtags: make([]FollowedHashtag, len(*follows))}
+	for i, follow := range *follows {
+		page.Hashtags[i] = FollowedHashtag{Tag: follow.Hashtag.Tag, FollowedAt: follow.CreatedAt}
+	}
+
+	return Marshal(ctx, page)
+}
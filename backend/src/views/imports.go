@@ -0,0 +1,53 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+// An import's progress. processed_tweets counts up to total_tweets as the worker goes, and each
+// processed tweet was either imported or skipped. upload_url is only there when the import was just
+// made, to PUT the archive to.
+type TwitterImport struct {
+	ImportID        string     `json:"import_id"`
+	Status          string     `json:"status"`
+	RequestedAt     time.Time  `json:"requested_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	TotalTweets     int        `json:"total_tweets"`
+	ProcessedTweets int        `json:"processed_tweets"`
+	ImportedTweets  int        `json:"imported_tweets"`
+	SkippedTweets   int        `json:"skipped_tweets"`
+	Progress        int        `json:"progress"`
+	UploadURL       string     `json:"upload_url,omitempty"`
+	ExpiresIn       int        `json:"expires_in,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+func MarshalTwitterImport(ctx context.Context, importModel *models.TwitterImport, uploadURL string) (string, error) {
+	imp := TwitterImport{
+		ImportID:        importModel.ImportID,
+		Status:          importModel.Status,
+		RequestedAt:     importModel.CreatedAt,
+		StartedAt:       importModel.StartedAt,
+		CompletedAt:     importModel.CompletedAt,
+		TotalTweets:     importModel.TotalTweets,
+		ProcessedTweets: importModel.ProcessedTweets,
+		ImportedTweets:  importModel.ImportedTweets,
+		SkippedTweets:   importModel.SkippedTweets,
+		Error:           importModel.Error,
+	}
+	// a percentage, so clients can draw a bar
+	if importModel.Status == models.ImportComplete {
+		imp.Progress = 100
+	} else if importModel.TotalTweets > 0 {
+		imp.Progress = importModel.ProcessedTweets * 100 / importModel.TotalTweets
+	}
+	if len(uploadURL) > 0 {
+		imp.UploadURL = uploadURL
+		imp.ExpiresIn = int(models.ImportUploadExpiration.Seconds())
+	}
+
+	return Marshal(ctx, imp)
+}
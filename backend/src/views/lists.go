@@ -0,0 +1,119 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+type ListRequest struct {
+	Name        string `json:"name" validate:"required,max=50"`
+	Description string `json:"description" validate:"max=200"`
+	IsPrivate   bool   `json:"is_private"`
+}
+
+// Fields an owner is allowed to change through PATCH - /lists/{listID}
+type PatchList struct {
+	Name        *string `json:"name" validate:"omitempty,max=50"`
+	Description *string `json:"description" validate:"omitempty,max=200"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+type List struct {
+	ListID      int64       `json:"list_id"`
+	Owner       models.User `json:"owner"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	IsPrivate   bool        `json:"is_private"`
+	MemberCount int64       `json:"member_count"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+type Lists struct {
+	Lists []List `json:"lists"`
+}
+
+// A user on a list and when they were added
+type ListMember struct {
+	models.User
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ListMemberPage struct {
+	Members    []ListMember `json:"members"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+// The list the request describes, for the owner
+func (request *ListRequest) List(owner string) *models.List {
+	return &models.List{
+		Owner:       owner,
+		Name:        strings.TrimSpace(request.Name),
+		Description: strings.TrimSpace(request.Description),
+		IsPrivate:   request.IsPrivate,
+	}
+}
+
+// The changes the patch asks for
+func (patch *PatchList) Changes() *models.ListChanges {
+	changes := models.ListChanges{Description: patch.Description, IsPrivate: patch.IsPrivate}
+	if patch.Name != nil {
+		name := strings.TrimSpace(*patch.Name)
+		changes.Name = &name
+	}
+	return &changes
+}
+
+func newList(list *models.List) List {
+	return List{
+		ListID:      list.ListID,
+		Owner:       list.OwnerUser,
+		Name:        list.Name,
+		Description: list.Description,
+		IsPrivate:   list.IsPrivate,
+		MemberCount: list.MemberCount,
+		CreatedAt:   list.CreatedAt,
+		UpdatedAt:   list.UpdatedAt,
+	}
+}
+
+func MarshalList(ctx context.Context, list *models.List) (string, error) {
+	return Marshal(ctx, newList(list))
+}
+
+func MarshalLists(ctx context.Context, lists *[]models.List) (string, error) {
+	page := Lists{Lists: make([]List, len(*lists))}
+	for i := range *lists {
+		page.Lists[i] = newList(&(*lists)[i])
+	}
+
+	return Marshal(ctx, page)
+}
+
+func MarshalListMemberPage(ctx context.Context, members *[]models.ListMember, cursors models.Cursors) (string, error) {
+	page := ListMemberPage{Members: make([]ListMember, len(*members))}
+	for i, member := range *members {
+		page.Members[i] = ListMember{User: member.User, AddedAt: member.CreatedAt}
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func UnmarshalListRequest(ctx context.Context, marshalledList string, list *ListRequest) error {
+	return UnmarshalRequest(ctx, marshalledList, list)
+}
+
+// Rejects any field that isn't part of PatchList
+func UnmarshalPatchList(ctx context.Context, marshalledPatch string, patch *PatchList) error {
+	return UnmarshalStrictRequest(ctx, marshalledPatch, patch)
+}
@@ -0,0 +1,55 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+type Notification struct {
+	NotificationID int64       `json:"notification_id"`
+	Type           string      `json:"type"`
+	Actor          models.User `json:"actor"`
+	Trill          *Trill      `json:"trill,omitempty"`
+	Read           bool        `json:"read"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// unread_count covers every notification, not just this page
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+	PrevCursor    string         `json:"prev_cursor,omitempty"`
+}
+
+func MarshalNotificationPage(ctx context.Context, notifications *[]models.Notification, unread int64,
+	viewer *models.TrillViewer, cursors models.Cursors) (string, error) {
+	page := NotificationPage{
+		Notifications: make([]Notification, len(*notifications)),
+		UnreadCount:   unread,
+	}
+	for i := range *notifications {
+		notification := &(*notifications)[i]
+		page.Notifications[i] = Notification{
+			NotificationID: notification.NotificationID,
+			Type:           notification.Type,
+			Actor:          notification.ActorUser,
+			Read:           notification.ReadAt != nil,
+			CreatedAt:      notification.CreatedAt,
+		}
+		if notification.Trill != nil {
+			trill := newTrill(notification.Trill, viewer)
+			page.Notifications[i].Trill = &trill
+		}
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
@@ -0,0 +1,152 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"time"
+	"trill/src/models"
+)
+
+// Token responses can't be cached anywhere along the way (RFC 6749 section 5.1)
+var NoStoreHeaders = map[string]string{
+	"Content-Type":                     "application/json",
+	"Access-Control-Allow-Origin":      "*",
+	"Access-Control-Allow-Credentials": "true",
+	"Cache-Control":                    "no-store",
+	"Pragma":                           "no-cache",
+}
+
+type OAuthAppRequest struct {
+	Name         string   `json:"name" validate:"required,max=64"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,max=5"`
+	Scopes       []string `json:"scopes" validate:"required,min=1"`
+}
+
+type OAuthApp struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// The only response that includes the client secret
+type NewOAuthApp struct {
+	OAuthApp
+	ClientSecret string `json:"client_secret"`
+}
+
+// The parameters of an authorization request, sent by the consent screen as a query string to
+// look the app up and as a JSON body once the user approves. PKCE only supports S256, so
+// code_challenge_method can be left out.
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" validate:"required,oneof=code"`
+	ClientID            string `json:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri" validate:"required"`
+	Scope               string `json:"scope"`
+	State               string `json:"state" validate:"max=512"`
+	CodeChallenge       string `json:"code_challenge" validate:"max=128"`
+	CodeChallengeMethod string `json:"code_challenge_method" validate:"omitempty,oneof=S256"`
+}
+
+// What the consent screen shows the user before they approve
+type OAuthConsent struct {
+	ClientID string   `json:"client_id"`
+	Name     string   `json:"name"`
+	Owner    string   `json:"owner"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Where to send the user's browser once they approve, with the code and state attached
+type AuthorizeResult struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// The token endpoint's response, in the shape RFC 6749 section 5.1 asks for
+type OAuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// Token endpoint errors, in the shape RFC 6749 section 5.2 asks for
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func newOAuthApp(app *models.OAuthApp) OAuthApp {
+	return OAuthApp{
+		ClientID:     app.ClientID,
+		Name:         app.Name,
+		RedirectURIs: app.RedirectURIList(),
+		Scopes:       app.ScopeList(),
+		CreatedAt:    app.CreatedAt,
+	}
+}
+
+// OAuth scopes are space-separated
+func (authorize *AuthorizeRequest) ScopeList() []string {
+	return strings.Fields(authorize.Scope)
+}
+
+func MarshalOAuthApps(ctx context.Context, apps []models.OAuthApp) (string, error) {
+	appViews := make([]OAuthApp, len(apps))
+	for i := range apps {
+		appViews[i] = newOAuthApp(&apps[i])
+	}
+	return Marshal(ctx, appViews)
+}
+
+func MarshalNewOAuthApp(ctx context.Context, app *models.OAuthApp, clientSecret string) (string, error) {
+	return Marshal(ctx, NewOAuthApp{OAuthApp: newOAuthApp(app), ClientSecret: clientSecret})
+}
+
+func MarshalOAuthConsent(ctx context.Context, app *models.OAuthApp, scopes []string) (string, error) {
+	return Marshal(ctx, OAuthConsent{ClientID: app.ClientID, Name: app.Name, Owner: app.Owner, Scopes: scopes})
+}
+
+func MarshalAuthorizeResult(ctx context.Context, redirectURI string) (string, error) {
+	return Marshal(ctx, AuthorizeResult{RedirectURI: redirectURI})
+}
+
+func MarshalOAuthTokens(ctx context.Context, grant *models.OAuthGrant) (string, error) {
+	return Marshal(ctx, OAuthTokens{
+		AccessToken:  grant.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    grant.ExpiresIn,
+		RefreshToken: grant.RefreshToken,
+		Scope:        strings.Join(grant.Scopes, " "),
+	})
+}
+
+func MarshalOAuthError(ctx context.Context, authErr *models.AuthError) (string, error) {
+	return Marshal(ctx, OAuthError{
+		Error:            authErr.Reason,
+		ErrorDescription: authErr.Error(),
+	})
+}
+
+func UnmarshalOAuthAppRequest(ctx context.Context, marshalledApp string, app *OAuthAppRequest) error {
+	return UnmarshalRequest(ctx, marshalledApp, app)
+}
+
+func UnmarshalAuthorizeRequest(ctx context.Context, marshalledAuthorize string, authorize *AuthorizeRequest) error {
+	return UnmarshalRequest(ctx, marshalledAuthorize, authorize)
+}
+
+// Fills an AuthorizeRequest from the query string the consent screen was opened with
+func AuthorizeRequestFromQuery(ctx context.Context, query map[string]string, authorize *AuthorizeRequest) error {
+	*authorize = AuthorizeRequest{
+		ResponseType:        query["response_type"],
+		ClientID:            query["client_id"],
+		RedirectURI:         query["redirect_uri"],
+		Scope:               query["scope"],
+		State:               query["state"],
+		CodeChallenge:       query["code_challenge"],
+		CodeChallengeMethod: query["code_challenge_method"],
+	}
+	return Validate(ctx, authorize)
+}
@@ -0,0 +1,83 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"trill/src/models"
+)
+
+const (
+	// the widest and narrowest an embedded trill is drawn
+	MaxEmbedWidth = 550
+	MinEmbedWidth = 220
+	// seconds consumers can cache an embed for
+	EmbedCacheAge = 3600
+)
+
+// The oEmbed 1.0 response for a trill, a rich embed whose html is a blockquote that embed.js from the web
+// app turns into a card; without the script it still reads as a quote with a link back
+type OEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	URL          string `json:"url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       *int   `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+// The embed for the trill, at most maxWidth wide when it's set. Sensitive trills show their content
+// warning in place of the text, and the script tag is left off with omitScript, for pages that load it
+// once themselves.
+func MarshalTrillOEmbed(ctx context.Context, trill *models.Trill, maxWidth int, omitScript bool) (string, error) {
+	width := MaxEmbedWidth
+	if maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+	}
+	if width < MinEmbedWidth {
+		width = MinEmbedWidth
+	}
+
+	webApp := models.WebAppURL()
+	authorURL := fmt.Sprintf("%s/%s", webApp, trill.Username)
+	name := trill.User.DisplayName
+	if name == "" {
+		name = trill.Username
+	}
+
+	text := trill.Text
+	if trill.Sensitive {
+		text = trill.ContentWarning
+		if text == "" {
+			text = "This trill may contain sensitive content."
+		}
+	}
+
+	var embed strings.Builder
+	fmt.Fprintf(&embed, `<blockquote class="trill-embed" data-width="%d">`, width)
+	fmt.Fprintf(&embed, `<p>%s</p>`, strings.ReplaceAll(html.EscapeString(text), "\n", "<br>"))
+	fmt.Fprintf(&embed, `&mdash; %s (@%s) <a href="%s">%s</a></blockquote>`, html.EscapeString(name), html.EscapeString(trill.Username),
+		html.EscapeString(models.TrillURL(trill)), trill.CreatedAt.UTC().Format("January 2, 2006"))
+	if !omitScript {
+		fmt.Fprintf(&embed, `<script async src="%s/embed.js" charset="utf-8"></script>`, html.EscapeString(webApp))
+	}
+
+	return Marshal(ctx, OEmbed{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Trill",
+		ProviderURL:  webApp,
+		AuthorName:   name,
+		AuthorURL:    authorURL,
+		URL:          models.TrillURL(trill),
+		HTML:         embed.String(),
+		Width:        width,
+		CacheAge:     EmbedCacheAge,
+	})
+}
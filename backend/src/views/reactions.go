@@ -0,0 +1,64 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji" validate:"required,max=32,emoji"`
+}
+
+// How many users reacted to a trill with an emoji, and whether the requestor is one of them
+type Reaction struct {
+	Emoji            string `json:"emoji"`
+	Count            int64  `json:"count"`
+	RequestorReacted bool   `json:"requestor_reacted"`
+}
+
+// A user who reacted to a trill, with the emoji and when
+type Reactor struct {
+	models.User
+	Emoji     string    `json:"emoji"`
+	ReactedAt time.Time `json:"reacted_at"`
+}
+
+type ReactorPage struct {
+	Reactors   []Reactor `json:"reactors"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+}
+
+func trillReactions(trill *models.Trill, viewer *models.TrillViewer) []Reaction {
+	reacted := make(map[string]bool)
+	for _, emoji := range viewer.Reacted[trill.TrillID] {
+		reacted[emoji] = true
+	}
+
+	reactions := make([]Reaction, len(trill.Reactions))
+	for i, count := range trill.Reactions {
+		reactions[i] = Reaction{Emoji: count.Emoji, Count: count.Count, RequestorReacted: reacted[count.Emoji]}
+	}
+	return reactions
+}
+
+func MarshalReactorPage(ctx context.Context, reactors *[]models.Reactor, cursors models.Cursors) (string, error) {
+	page := ReactorPage{Reactors: make([]Reactor, len(*reactors))}
+	for i, reactor := range *reactors {
+		page.Reactors[i] = Reactor{User: reactor.User, Emoji: reactor.Emoji, ReactedAt: reactor.ReactedAt}
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func UnmarshalReactionRequest(ctx context.Context, marshalledReaction string, reaction *ReactionRequest) error {
+	return UnmarshalRequest(ctx, marshalledReaction, reaction)
+}
@@ -0,0 +1,80 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+type ReportRequest struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate_speech impersonation inappropriate_content self_harm other"`
+	Details string `json:"details" validate:"max=1000"`
+}
+
+type ReportResolution struct {
+	Status string `json:"status" validate:"required,oneof=resolved dismissed"`
+}
+
+// reporter is empty for reports the link scanner opened, and trill_id is left out for reports about a
+// user as a whole
+type Report struct {
+	ReportID   int64      `json:"report_id"`
+	Reporter   string     `json:"reporter"`
+	Reported   string     `json:"reported"`
+	TrillID    *int64     `json:"trill_id,omitempty"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
+
+type ReportQueue struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	PrevCursor string   `json:"prev_cursor,omitempty"`
+}
+
+func newReport(report *models.Report) Report {
+	return Report{
+		ReportID:   report.ReportID,
+		Reporter:   report.Reporter,
+		Reported:   report.Reported,
+		TrillID:    report.TrillID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		CreatedAt:  report.CreatedAt,
+		ResolvedAt: report.ResolvedAt,
+		ResolvedBy: report.ResolvedBy,
+	}
+}
+
+func MarshalReport(ctx context.Context, report *models.Report) (string, error) {
+	return Marshal(ctx, newReport(report))
+}
+
+func MarshalReportQueue(ctx context.Context, reports *[]models.Report, cursors models.Cursors) (string, error) {
+	queue := ReportQueue{Reports: make([]Report, len(*reports))}
+	for i := range *reports {
+		queue.Reports[i] = newReport(&(*reports)[i])
+	}
+	if cursors.Next != nil {
+		queue.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		queue.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, queue)
+}
+
+func UnmarshalReportRequest(ctx context.Context, marshalledReport string, report *ReportRequest) error {
+	return UnmarshalRequest(ctx, marshalledReport, report)
+}
+
+func UnmarshalReportResolution(ctx context.Context, marshalledResolution string, resolution *ReportResolution) error {
+	return UnmarshalRequest(ctx, marshalledResolution, resolution)
+}
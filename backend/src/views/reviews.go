@@ -64,5 +64,5 @@ func MarshalReviews(ctx context.Context, reviewModels *[]models.Review, requesto
 }
 
 func UnmarshalReview(ctx context.Context, marshalledReview string, reviewModel *models.Review) error {
-	return Unmarshal(ctx, marshalledReview, reviewModel)
+	return UnmarshalRequest(ctx, marshalledReview, reviewModel)
 }
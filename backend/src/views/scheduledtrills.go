@@ -0,0 +1,46 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+// A trill waiting to be posted. status is pending until publish_at, and failed with the reason in error
+// if it couldn't be posted then; posted trills drop off the list.
+type ScheduledTrill struct {
+	ScheduledTrillID int64 `json:"scheduled_trill_id"`
+	PendingTrill
+	PublishAt time.Time `json:"publish_at"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTrills struct {
+	ScheduledTrills []ScheduledTrill `json:"scheduled_trills"`
+}
+
+func newScheduledTrill(scheduled *models.ScheduledTrill) ScheduledTrill {
+	return ScheduledTrill{
+		ScheduledTrillID: scheduled.ScheduledTrillID,
+		PendingTrill:     newPendingTrill(&scheduled.PendingTrill),
+		PublishAt:        scheduled.PublishAt,
+		Status:           scheduled.Status,
+		Error:            scheduled.Error,
+		CreatedAt:        scheduled.CreatedAt,
+	}
+}
+
+func MarshalScheduledTrill(ctx context.Context, scheduled *models.ScheduledTrill) (string, error) {
+	return Marshal(ctx, newScheduledTrill(scheduled))
+}
+
+func MarshalScheduledTrills(ctx context.Context, scheduled *[]models.ScheduledTrill) (string, error) {
+	list := ScheduledTrills{ScheduledTrills: make([]ScheduledTrill, len(*scheduled))}
+	for i := range *scheduled {
+		list.ScheduledTrills[i] = newScheduledTrill(&(*scheduled)[i])
+	}
+
+	return Marshal(ctx, list)
+}
@@ -0,0 +1,15 @@
+package views
+
+import (
+	"context"
+	"trill/src/models"
+)
+
+func MarshalUserSettings(ctx context.Context, settingsModel *models.UserSettings) (string, error) {
+	return Marshal(ctx, settingsModel)
+}
+
+// Fields missing from the body keep their current values
+func UnmarshalUserSettings(ctx context.Context, marshalledSettings string, settingsModel *models.UserSettings) error {
+	return UnmarshalRequest(ctx, marshalledSettings, settingsModel)
+}
@@ -0,0 +1,104 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+// media is the key from POST /trills/media for the story's one image or video
+type StoryRequest struct {
+	Media   string `json:"media" validate:"required"`
+	Caption string `json:"caption" validate:"max=200"`
+	AltText string `json:"alt_text" validate:"max=1000"`
+}
+
+// view_count is only shown to the author
+type Story struct {
+	StoryID   int64       `json:"story_id"`
+	User      models.User `json:"user"`
+	Caption   string      `json:"caption"`
+	Media     Media       `json:"media"`
+	ViewCount *int64      `json:"view_count,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+type Stories struct {
+	Stories []Story `json:"stories"`
+}
+
+// A user who saw a story and when
+type StoryViewer struct {
+	models.User
+	ViewedAt time.Time `json:"viewed_at"`
+}
+
+type StoryViewerPage struct {
+	Viewers    []StoryViewer `json:"viewers"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// The story the request describes, for the author, with its upload as checked by models.ValidateTrillMedia
+func (request *StoryRequest) Story(username string, media models.Media) *models.Story {
+	return &models.Story{Username: username, Caption: strings.TrimSpace(request.Caption), Media: media}
+}
+
+// alt_text as ApplyAltText takes it, keyed by the upload
+func (request *StoryRequest) AltTextByKey() map[string]string {
+	if request.AltText == "" {
+		return nil
+	}
+	return map[string]string{request.Media: request.AltText}
+}
+
+func newStory(story *models.Story, requestor string) Story {
+	view := Story{
+		StoryID:   story.StoryID,
+		User:      story.User,
+		Caption:   story.Caption,
+		Media:     newMedia(&story.Media, false),
+		CreatedAt: story.CreatedAt,
+		ExpiresAt: story.ExpiresAt,
+	}
+	if story.Username == requestor {
+		viewCount := story.ViewCount
+		view.ViewCount = &viewCount
+	}
+	return view
+}
+
+func MarshalStory(ctx context.Context, story *models.Story, requestor string) (string, error) {
+	return Marshal(ctx, newStory(story, requestor))
+}
+
+func MarshalStories(ctx context.Context, stories *[]models.Story, requestor string) (string, error) {
+	page := Stories{Stories: make([]Story, len(*stories))}
+	for i := range *stories {
+		page.Stories[i] = newStory(&(*stories)[i], requestor)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func MarshalStoryViewerPage(ctx context.Context, storyViews *[]models.StoryView, cursors models.Cursors) (string, error) {
+	page := StoryViewerPage{Viewers: make([]StoryViewer, len(*storyViews))}
+	for i, view := range *storyViews {
+		page.Viewers[i] = StoryViewer{User: view.User, ViewedAt: view.ViewedAt}
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func UnmarshalStoryRequest(ctx context.Context, marshalledStory string, story *StoryRequest) error {
+	return UnmarshalRequest(ctx, marshalledStory, story)
+}
@@ -0,0 +1,23 @@
+package views
+
+import (
+	"context"
+	"trill/src/models"
+)
+
+// source_language is the language Translate detected the trill was written in
+type TrillTranslation struct {
+	TrillID        int64  `json:"trill_id"`
+	Language       string `json:"language"`
+	SourceLanguage string `json:"source_language"`
+	Text           string `json:"text"`
+}
+
+func MarshalTrillTranslation(ctx context.Context, translation *models.TrillTranslation) (string, error) {
+	return Marshal(ctx, TrillTranslation{
+		TrillID:        translation.TrillID,
+		Language:       translation.Language,
+		SourceLanguage: translation.SourceLanguage,
+		Text:           translation.Text,
+	})
+}
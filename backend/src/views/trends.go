@@ -0,0 +1,32 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+)
+
+type Trend struct {
+	Tag        string `json:"tag"`
+	Rank       int    `json:"rank"`
+	TrillCount int64  `json:"trill_count"`
+}
+
+// region is left out for worldwide trends; computed_at is left out when nothing is trending
+type Trends struct {
+	Region     string     `json:"region,omitempty"`
+	ComputedAt *time.Time `json:"computed_at,omitempty"`
+	Trends     []Trend    `json:"trends"`
+}
+
+func MarshalTrends(ctx context.Context, region string, trends []models.Trend) (string, error) {
+	page := Trends{Region: region, Trends: make([]Trend, len(trends))}
+	for i, trend := range trends {
+		page.Trends[i] = Trend{Tag: trend.Tag, Rank: trend.Position, TrillCount: trend.TrillCount}
+	}
+	if len(trends) > 0 {
+		page.ComputedAt = &trends[0].ComputedAt
+	}
+
+	return Marshal(ctx, page)
+}
@@ -0,0 +1,530 @@
+package views
+
+import (
+	"context"
+	"time"
+	"trill/src/models"
+	"trill/src/pagination"
+)
+
+// Text, media or a GIF, or text with either; media are keys from POST /trills/media, gif is a url from
+// GET /gifs, and quote_of is the ID of a trill to embed. alt_text sets or replaces the alt text of the
+// media or GIF, keyed by the same key or url. A poll goes with text and no media. publish_at schedules
+// the trill to be posted later instead of now. sensitive covers the whole trill, and a content_warning
+// marks it sensitive and says why; sensitive_media covers only the media. reply_audience limits who can
+// reply, to the author's followers or the users the trill mentions.
+type TrillRequest struct {
+	Text           string            `json:"text" validate:"required_without_all=Media GIF,trill_length"`
+	Media          []string          `json:"media" validate:"max=4,unique,dive,required"`
+	GIF            string            `json:"gif" validate:"omitempty,url"`
+	AltText        map[string]string `json:"alt_text" validate:"dive,max=1000"`
+	Poll           *PollRequest      `json:"poll"`
+	QuoteOf        *int64            `json:"quote_of"`
+	PublishAt      *time.Time        `json:"publish_at"`
+	Sensitive      bool              `json:"sensitive"`
+	ContentWarning string            `json:"content_warning" validate:"max=100"`
+	SensitiveMedia bool              `json:"sensitive_media"`
+	ReplyAudience  string            `json:"reply_audience" validate:"omitempty,oneof=everyone followers mentioned"`
+}
+
+// The trills of a thread in order, each a reply to the one before; none of them can have publish_at
+type ThreadRequest struct {
+	Trills []TrillRequest `json:"trills" validate:"min=2,max=25,dive"`
+}
+
+// The new text for a trill; it can only be left empty if the trill has media
+type EditTrillRequest struct {
+	Text string `json:"text" validate:"trill_length"`
+}
+
+// The pending trill the request describes, replying to parentID when it's set
+func (request *TrillRequest) PendingTrill(parentID *int64) models.PendingTrill {
+	return newPendingTrillModel(request.Text, request.Media, request.GIF, request.AltText, request.Poll, parentID, request.QuoteOf,
+		request.Sensitive, request.ContentWarning, request.SensitiveMedia, request.ReplyAudience)
+}
+
+// 2-4 distinct options, open for 5 minutes to 7 days
+type PollRequest struct {
+	Options         []string `json:"options" validate:"min=2,max=4,unique,dive,required,max=25"`
+	DurationMinutes int      `json:"duration_minutes" validate:"min=5,max=10080"`
+}
+
+type VoteRequest struct {
+	Option *int `json:"option" validate:"required,min=0"`
+}
+
+type Trill struct {
+	TrillID             int64       `json:"trill_id"`
+	User                models.User `json:"user"`
+	Text                string      `json:"text"`
+	Media               []Media     `json:"media"`
+	Mentions            []Mention   `json:"mentions"`
+	Links               []Link      `json:"links"`
+	Sensitive           bool        `json:"sensitive"`
+	ContentWarning      string      `json:"content_warning,omitempty"`
+	Poll                *Poll       `json:"poll,omitempty"`
+	ReplyAudience       string      `json:"reply_audience"`
+	RequestorCanReply   bool        `json:"requestor_can_reply"`
+	ParentID            *int64      `json:"parent_id,omitempty"`
+	ConversationID      int64       `json:"conversation_id"`
+	ReplyCount          int64       `json:"reply_count"`
+	RetrillCount        int64       `json:"retrill_count"`
+	QuoteCount          int64       `json:"quote_count"`
+	LikeCount           int64       `json:"like_count"`
+	Reactions           []Reaction  `json:"reactions"`
+	ViewCount           int64       `json:"view_count"`
+	RequestorLiked      bool        `json:"requestor_liked"`
+	RequestorRetrilled  bool        `json:"requestor_retrilled"`
+	RequestorBookmarked bool        `json:"requestor_bookmarked"`
+	CreatedAt           time.Time   `json:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at"`
+	// edited_at is when the text was last changed, left out if it never was
+	Edited   bool       `json:"edited"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// left out until the page has been fetched, and for links that can't be previewed
+	LinkPreview *LinkPreview `json:"link_preview,omitempty"`
+	// the threat a link in the text was flagged for, e.g. MALWARE, left out if none was
+	LinkWarning string `json:"link_warning,omitempty"`
+	// the site it was imported from, e.g. twitter, left out for trills posted here; created_at is when it
+	// was first posted there
+	ImportedFrom string `json:"imported_from,omitempty"`
+	// set on a retrill, which has no content of its own; user and created_at are who retrilled it and when
+	RetrillOf *Trill `json:"retrill_of,omitempty"`
+	// quote_of is left out once the quoted trill is deleted, but quote_of_id stays; it's an UnavailableTrill
+	// when the requestor can't see the quoted trill
+	QuoteOfID *int64      `json:"quote_of_id,omitempty"`
+	QuoteOf   interface{} `json:"quote_of,omitempty"`
+	// why a home timeline trill from an account the requestor doesn't follow is there, left out otherwise
+	Reason *TimelineReason `json:"reason,omitempty"`
+}
+
+const (
+	ReasonFollowedHashtag = "followed_hashtag"
+)
+
+// text is ready to show above the trill, e.g. "because you follow #golang"
+type TimelineReason struct {
+	Type    string `json:"type"`
+	Hashtag string `json:"hashtag,omitempty"`
+	Text    string `json:"text"`
+}
+
+// What GET /trills/{trillID}?expand= can hydrate
+const (
+	ExpandAuthor = "author"
+	ExpandQuoted = "quoted"
+	ExpandMedia  = "media"
+)
+
+// A trill for its permalink page. author is the full profile of whoever posted it, as the requestor sees
+// them, and is only there when expanded. Without expanding quoted, quote_of is left out but quote_of_id
+// stays, and without media, media is null.
+type TrillPermalink struct {
+	Trill
+	Author *PublicUser `json:"author,omitempty"`
+}
+
+// The card for the first link in a trill's text
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// url is the image, or for a video the MP4 rendition, which like hls_url is only set once status is ready.
+// A voice note's url is its M4A rendition, also set once it's ready, along with a waveform of levels from
+// 0 to 100 to draw. Width and height are what it displays at, or 0 for images attached before dimensions
+// were recorded, and for voice notes.
+// Sensitive media, or any media on a sensitive trill, is blurred unless the viewer chose to see it.
+type Media struct {
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	AltText     string `json:"alt_text"`
+	Sensitive   bool   `json:"sensitive"`
+	Blurred     bool   `json:"blurred"`
+	URL         string `json:"url,omitempty"`
+	HLSURL      string `json:"hls_url,omitempty"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	DurationMs  int64  `json:"duration_ms,omitempty"`
+	Waveform    []int  `json:"waveform,omitempty"`
+}
+
+// An @username in the text that links to a user, with offsets in characters (not bytes or UTF-16 units);
+// start is on the @ and end is exclusive
+type Mention struct {
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// A link in the text and the short URL it should point at, so clicks on it are counted; offsets are in
+// characters like a mention's
+type Link struct {
+	URL      string `json:"url"`
+	ShortURL string `json:"short_url"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	// set once the link is flagged as unsafe, after which short_url no longer redirects
+	Threat string `json:"threat,omitempty"`
+}
+
+// The counts are left out until the requestor has voted or the poll has closed, so they can't sway the vote
+type Poll struct {
+	Options       []PollOption `json:"options"`
+	VoteCount     *int64       `json:"vote_count"`
+	ClosesAt      time.Time    `json:"closes_at"`
+	Closed        bool         `json:"closed"`
+	RequestorVote *int         `json:"requestor_vote"`
+}
+
+type PollOption struct {
+	Text      string `json:"text"`
+	VoteCount *int64 `json:"vote_count"`
+}
+
+// One version of a trill's text and when it was posted
+type TrillRevision struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Every version of a trill's text, newest first, starting with the current one
+type TrillHistory struct {
+	TrillID   int64           `json:"trill_id"`
+	Revisions []TrillRevision `json:"revisions"`
+}
+
+// prev_cursor is only set by endpoints that can page backward
+type TrillPage struct {
+	Trills     []Trill `json:"trills"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// A reply along with the first few replies to it; reply_count says whether there are more to fetch
+type ThreadReply struct {
+	Trill
+	Replies []Trill `json:"replies"`
+}
+
+// Stands in for a deleted trill in a thread, keeping the replies under it attached; deleted is always true
+type Tombstone struct {
+	TrillID        int64     `json:"trill_id"`
+	Deleted        bool      `json:"deleted"`
+	ParentID       *int64    `json:"parent_id,omitempty"`
+	ConversationID int64     `json:"conversation_id"`
+	ReplyCount     int64     `json:"reply_count"`
+	DeletedAt      time.Time `json:"deleted_at"`
+}
+
+// Stands in for a quoted trill the requestor can't see, without anything about its content or author;
+// unavailable is always true
+type UnavailableTrill struct {
+	TrillID     int64 `json:"trill_id"`
+	Unavailable bool  `json:"unavailable"`
+}
+
+// A deleted reply along with the first few replies to it
+type ThreadTombstone struct {
+	Tombstone
+	Replies []Trill `json:"replies"`
+}
+
+// One page of the replies under a trill, along with the trills above it in the thread. Any of them
+// that were deleted are a Tombstone, or a ThreadTombstone among the replies.
+type Conversation struct {
+	Ancestors  []interface{} `json:"ancestors"`
+	Trill      interface{}   `json:"trill"`
+	Replies    []interface{} `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+func trillMedia(trill *models.Trill) []Media {
+	media := make([]Media, len(trill.Media))
+	for i := range trill.Media {
+		media[i] = newMedia(&trill.Media[i], trill.Sensitive)
+	}
+	return media
+}
+
+// sensitive is whether whatever the media is on was marked sensitive as a whole
+func newMedia(m *models.Media, sensitive bool) Media {
+	media := Media{
+		Type:        m.Kind,
+		Status:      m.Status,
+		AltText:     m.AltText,
+		Sensitive:   m.Sensitive || sensitive,
+		ContentType: m.ContentType,
+		Width:       m.Width,
+		Height:      m.Height,
+		DurationMs:  m.DurationMs,
+	}
+	if m.ExternalURL != "" {
+		media.URL = m.ExternalURL
+	} else if m.Kind == models.MediaKindAudio {
+		if m.Status == models.MediaStatusReady {
+			media.URL = models.ContentBucketURL + m.MP4Key
+			media.Waveform = models.ParseWaveform(m.Waveform)
+		}
+	} else if m.Kind != models.MediaKindVideo {
+		media.URL = models.ContentBucketURL + m.ObjectKey
+	} else if m.Status == models.MediaStatusReady {
+		media.URL = models.ContentBucketURL + m.MP4Key
+		media.HLSURL = models.ContentBucketURL + m.HLSKey
+	}
+	return media
+}
+
+// The @usernames in the text that were recorded as mentions when the trill was posted, under the
+// mentioned user's casing of their name
+func trillMentions(trill *models.Trill) []Mention {
+	mentioned := make(map[string]string, len(trill.Mentions))
+	for _, mention := range trill.Mentions {
+		mentioned[models.NormalizeUsername(mention.Username)] = mention.Username
+	}
+
+	mentions := []Mention{}
+	for _, match := range models.FindMentions(trill.Text) {
+		if username, ok := mentioned[models.NormalizeUsername(match.Username)]; ok {
+			mentions = append(mentions, Mention{Username: username, Start: match.Start, End: match.End})
+		}
+	}
+	return mentions
+}
+
+func trillPoll(trill *models.Trill, viewer *models.TrillViewer) *Poll {
+	if trill.Poll == nil {
+		return nil
+	}
+
+	poll := Poll{
+		Options:  make([]PollOption, len(trill.Poll.Options)),
+		ClosesAt: trill.Poll.ClosesAt,
+		Closed:   trill.Poll.Closed(),
+	}
+	if vote, ok := viewer.Votes[trill.TrillID]; ok {
+		poll.RequestorVote = &vote
+	}
+	showResults := poll.Closed || poll.RequestorVote != nil
+	if showResults {
+		poll.VoteCount = &trill.Poll.VoteCount
+	}
+	for i, option := range trill.Poll.Options {
+		poll.Options[i] = PollOption{Text: option.Text}
+		if showResults {
+			poll.Options[i].VoteCount = &trill.Poll.Options[i].VoteCount
+		}
+	}
+	return &poll
+}
+
+func trillLinkPreview(trill *models.Trill) *LinkPreview {
+	if trill.LinkPreview == nil {
+		return nil
+	}
+
+	return &LinkPreview{
+		URL:         trill.LinkPreview.URL,
+		Title:       trill.LinkPreview.Title,
+		Description: trill.LinkPreview.Description,
+		ImageURL:    trill.LinkPreview.ImageURL,
+		SiteName:    trill.LinkPreview.SiteName,
+	}
+}
+
+func trillLinks(trill *models.Trill) []Link {
+	shortened := make(map[string]*models.ShortLink, len(trill.Links))
+	for i := range trill.Links {
+		shortened[trill.Links[i].URL] = &trill.Links[i]
+	}
+
+	links := []Link{}
+	for _, match := range models.FindLinks(trill.Text) {
+		if link, ok := shortened[match.URL]; ok {
+			links = append(links, Link{URL: match.URL, ShortURL: link.ShortURL(), Start: match.Start, End: match.End, Threat: link.Threat})
+		}
+	}
+	return links
+}
+
+func newTrill(trill *models.Trill, viewer *models.TrillViewer) Trill {
+	view := Trill{
+		TrillID:             trill.TrillID,
+		User:                trill.User,
+		Text:                trill.Text,
+		Media:               trillMedia(trill),
+		Mentions:            trillMentions(trill),
+		Links:               trillLinks(trill),
+		Sensitive:           trill.Sensitive,
+		ContentWarning:      trill.ContentWarning,
+		Poll:                trillPoll(trill, viewer),
+		ReplyAudience:       trill.ReplyAudience,
+		RequestorCanReply:   viewer.CanReply[trill.TrillID],
+		LinkPreview:         trillLinkPreview(trill),
+		LinkWarning:         trill.LinkWarning,
+		ImportedFrom:        trill.ImportedFrom,
+		ParentID:            trill.ParentID,
+		ConversationID:      trill.ConversationID,
+		ReplyCount:          trill.ReplyCount,
+		RetrillCount:        trill.RetrillCount,
+		QuoteCount:          trill.QuoteCount,
+		LikeCount:           trill.LikeCount,
+		Reactions:           trillReactions(trill, viewer),
+		ViewCount:           trill.ViewCount,
+		RequestorLiked:      viewer.Liked[trill.TrillID],
+		RequestorRetrilled:  viewer.Retrilled[trill.TrillID],
+		RequestorBookmarked: viewer.Bookmarked[trill.TrillID],
+		QuoteOfID:           trill.QuoteOfID,
+		CreatedAt:           trill.CreatedAt,
+		UpdatedAt:           trill.UpdatedAt,
+		Edited:              trill.EditedAt != nil,
+		EditedAt:            trill.EditedAt,
+	}
+	for i := range view.Media {
+		view.Media[i].Blurred = view.Media[i].Sensitive && !viewer.ShowSensitiveMedia
+	}
+	if trill.RetrillOf != nil {
+		original := newTrill(trill.RetrillOf, viewer)
+		view.RetrillOf = &original
+	}
+	if trill.QuoteOf != nil && viewer.HiddenQuotes[trill.QuoteOf.TrillID] {
+		view.QuoteOf = UnavailableTrill{TrillID: trill.QuoteOf.TrillID, Unavailable: true}
+	} else if trill.QuoteOf != nil {
+		view.QuoteOf = newTrill(trill.QuoteOf, viewer)
+	}
+	return view
+}
+
+func MarshalTrill(ctx context.Context, trill *models.Trill, viewer *models.TrillViewer) (string, error) {
+	return Marshal(ctx, newTrill(trill, viewer))
+}
+
+// The trill with only the expansions asked for; requestorFollows, followsRequestor, and requestorMuted
+// describe the author and are only used when the author is expanded
+func MarshalTrillPermalink(ctx context.Context, trill *models.Trill, viewer *models.TrillViewer, expand map[string]bool,
+	requestorFollows bool, followsRequestor bool, requestorMuted bool) (string, error) {
+	permalink := TrillPermalink{Trill: newTrill(trill, viewer)}
+	if expand[ExpandAuthor] {
+		author := newPublicUser(&trill.User, requestorFollows, followsRequestor, requestorMuted)
+		permalink.Author = &author
+	}
+	for _, view := range []*Trill{&permalink.Trill, permalink.RetrillOf} {
+		if view == nil {
+			continue
+		}
+		if !expand[ExpandQuoted] {
+			view.QuoteOf = nil
+		}
+		if !expand[ExpandMedia] {
+			view.Media = nil
+		}
+	}
+
+	return Marshal(ctx, permalink)
+}
+
+func MarshalTrillPage(ctx context.Context, trills *[]models.Trill, viewer *models.TrillViewer, cursors models.Cursors) (string, error) {
+	page := TrillPage{Trills: newTrills(*trills, viewer)}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func newTrills(trillModels []models.Trill, viewer *models.TrillViewer) []Trill {
+	trills := make([]Trill, len(trillModels))
+	for i := range trillModels {
+		trills[i] = newTrill(&trillModels[i], viewer)
+		if tag, ok := viewer.HashtagReasons[trillModels[i].TrillID]; ok {
+			trills[i].Reason = &TimelineReason{Type: ReasonFollowedHashtag, Hashtag: tag, Text: "because you follow #" + tag}
+		}
+	}
+	return trills
+}
+
+func newTombstone(tombstone *models.TrillTombstone) Tombstone {
+	return Tombstone{
+		TrillID:        tombstone.TrillID,
+		Deleted:        true,
+		ParentID:       tombstone.ParentID,
+		ConversationID: tombstone.ConversationID,
+		ReplyCount:     tombstone.ReplyCount,
+		DeletedAt:      tombstone.DeletedAt,
+	}
+}
+
+// The trill, or its tombstone
+func newThreadEntry(entry *models.ThreadEntry, viewer *models.TrillViewer) interface{} {
+	if entry.Trill != nil {
+		return newTrill(entry.Trill, viewer)
+	}
+	return newTombstone(entry.Tombstone)
+}
+
+func MarshalConversation(ctx context.Context, trill *models.ThreadEntry, ancestors []models.ThreadEntry, replies []models.ThreadEntry,
+	previews map[int64][]models.Trill, viewer *models.TrillViewer, cursors models.Cursors) (string, error) {
+	conversation := Conversation{
+		Ancestors: make([]interface{}, len(ancestors)),
+		Trill:     newThreadEntry(trill, viewer),
+		Replies:   make([]interface{}, len(replies)),
+	}
+	for i := range ancestors {
+		conversation.Ancestors[i] = newThreadEntry(&ancestors[i], viewer)
+	}
+	for i := range replies {
+		reply := &replies[i]
+		preview := newTrills(previews[reply.TrillID()], viewer)
+		if reply.Trill != nil {
+			conversation.Replies[i] = ThreadReply{Trill: newTrill(reply.Trill, viewer), Replies: preview}
+		} else {
+			conversation.Replies[i] = ThreadTombstone{Tombstone: newTombstone(reply.Tombstone), Replies: preview}
+		}
+	}
+	if cursors.Next != nil {
+		conversation.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		conversation.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, conversation)
+}
+
+func UnmarshalTrillRequest(ctx context.Context, marshalledTrill string, trill *TrillRequest) error {
+	return UnmarshalRequest(ctx, marshalledTrill, trill)
+}
+
+func MarshalTrillHistory(ctx context.Context, trill *models.Trill, revisions *[]models.TrillRevision) (string, error) {
+	current := TrillRevision{Text: trill.Text, CreatedAt: trill.CreatedAt}
+	if trill.EditedAt != nil {
+		current.CreatedAt = *trill.EditedAt
+	}
+
+	history := TrillHistory{TrillID: trill.TrillID, Revisions: []TrillRevision{current}}
+	for _, revision := range *revisions {
+		history.Revisions = append(history.Revisions, TrillRevision{Text: revision.Text, CreatedAt: revision.CreatedAt})
+	}
+
+	return Marshal(ctx, history)
+}
+
+func UnmarshalThreadRequest(ctx context.Context, marshalledThread string, thread *ThreadRequest) error {
+	return UnmarshalRequest(ctx, marshalledThread, thread)
+}
+
+func UnmarshalEditTrillRequest(ctx context.Context, marshalledEdit string, edit *EditTrillRequest) error {
+	return UnmarshalRequest(ctx, marshalledEdit, edit)
+}
+
+func UnmarshalVoteRequest(ctx context.Context, marshalledVote string, vote *VoteRequest) error {
+	return UnmarshalRequest(ctx, marshalledVote, vote)
+}
@@ -2,44 +2,406 @@ package views
 
 import (
 	"context"
+	"time"
 	"trill/src/models"
+	"trill/src/pagination"
 )
 
 type FullUser struct {
-	Username         string        `json:"username"`
-	Bio              string        `json:"bio"`
-	Email            string        `json:"email,omitempty"`
-	Nickname         string        `json:"nickname"`
-	ProfilePicture   string        `json:"profile_picture"`
+	ID                      string `json:"id"`
+	Username                string `json:"username"`
+	Bio                     string `json:"bio"`
+	Email                   string `json:"email,omitempty"`
+	Nickname                string `json:"nickname"`
+	DisplayName             string `json:"display_name"`
+	Pronouns                string `json:"pronouns"`
+	ProfilePicture          string `json:"profile_picture"`
+	ProfilePictureThumbnail string `json:"profile_picture_thumbnail"`
+	BannerImage             string `json:"banner_image"`
+	Location                string `json:"location"`
+	Website                 string `json:"website"`
+	// only on the requestor's own profile
+	Birthday         string        `json:"birthday,omitempty"`
+	IsPrivate        bool          `json:"is_private"`
+	Verified         bool          `json:"verified"`
 	Following        []models.User `json:"following"`
 	Followers        []models.User `json:"followers"`
 	RequestorFollows bool          `json:"requestor_follows"`
 	FollowsRequestor bool          `json:"follows_requestor"`
+	FollowingCount   int64         `json:"following_count"`
+	FollowerCount    int64         `json:"follower_count"`
 	ReviewCount      int64         `json:"review_count"`
+	TrillCount       int64         `json:"trill_count"`
+	ViewCount        *int64        `json:"view_count,omitempty"`
+	Presence         *Presence     `json:"presence,omitempty"`
+	PinnedTrill      *Trill        `json:"pinned_trill,omitempty"`
 }
 
-func MarshalFullUser(ctx context.Context, userModel *models.User, privateCognitoUserModel *models.PrivateCognitoUser,
-	following *[]models.User, followers *[]models.User, requestorFollows bool, followsRequestor bool, reviewCount int64) (string, error) {
+// Profile visible to any authenticated user; never includes private Cognito attributes
+type PublicUser struct {
+	ID                      string    `json:"id"`
+	Username                string    `json:"username"`
+	Nickname                string    `json:"nickname"`
+	DisplayName             string    `json:"display_name"`
+	Pronouns                string    `json:"pronouns"`
+	Bio                     string    `json:"bio"`
+	ProfilePicture          string    `json:"profile_picture"`
+	ProfilePictureThumbnail string    `json:"profile_picture_thumbnail"`
+	BannerImage             string    `json:"banner_image"`
+	Location                string    `json:"location"`
+	Website                 string    `json:"website"`
+	IsPrivate               bool      `json:"is_private"`
+	Verified                bool      `json:"verified"`
+	FollowingCount          int64     `json:"following_count"`
+	FollowerCount           int64     `json:"follower_count"`
+	ReviewCount             int64     `json:"review_count"`
+	TrillCount              int64     `json:"trill_count"`
+	RequestorFollows        bool      `json:"requestor_follows"`
+	FollowsRequestor        bool      `json:"follows_requestor"`
+	RequestorMuted          bool      `json:"requestor_muted"`
+	Presence                *Presence `json:"presence,omitempty"`
+	PinnedTrill             *Trill    `json:"pinned_trill,omitempty"`
+}
+
+// Only shown to mutual followers, and only when both of them share it
+type Presence struct {
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// What a non-follower sees of a private account
+type RestrictedUser struct {
+	ID                      string `json:"id"`
+	Username                string `json:"username"`
+	Nickname                string `json:"nickname"`
+	DisplayName             string `json:"display_name"`
+	Pronouns                string `json:"pronouns"`
+	ProfilePicture          string `json:"profile_picture"`
+	ProfilePictureThumbnail string `json:"profile_picture_thumbnail"`
+	IsPrivate               bool   `json:"is_private"`
+	Verified                bool   `json:"verified"`
+	FollowingCount          int64  `json:"following_count"`
+	FollowerCount           int64  `json:"follower_count"`
+	ReviewCount             int64  `json:"review_count"`
+	TrillCount              int64  `json:"trill_count"`
+	FollowsRequestor        bool   `json:"follows_requestor"`
+	FollowRequested         bool   `json:"follow_requested"`
+}
+
+type UserSearchResults struct {
+	Users      []models.User `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// A page of followers or followed accounts
+type UserPage struct {
+	Users      []models.User `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// Users who liked or retrilled a trill, with when they did
+type EngagerPage struct {
+	Users      []Engager `json:"users"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+}
+
+// An account suggested to follow, with how many of the accounts the requestor follows follow it and how
+// many hashtags they've both used lately
+type Suggestion struct {
+	models.User
+	MutualFollows  int64 `json:"mutual_follows"`
+	SharedHashtags int64 `json:"shared_hashtags"`
+}
+
+type Suggestions struct {
+	Users []Suggestion `json:"users"`
+}
+
+type Engager struct {
+	models.User
+	EngagedAt time.Time `json:"engaged_at"`
+}
+
+type BatchUsers struct {
+	Usernames []string `json:"usernames" validate:"min=1,max=100,dive,required"`
+}
+
+type ChangeUsername struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type UploadRequest struct {
+	ContentType string `json:"content_type" validate:"required"`
+}
+
+// alt_text describes the image or video for screen readers; it can also be set when the trill is posted
+type TrillMediaUploadRequest struct {
+	ContentType string `json:"content_type" validate:"required"`
+	AltText     string `json:"alt_text" validate:"max=1000"`
+}
+
+type Upload struct {
+	UploadURL string `json:"upload_url"`
+	Key       string `json:"key"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type Verification struct {
+	Verified *bool `json:"verified" validate:"required"`
+}
+
+type Reactivation struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// Tokens from logging in, in the same shape Cognito returns them plus when the access and id tokens expire
+type Tokens struct {
+	AccessToken  string    `json:"access_token"`
+	IDToken      string    `json:"id_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int32     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type ConfirmUpload struct {
+	Key string `json:"key" validate:"required"`
+}
+
+// Fields a user is allowed to change through PATCH - /users
+type PatchUser struct {
+	Bio         *string `json:"bio"`
+	DisplayName *string `json:"display_name"`
+	Pronouns    *string `json:"pronouns"`
+	IsPrivate   *bool   `json:"is_private"`
+	Location    *string `json:"location"`
+	Website     *string `json:"website"`
+	Birthday    *string `json:"birthday"`
+}
+
+func MarshalFullUser(ctx context.Context, userModel *models.User, includeEmail bool,
+	following *[]models.User, followers *[]models.User, requestorFollows bool, followsRequestor bool, ownProfile bool, showPresence bool,
+	pinned *models.Trill, viewer *models.TrillViewer) (string, error) {
 	user := FullUser{
-		Username:         userModel.Username,
-		Nickname:         userModel.Nickname,
-		Bio:              userModel.Bio,
-		ProfilePicture:   userModel.ProfilePicture,
-		Email:            privateCognitoUserModel.Email,
-		Following:        *following,
-		Followers:        *followers,
-		RequestorFollows: requestorFollows,
-		FollowsRequestor: followsRequestor,
-		ReviewCount:      reviewCount,
+		ID:                      userModel.ID,
+		Username:                userModel.Username,
+		Nickname:                userModel.Nickname,
+		DisplayName:             userModel.DisplayName,
+		Pronouns:                userModel.Pronouns,
+		Bio:                     userModel.Bio,
+		ProfilePicture:          userModel.ProfilePicture,
+		ProfilePictureThumbnail: userModel.ProfilePictureThumbnail,
+		BannerImage:             userModel.BannerImage,
+		Location:                userModel.Location,
+		Website:                 userModel.Website,
+		IsPrivate:               userModel.IsPrivate,
+		Verified:                userModel.Verified,
+		Following:               *following,
+		Followers:               *followers,
+		RequestorFollows:        requestorFollows,
+		FollowsRequestor:        followsRequestor,
+		FollowingCount:          userModel.FollowingCount,
+		FollowerCount:           userModel.FollowerCount,
+		ReviewCount:             userModel.ReviewCount,
+		TrillCount:              userModel.TrillCount,
+	}
+	if includeEmail {
+		user.Email = userModel.Email
+	}
+	if ownProfile {
+		user.Birthday = models.FormatBirthday(userModel.Birthday)
+		user.ViewCount = &userModel.ViewCount
+	}
+	if showPresence {
+		user.Presence = newPresence(userModel)
+	}
+	user.PinnedTrill = pinnedTrill(pinned, viewer)
+
+	return Marshal(ctx, user)
+}
+
+func MarshalPublicUser(ctx context.Context, userModel *models.User, requestorFollows bool, followsRequestor bool,
+	requestorMuted bool, showPresence bool, pinned *models.Trill, viewer *models.TrillViewer) (string, error) {
+	user := newPublicUser(userModel, requestorFollows, followsRequestor, requestorMuted)
+	if showPresence {
+		user.Presence = newPresence(userModel)
+	}
+	user.PinnedTrill = pinnedTrill(pinned, viewer)
+
+	return Marshal(ctx, user)
+}
+
+func newPublicUser(userModel *models.User, requestorFollows bool, followsRequestor bool, requestorMuted bool) PublicUser {
+	return PublicUser{
+		ID:                      userModel.ID,
+		Username:                userModel.Username,
+		Nickname:                userModel.Nickname,
+		DisplayName:             userModel.DisplayName,
+		Pronouns:                userModel.Pronouns,
+		Bio:                     userModel.Bio,
+		ProfilePicture:          userModel.ProfilePicture,
+		ProfilePictureThumbnail: userModel.ProfilePictureThumbnail,
+		BannerImage:             userModel.BannerImage,
+		Location:                userModel.Location,
+		Website:                 userModel.Website,
+		IsPrivate:               userModel.IsPrivate,
+		Verified:                userModel.Verified,
+		FollowingCount:          userModel.FollowingCount,
+		FollowerCount:           userModel.FollowerCount,
+		ReviewCount:             userModel.ReviewCount,
+		TrillCount:              userModel.TrillCount,
+		RequestorFollows:        requestorFollows,
+		FollowsRequestor:        followsRequestor,
+		RequestorMuted:          requestorMuted,
+	}
+}
+
+func MarshalRestrictedUser(ctx context.Context, userModel *models.User, followsRequestor bool, followRequested bool) (string, error) {
+	user := RestrictedUser{
+		ID:                      userModel.ID,
+		Username:                userModel.Username,
+		Nickname:                userModel.Nickname,
+		DisplayName:             userModel.DisplayName,
+		Pronouns:                userModel.Pronouns,
+		ProfilePicture:          userModel.ProfilePicture,
+		ProfilePictureThumbnail: userModel.ProfilePictureThumbnail,
+		IsPrivate:               userModel.IsPrivate,
+		Verified:                userModel.Verified,
+		FollowingCount:          userModel.FollowingCount,
+		FollowerCount:           userModel.FollowerCount,
+		ReviewCount:             userModel.ReviewCount,
+		TrillCount:              userModel.TrillCount,
+		FollowsRequestor:        followsRequestor,
+		FollowRequested:         followRequested,
 	}
 
 	return Marshal(ctx, user)
 }
 
+// Left out of the profile when nothing is pinned
+func pinnedTrill(pinned *models.Trill, viewer *models.TrillViewer) *Trill {
+	if pinned == nil {
+		return nil
+	}
+	trill := newTrill(pinned, viewer)
+	return &trill
+}
+
+func newPresence(userModel *models.User) *Presence {
+	return &Presence{Online: models.IsOnline(userModel), LastSeen: userModel.LastActiveAt}
+}
+
+func MarshalUpload(ctx context.Context, uploadURL string, key string) (string, error) {
+	return Marshal(ctx, Upload{
+		UploadURL: uploadURL,
+		Key:       key,
+		ExpiresIn: int(models.PresignExpiration.Seconds()),
+	})
+}
+
+func MarshalUserSearchResults(ctx context.Context, rankedUsers *[]models.RankedUser, cursors models.Cursors) (string, error) {
+	results := UserSearchResults{Users: make([]models.User, len(*rankedUsers))}
+	for i, u := range *rankedUsers {
+		results.Users[i] = u.User
+	}
+	if cursors.Next != nil {
+		results.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		results.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, results)
+}
+
+func MarshalUserPage(ctx context.Context, users *[]models.User, cursors models.Cursors) (string, error) {
+	page := UserPage{Users: *users}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func MarshalSuggestions(ctx context.Context, suggestions *[]models.FollowSuggestion) (string, error) {
+	page := Suggestions{Users: make([]Suggestion, len(*suggestions))}
+	for i, suggestion := range *suggestions {
+		page.Users[i] = Suggestion{User: suggestion.SuggestedUser, MutualFollows: suggestion.MutualFollows, SharedHashtags: suggestion.SharedHashtags}
+	}
+
+	return Marshal(ctx, page)
+}
+
+func MarshalEngagerPage(ctx context.Context, engagers *[]models.Engager, cursors models.Cursors) (string, error) {
+	page := EngagerPage{Users: make([]Engager, len(*engagers))}
+	for i, engager := range *engagers {
+		page.Users[i] = Engager{User: engager.User, EngagedAt: engager.EngagedAt}
+	}
+	if cursors.Next != nil {
+		page.NextCursor = pagination.Encode(cursors.Next)
+	}
+	if cursors.Prev != nil {
+		page.PrevCursor = pagination.Encode(cursors.Prev)
+	}
+
+	return Marshal(ctx, page)
+}
+
+func MarshalTokens(ctx context.Context, accessToken string, idToken string, refreshToken string, expiresIn int32) (string, error) {
+	return Marshal(ctx, Tokens{
+		AccessToken:  accessToken,
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    expiresIn,
+		ExpiresAt:    time.Now().UTC().Add(time.Duration(expiresIn) * time.Second).Truncate(time.Second),
+	})
+}
+
 func MarshalUsers(ctx context.Context, userModels *[]models.User) (string, error) {
 	return Marshal(ctx, userModels)
 }
 
+func UnmarshalBatchUsers(ctx context.Context, marshalledBatch string, batch *BatchUsers) error {
+	return UnmarshalRequest(ctx, marshalledBatch, batch)
+}
+
+func UnmarshalChangeUsername(ctx context.Context, marshalledChange string, change *ChangeUsername) error {
+	return UnmarshalRequest(ctx, marshalledChange, change)
+}
+
+func UnmarshalUploadRequest(ctx context.Context, marshalledRequest string, uploadRequest *UploadRequest) error {
+	return UnmarshalRequest(ctx, marshalledRequest, uploadRequest)
+}
+
+func UnmarshalTrillMediaUploadRequest(ctx context.Context, marshalledRequest string, uploadRequest *TrillMediaUploadRequest) error {
+	return UnmarshalRequest(ctx, marshalledRequest, uploadRequest)
+}
+
+func UnmarshalVerification(ctx context.Context, marshalledVerification string, verification *Verification) error {
+	return UnmarshalRequest(ctx, marshalledVerification, verification)
+}
+
+func UnmarshalReactivation(ctx context.Context, marshalledReactivation string, reactivation *Reactivation) error {
+	return UnmarshalRequest(ctx, marshalledReactivation, reactivation)
+}
+
+func UnmarshalConfirmUpload(ctx context.Context, marshalledConfirm string, confirm *ConfirmUpload) error {
+	return UnmarshalRequest(ctx, marshalledConfirm, confirm)
+}
+
 func UnmarshalUser(ctx context.Context, marshalledUser string, userModel *models.User) error {
 	return Unmarshal(ctx, marshalledUser, userModel)
 }
+
+// Rejects any field that isn't part of PatchUser
+func UnmarshalPatchUser(ctx context.Context, marshalledPatch string, patch *PatchUser) error {
+	return UnmarshalStrictRequest(ctx, marshalledPatch, patch)
+}
@@ -0,0 +1,212 @@
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"trill/src/models"
+	"trill/src/utils"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// The body of every 400 caused by a malformed or invalid request body
+type RequestError struct {
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *RequestError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = fmt.Sprintf("%s %s", f.Field, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(messages, ", "))
+}
+
+const (
+	invalidRequestBody = "invalid request body"
+	unknownFieldPrefix = "json: unknown field "
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// report fields by the names clients actually send
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		} else if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// trill text is counted the way people read it, not in bytes or code points
+	v.RegisterValidation("trill_length", func(fl validator.FieldLevel) bool {
+		return models.TrillTextFits(fl.Field().String())
+	})
+	v.RegisterValidation("emoji", func(fl validator.FieldLevel) bool {
+		return utils.IsEmoji(fl.Field().String())
+	})
+	return v
+}
+
+// Decodes a request body and checks it against the view's validate tags, failing with a *RequestError
+func UnmarshalRequest(ctx context.Context, marshalled string, view interface{}) error {
+	if err := json.Unmarshal([]byte(marshalled), view); err != nil {
+		return decodeError(err)
+	}
+
+	return Validate(ctx, view)
+}
+
+// Like UnmarshalRequest, but also rejects any field the view doesn't have
+func UnmarshalStrictRequest(ctx context.Context, marshalled string, view interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(marshalled))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(view); err != nil {
+		return decodeError(err)
+	}
+
+	return Validate(ctx, view)
+}
+
+func Validate(ctx context.Context, view interface{}) error {
+	err := validate.StructCtx(ctx, view)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	requestErr := &RequestError{Message: invalidRequestBody, Fields: make([]FieldError, len(validationErrors))}
+	for i, fieldErr := range validationErrors {
+		requestErr.Fields[i] = FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Rule:    fieldErr.Tag(),
+			Message: ruleMessage(fieldErr),
+		}
+	}
+	return requestErr
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &RequestError{Message: invalidRequestBody, Fields: []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be a %s", jsonKind(typeErr.Type)),
+		}}}
+	}
+
+	// unknown fields only come back as a plain error from the decoder
+	if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		return &RequestError{Message: invalidRequestBody, Fields: []FieldError{{
+			Field:   strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`),
+			Rule:    "unknown",
+			Message: "is not a recognized field",
+		}}}
+	}
+
+	return &RequestError{Message: fmt.Sprintf("%s: %s", invalidRequestBody, err.Error())}
+}
+
+// Drops the struct name the validator puts at the front, e.g. BatchUsers.usernames[0] -> usernames[0]
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
+
+func ruleMessage(fieldErr validator.FieldError) string {
+	unit := ""
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	if fieldErr.Param() == "1" {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "required_if", "required_unless":
+		// the param is the other field's Go name and value, e.g. "Challenge SOFTWARE_TOKEN_MFA"
+		condition := "when"
+		if fieldErr.Tag() == "required_unless" {
+			condition = "unless"
+		}
+		if field, value, found := strings.Cut(fieldErr.Param(), " "); found {
+			return fmt.Sprintf("is required %s %s is %s", condition, strings.ToLower(field), value)
+		}
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required without %s", strings.ToLower(fieldErr.Param()))
+	case "required_without_all":
+		return fmt.Sprintf("is required without any of %s", strings.ToLower(strings.ReplaceAll(fieldErr.Param(), " ", ", ")))
+	case "min":
+		if unit == "" {
+			return fmt.Sprintf("must be at least %s", fieldErr.Param())
+		}
+		return fmt.Sprintf("must have at least %s%s", fieldErr.Param(), unit)
+	case "max":
+		if unit == "" {
+			return fmt.Sprintf("must be at most %s", fieldErr.Param())
+		}
+		return fmt.Sprintf("must have at most %s%s", fieldErr.Param(), unit)
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "unique":
+		return "must not contain duplicates"
+	case "trill_length":
+		return fmt.Sprintf("must have at most %d characters, counting each link as %d", models.MaxTrillLength(), models.TrillURLWeight)
+	case "emoji":
+		return "must be a single emoji"
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	}
+
+	return fmt.Sprintf("failed the %s check", fieldErr.Tag())
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "integer"
+}